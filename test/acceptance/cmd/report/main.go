@@ -0,0 +1,70 @@
+package main
+
+// This tool generates a static HTML summary of an acceptance test run
+// for humans reviewing nightly runs.
+//
+// Usage: go run ./cmd/report [-debug-directory <dir>] [-output <file>] <jsonfile>...
+//        Where <jsonfile> is the output of `go test -json` or gotestsum's --jsonfile,
+//        and -debug-directory is the directory passed to the tests with -debug-directory.
+//        Multiple JSON files can be passed, e.g. one per package when packages are run separately.
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	debugDirectory := flag.String("debug-directory", "", "The directory where the tests wrote debug information. "+
+		"If set, the report links each test to its debug artifacts.")
+	output := flag.String("output", "report.html", "The file to write the HTML report to.")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Println("Error: at least one JSON report file is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var events []testEvent
+	for _, path := range flag.Args() {
+		fileEvents, err := readEvents(path)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+		events = append(events, fileEvents...)
+	}
+
+	r := newReport(events)
+
+	if *debugDirectory != "" {
+		outputDir, err := filepath.Abs(filepath.Dir(*output))
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+		if err := r.linkArtifacts(*debugDirectory, outputDir); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+	if err := r.writeHTML(f); err != nil {
+		f.Close()
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+	if err := f.Close(); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Wrote report for %d tests (%d failed) to %s\n", len(r.Tests), r.Failed, *output)
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+)
+
+const (
+	statusPass    = "pass"
+	statusFail    = "fail"
+	statusSkip    = "skip"
+	statusRunning = "running"
+
+	// maxExcerptLines is the maximum number of output lines
+	// shown in the failure excerpt of a test.
+	maxExcerptLines = 40
+)
+
+// testEvent is a single event emitted by `go test -json`.
+// See https://golang.org/cmd/test2json/ for the format.
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// testResult is the result of a single test or subtest.
+type testResult struct {
+	Package string
+	Name    string
+	Status  string
+	Start   time.Time
+	Elapsed time.Duration
+	Output  []string
+
+	// ConsulImage is the Consul image the test installed
+	// when the tests ran in version canary mode.
+	ConsulImage string
+
+	// Artifacts are links to the debug artifacts of the test,
+	// relative to the report file.
+	Artifacts []string
+
+	// Offset and Width position the test on the timeline
+	// as a percentage of the whole run.
+	Offset float64
+	Width  float64
+}
+
+// Excerpt returns the part of the test output that is most useful
+// for understanding a failure. It starts at the first testify error trace
+// if there is one and otherwise returns the end of the output.
+func (r *testResult) Excerpt() string {
+	start := len(r.Output) - maxExcerptLines
+	for i, line := range r.Output {
+		if strings.Contains(line, "Error Trace:") {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + maxExcerptLines
+	if end > len(r.Output) {
+		end = len(r.Output)
+	}
+	return strings.Join(r.Output[start:end], "")
+}
+
+// FullOutput returns the whole output of the test.
+func (r *testResult) FullOutput() string {
+	return strings.Join(r.Output, "")
+}
+
+// ID returns an identifier for the test that can be used as an HTML anchor.
+func (r *testResult) ID() string {
+	return strings.NewReplacer("/", "-", ".", "-", " ", "_").Replace(r.Package + "-" + r.Name)
+}
+
+type report struct {
+	Start    time.Time
+	Duration time.Duration
+	Tests    []*testResult
+	Passed   int
+	Failed   int
+	Skipped  int
+
+	// Canary compares the results of the tests per Consul image
+	// when the tests ran in version canary mode.
+	Canary []*canaryResult
+
+	// InstallBenchmark summarizes the install times per values profile
+	// when TestInstallBenchmark ran.
+	InstallBenchmark []*installBenchmark
+}
+
+// canaryResult are the results of the tests that installed a Consul image
+// in version canary mode.
+type canaryResult struct {
+	Image       string
+	Passed      int
+	Failed      int
+	FailedTests []*testResult
+}
+
+// installBenchmark are the install times of a values profile
+// measured by TestInstallBenchmark.
+type installBenchmark struct {
+	Profile string
+	Times   []time.Duration
+}
+
+// Min returns the shortest install time.
+func (b *installBenchmark) Min() time.Duration {
+	min := b.Times[0]
+	for _, d := range b.Times {
+		if d < min {
+			min = d
+		}
+	}
+	return min
+}
+
+// Max returns the longest install time.
+func (b *installBenchmark) Max() time.Duration {
+	max := b.Times[0]
+	for _, d := range b.Times {
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// Mean returns the mean install time.
+func (b *installBenchmark) Mean() time.Duration {
+	var total time.Duration
+	for _, d := range b.Times {
+		total += d
+	}
+	return total / time.Duration(len(b.Times))
+}
+
+// readEvents reads test events from a file containing the output of `go test -json`.
+// Lines that aren't JSON, e.g. build output, are ignored.
+func readEvents(path string) ([]testEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []testEvent
+	scanner := bufio.NewScanner(f)
+	// Test output lines can be long, e.g. when a test dumps JSON config.
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var event testEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %s", path, err)
+	}
+	return events, nil
+}
+
+// logMessage returns the message of a line of test output, decoding
+// the lines that the tests log with -log-format=json.
+func logMessage(output string) string {
+	if entry, ok := logger.ParseEntry(output); ok {
+		return entry.Message
+	}
+	return output
+}
+
+// newReport builds a report out of test events.
+// Package-level events are ignored since we only report on tests.
+func newReport(events []testEvent) *report {
+	results := make(map[string]*testResult)
+	var tests []*testResult
+	benchmarks := make(map[string]*installBenchmark)
+	var installBenchmarks []*installBenchmark
+	for _, event := range events {
+		if event.Test == "" {
+			continue
+		}
+		key := event.Package + " " + event.Test
+		result, ok := results[key]
+		if !ok {
+			result = &testResult{
+				Package: event.Package,
+				Name:    event.Test,
+				Status:  statusRunning,
+				Start:   event.Time,
+			}
+			results[key] = result
+			tests = append(tests, result)
+		}
+
+		switch event.Action {
+		case "run":
+			result.Start = event.Time
+		case "output":
+			result.Output = append(result.Output, event.Output)
+			message := logMessage(event.Output)
+			if i := strings.Index(message, config.CanaryImageLogMessage); i >= 0 {
+				result.ConsulImage = strings.TrimSpace(message[i+len(config.CanaryImageLogMessage):])
+			}
+			if profile, elapsed, ok := config.ParseInstallBenchmarkResult(message); ok {
+				benchmark, ok := benchmarks[profile]
+				if !ok {
+					benchmark = &installBenchmark{Profile: profile}
+					benchmarks[profile] = benchmark
+					installBenchmarks = append(installBenchmarks, benchmark)
+				}
+				benchmark.Times = append(benchmark.Times, elapsed)
+			}
+		case statusPass, statusFail, statusSkip:
+			result.Status = event.Action
+			result.Elapsed = time.Duration(event.Elapsed * float64(time.Second))
+		}
+	}
+
+	sort.SliceStable(tests, func(i, j int) bool {
+		return tests[i].Start.Before(tests[j].Start)
+	})
+
+	r := &report{
+		Tests:            tests,
+		InstallBenchmark: installBenchmarks,
+	}
+
+	var end time.Time
+	for _, test := range tests {
+		if r.Start.IsZero() || test.Start.Before(r.Start) {
+			r.Start = test.Start
+		}
+		if testEnd := test.Start.Add(test.Elapsed); testEnd.After(end) {
+			end = testEnd
+		}
+
+		switch test.Status {
+		case statusPass:
+			r.Passed++
+		case statusFail:
+			r.Failed++
+		case statusSkip:
+			r.Skipped++
+		}
+	}
+	r.Duration = end.Sub(r.Start)
+	r.Canary = canaryResults(tests)
+
+	if r.Duration > 0 {
+		for _, test := range tests {
+			test.Offset = 100 * float64(test.Start.Sub(r.Start)) / float64(r.Duration)
+			test.Width = 100 * float64(test.Elapsed) / float64(r.Duration)
+		}
+	}
+
+	return r
+}
+
+// canaryResults groups the results of tests by the Consul image they installed
+// in version canary mode. It returns nil if the tests didn't run in that mode.
+func canaryResults(tests []*testResult) []*canaryResult {
+	byImage := make(map[string]*canaryResult)
+	var results []*canaryResult
+	for _, test := range tests {
+		if test.ConsulImage == "" {
+			continue
+		}
+		result, ok := byImage[test.ConsulImage]
+		if !ok {
+			result = &canaryResult{Image: test.ConsulImage}
+			byImage[test.ConsulImage] = result
+			results = append(results, result)
+		}
+		switch test.Status {
+		case statusPass:
+			result.Passed++
+		case statusFail:
+			result.Failed++
+			result.FailedTests = append(result.FailedTests, test)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Image < results[j].Image
+	})
+	return results
+}
+
+// linkArtifacts finds the debug artifacts of each test in debugDirectory and
+// records links to them relative to outputDir. The tests write their artifacts
+// to <debugDirectory>/<test name>/<kube context>/.
+// Only artifacts of the test itself are linked, not the ones of its subtests.
+func (r *report) linkArtifacts(debugDirectory, outputDir string) error {
+	debugDirectory, err := filepath.Abs(debugDirectory)
+	if err != nil {
+		return err
+	}
+
+	for _, test := range r.Tests {
+		testDir := filepath.Join(debugDirectory, filepath.FromSlash(test.Name))
+		contextDirs, err := ioutil.ReadDir(testDir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, contextDir := range contextDirs {
+			// Files of the test itself, such as the triage hints, are next to the context directories.
+			if !contextDir.IsDir() {
+				link, err := filepath.Rel(outputDir, filepath.Join(testDir, contextDir.Name()))
+				if err != nil {
+					return err
+				}
+				test.Artifacts = append(test.Artifacts, filepath.ToSlash(link))
+				continue
+			}
+			files, err := ioutil.ReadDir(filepath.Join(testDir, contextDir.Name()))
+			if err != nil {
+				return err
+			}
+			for _, file := range files {
+				if file.IsDir() {
+					continue
+				}
+				link, err := filepath.Rel(outputDir, filepath.Join(testDir, contextDir.Name(), file.Name()))
+				if err != nil {
+					return err
+				}
+				test.Artifacts = append(test.Artifacts, filepath.ToSlash(link))
+			}
+		}
+	}
+	return nil
+}
+
+// writeHTML renders the report as a static HTML page.
+func (r *report) writeHTML(w io.Writer) error {
+	return reportTmpl.Execute(w, r)
+}
+
+var reportTmpl = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+	"seconds": func(d time.Duration) string {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	},
+	"percent": func(f float64) string {
+		return fmt.Sprintf("%.2f%%", f)
+	},
+	"base": filepath.Base,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Acceptance test report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+td, th { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+pre { background: #f6f6f6; padding: 8px; overflow-x: auto; font-size: 12px; }
+.pass { color: #2e7d32; }
+.fail { color: #c62828; font-weight: bold; }
+.skip, .running { color: #757575; }
+.timeline { position: relative; height: 14px; width: 400px; background: #f0f0f0; }
+.bar { position: absolute; height: 14px; min-width: 1px; }
+.bar.pass { background: #66bb6a; }
+.bar.fail { background: #ef5350; }
+.bar.skip, .bar.running { background: #bdbdbd; }
+</style>
+</head>
+<body>
+<h1>Acceptance test report</h1>
+<p>
+Started {{ .Start.Format "2006-01-02 15:04:05 MST" }}, took {{ duration .Duration }}.
+<span class="pass">{{ .Passed }} passed</span>,
+<span class="fail">{{ .Failed }} failed</span>,
+<span class="skip">{{ .Skipped }} skipped</span>.
+</p>
+
+{{- if .Canary }}
+<h2>Consul version canary</h2>
+<table>
+<tr><th>Consul image</th><th>Passed</th><th>Failed</th><th>Failed tests</th></tr>
+{{- range .Canary }}
+<tr>
+<td>{{ .Image }}</td>
+<td class="pass">{{ .Passed }}</td>
+<td class="fail">{{ .Failed }}</td>
+<td>{{ range .FailedTests }}<a href="#{{ .ID }}">{{ .Name }}</a><br>{{ end }}</td>
+</tr>
+{{- end }}
+</table>
+{{- end }}
+
+{{- if .InstallBenchmark }}
+<h2>Install benchmark</h2>
+<table>
+<tr><th>Profile</th><th>Installs</th><th>Min</th><th>Mean</th><th>Max</th></tr>
+{{- range .InstallBenchmark }}
+<tr>
+<td>{{ .Profile }}</td>
+<td>{{ len .Times }}</td>
+<td>{{ seconds .Min }}</td>
+<td>{{ seconds .Mean }}</td>
+<td>{{ seconds .Max }}</td>
+</tr>
+{{- end }}
+</table>
+{{- end }}
+
+<h2>Timeline</h2>
+<table>
+<tr><th>Test</th><th>Package</th><th>Status</th><th>Duration</th><th>Timeline</th></tr>
+{{- range .Tests }}
+<tr>
+<td><a href="#{{ .ID }}">{{ .Name }}</a></td>
+<td>{{ .Package }}</td>
+<td class="{{ .Status }}">{{ .Status }}</td>
+<td>{{ duration .Elapsed }}</td>
+<td><div class="timeline"><div class="bar {{ .Status }}" style="left: {{ percent .Offset }}; width: {{ percent .Width }};"></div></div></td>
+</tr>
+{{- end }}
+</table>
+
+<h2>Tests</h2>
+{{- range .Tests }}
+<h3 id="{{ .ID }}" class="{{ .Status }}">{{ .Name }}</h3>
+<p>{{ .Package }}, {{ .Status }} after {{ duration .Elapsed }}{{ if .ConsulImage }}, installed {{ .ConsulImage }}{{ end }}</p>
+{{- if .Artifacts }}
+<p>Debug artifacts:</p>
+<ul>
+{{- range .Artifacts }}
+<li><a href="{{ . }}">{{ base . }}</a></li>
+{{- end }}
+</ul>
+{{- end }}
+{{- if eq .Status "fail" }}
+<p>Failure excerpt:</p>
+<pre>{{ .Excerpt }}</pre>
+{{- end }}
+<details>
+<summary>Full output</summary>
+<pre>{{ .FullOutput }}</pre>
+</details>
+{{- end }}
+</body>
+</html>
+`))
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testEvents = `go: downloading github.com/hashicorp/consul/api v1.4.1-0.20201015173526-3be2c8d0e4fb
+{"Time":"2021-04-01T10:00:00Z","Action":"run","Package":"github.com/hashicorp/consul-helm/test/acceptance/tests/basic","Test":"TestBasicInstallation"}
+{"Time":"2021-04-01T10:00:00Z","Action":"run","Package":"github.com/hashicorp/consul-helm/test/acceptance/tests/basic","Test":"TestBasicInstallation/secure:_true"}
+{"Time":"2021-04-01T10:00:01Z","Action":"output","Package":"github.com/hashicorp/consul-helm/test/acceptance/tests/basic","Test":"TestBasicInstallation/secure:_true","Output":"    logger.go:19: installing consul\n"}
+{"Time":"2021-04-01T10:01:00Z","Action":"output","Package":"github.com/hashicorp/consul-helm/test/acceptance/tests/basic","Test":"TestBasicInstallation/secure:_true","Output":"        \tError Trace:\tbasic_installation_test.go:60\n"}
+{"Time":"2021-04-01T10:01:00Z","Action":"output","Package":"github.com/hashicorp/consul-helm/test/acceptance/tests/basic","Test":"TestBasicInstallation/secure:_true","Output":"        \tError:      \tReceived unexpected error: <nil>\n"}
+{"Time":"2021-04-01T10:01:00Z","Action":"fail","Package":"github.com/hashicorp/consul-helm/test/acceptance/tests/basic","Test":"TestBasicInstallation/secure:_true","Elapsed":60}
+{"Time":"2021-04-01T10:01:00Z","Action":"fail","Package":"github.com/hashicorp/consul-helm/test/acceptance/tests/basic","Test":"TestBasicInstallation","Elapsed":60}
+{"Time":"2021-04-01T10:01:00Z","Action":"run","Package":"github.com/hashicorp/consul-helm/test/acceptance/tests/basic","Test":"TestEnterpriseLicense"}
+{"Time":"2021-04-01T10:01:00Z","Action":"output","Package":"github.com/hashicorp/consul-helm/test/acceptance/tests/basic","Test":"TestEnterpriseLicense","Output":"    enterprise_license_test.go:25: skipping this test because -enable-enterprise is not set\n"}
+{"Time":"2021-04-01T10:01:00Z","Action":"skip","Package":"github.com/hashicorp/consul-helm/test/acceptance/tests/basic","Test":"TestEnterpriseLicense","Elapsed":0}
+{"Time":"2021-04-01T10:01:00Z","Action":"run","Package":"github.com/hashicorp/consul-helm/test/acceptance/tests/basic","Test":"TestBasicUpgrade"}
+{"Time":"2021-04-01T10:02:00Z","Action":"pass","Package":"github.com/hashicorp/consul-helm/test/acceptance/tests/basic","Test":"TestBasicUpgrade","Elapsed":60}
+{"Time":"2021-04-01T10:02:00Z","Action":"fail","Package":"github.com/hashicorp/consul-helm/test/acceptance/tests/basic","Elapsed":120}
+`
+
+func TestReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	jsonFile := filepath.Join(dir, "jsonfile")
+	require.NoError(t, ioutil.WriteFile(jsonFile, []byte(testEvents), 0644))
+
+	// Create debug artifacts for the failed subtest.
+	debugDir := filepath.Join(dir, "debug")
+	contextDir := filepath.Join(debugDir, "TestBasicInstallation", "secure:_true", "kind-dc1")
+	require.NoError(t, os.MkdirAll(contextDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(contextDir, "consul-server-0.log"), []byte("log"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(debugDir, "TestBasicInstallation", "secure:_true", "triage.txt"), []byte("hints"), 0644))
+
+	events, err := readEvents(jsonFile)
+	require.NoError(t, err)
+
+	r := newReport(events)
+	require.NoError(t, r.linkArtifacts(debugDir, dir))
+
+	require.Len(t, r.Tests, 4)
+	require.Equal(t, 1, r.Passed)
+	require.Equal(t, 2, r.Failed)
+	require.Equal(t, 1, r.Skipped)
+	require.Equal(t, "2m0s", r.Duration.String())
+	require.Empty(t, r.Canary)
+	require.Empty(t, r.InstallBenchmark)
+
+	subtest := r.Tests[1]
+	require.Equal(t, "TestBasicInstallation/secure:_true", subtest.Name)
+	require.Equal(t, statusFail, subtest.Status)
+	require.Equal(t, []string{
+		"debug/TestBasicInstallation/secure:_true/kind-dc1/consul-server-0.log",
+		"debug/TestBasicInstallation/secure:_true/triage.txt",
+	}, subtest.Artifacts)
+	require.True(t, strings.HasPrefix(subtest.Excerpt(), "        \tError Trace:"))
+	require.Equal(t, float64(0), subtest.Offset)
+	require.Equal(t, float64(50), subtest.Width)
+
+	upgrade := r.Tests[3]
+	require.Equal(t, "TestBasicUpgrade", upgrade.Name)
+	require.Equal(t, float64(50), upgrade.Offset)
+	require.Empty(t, upgrade.Artifacts)
+
+	var buf bytes.Buffer
+	require.NoError(t, r.writeHTML(&buf))
+	html := buf.String()
+	require.Contains(t, html, `<a href="debug/TestBasicInstallation/secure:_true/kind-dc1/consul-server-0.log">consul-server-0.log</a>`)
+	require.Contains(t, html, "Received unexpected error: &lt;nil&gt;")
+	require.Contains(t, html, "left: 50.00%; width: 50.00%;")
+	require.NotContains(t, html, "Consul version canary")
+}
+
+func TestExcerpt(t *testing.T) {
+	var output []string
+	for i := 0; i < 100; i++ {
+		output = append(output, "line\n")
+	}
+
+	cases := map[string]struct {
+		output       []string
+		expStartLine string
+		expLines     int
+	}{
+		"no error trace": {
+			output:       append(output, "last\n"),
+			expStartLine: "line\n",
+			expLines:     maxExcerptLines,
+		},
+		"error trace": {
+			output:       append([]string{"start\n", "Error Trace: foo.go:1\n"}, output...),
+			expStartLine: "Error Trace: foo.go:1\n",
+			expLines:     maxExcerptLines,
+		},
+		"short output": {
+			output:       []string{"a\n", "b\n"},
+			expStartLine: "a\n",
+			expLines:     2,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			result := testResult{Output: c.output}
+			excerpt := result.Excerpt()
+			require.True(t, strings.HasPrefix(excerpt, c.expStartLine))
+			require.Equal(t, c.expLines, strings.Count(excerpt, "\n"))
+		})
+	}
+}
+
+func TestReport_Canary(t *testing.T) {
+	events := []testEvent{
+		{Action: "run", Test: "TestA"},
+		{Action: "output", Test: "TestA", Output: "    logger.go:19: 2021-04-01T10:00:00Z consul version canary: installing Consul image hashicorp/consul:1.10.0\n"},
+		{Action: "pass", Test: "TestA"},
+		{Action: "run", Test: "TestB"},
+		{Action: "output", Test: "TestB", Output: "    logger.go:19: 2021-04-01T10:00:00Z consul version canary: installing Consul image hashicorp/consul:1.9.5\n"},
+		{Action: "fail", Test: "TestB"},
+		{Action: "run", Test: "TestC"},
+		{Action: "output", Test: "TestC", Output: "    logger.go:19: 2021-04-01T10:00:00Z consul version canary: installing Consul image hashicorp/consul:1.9.5\n"},
+		{Action: "pass", Test: "TestC"},
+		{Action: "run", Test: "TestD"},
+		{Action: "skip", Test: "TestD"},
+	}
+
+	r := newReport(events)
+	require.Len(t, r.Canary, 2)
+
+	require.Equal(t, "hashicorp/consul:1.10.0", r.Canary[0].Image)
+	require.Equal(t, 1, r.Canary[0].Passed)
+	require.Equal(t, 0, r.Canary[0].Failed)
+
+	require.Equal(t, "hashicorp/consul:1.9.5", r.Canary[1].Image)
+	require.Equal(t, 1, r.Canary[1].Passed)
+	require.Equal(t, 1, r.Canary[1].Failed)
+	require.Len(t, r.Canary[1].FailedTests, 1)
+	require.Equal(t, "TestB", r.Canary[1].FailedTests[0].Name)
+
+	var buf bytes.Buffer
+	require.NoError(t, r.writeHTML(&buf))
+	require.Contains(t, buf.String(), "<h2>Consul version canary</h2>")
+	require.Contains(t, buf.String(), "installed hashicorp/consul:1.9.5")
+}
+
+func TestReport_InstallBenchmark(t *testing.T) {
+	events := []testEvent{
+		{Action: "run", Test: "TestInstallBenchmark/minimal/1"},
+		{Action: "output", Test: "TestInstallBenchmark/minimal/1", Output: "    logger.go:19: 2021-04-01T10:00:00Z install benchmark: minimal 1m0s\n"},
+		{Action: "pass", Test: "TestInstallBenchmark/minimal/1"},
+		{Action: "run", Test: "TestInstallBenchmark/minimal/2"},
+		{Action: "output", Test: "TestInstallBenchmark/minimal/2", Output: "    logger.go:19: 2021-04-01T10:00:00Z install benchmark: minimal 1m30s\n"},
+		{Action: "pass", Test: "TestInstallBenchmark/minimal/2"},
+		{Action: "run", Test: "TestInstallBenchmark/secure/1"},
+		{Action: "output", Test: "TestInstallBenchmark/secure/1", Output: "    logger.go:19: 2021-04-01T10:00:00Z install benchmark: secure 2m0.5s\n"},
+		{Action: "pass", Test: "TestInstallBenchmark/secure/1"},
+	}
+
+	r := newReport(events)
+	require.Len(t, r.InstallBenchmark, 2)
+
+	minimal := r.InstallBenchmark[0]
+	require.Equal(t, "minimal", minimal.Profile)
+	require.Len(t, minimal.Times, 2)
+	require.Equal(t, time.Minute, minimal.Min())
+	require.Equal(t, 75*time.Second, minimal.Mean())
+	require.Equal(t, 90*time.Second, minimal.Max())
+
+	require.Equal(t, "secure", r.InstallBenchmark[1].Profile)
+
+	var buf bytes.Buffer
+	require.NoError(t, r.writeHTML(&buf))
+	require.Contains(t, buf.String(), "<h2>Install benchmark</h2>")
+	require.Contains(t, buf.String(), "<td>75.0s</td>")
+	require.Contains(t, buf.String(), "<td>120.5s</td>")
+}
+
+// Test that the markers are found in the output of tests run with -log-format=json.
+func TestReport_JSONLogFormat(t *testing.T) {
+	events := []testEvent{
+		{Action: "run", Test: "TestA"},
+		{Action: "output", Test: "TestA", Output: `    logger.go:106: {"time":"2021-04-01T10:00:00Z","test":"TestA","phase":"test","message":"consul version canary: installing Consul image hashicorp/consul:1.10.0"}` + "\n"},
+		{Action: "pass", Test: "TestA"},
+		{Action: "run", Test: "TestInstallBenchmark/minimal/1"},
+		{Action: "output", Test: "TestInstallBenchmark/minimal/1", Output: `    logger.go:106: {"time":"2021-04-01T10:00:00Z","test":"TestInstallBenchmark/minimal/1","phase":"test","message":"install benchmark: minimal 1m0s"}` + "\n"},
+		{Action: "pass", Test: "TestInstallBenchmark/minimal/1"},
+	}
+
+	r := newReport(events)
+	require.Len(t, r.Canary, 1)
+	require.Equal(t, "hashicorp/consul:1.10.0", r.Canary[0].Image)
+	require.Len(t, r.InstallBenchmark, 1)
+	require.Equal(t, "minimal", r.InstallBenchmark[0].Profile)
+	require.Equal(t, []time.Duration{time.Minute}, r.InstallBenchmark[0].Times)
+}
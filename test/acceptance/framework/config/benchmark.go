@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// InstallBenchmarkLogMessage is logged by TestInstallBenchmark, followed by
+// the values profile and the install time, for every installation it measures
+// so that the test report can summarize the install times per profile.
+const InstallBenchmarkLogMessage = "install benchmark: "
+
+// InstallBenchmarkResult formats the log line for an installation
+// of profile that took elapsed to become ready.
+func InstallBenchmarkResult(profile string, elapsed time.Duration) string {
+	return fmt.Sprintf("%s%s %s", InstallBenchmarkLogMessage, profile, elapsed.Round(time.Millisecond))
+}
+
+// ParseInstallBenchmarkResult returns the profile and install time
+// from a line of test output logged with InstallBenchmarkResult.
+// It returns false if the line doesn't contain a result.
+func ParseInstallBenchmarkResult(line string) (string, time.Duration, bool) {
+	i := strings.Index(line, InstallBenchmarkLogMessage)
+	if i < 0 {
+		return "", 0, false
+	}
+	fields := strings.Fields(line[i+len(InstallBenchmarkLogMessage):])
+	if len(fields) != 2 {
+		return "", 0, false
+	}
+	elapsed, err := time.ParseDuration(fields[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return fields[0], elapsed, true
+}
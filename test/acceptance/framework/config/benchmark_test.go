@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallBenchmarkResult(t *testing.T) {
+	line := "    logger.go:19: 2021-04-01T10:00:00Z " + InstallBenchmarkResult("secure", 83*time.Second+123456*time.Microsecond) + "\n"
+
+	profile, elapsed, ok := ParseInstallBenchmarkResult(line)
+	require.True(t, ok)
+	require.Equal(t, "secure", profile)
+	require.Equal(t, 83*time.Second+123*time.Millisecond, elapsed)
+
+	for _, line := range []string{
+		"    logger.go:19: installing consul\n",
+		"    logger.go:19: install benchmark: secure\n",
+		"    logger.go:19: install benchmark: secure soon\n",
+	} {
+		_, _, ok := ParseInstallBenchmarkResult(line)
+		require.False(t, ok, line)
+	}
+}
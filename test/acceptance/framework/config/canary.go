@@ -0,0 +1,170 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CanaryImageLogMessage is logged by HelmCluster, followed by the Consul image,
+// when it installs Consul in version canary mode, so that the test report
+// can group the test results by Consul image.
+const CanaryImageLogMessage = "consul version canary: installing Consul image "
+
+// dockerHubTagsURL is the Docker Hub API endpoint listing the tags of a repository.
+// It is a variable so that tests can point it at a test server.
+var dockerHubTagsURL = "https://hub.docker.com/v2/repositories/%s/tags?page_size=100&name=%s"
+
+// ResolveCanaryImages returns the Consul images to use in version canary mode:
+// the current image and the latest patch release of the previous minor version.
+// The current image is the -consul-image if it's set or the image of the
+// chart's appVersion otherwise. The previous minor version is looked up on Docker Hub.
+func (t *TestConfig) ResolveCanaryImages() ([]string, error) {
+	current := t.ConsulImage
+	if current == "" {
+		var err error
+		current, err = t.chartConsulImage()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	repo, tag, err := splitImage(current)
+	if err != nil {
+		return nil, err
+	}
+	version, err := parseImageVersion(tag)
+	if err != nil {
+		return nil, fmt.Errorf("parsing version of %s: %s", current, err)
+	}
+	if version.minor == 0 {
+		return nil, fmt.Errorf("%s has no previous minor version", current)
+	}
+	prefix := fmt.Sprintf("%d.%d.", version.major, version.minor-1)
+
+	tags, err := dockerHubTags(repo, prefix)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := latestPatchTag(tags, prefix, version.suffix)
+	if err != nil {
+		return nil, fmt.Errorf("finding previous minor version of %s: %s", current, err)
+	}
+
+	return []string{current, fmt.Sprintf("%s:%s", repo, previous)}, nil
+}
+
+// chartConsulImage returns the Consul image matching the appVersion of the chart.
+func (t *TestConfig) chartConsulImage() (string, error) {
+	if t.EnableEnterprise {
+		return t.entImage()
+	}
+	appVersion, err := t.chartAppVersion()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("hashicorp/consul:%s", appVersion), nil
+}
+
+// imageVersion is the version in a Consul image tag, e.g. 1.9.5 or 1.9.5-ent.
+type imageVersion struct {
+	major, minor, patch int
+	// suffix is "-ent" for enterprise images and empty otherwise.
+	// Pre-release suffixes such as -beta1 are dropped.
+	suffix string
+}
+
+// parseImageVersion parses an image tag of the form major.minor.patch[-ent][-prerelease].
+func parseImageVersion(tag string) (imageVersion, error) {
+	var version imageVersion
+	parts := strings.SplitN(tag, "-", 2)
+	if len(parts) == 2 && strings.HasPrefix(parts[1], "ent") {
+		version.suffix = "-ent"
+	}
+
+	numbers := strings.Split(parts[0], ".")
+	if len(numbers) != 3 {
+		return version, fmt.Errorf("expected a version of the form major.minor.patch, got %q", tag)
+	}
+	ints := make([]int, 3)
+	for i, n := range numbers {
+		var err error
+		ints[i], err = strconv.Atoi(n)
+		if err != nil {
+			return version, fmt.Errorf("expected a version of the form major.minor.patch, got %q", tag)
+		}
+	}
+	version.major, version.minor, version.patch = ints[0], ints[1], ints[2]
+	return version, nil
+}
+
+// latestPatchTag returns the tag with the highest patch version out of the tags
+// that are exactly prefix followed by a patch version and suffix.
+// Pre-releases don't match, so they are never returned.
+func latestPatchTag(tags []string, prefix, suffix string) (string, error) {
+	latest := -1
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, prefix) || !strings.HasSuffix(tag, suffix) {
+			continue
+		}
+		patch, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(tag, prefix), suffix))
+		if err != nil {
+			continue
+		}
+		if patch > latest {
+			latest = patch
+		}
+	}
+	if latest < 0 {
+		return "", fmt.Errorf("no tags matching %sX%s", prefix, suffix)
+	}
+	return fmt.Sprintf("%s%d%s", prefix, latest, suffix), nil
+}
+
+// splitImage splits an image into its repository and tag.
+func splitImage(image string) (string, string, error) {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return "", "", fmt.Errorf("image %s has no tag", image)
+	}
+	return image[:i], image[i+1:], nil
+}
+
+// dockerHubTags returns the tags of the Docker Hub repository repo that contain name.
+func dockerHubTags(repo, name string) ([]string, error) {
+	// Official images such as consul live in the library namespace.
+	if !strings.Contains(repo, "/") {
+		repo = "library/" + repo
+	}
+
+	var tags []string
+	next := fmt.Sprintf(dockerHubTagsURL, repo, url.QueryEscape(name))
+	for next != "" {
+		resp, err := http.Get(next)
+		if err != nil {
+			return nil, fmt.Errorf("listing tags of %s: %s", repo, err)
+		}
+		var page struct {
+			Next    string `json:"next"`
+			Results []struct {
+				Name string `json:"name"`
+			} `json:"results"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("listing tags of %s: unexpected status %s", repo, resp.Status)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("listing tags of %s: %s", repo, err)
+		}
+		for _, result := range page.Results {
+			tags = append(tags, result.Name)
+		}
+		next = page.Next
+	}
+	return tags, nil
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImageVersion(t *testing.T) {
+	cases := map[string]struct {
+		tag     string
+		version imageVersion
+		expErr  string
+	}{
+		"release": {
+			tag:     "1.9.5",
+			version: imageVersion{major: 1, minor: 9, patch: 5},
+		},
+		"pre-release": {
+			tag:     "1.10.0-beta2",
+			version: imageVersion{major: 1, minor: 10, patch: 0},
+		},
+		"enterprise": {
+			tag:     "1.10.0-ent-beta2",
+			version: imageVersion{major: 1, minor: 10, patch: 0, suffix: "-ent"},
+		},
+		"not a version": {
+			tag:    "latest",
+			expErr: `expected a version of the form major.minor.patch, got "latest"`,
+		},
+		"minor version only": {
+			tag:    "1.9",
+			expErr: `expected a version of the form major.minor.patch, got "1.9"`,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			version, err := parseImageVersion(c.tag)
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.version, version)
+		})
+	}
+}
+
+func TestLatestPatchTag(t *testing.T) {
+	tags := []string{"1.9.0", "1.9.10", "1.9.2", "1.9.11-rc1", "1.9.3-ent", "1.9", "1.90.0"}
+
+	tag, err := latestPatchTag(tags, "1.9.", "")
+	require.NoError(t, err)
+	require.Equal(t, "1.9.10", tag)
+
+	tag, err = latestPatchTag(tags, "1.9.", "-ent")
+	require.NoError(t, err)
+	require.Equal(t, "1.9.3-ent", tag)
+
+	_, err = latestPatchTag(tags, "1.8.", "")
+	require.EqualError(t, err, "no tags matching 1.8.X")
+}
+
+func TestSplitImage(t *testing.T) {
+	repo, tag, err := splitImage("localhost:5000/hashicorp/consul:1.9.5")
+	require.NoError(t, err)
+	require.Equal(t, "localhost:5000/hashicorp/consul", repo)
+	require.Equal(t, "1.9.5", tag)
+
+	_, _, err = splitImage("localhost:5000/hashicorp/consul")
+	require.EqualError(t, err, "image localhost:5000/hashicorp/consul has no tag")
+}
+
+func TestResolveCanaryImages(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `{"next": null, "results": [{"name": "1.9.5"}]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"next": "http://%s%s&page=2", "results": [{"name": "1.9.4"}, {"name": "1.9.6-rc1"}]}`, r.Host, r.URL.RequestURI())
+	}))
+	defer server.Close()
+
+	oldURL := dockerHubTagsURL
+	dockerHubTagsURL = server.URL + "/v2/repositories/%s/tags?page_size=100&name=%s"
+	defer func() { dockerHubTagsURL = oldURL }()
+
+	cfg := TestConfig{ConsulImage: "hashicorp/consul:1.10.0-beta2"}
+	images, err := cfg.ResolveCanaryImages()
+	require.NoError(t, err)
+	require.Equal(t, []string{"hashicorp/consul:1.10.0-beta2", "hashicorp/consul:1.9.5"}, images)
+	require.Equal(t, []string{
+		"/v2/repositories/hashicorp/consul/tags?page_size=100&name=1.9.",
+		"/v2/repositories/hashicorp/consul/tags?page_size=100&name=1.9.&page=2",
+	}, requests)
+}
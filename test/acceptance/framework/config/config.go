@@ -1,19 +1,34 @@
 package config
 
 import (
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
-// The path to the helm chart.
+// The path to the local helm chart.
 // Note: this will need to be changed if this file is moved.
 const HelmChartPath = "../../../.."
 
+// ReleasedHelmChart is the reference of the released chart
+// in the HashiCorp Helm repo that upgrade tests upgrade from.
+const ReleasedHelmChart = "hashicorp/consul"
+
+// The name and key of the Kubernetes secret that is created
+// from the enterprise license file at EnterpriseLicensePath.
+const (
+	LicenseSecretName = "consul-ent-license"
+	LicenseSecretKey  = "key"
+)
+
 // TestConfig holds configuration for the test suite
 type TestConfig struct {
 	Kubeconfig    string
@@ -25,9 +40,17 @@ type TestConfig struct {
 	SecondaryKubeContext   string
 	SecondaryKubeNamespace string
 
+	// AdditionalKubeEnvs configures any Kubernetes clusters
+	// beyond the primary and secondary ones.
+	AdditionalKubeEnvs []KubeEnv
+
 	EnableEnterprise            bool
 	EnterpriseLicenseSecretName string
 	EnterpriseLicenseSecretKey  string
+	// EnterpriseLicensePath is the path to a file with the enterprise license.
+	// If set, HelmCluster creates the license secret from it in the namespace
+	// it installs Consul into.
+	EnterpriseLicensePath string
 
 	EnableOpenshift bool
 
@@ -35,13 +58,150 @@ type TestConfig struct {
 
 	ConsulImage    string
 	ConsulK8SImage string
+	EnvoyImage     string
+
+	// ConsulVersionCanary enables version canary mode, see CanaryImages.
+	ConsulVersionCanary bool
+	// CanaryImages are the current and previous minor Consul images in
+	// version canary mode. Each test case installs one of them, so that
+	// about half of the tests run against each version.
+	CanaryImages []string
+
+	// UseLocalRegistry deploys a registry into each cluster before the tests run
+	// and replaces the images above with copies pushed into it.
+	UseLocalRegistry bool
+
+	// ConsulImages are the Consul images that tests using
+	// consul.RunForEachConsulImage run against.
+	ConsulImages []string
 
 	NoCleanupOnFailure bool
 	DebugDirectory     string
 
+	// LogFormat is the format of the test logs, see logger.SetFormat.
+	LogFormat string
+
+	// RunTags are the tags that a test must have to run, see helpers.SkipUnlessTag.
+	// If empty, all tests run.
+	RunTags []string
+
+	// Matrix selects the values of the dimensions of test matrices
+	// that run, see helpers.SkipUnlessInMatrix. If empty, all values run.
+	Matrix Matrix
+
+	// ReportDirectory is the directory where the JUnit XML report and the
+	// JSON summary of each test package are written. If empty, they aren't written.
+	ReportDirectory string
+
+	// Strict fails tests if the Consul servers, clients, or connect injector
+	// log errors that the test doesn't allow, see Cluster.AllowErrorLogs.
+	Strict bool
+
+	EnableClusterStateCheck bool
+
+	ForceDeleteStuckResources bool
+
+	UpdateGoldenFiles bool
+
+	// InstallBenchmarkIterations is the number of times TestInstallBenchmark
+	// installs each values profile. The benchmark is skipped if it's 0.
+	InstallBenchmarkIterations int
+
+	// MaxLeaderUnavailability is the longest time that the servers may be
+	// without a leader while tests roll them during an upgrade.
+	MaxLeaderUnavailability time.Duration
+
+	// ResumeFile is the state file where the tests that pass are recorded.
+	// If empty, they aren't recorded.
+	ResumeFile string
+	// Resume skips the tests recorded in ResumeFile as passed
+	// with the same configuration, see Fingerprint.
+	Resume bool
+
 	UseKind bool
 
-	helmChartPath string
+	// Provider is the name of the provider used to create Kubernetes clusters
+	// for the test run, e.g. "kind" or "eks". If empty, the tests run against
+	// existing clusters.
+	Provider   string
+	Nodes      int
+	AWSRegion  string
+	AWSProfile string
+	GCPProject string
+	GCPZone    string
+
+	AzureResourceGroup string
+	AzureLocation      string
+
+	// ClusterName is the name of the cluster created by Provider for the
+	// default context. It is empty if the tests run against existing clusters.
+	ClusterName string
+
+	// HelmChartPath is the chart to install. It can be a path to a chart
+	// directory or packaged chart, or a chart reference such as hashicorp/consul.
+	// If empty, the local chart at HelmChartPath is used.
+	HelmChartPath string
+	// HelmChartVersion is the version of the chart to install
+	// when HelmChartPath is a chart reference.
+	HelmChartVersion string
+
+	// UpgradeFromChartVersion is the version of ReleasedHelmChart that upgrade
+	// tests install before upgrading to the chart under test.
+	// If empty, the upgrade tests are skipped.
+	UpgradeFromChartVersion string
+}
+
+// KubeEnv holds the configuration of a single Kubernetes cluster
+// that the tests run against.
+type KubeEnv struct {
+	Kubeconfig    string
+	KubeContext   string
+	KubeNamespace string
+}
+
+// KubeEnvs returns the configuration of all Kubernetes clusters the tests
+// run against in order: the primary cluster, the secondary cluster if
+// multi-cluster tests are enabled, and then any additional clusters.
+func (t *TestConfig) KubeEnvs() []KubeEnv {
+	envs := []KubeEnv{
+		{
+			Kubeconfig:    t.Kubeconfig,
+			KubeContext:   t.KubeContext,
+			KubeNamespace: t.KubeNamespace,
+		},
+	}
+
+	if t.EnableMultiCluster {
+		envs = append(envs, KubeEnv{
+			Kubeconfig:    t.SecondaryKubeconfig,
+			KubeContext:   t.SecondaryKubeContext,
+			KubeNamespace: t.SecondaryKubeNamespace,
+		})
+		envs = append(envs, t.AdditionalKubeEnvs...)
+	}
+
+	return envs
+}
+
+// Fingerprint identifies the parts of the configuration that change what the tests
+// test, i.e. the chart, the images, and which features are enabled, but not
+// the clusters the tests run against.
+func (t *TestConfig) Fingerprint() string {
+	parts := []string{
+		t.ChartPath(),
+		t.HelmChartVersion,
+		t.UpgradeFromChartVersion,
+		t.ConsulImage,
+		t.ConsulK8SImage,
+		t.EnvoyImage,
+		strings.Join(t.ConsulImages, ","),
+		fmt.Sprintf("enterprise=%t", t.EnableEnterprise),
+		fmt.Sprintf("multi-cluster=%t", t.EnableMultiCluster),
+		fmt.Sprintf("openshift=%t", t.EnableOpenshift),
+		fmt.Sprintf("psp=%t", t.EnablePodSecurityPolicies),
+		fmt.Sprintf("canary=%t", t.ConsulVersionCanary),
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(parts, "\n"))))[:12]
 }
 
 // HelmValuesFromConfig returns a map of Helm values
@@ -74,19 +234,41 @@ func (t *TestConfig) HelmValuesFromConfig() (map[string]string, error) {
 
 	setIfNotEmpty(helmValues, "global.image", t.ConsulImage)
 	setIfNotEmpty(helmValues, "global.imageK8S", t.ConsulK8SImage)
+	setIfNotEmpty(helmValues, "global.imageEnvoy", t.EnvoyImage)
 
 	return helmValues, nil
 }
 
+// ChartPath returns the chart to pass to helm install and helm upgrade.
+func (t *TestConfig) ChartPath() string {
+	if t.HelmChartPath == "" {
+		return HelmChartPath
+	}
+	return t.HelmChartPath
+}
+
 // entImage parses out consul version from Chart.yaml
 // and sets global.image to the consul enterprise image with that version.
 func (t *TestConfig) entImage() (string, error) {
-	if t.helmChartPath == "" {
-		t.helmChartPath = HelmChartPath
+	appVersion, err := t.chartAppVersion()
+	if err != nil {
+		return "", err
+	}
+
+	var preRelease string
+	// Handle versions like 1.9.0-rc1.
+	if strings.Contains(appVersion, "-") {
+		split := strings.Split(appVersion, "-")
+		appVersion = split[0]
+		preRelease = fmt.Sprintf("-%s", split[1])
 	}
 
-	// Unmarshal Chart.yaml to get appVersion (i.e. Consul version)
-	chart, err := ioutil.ReadFile(filepath.Join(t.helmChartPath, "Chart.yaml"))
+	return fmt.Sprintf("hashicorp/consul-enterprise:%s-ent%s", appVersion, preRelease), nil
+}
+
+// chartAppVersion returns the appVersion (i.e. Consul version) from Chart.yaml.
+func (t *TestConfig) chartAppVersion() (string, error) {
+	chart, err := t.chartYAML()
 	if err != nil {
 		return "", err
 	}
@@ -101,15 +283,27 @@ func (t *TestConfig) entImage() (string, error) {
 	if !ok {
 		return "", errors.New("unable to cast chartMap.appVersion to string")
 	}
-	var preRelease string
-	// Handle versions like 1.9.0-rc1.
-	if strings.Contains(appVersion, "-") {
-		split := strings.Split(appVersion, "-")
-		appVersion = split[0]
-		preRelease = fmt.Sprintf("-%s", split[1])
+	return appVersion, nil
+}
+
+// chartYAML returns the contents of Chart.yaml of the chart under test.
+// Chart.yaml is read directly from chart directories. For packaged charts
+// and chart references, it is read with helm show chart.
+func (t *TestConfig) chartYAML() ([]byte, error) {
+	chartPath := t.ChartPath()
+	if info, err := os.Stat(chartPath); err == nil && info.IsDir() {
+		return ioutil.ReadFile(filepath.Join(chartPath, "Chart.yaml"))
 	}
 
-	return fmt.Sprintf("hashicorp/consul-enterprise:%s-ent%s", appVersion, preRelease), nil
+	args := []string{"show", "chart", chartPath}
+	if t.HelmChartVersion != "" {
+		args = append(args, "--version", t.HelmChartVersion)
+	}
+	out, err := exec.Command("helm", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Chart.yaml of %s: %s", chartPath, err)
+	}
+	return out, nil
 }
 
 // setIfNotEmpty sets key to val in map m if value is not empty
@@ -35,6 +35,13 @@ func TestConfig_HelmValuesFromConfig(t *testing.T) {
 			},
 			map[string]string{"global.imageK8S": "consul-k8s:test-version"},
 		},
+		{
+			"sets envoy image",
+			TestConfig{
+				EnvoyImage: "envoy:test-version",
+			},
+			map[string]string{"global.imageEnvoy": "envoy:test-version"},
+		},
 		{
 			"sets both images",
 			TestConfig{
@@ -137,7 +144,7 @@ appVersion: %s
 
 			cfg := TestConfig{
 				EnableEnterprise: true,
-				helmChartPath:    tmp,
+				HelmChartPath:    tmp,
 			}
 			values, err := cfg.HelmValuesFromConfig()
 			if tt.expErr != "" {
@@ -149,3 +156,54 @@ appVersion: %s
 		})
 	}
 }
+
+func TestConfig_KubeEnvs(t *testing.T) {
+	cfg := TestConfig{
+		Kubeconfig:           "primary-config",
+		KubeContext:          "primary",
+		SecondaryKubeContext: "secondary",
+		AdditionalKubeEnvs: []KubeEnv{
+			{KubeContext: "third"},
+		},
+	}
+
+	// Only the primary cluster is used when multi cluster tests are disabled.
+	require.Equal(t, []KubeEnv{
+		{Kubeconfig: "primary-config", KubeContext: "primary"},
+	}, cfg.KubeEnvs())
+
+	cfg.EnableMultiCluster = true
+	require.Equal(t, []KubeEnv{
+		{Kubeconfig: "primary-config", KubeContext: "primary"},
+		{KubeContext: "secondary"},
+		{KubeContext: "third"},
+	}, cfg.KubeEnvs())
+}
+
+func TestConfig_ChartPath(t *testing.T) {
+	cfg := TestConfig{}
+	require.Equal(t, HelmChartPath, cfg.ChartPath())
+
+	cfg.HelmChartPath = "hashicorp/consul"
+	require.Equal(t, "hashicorp/consul", cfg.ChartPath())
+}
+
+func TestConfig_Fingerprint(t *testing.T) {
+	cfg := &TestConfig{ConsulImage: "hashicorp/consul:1.9.5", Kubeconfig: "kubeconfig"}
+	fingerprint := cfg.Fingerprint()
+	require.Len(t, fingerprint, 12)
+
+	// The clusters the tests run against don't change the fingerprint.
+	sameTests := *cfg
+	sameTests.Kubeconfig = "other-kubeconfig"
+	sameTests.KubeContext = "other-context"
+	require.Equal(t, fingerprint, sameTests.Fingerprint())
+
+	otherImage := *cfg
+	otherImage.ConsulImage = "hashicorp/consul:1.10.0"
+	require.NotEqual(t, fingerprint, otherImage.Fingerprint())
+
+	enterprise := *cfg
+	enterprise.EnableEnterprise = true
+	require.NotEqual(t, fingerprint, enterprise.Fingerprint())
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The dimensions of the matrices of table-driven tests
+// that values can be selected of with the -matrix flag.
+const (
+	// MatrixSecure is whether Consul is installed with TLS and ACLs.
+	MatrixSecure = "secure"
+	// MatrixAutoEncrypt is whether the clients get their certificates with auto-encrypt.
+	MatrixAutoEncrypt = "auto-encrypt"
+	// MatrixTProxy is whether transparent proxy is enabled for injected pods.
+	MatrixTProxy = "tproxy"
+)
+
+// MatrixDimensions are all the dimensions that values can be selected of.
+var MatrixDimensions = []string{MatrixSecure, MatrixAutoEncrypt, MatrixTProxy}
+
+// matrixAny is the value that selects all values of a dimension.
+const matrixAny = "any"
+
+// Matrix maps dimensions of a test matrix to their values.
+type Matrix map[string]bool
+
+// ParseMatrix parses a comma-separated list of <dimension>=<value>, where value
+// is true, false, or any, e.g. secure=true,tproxy=any. Dimensions that are any
+// are left out of the result, like the dimensions that aren't listed.
+func ParseMatrix(matrix string) (Matrix, error) {
+	result := make(Matrix)
+	for _, entry := range strings.Split(matrix, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q must be in the format <dimension>=<value>", entry)
+		}
+		dimension, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if !contains(MatrixDimensions, dimension) {
+			return nil, fmt.Errorf("%q has unknown dimension %q, must be one of: %s", entry, dimension, strings.Join(MatrixDimensions, ", "))
+		}
+		if value == matrixAny {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q has invalid value %q, must be true, false, or %s", entry, value, matrixAny)
+		}
+		result[dimension] = b
+	}
+	return result, nil
+}
+
+// InMatrix returns true if the values of the dimensions of a test case
+// are the ones selected by Matrix, i.e. if the test case should run.
+// Dimensions that the test case doesn't have aren't compared.
+func (t *TestConfig) InMatrix(values Matrix) bool {
+	for dimension, selected := range t.Matrix {
+		if value, ok := values[dimension]; ok && value != selected {
+			return false
+		}
+	}
+	return true
+}
+
+// contains returns true if s contains target.
+func contains(s []string, target string) bool {
+	for _, elem := range s {
+		if elem == target {
+			return true
+		}
+	}
+	return false
+}
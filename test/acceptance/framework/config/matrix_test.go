@@ -0,0 +1,89 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMatrix(t *testing.T) {
+	cases := map[string]struct {
+		matrix    string
+		expMatrix Matrix
+		expErr    string
+	}{
+		"empty": {
+			matrix:    "",
+			expMatrix: Matrix{},
+		},
+		"any is left out": {
+			matrix:    "secure=true, auto-encrypt=false, tproxy=any",
+			expMatrix: Matrix{MatrixSecure: true, MatrixAutoEncrypt: false},
+		},
+		"missing value": {
+			matrix: "secure",
+			expErr: `"secure" must be in the format <dimension>=<value>`,
+		},
+		"unknown dimension": {
+			matrix: "namespaces=true",
+			expErr: `"namespaces=true" has unknown dimension "namespaces"`,
+		},
+		"invalid value": {
+			matrix: "tproxy=maybe",
+			expErr: `"tproxy=maybe" has invalid value "maybe"`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			matrix, err := ParseMatrix(c.matrix)
+			if c.expErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), c.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expMatrix, matrix)
+		})
+	}
+}
+
+func TestConfig_InMatrix(t *testing.T) {
+	cases := map[string]struct {
+		matrix   Matrix
+		values   Matrix
+		expected bool
+	}{
+		"no matrix": {
+			values:   Matrix{MatrixSecure: false},
+			expected: true,
+		},
+		"selected value": {
+			matrix:   Matrix{MatrixSecure: true},
+			values:   Matrix{MatrixSecure: true, MatrixTProxy: false},
+			expected: true,
+		},
+		"other value": {
+			matrix:   Matrix{MatrixSecure: true},
+			values:   Matrix{MatrixSecure: false, MatrixTProxy: false},
+			expected: false,
+		},
+		"one of several dimensions has another value": {
+			matrix:   Matrix{MatrixSecure: true, MatrixTProxy: true},
+			values:   Matrix{MatrixSecure: true, MatrixTProxy: false},
+			expected: false,
+		},
+		"test case without the dimension": {
+			matrix:   Matrix{MatrixTProxy: true},
+			values:   Matrix{MatrixSecure: true},
+			expected: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := &TestConfig{Matrix: c.matrix}
+			require.Equal(t, c.expected, cfg.InMatrix(c.values))
+		})
+	}
+}
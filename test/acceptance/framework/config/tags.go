@@ -0,0 +1,35 @@
+package config
+
+// The tags that tests can be selected by with RunTags, see helpers.SkipUnlessTag.
+const (
+	// TagSecure is for tests that install Consul with TLS and ACLs.
+	TagSecure = "secure"
+	// TagEnterprise is for tests that require Consul Enterprise.
+	TagEnterprise = "enterprise"
+	// TagNamespaces is for tests of Consul Enterprise namespaces.
+	TagNamespaces = "namespaces"
+	// TagSlow is for tests that take much longer than an installation,
+	// e.g. because they upgrade Consul or wait for timeouts.
+	TagSlow = "slow"
+)
+
+// Tags are all the tags that tests can be selected by.
+var Tags = []string{TagSecure, TagEnterprise, TagNamespaces, TagSlow}
+
+// HasRunTags returns true if tags include all of the RunTags,
+// i.e. if a test with tags should run.
+func (t *TestConfig) HasRunTags(tags ...string) bool {
+	for _, runTag := range t.RunTags {
+		found := false
+		for _, tag := range tags {
+			if tag == runTag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
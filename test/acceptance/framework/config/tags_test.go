@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_HasRunTags(t *testing.T) {
+	cases := map[string]struct {
+		runTags  []string
+		tags     []string
+		expected bool
+	}{
+		"no run tags": {
+			tags:     []string{TagSecure},
+			expected: true,
+		},
+		"no run tags and an untagged test": {
+			expected: true,
+		},
+		"test has the run tag": {
+			runTags:  []string{TagSecure},
+			tags:     []string{TagNamespaces, TagSecure},
+			expected: true,
+		},
+		"test has all run tags": {
+			runTags:  []string{TagSecure, TagNamespaces},
+			tags:     []string{TagEnterprise, TagNamespaces, TagSecure},
+			expected: true,
+		},
+		"test has only some run tags": {
+			runTags:  []string{TagSecure, TagNamespaces},
+			tags:     []string{TagSecure},
+			expected: false,
+		},
+		"untagged test": {
+			runTags:  []string{TagSlow},
+			expected: false,
+		},
+		"empty tags don't match run tags": {
+			runTags:  []string{TagSecure},
+			tags:     []string{""},
+			expected: false,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := &TestConfig{RunTags: c.runTags}
+			require.Equal(t, c.expected, cfg.HasRunTags(c.tags...))
+		})
+	}
+}
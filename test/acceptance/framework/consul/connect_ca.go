@@ -0,0 +1,69 @@
+package consul
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// RotateConnectCA sets the configuration of the Connect CA to provider with config,
+// which makes the servers generate a new root, e.g. when the private key type of the
+// built-in "consul" provider changes. It waits until the new root is active and checks
+// that the old root is still trusted so that leaf certificates signed by it keep working
+// while they are re-issued, and returns both roots.
+// secure has the same meaning as in SetupConsulClient.
+func (h *HelmCluster) RotateConnectCA(t *testing.T, secure bool, provider string, config map[string]interface{}) (oldRoot, newRoot *api.CARoot) {
+	t.Helper()
+
+	client := h.SetupConsulClient(t, secure)
+	roots, _, err := client.Connect().CARoots(nil)
+	require.NoError(t, err)
+	oldRoot = activeCARoot(roots)
+	require.NotNil(t, oldRoot, "no active Connect CA root")
+
+	logger.Logf(t, "rotating the Connect CA from root %s with provider %s", oldRoot.ID, provider)
+	_, err = client.Connect().CASetConfig(&api.CAConfig{Provider: provider, Config: config}, nil)
+	require.NoError(t, err)
+
+	retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		roots, _, err := client.Connect().CARoots(nil)
+		require.NoError(r, err)
+		newRoot, err = checkRotatedCARoots(roots, oldRoot.ID)
+		require.NoError(r, err)
+	})
+	logger.Logf(t, "the Connect CA root %s is active", newRoot.ID)
+	return oldRoot, newRoot
+}
+
+// activeCARoot returns the active root of roots, or nil if none is active.
+func activeCARoot(roots *api.CARootList) *api.CARoot {
+	for _, root := range roots.Roots {
+		if root.ID == roots.ActiveRootID {
+			return root
+		}
+	}
+	return nil
+}
+
+// checkRotatedCARoots returns the active root of roots if it has replaced the root
+// oldRootID, which has to still be trusted alongside it.
+func checkRotatedCARoots(roots *api.CARootList, oldRootID string) (*api.CARoot, error) {
+	active := activeCARoot(roots)
+	if active == nil {
+		return nil, fmt.Errorf("no active Connect CA root")
+	}
+	if active.ID == oldRootID {
+		return nil, fmt.Errorf("root %s is still active", oldRootID)
+	}
+	for _, root := range roots.Roots {
+		if root.ID == oldRootID {
+			return active, nil
+		}
+	}
+	return nil, fmt.Errorf("old root %s is no longer trusted", oldRootID)
+}
@@ -0,0 +1,48 @@
+package consul
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRotatedCARoots(t *testing.T) {
+	oldRoot := &api.CARoot{ID: "old"}
+	newRoot := &api.CARoot{ID: "new", Active: true}
+
+	cases := map[string]struct {
+		roots     *api.CARootList
+		expRoot   *api.CARoot
+		expErrMsg string
+	}{
+		"rotated": {
+			roots:   &api.CARootList{ActiveRootID: "new", Roots: []*api.CARoot{oldRoot, newRoot}},
+			expRoot: newRoot,
+		},
+		"not rotated yet": {
+			roots:     &api.CARootList{ActiveRootID: "old", Roots: []*api.CARoot{oldRoot}},
+			expErrMsg: "root old is still active",
+		},
+		"old root pruned": {
+			roots:     &api.CARootList{ActiveRootID: "new", Roots: []*api.CARoot{newRoot}},
+			expErrMsg: "old root old is no longer trusted",
+		},
+		"no active root": {
+			roots:     &api.CARootList{ActiveRootID: "missing", Roots: []*api.CARoot{oldRoot}},
+			expErrMsg: "no active Connect CA root",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			root, err := checkRotatedCARoots(c.roots, "old")
+			if c.expErrMsg != "" {
+				require.EqualError(t, err, c.expErrMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expRoot, root)
+		})
+	}
+}
@@ -4,15 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gruntwork-io/terratest/modules/helm"
-	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
 	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/faults"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
@@ -30,12 +35,72 @@ import (
 // Cluster represents a consul cluster object
 type Cluster interface {
 	Create(t *testing.T)
+	// CreateE is like Create, but it returns the error of helm install instead of
+	// failing the test, for tests of installations that are expected to fail.
+	// helm install waits for at most installTimeout so that such tests don't wait
+	// for the default timeout. The release is destroyed when the test finishes
+	// whether or not it was installed.
+	CreateE(t *testing.T, installTimeout time.Duration) error
 	Destroy(t *testing.T)
 	// Upgrade runs helm upgrade. It will merge the helm values from the
 	// initial install with helmValues. Any keys that were previously set
-	// will be overridden by the helmValues keys.
+	// will be overridden by the helmValues keys. It waits for the rollouts
+	// of all components to complete and checks that the servers have a quorum.
 	Upgrade(t *testing.T, helmValues map[string]string)
+	// UpgradeToChart is like Upgrade but also switches the release to chartPath
+	// at version, e.g. from a released chart to the chart under test.
+	// Subsequent upgrades use that chart.
+	UpgradeToChart(t *testing.T, chartPath, version string, helmValues map[string]string)
+	// Rollback runs helm rollback to the given revision and waits for the
+	// rollouts of all components to complete. Subsequent upgrades use the helm
+	// values of that revision.
+	Rollback(t *testing.T, revision int)
+	// Revisions returns the revisions of the helm release, oldest first.
+	Revisions(t *testing.T) []Revision
 	SetupConsulClient(t *testing.T, secure bool) *api.Client
+	// ConsulClientForNamespace returns a client like SetupConsulClient whose
+	// requests target the Consul Enterprise namespace by default.
+	ConsulClientForNamespace(t *testing.T, secure bool, namespace string) *api.Client
+	// BootstrapToken returns the ACL bootstrap token of the release.
+	// It fails the test if the release has no bootstrap token,
+	// e.g. because ACLs are disabled or it is a secondary datacenter.
+	BootstrapToken(t *testing.T) string
+	// CACert returns the PEM-encoded CA certificate of the release
+	// so that tests can build their own TLS configs. It fails the test
+	// if the release doesn't have a CA certificate, e.g. because TLS is disabled.
+	CACert(t *testing.T) []byte
+	// ConsulExec runs the consul CLI with args in the consul container of pod
+	// and returns its stdout and stderr. It passes the ACL token of the release
+	// if it has one.
+	ConsulExec(t *testing.T, pod string, args ...string) (string, string, error)
+	// Snapshot saves a snapshot of the state of the Consul servers and returns it.
+	Snapshot(t *testing.T) []byte
+	// Restore restores a snapshot returned by Snapshot on the Consul servers.
+	Restore(t *testing.T, snapshot []byte)
+	// RotateConnectCA sets the configuration of the Connect CA to provider with config
+	// and waits until the servers have rotated to a new root while still trusting
+	// the old one. It returns the old and the new root.
+	RotateConnectCA(t *testing.T, secure bool, provider string, config map[string]interface{}) (oldRoot, newRoot *api.CARoot)
+	// InstallGossipKey installs a gossip encryption key on all members of the cluster.
+	InstallGossipKey(t *testing.T, key string)
+	// UseGossipKey makes an installed gossip encryption key the primary key of all members.
+	UseGossipKey(t *testing.T, key string)
+	// RemoveGossipKey removes a gossip encryption key that isn't primary from all members.
+	RemoveGossipKey(t *testing.T, key string)
+	// AllowErrorLogs allows the servers, clients, and connect injector to log errors
+	// that match any of the regular expressions in patterns, e.g. errors that are
+	// expected while a test restarts the servers. Any other errors that they log
+	// after Create has finished fail the test if -strict is set.
+	AllowErrorLogs(t *testing.T, patterns ...string)
+}
+
+// Revision is a revision of a helm release as reported by helm history.
+type Revision struct {
+	Number      int    `json:"revision"`
+	Status      string `json:"status"`
+	Chart       string `json:"chart"`
+	AppVersion  string `json:"app_version"`
+	Description string `json:"description"`
 }
 
 // HelmCluster implements Cluster and uses Helm
@@ -44,25 +109,104 @@ type HelmCluster struct {
 	cfg                config.TestConfig
 	ctx                environment.TestContext
 	helmOptions        *helm.Options
+	chartPath          string
 	releaseName        string
 	kubernetesClient   kubernetes.Interface
 	noCleanupOnFailure bool
 	debugDirectory     string
+	checkClusterState  bool
+	forceDeleteStuck   bool
 	logger             terratestLogger.TestLogger
+	portForwarder      *k8s.PortForwarder
+
+	// logStream streams the logs of the pods of the release while it's installed.
+	// Create starts it and Destroy stops it so that only one stream follows the pods.
+	logStream *k8s.LogStream
+
+	// consulClients caches Consul API clients per test so that
+	// port-forwards and HTTP connections are reused across calls to SetupConsulClient.
+	// The cache is reset whenever the release is installed, upgraded, rolled back or
+	// destroyed because the servers restart and the ACL token may change.
+	consulClients     map[consulClientKey]*api.Client
+	consulClientsLock sync.Mutex
+
+	// revisionValues are the helm values of each release revision
+	// installed through this cluster, so that they can be restored on rollback.
+	revisionValues map[int]map[string]string
+
+	// strictErrorLogs fails the test if the release logs errors, see checkErrorLogs.
+	strictErrorLogs bool
+	// allowedErrorLogs are the errors that checkErrorLogs ignores.
+	allowedErrorLogs []*regexp.Regexp
+	// errorLogsSince is when the current installation of the release became ready,
+	// or zero if its errors have been checked or it isn't installed.
+	errorLogsSince time.Time
+	// errorLogsCheckRegistered is true once Create has registered checkErrorLogs.
+	errorLogsCheckRegistered bool
+}
+
+const (
+	// uninstallTimeout bounds how long helm delete waits for pre-delete hooks.
+	uninstallTimeout = "5m"
+	// stuckResourcesTimeout is how long Destroy waits for the resources of the release
+	// to be deleted before it considers them stuck.
+	stuckResourcesTimeout = 2 * time.Minute
+)
+
+// errorLogComponents are the components of the release whose logs checkErrorLogs checks.
+var errorLogComponents = []string{"server", "client", "connect-injector"}
+
+// releaseResourceKinds are the kinds of namespaced resources that the chart creates
+// and that Destroy checks for after uninstalling.
+var releaseResourceKinds = []string{
+	"pods",
+	"persistentvolumeclaims",
+	"serviceaccounts",
+	"roles",
+	"rolebindings",
+	"secrets",
+	"configmaps",
+	"services",
+	"jobs",
+	"deployments",
+	"statefulsets",
+	"daemonsets",
+	"poddisruptionbudgets",
 }
 
+// consulClientKey identifies a cached Consul API client.
+type consulClientKey struct {
+	testName  string
+	secure    bool
+	namespace string
+}
+
+// NewHelmCluster returns a Cluster that installs the chart with the given helm values
+// as the release releaseName. Values files can be passed in addition to helmValues
+// for values that are hard to express in --set notation, see WriteValuesFile.
+// helmValues take precedence over the values files, which in turn take
+// precedence over the test defaults and the values from the test config.
 func NewHelmCluster(
 	t *testing.T,
 	helmValues map[string]string,
 	ctx environment.TestContext,
 	cfg *config.TestConfig,
 	releaseName string,
+	valuesFiles ...string,
 ) Cluster {
 
 	if cfg.EnablePodSecurityPolicies {
 		configurePodSecurityPolicies(t, ctx.KubernetesClient(t), cfg, ctx.KubectlOptions(t).Namespace)
 	}
 
+	if cfg.EnableOpenshift {
+		configureSecurityContextConstraints(t, ctx, cfg)
+	}
+
+	if cfg.EnterpriseLicensePath != "" {
+		createOrUpdateLicenseSecret(t, ctx.KubernetesClient(t), cfg, ctx.KubectlOptions(t).Namespace)
+	}
+
 	// Deploy with the following defaults unless helmValues overwrites it.
 	values := map[string]string{
 		"server.replicas":              "1",
@@ -78,6 +222,30 @@ func NewHelmCluster(
 
 	// Merge all helm values
 	mergeMaps(values, valuesFromConfig)
+
+	// In version canary mode, each test case installs one of the canary images
+	// unless it sets the image itself.
+	if _, ok := helmValues["global.image"]; !ok && len(cfg.CanaryImages) > 0 {
+		image := canaryImage(cfg.CanaryImages, t.Name())
+		logger.Logf(t, "%s%s", config.CanaryImageLogMessage, image)
+		values["global.image"] = image
+	}
+
+	// Values passed with --set always take precedence over values files,
+	// so drop the defaults that the values files override.
+	fileKeys := valuesFileKeys(t, valuesFiles)
+	for key := range values {
+		if setByValuesFiles(key, fileKeys) {
+			delete(values, key)
+		}
+	}
+	// The chart expects as many servers as there are replicas unless bootstrapExpect
+	// is set, so the default of one must not be kept if a values file sets either.
+	if setByValuesFiles("server.replicas", fileKeys) || setByValuesFiles("server.bootstrapExpect", fileKeys) {
+		delete(values, "server.replicas")
+		delete(values, "server.bootstrapExpect")
+	}
+
 	mergeMaps(values, helmValues)
 
 	logger := terratestLogger.New(logger.TestLogger{})
@@ -87,10 +255,13 @@ func NewHelmCluster(
 	// like AKS where volumes take a long time to mount.
 	extraArgs := map[string][]string{
 		"install": {"--timeout", "15m"},
+		"delete":  {"--timeout", uninstallTimeout},
 	}
 
 	opts := &helm.Options{
 		SetValues:      values,
+		ValuesFiles:    valuesFiles,
+		Version:        cfg.HelmChartVersion,
 		KubectlOptions: ctx.KubectlOptions(t),
 		Logger:         logger,
 		ExtraArgs:      extraArgs,
@@ -98,17 +269,54 @@ func NewHelmCluster(
 	return &HelmCluster{
 		ctx:                ctx,
 		helmOptions:        opts,
+		chartPath:          cfg.ChartPath(),
 		releaseName:        releaseName,
 		kubernetesClient:   ctx.KubernetesClient(t),
 		noCleanupOnFailure: cfg.NoCleanupOnFailure,
 		debugDirectory:     cfg.DebugDirectory,
+		checkClusterState:  cfg.EnableClusterStateCheck,
+		forceDeleteStuck:   cfg.ForceDeleteStuckResources,
 		logger:             logger,
+		portForwarder:      k8s.NewPortForwarder(ctx.KubectlOptions(t), logger),
+		consulClients:      make(map[consulClientKey]*api.Client),
+		revisionValues:     make(map[int]map[string]string),
+		strictErrorLogs:    cfg.Strict,
 	}
 }
 
 func (h *HelmCluster) Create(t *testing.T) {
 	t.Helper()
 
+	require.NoError(t, h.create(t, h.helmOptions))
+}
+
+func (h *HelmCluster) CreateE(t *testing.T, installTimeout time.Duration) error {
+	t.Helper()
+
+	options := *h.helmOptions
+	options.ExtraArgs = make(map[string][]string, len(h.helmOptions.ExtraArgs))
+	for command, args := range h.helmOptions.ExtraArgs {
+		options.ExtraArgs[command] = args
+	}
+	options.ExtraArgs["install"] = []string{"--timeout", installTimeout.String()}
+	return h.create(t, &options)
+}
+
+// create installs the release with helmOptions. It returns the error of
+// helm install and fails the test if anything else goes wrong.
+func (h *HelmCluster) create(t *testing.T, helmOptions *helm.Options) error {
+	t.Helper()
+
+	logger.SetPhase(t, logger.PhaseSetup)
+	defer logger.SetPhase(t, logger.PhaseTest)
+
+	// Record cluster-scoped resources before installing so that we can check
+	// they are the same after the cluster is destroyed. This needs to be registered
+	// before the cleanup below so that it runs after it.
+	if h.checkClusterState {
+		k8s.CheckClusterStateUnchangedOnCleanup(t, h.helmOptions.KubectlOptions)
+	}
+
 	// Make sure we delete the cluster if we receive an interrupt signal and
 	// register cleanup so that we delete the cluster when test finishes.
 	helpers.Cleanup(t, h.noCleanupOnFailure, func() {
@@ -118,24 +326,107 @@ func (h *HelmCluster) Create(t *testing.T) {
 	// Fail if there are any existing installations of the Helm chart.
 	h.checkForPriorInstallations(t)
 
-	helm.Install(t, h.helmOptions, config.HelmChartPath, h.releaseName)
+	h.resetConsulClients()
+
+	// Start streaming before installing so that the logs of
+	// containers that crash while the release comes up are kept.
+	if h.logStream == nil {
+		h.logStream = k8s.StreamPodLogs(t, h.helmOptions.KubectlOptions, h.debugDirectory, "release="+h.releaseName)
+	}
+
+	faults.Inject(t, faults.BeforeInstall)
+	if err := helm.InstallE(t, helmOptions, h.chartPath, h.releaseName); err != nil {
+		return err
+	}
+	h.recordRevisionValues(t)
+	h.checkDeprecatedAPIs(t)
 
 	helpers.WaitForAllPodsToBeReady(t, h.kubernetesClient, h.helmOptions.KubectlOptions.Namespace, fmt.Sprintf("release=%s", h.releaseName))
+	faults.Inject(t, faults.AfterInstall)
 
 	// We no longer have readiness checks in the connect-inject webhook,
 	// and we need to allow some extra time for the webhook to come up and start serving requests.
 	// TODO: remove once we have readiness checks for the webhook (before GA)
 	time.Sleep(30 * time.Second)
+
+	// Errors are only checked from now on because Consul logs errors while it starts,
+	// e.g. until the servers have elected a leader. The check is registered after the
+	// cleanup above so that it runs before the release is destroyed, and only once
+	// because Destroy checks the errors of installations that it destroys.
+	h.errorLogsSince = time.Now()
+	if !h.errorLogsCheckRegistered {
+		h.errorLogsCheckRegistered = true
+		t.Cleanup(func() {
+			h.checkErrorLogs(t)
+		})
+	}
+	return nil
+}
+
+func (h *HelmCluster) AllowErrorLogs(t *testing.T, patterns ...string) {
+	t.Helper()
+
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		require.NoError(t, err)
+		h.allowedErrorLogs = append(h.allowedErrorLogs, re)
+	}
+}
+
+// checkErrorLogs checks the streamed logs of the errorLogComponents for errors that
+// they have logged since the current installation of the release became ready and
+// that aren't allowed with AllowErrorLogs. Such errors fail the test in strict mode
+// and are only logged otherwise, because they often point to problems that the
+// functional checks of the test don't catch. Each installation is checked once.
+func (h *HelmCluster) checkErrorLogs(t *testing.T) {
+	t.Helper()
+
+	if h.errorLogsSince.IsZero() || h.logStream == nil {
+		return
+	}
+	since := h.errorLogsSince
+	h.errorLogsSince = time.Time{}
+
+	selector := fmt.Sprintf("release=%s,component in (%s)", h.releaseName, strings.Join(errorLogComponents, ","))
+	errorLines := h.logStream.ErrorLogs(t, selector, since, h.allowedErrorLogs)
+	if len(errorLines) == 0 {
+		return
+	}
+
+	message := fmt.Sprintf("release %s logged %d error(s) during the test:\n%s", h.releaseName, len(errorLines), strings.Join(errorLines, "\n"))
+	if h.strictErrorLogs {
+		t.Error(message)
+	} else {
+		logger.Log(t, message)
+	}
 }
 
 func (h *HelmCluster) Destroy(t *testing.T) {
 	t.Helper()
 
+	// The logs are streamed until the pods are deleted. If the test creates the
+	// release again, Create starts a new stream that appends to the same files.
+	defer func() {
+		if h.logStream != nil {
+			h.logStream.Stop()
+			h.logStream = nil
+		}
+	}()
+
+	// Check the errors before uninstalling because uninstalling makes Consul log errors.
+	h.checkErrorLogs(t)
+
+	h.resetConsulClients()
+
 	k8s.WritePodsDebugInfoIfFailed(t, h.helmOptions.KubectlOptions, h.debugDirectory, "release="+h.releaseName)
+	k8s.WriteConsulDebugArchiveIfFailed(t, h.helmOptions.KubectlOptions, h.debugDirectory, fmt.Sprintf("%s-consul-server-0", h.releaseName), h.debugACLToken())
 
-	// Ignore the error returned by the helm delete here so that we can
-	// always idempotently clean up resources in the cluster.
-	helm.DeleteE(t, h.helmOptions, h.releaseName, false)
+	// Don't fail on the error returned by the helm delete here so that we can
+	// always idempotently clean up resources in the cluster. Any resources it
+	// fails to delete are reported below.
+	if err := helm.DeleteE(t, h.helmOptions, h.releaseName, false); err != nil {
+		logger.Logf(t, "helm delete of %s failed: %s", h.releaseName, err)
+	}
 
 	// Force delete any pods that have h.releaseName in their name because sometimes
 	// graceful termination takes a long time and since this is an uninstall
@@ -214,22 +505,166 @@ func (h *HelmCluster) Destroy(t *testing.T) {
 			}
 		}
 	}
+
+	h.handleStuckResources(t)
+}
+
+// handleStuckResources waits for all resources of the release to be deleted.
+// Resources that are still there after stuckResourcesTimeout, e.g. because of
+// finalizers or failed helm hooks, would make the next installation fail,
+// so they are reported as a failure of this test or force-deleted if
+// -force-delete-stuck-resources is set.
+func (h *HelmCluster) handleStuckResources(t *testing.T) {
+	t.Helper()
+
+	selector := "release=" + h.releaseName
+	stuck := k8s.WaitForResourcesDeleted(t, h.helmOptions.KubectlOptions, releaseResourceKinds, selector, stuckResourcesTimeout)
+	if len(stuck) == 0 {
+		return
+	}
+
+	if !h.forceDeleteStuck {
+		k8s.ReportStuckResources(t, stuck, fmt.Sprintf("resources of release %s were not deleted within %s", h.releaseName, stuckResourcesTimeout))
+		return
+	}
+
+	k8s.ForceDeleteResources(t, h.helmOptions.KubectlOptions, stuck)
+	stuck = k8s.WaitForResourcesDeleted(t, h.helmOptions.KubectlOptions, releaseResourceKinds, selector, stuckResourcesTimeout)
+	k8s.ReportStuckResources(t, stuck, fmt.Sprintf("resources of release %s were not deleted after force deleting them", h.releaseName))
 }
 
 func (h *HelmCluster) Upgrade(t *testing.T, helmValues map[string]string) {
 	t.Helper()
 
 	mergeMaps(h.helmOptions.SetValues, helmValues)
-	helm.Upgrade(t, h.helmOptions, config.HelmChartPath, h.releaseName)
+	faults.Inject(t, faults.BeforeUpgrade)
+	h.resetConsulClients()
+	helm.Upgrade(t, h.helmOptions, h.chartPath, h.releaseName)
+	h.recordRevisionValues(t)
+	h.checkDeprecatedAPIs(t)
+	k8s.WaitForRolloutsToComplete(t, h.kubernetesClient, h.helmOptions.KubectlOptions.Namespace, fmt.Sprintf("release=%s", h.releaseName))
 	helpers.WaitForAllPodsToBeReady(t, h.kubernetesClient, h.helmOptions.KubectlOptions.Namespace, fmt.Sprintf("release=%s", h.releaseName))
+	h.requireServerQuorum(t)
+	faults.Inject(t, faults.AfterUpgrade)
 }
 
+func (h *HelmCluster) UpgradeToChart(t *testing.T, chartPath, version string, helmValues map[string]string) {
+	t.Helper()
+
+	logger.Logf(t, "upgrading release %s to chart %s %s", h.releaseName, chartPath, version)
+	h.chartPath = chartPath
+	h.helmOptions.Version = version
+	h.Upgrade(t, helmValues)
+}
+
+func (h *HelmCluster) Rollback(t *testing.T, revision int) {
+	t.Helper()
+
+	values, ok := h.revisionValues[revision]
+	require.True(t, ok, "revision %d was not installed by this cluster so its helm values are unknown", revision)
+
+	logger.Logf(t, "rolling back release %s to revision %d", h.releaseName, revision)
+	h.resetConsulClients()
+	helm.Rollback(t, h.helmOptions, h.releaseName, strconv.Itoa(revision))
+
+	h.helmOptions.SetValues = copyMap(values)
+	h.recordRevisionValues(t)
+
+	// Stateful sets don't replace pods that were created from a broken revision
+	// and never became ready, e.g. because of a bad image tag, so we need to delete them.
+	// See https://kubernetes.io/docs/concepts/workloads/controllers/statefulset/#forced-rollback.
+	k8s.DeleteStuckStatefulSetPods(t, h.kubernetesClient, h.helmOptions.KubectlOptions.Namespace, fmt.Sprintf("release=%s", h.releaseName))
+
+	k8s.WaitForRolloutsToComplete(t, h.kubernetesClient, h.helmOptions.KubectlOptions.Namespace, fmt.Sprintf("release=%s", h.releaseName))
+	helpers.WaitForAllPodsToBeReady(t, h.kubernetesClient, h.helmOptions.KubectlOptions.Namespace, fmt.Sprintf("release=%s", h.releaseName))
+	h.requireServerQuorum(t)
+}
+
+func (h *HelmCluster) Revisions(t *testing.T) []Revision {
+	t.Helper()
+
+	output, err := helm.RunHelmCommandAndGetOutputE(t, h.helmOptions, "history", h.releaseName, "--output", "json")
+	require.NoError(t, err)
+
+	var revisions []Revision
+	require.NoError(t, json.Unmarshal([]byte(output), &revisions), "unmarshalling %q", output)
+	return revisions
+}
+
+// recordRevisionValues records the current helm values
+// as the values of the latest revision of the release.
+func (h *HelmCluster) recordRevisionValues(t *testing.T) {
+	t.Helper()
+
+	revisions := h.Revisions(t)
+	require.NotEmpty(t, revisions)
+	h.revisionValues[revisions[len(revisions)-1].Number] = copyMap(h.helmOptions.SetValues)
+}
+
+// SetupConsulClient returns a Consul API client that talks to the first
+// Consul server through a port-forward. Clients are cached per test so that
+// calling this function multiple times from the same test reuses the same
+// port-forward and HTTP transport. The port-forward is closed when the test finishes.
 func (h *HelmCluster) SetupConsulClient(t *testing.T, secure bool) *api.Client {
 	t.Helper()
 
+	return h.consulClient(t, secure, "")
+}
+
+// ConsulClientForNamespace returns a Consul API client like SetupConsulClient
+// whose requests, including writes such as intentions and config entries,
+// target the Consul Enterprise namespace unless their options set another one.
+func (h *HelmCluster) ConsulClientForNamespace(t *testing.T, secure bool, namespace string) *api.Client {
+	t.Helper()
+
+	return h.consulClient(t, secure, namespace)
+}
+
+// consulClient returns the cached Consul API client of the test for secure
+// and namespace, creating it if it doesn't exist yet.
+func (h *HelmCluster) consulClient(t *testing.T, secure bool, namespace string) *api.Client {
+	t.Helper()
+
+	key := consulClientKey{testName: t.Name(), secure: secure, namespace: namespace}
+
+	h.consulClientsLock.Lock()
+	defer h.consulClientsLock.Unlock()
+
+	if client, ok := h.consulClients[key]; ok {
+		return client
+	}
+
+	client := h.newConsulClient(t, secure, namespace)
+	h.consulClients[key] = client
+
+	t.Cleanup(func() {
+		h.consulClientsLock.Lock()
+		defer h.consulClientsLock.Unlock()
+		delete(h.consulClients, key)
+	})
+
+	return client
+}
+
+// resetConsulClients drops the cached Consul API clients of all tests so that
+// the next calls to SetupConsulClient create new clients, e.g. with the ACL token of
+// a new installation, instead of reusing connections to servers that have been restarted.
+func (h *HelmCluster) resetConsulClients() {
+	h.consulClientsLock.Lock()
+	defer h.consulClientsLock.Unlock()
+
+	h.consulClients = make(map[consulClientKey]*api.Client)
+}
+
+// newConsulClient returns a Consul API client that talks to the first
+// Consul server through a port-forward that is reconnected if it dies.
+// If consulNamespace isn't empty, requests default to that Consul namespace.
+func (h *HelmCluster) newConsulClient(t *testing.T, secure bool, consulNamespace string) *api.Client {
+	t.Helper()
+
 	namespace := h.helmOptions.KubectlOptions.Namespace
 	config := api.DefaultConfig()
-	localPort := terratestk8s.GetAvailablePort(t)
+	config.Namespace = consulNamespace
 	remotePort := 8500 // use non-secure by default
 
 	if secure {
@@ -245,7 +680,7 @@ func (h *HelmCluster) SetupConsulClient(t *testing.T, secure bool) *api.Client {
 		// and will try to read the replication token from the federation secret.
 		// In secondary servers, we don't create a bootstrap token since ACLs are only bootstrapped in the primary.
 		// Instead, we provide a replication token that serves the role of the bootstrap token.
-		aclSecret, err := h.kubernetesClient.CoreV1().Secrets(namespace).Get(context.Background(), h.releaseName+"-consul-bootstrap-acl-token", metav1.GetOptions{})
+		aclSecret, err := h.kubernetesClient.CoreV1().Secrets(namespace).Get(context.Background(), h.bootstrapTokenSecretName(), metav1.GetOptions{})
 		if err != nil && errors.IsNotFound(err) {
 			federationSecret := fmt.Sprintf("%s-consul-federation", h.releaseName)
 			aclSecret, err = h.kubernetesClient.CoreV1().Secrets(namespace).Get(context.Background(), federationSecret, metav1.GetOptions{})
@@ -258,31 +693,47 @@ func (h *HelmCluster) SetupConsulClient(t *testing.T, secure bool) *api.Client {
 		}
 	}
 
-	tunnel := terratestk8s.NewTunnelWithLogger(
-		h.helmOptions.KubectlOptions,
-		terratestk8s.ResourceTypePod,
-		fmt.Sprintf("%s-consul-server-0", h.releaseName),
-		localPort,
-		remotePort,
-		h.logger)
+	config.Address = h.portForwarder.Forward(t, fmt.Sprintf("%s-consul-server-0", h.releaseName), remotePort)
+	consulClient, err := api.NewClient(config)
+	require.NoError(t, err)
 
-	// Retry creating the port forward since it can fail occasionally.
-	retry.RunWith(&retry.Counter{Wait: 1 * time.Second, Count: 3}, t, func(r *retry.R) {
-		// NOTE: It's okay to pass in `t` to ForwardPortE despite being in a retry
-		// because we're using ForwardPortE (not ForwardPort) so the `t` won't
-		// get used to fail the test, just for logging.
-		require.NoError(r, tunnel.ForwardPortE(t))
-	})
+	return consulClient
+}
 
-	t.Cleanup(func() {
-		tunnel.Close()
-	})
+func (h *HelmCluster) BootstrapToken(t *testing.T) string {
+	t.Helper()
 
-	config.Address = fmt.Sprintf("127.0.0.1:%d", localPort)
-	consulClient, err := api.NewClient(config)
+	secret, err := h.kubernetesClient.CoreV1().Secrets(h.helmOptions.KubectlOptions.Namespace).Get(context.Background(), h.bootstrapTokenSecretName(), metav1.GetOptions{})
 	require.NoError(t, err)
+	token, ok := secret.Data["token"]
+	require.True(t, ok, "secret %s has no token", secret.Name)
+	return string(token)
+}
 
-	return consulClient
+// bootstrapTokenSecretName is the name of the secret server-acl-init
+// stores the bootstrap token in.
+func (h *HelmCluster) bootstrapTokenSecretName() string {
+	return fmt.Sprintf("%s-consul-bootstrap-acl-token", h.releaseName)
+}
+
+// debugACLToken returns the ACL token to use when capturing debug information
+// from the Consul servers. It returns an empty string if neither the bootstrap token
+// nor the replication token secret exist, e.g. when ACLs are disabled.
+// Unlike SetupConsulClient, it never fails the test because it's used during cleanup.
+func (h *HelmCluster) debugACLToken() string {
+	namespace := h.helmOptions.KubectlOptions.Namespace
+
+	aclSecret, err := h.kubernetesClient.CoreV1().Secrets(namespace).Get(context.Background(), h.bootstrapTokenSecretName(), metav1.GetOptions{})
+	if err == nil {
+		return string(aclSecret.Data["token"])
+	}
+
+	federationSecret, err := h.kubernetesClient.CoreV1().Secrets(namespace).Get(context.Background(), fmt.Sprintf("%s-consul-federation", h.releaseName), metav1.GetOptions{})
+	if err == nil {
+		return string(federationSecret.Data["replicationToken"])
+	}
+
+	return ""
 }
 
 // checkForPriorInstallations checks if there is an existing Helm release
@@ -436,6 +887,125 @@ func configurePodSecurityPolicies(t *testing.T, client kubernetes.Interface, cfg
 	})
 }
 
+// testSCCName is the name of the security context constraints created for test resources on OpenShift.
+const testSCCName = "test-scc"
+
+// testSCC is an OpenShift security context constraints that can be used by any test resources.
+// OpenShift's default restricted SCC requires pods to run with a UID from the namespace's
+// UID range, but injected sidecars and test fixtures run with fixed UIDs. Similar to the pod
+// security policy above, this SCC only prevents running privileged containers.
+const testSCC = `apiVersion: security.openshift.io/v1
+kind: SecurityContextConstraints
+metadata:
+  name: test-scc
+allowPrivilegedContainer: false
+allowedCapabilities:
+  - NET_ADMIN
+runAsUser:
+  type: RunAsAny
+seLinuxContext:
+  type: MustRunAs
+fsGroup:
+  type: RunAsAny
+supplementalGroups:
+  type: RunAsAny
+volumes:
+  - configMap
+  - downwardAPI
+  - emptyDir
+  - projected
+  - secret
+`
+
+// configureSecurityContextConstraints creates an OpenShift security context constraints,
+// a cluster role to allow access to the SCC, and a role binding that binds the default
+// service account in the helm installation namespace to the cluster role.
+// Test fixtures bind their own service accounts to the same cluster role
+// so that they are admitted on OpenShift.
+func configureSecurityContextConstraints(t *testing.T, ctx environment.TestContext, cfg *config.TestConfig) {
+	client := ctx.KubernetesClient(t)
+	namespace := ctx.KubectlOptions(t).Namespace
+
+	// Security context constraints. There is no typed client for OpenShift
+	// resources so we apply it with kubectl.
+	{
+		sccFile, err := ioutil.TempFile("", "test-scc-*.yaml")
+		require.NoError(t, err)
+		defer os.Remove(sccFile.Name())
+		_, err = sccFile.WriteString(testSCC)
+		require.NoError(t, err)
+		require.NoError(t, sccFile.Close())
+
+		k8s.KubectlApply(t, ctx.KubectlOptions(t), sccFile.Name())
+	}
+
+	// Cluster role for the SCC.
+	{
+		_, err := client.RbacV1().ClusterRoles().Get(context.Background(), testSCCName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			sccClusterRole := &rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testSCCName,
+				},
+				Rules: []rbacv1.PolicyRule{
+					{
+						Verbs:         []string{"use"},
+						APIGroups:     []string{"security.openshift.io"},
+						Resources:     []string{"securitycontextconstraints"},
+						ResourceNames: []string{testSCCName},
+					},
+				},
+			}
+			_, err = client.RbacV1().ClusterRoles().Create(context.Background(), sccClusterRole, metav1.CreateOptions{})
+			require.NoError(t, err)
+		} else {
+			require.NoError(t, err)
+		}
+	}
+
+	// A role binding to allow default service account in the installation namespace access to the SCC.
+	{
+		_, err := client.RbacV1().RoleBindings(namespace).Get(context.Background(), testSCCName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			sccRoleBinding := &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testSCCName,
+				},
+				Subjects: []rbacv1.Subject{
+					{
+						Kind:      rbacv1.ServiceAccountKind,
+						Name:      "default",
+						Namespace: namespace,
+					},
+				},
+				RoleRef: rbacv1.RoleRef{
+					Kind: "ClusterRole",
+					Name: testSCCName,
+				},
+			}
+			_, err = client.RbacV1().RoleBindings(namespace).Create(context.Background(), sccRoleBinding, metav1.CreateOptions{})
+			require.NoError(t, err)
+		} else {
+			require.NoError(t, err)
+		}
+	}
+
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "delete", "securitycontextconstraints", testSCCName, "--ignore-not-found")
+		client.RbacV1().ClusterRoles().Delete(context.Background(), testSCCName, metav1.DeleteOptions{})
+		client.RbacV1().RoleBindings(namespace).Delete(context.Background(), testSCCName, metav1.DeleteOptions{})
+	})
+}
+
+// copyMap returns a shallow copy of m.
+func copyMap(m map[string]string) map[string]string {
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
 // mergeValues will merge the values in b with values in a and save in a.
 // If there are conflicts, the values in b will overwrite the values in a.
 func mergeMaps(a, b map[string]string) {
@@ -1,11 +1,15 @@
 package consul
 
 import (
+	"context"
 	"testing"
 
 	"github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul/api"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 )
@@ -61,6 +65,89 @@ func TestNewHelmCluster(t *testing.T) {
 	}
 }
 
+// Test that values files override the defaults and the values from TestConfig
+// but not helmValues.
+func TestNewHelmCluster_ValuesFiles(t *testing.T) {
+	valuesFile := WriteValuesFile(t, map[string]interface{}{
+		"global": map[string]interface{}{
+			"image": "values-file-image",
+		},
+		"server": map[string]interface{}{
+			"replicas": 3,
+		},
+		"connectInject": map[string]interface{}{
+			"logLevel": "info",
+		},
+	})
+
+	helmValues := map[string]string{
+		"connectInject.logLevel": "warn",
+	}
+	cluster := NewHelmCluster(t, helmValues, &ctx{}, &config.TestConfig{ConsulImage: "test-config-image"}, "test", valuesFile).(*HelmCluster)
+
+	// The default server.bootstrapExpect is dropped together with server.replicas.
+	require.Equal(t, map[string]string{
+		"connectInject.envoyExtraArgs":                  "--log-level debug",
+		"connectInject.logLevel":                        "warn",
+		"connectInject.transparentProxy.defaultEnabled": "false",
+	}, cluster.helmOptions.SetValues)
+	require.Equal(t, []string{valuesFile}, cluster.helmOptions.ValuesFiles)
+}
+
+// Test that SetupConsulClient returns the cached client
+// if one already exists for the test.
+func TestSetupConsulClient_ReturnsCachedClient(t *testing.T) {
+	cluster := NewHelmCluster(t, nil, &ctx{}, &config.TestConfig{}, "test").(*HelmCluster)
+
+	cachedClient, err := api.NewClient(api.DefaultConfig())
+	require.NoError(t, err)
+	cluster.consulClients[consulClientKey{testName: t.Name(), secure: true}] = cachedClient
+
+	require.Same(t, cachedClient, cluster.SetupConsulClient(t, true))
+}
+
+// Test that ConsulClientForNamespace caches clients per namespace
+// separately from the client that SetupConsulClient returns.
+func TestConsulClientForNamespace_ReturnsCachedClient(t *testing.T) {
+	cluster := NewHelmCluster(t, nil, &ctx{}, &config.TestConfig{}, "test").(*HelmCluster)
+
+	defaultClient, err := api.NewClient(api.DefaultConfig())
+	require.NoError(t, err)
+	nsClient, err := api.NewClient(api.DefaultConfig())
+	require.NoError(t, err)
+	cluster.consulClients[consulClientKey{testName: t.Name(), secure: true}] = defaultClient
+	cluster.consulClients[consulClientKey{testName: t.Name(), secure: true, namespace: "ns1"}] = nsClient
+
+	require.Same(t, nsClient, cluster.ConsulClientForNamespace(t, true, "ns1"))
+	require.Same(t, defaultClient, cluster.SetupConsulClient(t, true))
+}
+
+// Test that resetConsulClients drops the cached clients
+// so that SetupConsulClient creates new ones, e.g. after a rollback.
+func TestResetConsulClients(t *testing.T) {
+	cluster := NewHelmCluster(t, nil, &ctx{}, &config.TestConfig{}, "test").(*HelmCluster)
+
+	cachedClient, err := api.NewClient(api.DefaultConfig())
+	require.NoError(t, err)
+	cluster.consulClients[consulClientKey{testName: t.Name(), secure: true}] = cachedClient
+
+	cluster.resetConsulClients()
+
+	require.Empty(t, cluster.consulClients)
+}
+
+func TestBootstrapToken(t *testing.T) {
+	cluster := NewHelmCluster(t, nil, &ctx{}, &config.TestConfig{}, "test").(*HelmCluster)
+
+	_, err := cluster.kubernetesClient.CoreV1().Secrets("").Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-consul-bootstrap-acl-token"},
+		Data:       map[string][]byte{"token": []byte("bootstrap-token")},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	require.Equal(t, "bootstrap-token", cluster.BootstrapToken(t))
+}
+
 type ctx struct{}
 
 func (c *ctx) Name() string {
@@ -70,6 +157,9 @@ func (c *ctx) Name() string {
 func (c *ctx) KubectlOptions(_ *testing.T) *k8s.KubectlOptions {
 	return &k8s.KubectlOptions{}
 }
+func (c *ctx) KubectlOptionsForNamespace(_ *testing.T, namespace string) *k8s.KubectlOptions {
+	return &k8s.KubectlOptions{Namespace: namespace}
+}
 func (c *ctx) KubernetesClient(_ *testing.T) kubernetes.Interface {
 	return fake.NewSimpleClientset()
 }
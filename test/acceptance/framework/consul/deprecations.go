@@ -0,0 +1,53 @@
+package consul
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// allowedDeprecatedAPIs are the deprecated Kubernetes APIs that the chart may
+// still use, with the reason why. Deprecation warnings for these APIs are only
+// logged; any other deprecated API fails the test.
+var allowedDeprecatedAPIs = map[schema.GroupVersionKind]string{
+	{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget"}: "policy/v1 is only available in Kubernetes 1.21+, which the chart doesn't require",
+	{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"}:   "pod security policies are removed without a replacement in Kubernetes 1.25",
+}
+
+// checkDeprecatedAPIs fails the test if the current revision of the release creates
+// objects with Kubernetes APIs that are deprecated in the version of the cluster,
+// according to the warnings of the API server, unless the API is in allowedDeprecatedAPIs,
+// so that manifests are moved to newer API versions before the old ones are removed.
+func (h *HelmCluster) checkDeprecatedAPIs(t *testing.T) {
+	t.Helper()
+
+	// The manifest is long and isn't useful in the test logs, so it's not logged.
+	options := *h.helmOptions
+	options.Logger = terratestLogger.Discard
+	manifest, err := helm.RunHelmCommandAndGetOutputE(t, &options, "get", "manifest", h.releaseName,
+		"--namespace", options.KubectlOptions.Namespace)
+	require.NoError(t, err)
+
+	var denied []string
+	for gvk, warnings := range k8s.DeprecationWarnings(t, h.helmOptions.KubectlOptions, manifest) {
+		if reason, ok := allowedDeprecatedAPIs[gvk]; ok {
+			logger.Logf(t, "release %s uses deprecated Kubernetes APIs, which is allowed because %s:\n%s",
+				h.releaseName, reason, strings.Join(warnings, "\n"))
+			continue
+		}
+		denied = append(denied, warnings...)
+	}
+	if len(denied) == 0 {
+		return
+	}
+
+	sort.Strings(denied)
+	t.Errorf("release %s uses deprecated Kubernetes APIs:\n%s", h.releaseName, strings.Join(denied, "\n"))
+}
@@ -0,0 +1,31 @@
+package consul
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+)
+
+// ConsulExec runs the consul CLI with args in the consul container of pod,
+// usually one of the server pods of the release, and returns its stdout and stderr.
+// The pods of the chart already point the CLI at their local agent,
+// with the CA certificate when TLS is enabled, so only the ACL token is added:
+// if the release has one, the bootstrap token (or the replication token in
+// a secondary datacenter) is passed in CONSUL_HTTP_TOKEN. An environment
+// variable is used rather than -token because flags have to come before
+// positional arguments, e.g. in `consul snapshot save <file>`.
+// Neither the command nor its output are logged so that the token isn't leaked.
+func (h *HelmCluster) ConsulExec(t *testing.T, pod string, args ...string) (string, string, error) {
+	return k8s.RunKubectlAndGetStdoutStderrE(t, h.helmOptions.KubectlOptions, consulExecArgs(pod, h.debugACLToken(), args)...)
+}
+
+// consulExecArgs returns the kubectl arguments to run the consul CLI
+// with args and token in the consul container of pod.
+func consulExecArgs(pod, token string, args []string) []string {
+	kubectlArgs := []string{"exec", pod, "-c", "consul", "--"}
+	if token != "" {
+		kubectlArgs = append(kubectlArgs, "env", "CONSUL_HTTP_TOKEN="+token)
+	}
+	kubectlArgs = append(kubectlArgs, "consul")
+	return append(kubectlArgs, args...)
+}
@@ -0,0 +1,31 @@
+package consul
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsulExecArgs(t *testing.T) {
+	cases := map[string]struct {
+		token    string
+		args     []string
+		expected []string
+	}{
+		"without token": {
+			args:     []string{"operator", "raft", "list-peers"},
+			expected: []string{"exec", "consul-server-0", "-c", "consul", "--", "consul", "operator", "raft", "list-peers"},
+		},
+		"with token": {
+			token:    "secret",
+			args:     []string{"snapshot", "save", "/tmp/backup.snap"},
+			expected: []string{"exec", "consul-server-0", "-c", "consul", "--", "env", "CONSUL_HTTP_TOKEN=secret", "consul", "snapshot", "save", "/tmp/backup.snap"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, c.expected, consulExecArgs("consul-server-0", c.token, c.args))
+		})
+	}
+}
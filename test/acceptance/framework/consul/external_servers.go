@@ -0,0 +1,79 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ExternalServersHelmValues returns the Helm values that configure a release in
+// clientContext to use the Consul servers of the release serverReleaseName in
+// serverContext as external servers, with clients that join these servers.
+// The servers must have been installed with TLS and system ACLs enabled and Consul
+// clients disabled, since clients from both releases would bind the same host ports.
+//
+// The CA and the bootstrap ACL token of the servers are copied to the namespace of
+// clientContext so that the release there can use them. The copies are deleted when
+// the test finishes.
+func ExternalServersHelmValues(t *testing.T, serverContext, clientContext environment.TestContext, serverReleaseName string, noCleanupOnFailure bool) map[string]string {
+	t.Helper()
+
+	serverNamespace := serverContext.KubectlOptions(t).Namespace
+	serverService := fmt.Sprintf("%s-consul-server.%s.svc", serverReleaseName, serverNamespace)
+
+	caCertSecret := fmt.Sprintf("%s-consul-ca-cert", serverReleaseName)
+	caKeySecret := fmt.Sprintf("%s-consul-ca-key", serverReleaseName)
+	bootstrapTokenSecret := fmt.Sprintf("%s-consul-bootstrap-acl-token", serverReleaseName)
+	for _, name := range []string{caCertSecret, caKeySecret, bootstrapTokenSecret} {
+		copySecret(t, serverContext, clientContext, name, noCleanupOnFailure)
+	}
+
+	return map[string]string{
+		"server.enabled": "false",
+
+		"externalServers.enabled":       "true",
+		"externalServers.hosts[0]":      serverService,
+		"externalServers.httpsPort":     "8501",
+		"externalServers.tlsServerName": "server.dc1.consul",
+		// The servers run in the same Kubernetes cluster, so they can reach its
+		// API server through the kubernetes service to validate service account tokens.
+		"externalServers.k8sAuthMethodHost": "https://kubernetes.default.svc",
+
+		"client.join[0]": serverService,
+
+		"global.tls.enabled":           "true",
+		"global.tls.caCert.secretName": caCertSecret,
+		"global.tls.caCert.secretKey":  "tls.crt",
+		"global.tls.caKey.secretName":  caKeySecret,
+		"global.tls.caKey.secretKey":   "tls.key",
+
+		"global.acls.manageSystemACLs":          "true",
+		"global.acls.bootstrapToken.secretName": bootstrapTokenSecret,
+		"global.acls.bootstrapToken.secretKey":  "token",
+	}
+}
+
+// copySecret copies the secret name from the namespace of sourceContext
+// to the namespace of destContext and deletes the copy when the test finishes.
+func copySecret(t *testing.T, sourceContext, destContext environment.TestContext, name string, noCleanupOnFailure bool) {
+	t.Helper()
+
+	destNamespace := destContext.KubectlOptions(t).Namespace
+	logger.Logf(t, "copying secret %s from namespace %s to namespace %s", name, sourceContext.KubectlOptions(t).Namespace, destNamespace)
+	secret, err := sourceContext.KubernetesClient(t).CoreV1().Secrets(sourceContext.KubectlOptions(t).Namespace).Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	// Drop the labels too so that the copy isn't mistaken for a resource of the source release.
+	secret.ObjectMeta = metav1.ObjectMeta{Name: secret.Name, Namespace: destNamespace}
+	_, err = destContext.KubernetesClient(t).CoreV1().Secrets(destNamespace).Create(context.Background(), secret, metav1.CreateOptions{})
+	require.NoError(t, err)
+	helpers.Cleanup(t, noCleanupOnFailure, func() {
+		_ = destContext.KubernetesClient(t).CoreV1().Secrets(destNamespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	})
+}
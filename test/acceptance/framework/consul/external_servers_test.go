@@ -0,0 +1,66 @@
+package consul
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestExternalServersHelmValues(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	clientContext := &clusterContext{client: client, namespace: "default"}
+	serverContext := environment.ContextForNamespace(clientContext, "consul-servers")
+
+	secrets := map[string]string{
+		"servers-consul-ca-cert":             "tls.crt",
+		"servers-consul-ca-key":              "tls.key",
+		"servers-consul-bootstrap-acl-token": "token",
+	}
+	for name, key := range secrets {
+		_, err := client.CoreV1().Secrets("consul-servers").Create(context.Background(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{"release": "servers"},
+			},
+			Data: map[string][]byte{key: []byte(name)},
+		}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	values := ExternalServersHelmValues(t, serverContext, clientContext, "servers", false)
+	require.Equal(t, "servers-consul-server.consul-servers.svc", values["externalServers.hosts[0]"])
+	require.Equal(t, "servers-consul-server.consul-servers.svc", values["client.join[0]"])
+	require.Equal(t, "false", values["server.enabled"])
+
+	for name, key := range secrets {
+		secret, err := client.CoreV1().Secrets("default").Get(context.Background(), name, metav1.GetOptions{})
+		require.NoError(t, err)
+		require.Equal(t, name, string(secret.Data[key]))
+		require.Empty(t, secret.Labels)
+	}
+}
+
+// clusterContext is a TestContext whose clients share the same fake clientset.
+type clusterContext struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+func (c *clusterContext) KubectlOptions(_ *testing.T) *k8s.KubectlOptions {
+	return &k8s.KubectlOptions{Namespace: c.namespace}
+}
+
+func (c *clusterContext) KubectlOptionsForNamespace(_ *testing.T, namespace string) *k8s.KubectlOptions {
+	return &k8s.KubectlOptions{Namespace: namespace}
+}
+
+func (c *clusterContext) KubernetesClient(_ *testing.T) kubernetes.Interface {
+	return c.client
+}
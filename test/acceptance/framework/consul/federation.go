@@ -0,0 +1,76 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FederationSecretName returns the name of the federation secret
+// created by the primary datacenter installed with releaseName.
+func FederationSecretName(releaseName string) string {
+	return fmt.Sprintf("%s-consul-federation", releaseName)
+}
+
+// CopyFederationSecret copies the federation secret created by the primary datacenter
+// installed with releaseName from the primary context to the secondary context
+// and returns its name. The primary datacenter must have been installed with
+// global.federation.createFederationSecret set to true.
+// The secret doesn't need to be cleaned up separately because HelmCluster.Destroy
+// deletes all secrets that contain the release name.
+func CopyFederationSecret(t *testing.T, primaryContext, secondaryContext environment.TestContext, releaseName string) string {
+	t.Helper()
+
+	federationSecretName := FederationSecretName(releaseName)
+	logger.Logf(t, "retrieving federation secret %s from the primary cluster and applying to the secondary", federationSecretName)
+	federationSecret, err := primaryContext.KubernetesClient(t).CoreV1().Secrets(primaryContext.KubectlOptions(t).Namespace).Get(context.Background(), federationSecretName, metav1.GetOptions{})
+	require.NoError(t, err)
+	federationSecret.ResourceVersion = ""
+	federationSecret.Namespace = secondaryContext.KubectlOptions(t).Namespace
+	_, err = secondaryContext.KubernetesClient(t).CoreV1().Secrets(secondaryContext.KubectlOptions(t).Namespace).Create(context.Background(), federationSecret, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	return federationSecretName
+}
+
+// SecondaryDatacenterHelmValues returns the Helm values that configure a secondary
+// datacenter to federate with the primary datacenter using the federation secret
+// with the given name. If secure is true, it also configures ACL replication
+// from the primary datacenter. Tests need to set the datacenter name and
+// enable the components they need, such as mesh gateways, in addition to these values.
+func SecondaryDatacenterHelmValues(federationSecretName string, secure bool) map[string]string {
+	values := map[string]string{
+		"global.tls.enabled":           "true",
+		"global.tls.httpsOnly":         "false",
+		"global.tls.caCert.secretName": federationSecretName,
+		"global.tls.caCert.secretKey":  "caCert",
+		"global.tls.caKey.secretName":  federationSecretName,
+		"global.tls.caKey.secretKey":   "caKey",
+
+		"global.federation.enabled": "true",
+
+		"server.extraVolumes[0].type":          "secret",
+		"server.extraVolumes[0].name":          federationSecretName,
+		"server.extraVolumes[0].load":          "true",
+		"server.extraVolumes[0].items[0].key":  "serverConfigJSON",
+		"server.extraVolumes[0].items[0].path": "config.json",
+
+		// Enterprise license job will fail if it runs in the secondary DC,
+		// so we're explicitly setting these values to empty to avoid that.
+		"server.enterpriseLicense.secretName": "",
+		"server.enterpriseLicense.secretKey":  "",
+	}
+
+	if secure {
+		values["global.acls.manageSystemACLs"] = "true"
+		values["global.acls.replicationToken.secretName"] = federationSecretName
+		values["global.acls.replicationToken.secretKey"] = "replicationToken"
+	}
+
+	return values
+}
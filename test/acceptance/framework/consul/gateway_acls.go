@@ -0,0 +1,66 @@
+package consul
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+// ServiceWriteRules returns ACL rules that grant service:write on services.
+// If namespace isn't empty, the rules are scoped to that Consul namespace.
+func ServiceWriteRules(namespace string, services ...string) string {
+	var rules strings.Builder
+	for _, service := range services {
+		fmt.Fprintf(&rules, "service %q {\n  policy = \"write\"\n}\n", service)
+	}
+	if namespace == "" {
+		return rules.String()
+	}
+	return fmt.Sprintf("namespace %q {\n%s}\n", namespace, rules.String())
+}
+
+// AddTerminatingGatewayPolicy performs the documented workflow for terminating gateways
+// when the chart manages ACLs with global.acls.manageSystemACLs: it creates a policy
+// named policyName with rules, e.g. from ServiceWriteRules, and adds it to the ACL
+// token that the chart created for the terminating gateway gatewayName, so that the
+// gateway can request Connect certificates for the services it fronts.
+// The gateway picks up the new permissions without restarting.
+func AddTerminatingGatewayPolicy(t *testing.T, consulClient *api.Client, gatewayName, policyName, rules string) {
+	t.Helper()
+
+	logger.Logf(t, "adding policy %s to the token of terminating gateway %s", policyName, gatewayName)
+	policy, _, err := consulClient.ACL().PolicyCreate(&api.ACLPolicy{
+		Name:  policyName,
+		Rules: rules,
+	}, nil)
+	require.NoError(t, err)
+
+	token := TerminatingGatewayToken(t, consulClient, gatewayName)
+	token.Policies = append(token.Policies, &api.ACLTokenPolicyLink{ID: policy.ID})
+	_, _, err = consulClient.ACL().TokenUpdate(token, nil)
+	require.NoError(t, err)
+}
+
+// TerminatingGatewayToken returns the ACL token that the chart created
+// for the terminating gateway gatewayName.
+func TerminatingGatewayToken(t *testing.T, consulClient *api.Client, gatewayName string) *api.ACLToken {
+	t.Helper()
+
+	// server-acl-init names the token of a terminating gateway in its description.
+	description := fmt.Sprintf("%s-terminating-gateway-token", gatewayName)
+	tokens, _, err := consulClient.ACL().TokenList(nil)
+	require.NoError(t, err)
+	for _, token := range tokens {
+		if strings.Contains(token.Description, description) {
+			token, _, err := consulClient.ACL().TokenRead(token.AccessorID, nil)
+			require.NoError(t, err)
+			return token
+		}
+	}
+	require.FailNowf(t, "token not found", "no ACL token for terminating gateway %s", gatewayName)
+	return nil
+}
@@ -0,0 +1,24 @@
+package consul
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceWriteRules(t *testing.T) {
+	require.Equal(t, `service "static-server" {
+  policy = "write"
+}
+`, ServiceWriteRules("", "static-server"))
+
+	require.Equal(t, `namespace "ns1" {
+service "a" {
+  policy = "write"
+}
+service "b" {
+  policy = "write"
+}
+}
+`, ServiceWriteRules("ns1", "a", "b"))
+}
@@ -0,0 +1,70 @@
+package consul
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// checkPollInterval is how often the helpers in this file poll Consul.
+// It bounds the precision of the times they return.
+const checkPollInterval = 1 * time.Second
+
+// WaitForCheckStatus waits up to timeout for the agent check with the given ID
+// to have the given status and returns the time at which the status was observed.
+func WaitForCheckStatus(t *testing.T, client *api.Client, checkID, status string, timeout time.Duration) time.Time {
+	t.Helper()
+
+	var observed time.Time
+	retry.RunWith(&retry.Timer{Timeout: timeout, Wait: checkPollInterval}, t, func(r *retry.R) {
+		checks, err := client.Agent().Checks()
+		require.NoError(r, err)
+		check, ok := checks[checkID]
+		require.True(r, ok, "check %s not found", checkID)
+		require.Equal(r, status, check.Status)
+		observed = time.Now()
+	})
+	logger.Logf(t, "check %s is %s", checkID, status)
+	return observed
+}
+
+// RequireServiceDeregisteredWithin waits for the agent service with the given ID
+// to be deregistered and checks that this happened no earlier than min and no
+// later than max after since, give or take the interval at which it polls Consul.
+// This is used to check that services are automatically deregistered according
+// to DeregisterCriticalServiceAfter. Note that Consul agents reap critical
+// services periodically, so max needs to account for the agent's reap interval.
+func RequireServiceDeregisteredWithin(t *testing.T, client *api.Client, serviceID string, since time.Time, min, max time.Duration) {
+	t.Helper()
+
+	var deregistered time.Time
+	retry.RunWith(&retry.Timer{Timeout: time.Until(since.Add(max)) + checkPollInterval, Wait: checkPollInterval}, t, func(r *retry.R) {
+		services, err := client.Agent().Services()
+		require.NoError(r, err)
+		_, ok := services[serviceID]
+		require.False(r, ok, "service %s is still registered", serviceID)
+		deregistered = time.Now()
+	})
+
+	elapsed := deregistered.Sub(since)
+	logger.Logf(t, "service %s was deregistered after %s", serviceID, elapsed)
+	// Both since, e.g. as returned by WaitForCheckStatus, and the deregistration are
+	// observed by polling, so each can be up to a poll interval later than it happened.
+	require.NoError(t, checkElapsedWithin(elapsed, min-checkPollInterval, max+checkPollInterval))
+}
+
+// checkElapsedWithin returns an error if elapsed is not between min and max.
+func checkElapsedWithin(elapsed, min, max time.Duration) error {
+	if elapsed < min {
+		return fmt.Errorf("expected at least %s to elapse but only %s did", min, elapsed)
+	}
+	if elapsed > max {
+		return fmt.Errorf("expected at most %s to elapse but %s did", max, elapsed)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package consul
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckElapsedWithin(t *testing.T) {
+	cases := map[string]struct {
+		elapsed time.Duration
+		expErr  string
+	}{
+		"too early": {
+			elapsed: 30 * time.Second,
+			expErr:  "expected at least 1m0s to elapse but only 30s did",
+		},
+		"within bounds": {
+			elapsed: 80 * time.Second,
+		},
+		"at lower bound": {
+			elapsed: 1 * time.Minute,
+		},
+		"at upper bound": {
+			elapsed: 2 * time.Minute,
+		},
+		"too late": {
+			elapsed: 3 * time.Minute,
+			expErr:  "expected at most 2m0s to elapse but 3m0s did",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := checkElapsedWithin(c.elapsed, 1*time.Minute, 2*time.Minute)
+			if c.expErr != "" {
+				require.EqualError(t, err, c.expErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package consul
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// NewGossipKey returns a new random gossip encryption key
+// in the format of `consul keygen`.
+func NewGossipKey(t *testing.T) string {
+	t.Helper()
+
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// InstallGossipKey installs the gossip encryption key on all members
+// of the cluster with `consul keyring -install`. The members keep
+// encrypting gossip with their primary key until UseGossipKey is called.
+func (h *HelmCluster) InstallGossipKey(t *testing.T, key string) {
+	t.Helper()
+
+	h.keyring(t, "install", key)
+}
+
+// UseGossipKey makes the gossip encryption key, which has to be installed
+// with InstallGossipKey first, the primary key of all members of the
+// cluster with `consul keyring -use`.
+func (h *HelmCluster) UseGossipKey(t *testing.T, key string) {
+	t.Helper()
+
+	h.keyring(t, "use", key)
+}
+
+// RemoveGossipKey removes the gossip encryption key from all members of
+// the cluster with `consul keyring -remove`. The primary key can't be removed.
+func (h *HelmCluster) RemoveGossipKey(t *testing.T, key string) {
+	t.Helper()
+
+	h.keyring(t, "remove", key)
+}
+
+// keyring runs `consul keyring -<operation> <key>` in the first server pod.
+// The key isn't logged because it is a secret.
+func (h *HelmCluster) keyring(t *testing.T, operation, key string) {
+	t.Helper()
+
+	pod := fmt.Sprintf("%s-consul-server-0", h.releaseName)
+	logger.Logf(t, "running consul keyring -%s in %s", operation, pod)
+
+	_, stderr, err := h.ConsulExec(t, pod, "keyring", fmt.Sprintf("-%s=%s", operation, key))
+	require.NoError(t, err, stderr)
+}
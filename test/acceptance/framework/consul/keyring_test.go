@@ -0,0 +1,16 @@
+package consul
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGossipKey(t *testing.T) {
+	key := NewGossipKey(t)
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	require.NoError(t, err)
+	require.Len(t, decoded, 32)
+	require.NotEqual(t, key, NewGossipKey(t))
+}
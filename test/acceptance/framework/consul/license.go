@@ -0,0 +1,45 @@
+package consul
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// createOrUpdateLicenseSecret creates the enterprise license secret in namespace
+// from the license file at cfg.EnterpriseLicensePath, or updates it if it exists.
+// The secret is shared by all tests installing Consul into the namespace,
+// so it is not deleted when the test finishes.
+func createOrUpdateLicenseSecret(t *testing.T, client kubernetes.Interface, cfg *config.TestConfig, namespace string) {
+	t.Helper()
+
+	license, err := ioutil.ReadFile(cfg.EnterpriseLicensePath)
+	require.NoError(t, err)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: config.LicenseSecretName,
+		},
+		StringData: map[string]string{
+			config.LicenseSecretKey: strings.TrimSpace(string(license)),
+		},
+		Type: corev1.SecretTypeOpaque,
+	}
+
+	_, err = client.CoreV1().Secrets(namespace).Create(context.Background(), secret, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = client.CoreV1().Secrets(namespace).Update(context.Background(), secret, metav1.UpdateOptions{})
+	} else if err == nil {
+		logger.Logf(t, "created enterprise license secret %s from %s", config.LicenseSecretName, cfg.EnterpriseLicensePath)
+	}
+	require.NoError(t, err)
+}
@@ -0,0 +1,39 @@
+package consul
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCreateOrUpdateLicenseSecret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "license")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	licensePath := filepath.Join(dir, "license.hclic")
+	require.NoError(t, ioutil.WriteFile(licensePath, []byte("first-license\n"), 0600))
+
+	client := fake.NewSimpleClientset()
+	cfg := &config.TestConfig{EnterpriseLicensePath: licensePath}
+	createOrUpdateLicenseSecret(t, client, cfg, "default")
+
+	secret, err := client.CoreV1().Secrets("default").Get(context.Background(), config.LicenseSecretName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "first-license", secret.StringData[config.LicenseSecretKey])
+
+	// An existing secret is updated with the new license.
+	require.NoError(t, ioutil.WriteFile(licensePath, []byte("second-license"), 0600))
+	createOrUpdateLicenseSecret(t, client, cfg, "default")
+
+	secret, err = client.CoreV1().Secrets("default").Get(context.Background(), config.LicenseSecretName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "second-license", secret.StringData[config.LicenseSecretKey])
+}
@@ -0,0 +1,122 @@
+package consul
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// raftPeer is a peer in the Raft configuration as reported
+// by `consul operator raft list-peers`.
+type raftPeer struct {
+	Node  string
+	State string
+	Voter bool
+}
+
+// requireServerQuorum checks that all Consul servers of the release agree
+// that there is a leader and that all servers are voters in the Raft configuration.
+// It asks every server rather than one through a port-forward so that
+// a server that failed to rejoin after being restarted is caught too.
+func (h *HelmCluster) requireServerQuorum(t *testing.T) {
+	t.Helper()
+
+	replicas := h.serverReplicas(t)
+	if replicas == 0 {
+		return
+	}
+
+	logger.Logf(t, "checking that the %d servers have a leader and all are voters", replicas)
+	retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		for i := 0; i < replicas; i++ {
+			podName := fmt.Sprintf("%s-consul-server-%d", h.releaseName, i)
+			// ConsulExec reads the token on every attempt, which matters because an upgrade
+			// that enables ACLs creates the bootstrap token after the servers have been restarted.
+			output, stderr, err := h.ConsulExec(t, podName, "operator", "raft", "list-peers")
+			require.NoError(r, err, stderr)
+
+			peers := parseRaftPeers(output)
+			require.Len(r, peers, replicas, "%s has an unexpected number of raft peers: %s", podName, output)
+			leaders := 0
+			for _, peer := range peers {
+				require.True(r, peer.Voter, "%s is not a voter according to %s", peer.Node, podName)
+				if peer.State == "leader" {
+					leaders++
+				}
+			}
+			require.Equal(r, 1, leaders, "%s doesn't know about exactly one leader: %s", podName, output)
+		}
+	})
+}
+
+// releaseServerValues are the values of a release that determine its servers.
+type releaseServerValues struct {
+	Global struct {
+		Enabled bool `json:"enabled"`
+	} `json:"global"`
+	Server struct {
+		// Enabled is either a bool or "-" to inherit global.enabled.
+		Enabled  interface{} `json:"enabled"`
+		Replicas int         `json:"replicas"`
+	} `json:"server"`
+}
+
+// serverReplicas returns the number of servers of the current revision of the release,
+// or 0 if the servers are disabled. It reads the values that helm computed for the release,
+// so that values from values files and the defaults of the chart are taken into account.
+func (h *HelmCluster) serverReplicas(t *testing.T) int {
+	t.Helper()
+
+	output, err := helm.RunHelmCommandAndGetOutputE(t, h.helmOptions, "get", "values", h.releaseName, "--all", "--output", "json")
+	require.NoError(t, err)
+
+	var values releaseServerValues
+	require.NoError(t, json.Unmarshal([]byte(output), &values), "unmarshalling %q", output)
+	return values.replicas()
+}
+
+// replicas returns the number of servers, or 0 if the servers are disabled.
+func (v releaseServerValues) replicas() int {
+	enabled, ok := v.Server.Enabled.(bool)
+	if !ok {
+		enabled = v.Global.Enabled
+	}
+	if !enabled {
+		return 0
+	}
+	return v.Server.Replicas
+}
+
+// parseRaftPeers parses the output of `consul operator raft list-peers`, e.g.
+//
+//	Node                 ID                                    Address          State   Voter  RaftProtocol
+//	test-consul-server-0 4b2a7e55-1f6e-2d1a-3e38-1c6f0e0ba97e  10.0.0.1:8300    leader  true   3
+//
+// Lines that don't look like peers are ignored.
+func parseRaftPeers(output string) []raftPeer {
+	var peers []raftPeer
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || fields[0] == "Node" {
+			continue
+		}
+		voter, err := strconv.ParseBool(fields[4])
+		if err != nil {
+			continue
+		}
+		peers = append(peers, raftPeer{
+			Node:  fields[0],
+			State: fields[3],
+			Voter: voter,
+		})
+	}
+	return peers
+}
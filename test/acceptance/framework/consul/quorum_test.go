@@ -0,0 +1,78 @@
+package consul
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRaftPeers(t *testing.T) {
+	cases := map[string]struct {
+		output   string
+		expPeers []raftPeer
+	}{
+		"empty": {
+			output: "",
+		},
+		"single server": {
+			output: `Node                  ID                                    Address          State   Voter  RaftProtocol
+test-consul-server-0  4b2a7e55-1f6e-2d1a-3e38-1c6f0e0ba97e  10.0.0.1:8300    leader  true   3
+`,
+			expPeers: []raftPeer{
+				{Node: "test-consul-server-0", State: "leader", Voter: true},
+			},
+		},
+		"multiple servers with a non-voter": {
+			output: `Node                  ID                                    Address          State     Voter  RaftProtocol
+test-consul-server-0  4b2a7e55-1f6e-2d1a-3e38-1c6f0e0ba97e  10.0.0.1:8300    leader    true   3
+test-consul-server-1  5c3b8f66-2a7f-3e2b-4f49-2d7a1f1cb08f  10.0.0.2:8300    follower  true   3
+test-consul-server-2  6d4c9a77-3b8a-4f3c-5a5a-3e8b2a2dc19a  10.0.0.3:8300    follower  false  3
+`,
+			expPeers: []raftPeer{
+				{Node: "test-consul-server-0", State: "leader", Voter: true},
+				{Node: "test-consul-server-1", State: "follower", Voter: true},
+				{Node: "test-consul-server-2", State: "follower", Voter: false},
+			},
+		},
+		"error output": {
+			output: "Error getting peers: Failed to retrieve raft configuration: Unexpected response code: 500 (No cluster leader)\n",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, c.expPeers, parseRaftPeers(c.output))
+		})
+	}
+}
+
+func TestReleaseServerValues_replicas(t *testing.T) {
+	cases := map[string]struct {
+		values      string
+		expReplicas int
+	}{
+		"enabled by global.enabled": {
+			values:      `{"global": {"enabled": true}, "server": {"enabled": "-", "replicas": 3}}`,
+			expReplicas: 3,
+		},
+		"disabled by global.enabled": {
+			values: `{"global": {"enabled": false}, "server": {"enabled": "-", "replicas": 3}}`,
+		},
+		"enabled by server.enabled": {
+			values:      `{"global": {"enabled": false}, "server": {"enabled": true, "replicas": 1}}`,
+			expReplicas: 1,
+		},
+		"disabled by server.enabled": {
+			values: `{"global": {"enabled": true}, "server": {"enabled": false, "replicas": 1}}`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var values releaseServerValues
+			require.NoError(t, json.Unmarshal([]byte(c.values), &values))
+			require.Equal(t, c.expReplicas, values.replicas())
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package consul
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// snapshotPath is where Snapshot and Restore keep the snapshot
+// in the server pod while running the consul CLI.
+const snapshotPath = "/tmp/acceptance-test.snap"
+
+// Snapshot saves a snapshot of the state of the Consul servers
+// with `consul snapshot save` and returns it.
+func (h *HelmCluster) Snapshot(t *testing.T) []byte {
+	t.Helper()
+
+	pod := fmt.Sprintf("%s-consul-server-0", h.releaseName)
+	logger.Logf(t, "saving a snapshot in %s", pod)
+
+	_, stderr, err := h.ConsulExec(t, pod, "snapshot", "save", snapshotPath)
+	require.NoError(t, err, stderr)
+	defer h.removeSnapshot(t, pod)
+
+	snapshot, stderr, err := k8s.RunKubectlAndGetStdoutStderrE(t, h.helmOptions.KubectlOptions, "exec", pod, "-c", "consul", "--", "cat", snapshotPath)
+	require.NoError(t, err, stderr)
+	return []byte(snapshot)
+}
+
+// Restore restores snapshot, e.g. one returned by Snapshot, on the Consul servers
+// with `consul snapshot restore`. It replaces all of the state of the servers,
+// including ACL tokens, so Consul clients that use the bootstrap token of the
+// current release stop working if the snapshot is from another installation.
+func (h *HelmCluster) Restore(t *testing.T, snapshot []byte) {
+	t.Helper()
+
+	pod := fmt.Sprintf("%s-consul-server-0", h.releaseName)
+	logger.Logf(t, "restoring a snapshot in %s", pod)
+
+	_, stderr, err := k8s.RunKubectlWithInputAndGetStdoutStderrE(t, h.helmOptions.KubectlOptions, snapshot,
+		"exec", "-i", pod, "-c", "consul", "--", "sh", "-c", "cat > "+snapshotPath)
+	require.NoError(t, err, stderr)
+	defer h.removeSnapshot(t, pod)
+
+	_, stderr, err = h.ConsulExec(t, pod, "snapshot", "restore", snapshotPath)
+	require.NoError(t, err, stderr)
+}
+
+// removeSnapshot removes the snapshot file from pod.
+func (h *HelmCluster) removeSnapshot(t *testing.T, pod string) {
+	_, stderr, err := k8s.RunKubectlAndGetStdoutStderrE(t, h.helmOptions.KubectlOptions, "exec", pod, "-c", "consul", "--", "rm", "-f", snapshotPath)
+	if err != nil {
+		logger.Logf(t, "failed to remove snapshot from %s: %s", pod, stderr)
+	}
+}
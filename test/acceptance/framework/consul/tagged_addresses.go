@@ -0,0 +1,56 @@
+package consul
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// The keys of the tagged addresses that services are registered with.
+const (
+	// TaggedAddressLAN is the address of a mesh gateway in its datacenter.
+	TaggedAddressLAN = "lan"
+	// TaggedAddressWAN is the address of a mesh gateway that other datacenters connect to.
+	TaggedAddressWAN = "wan"
+	// TaggedAddressVirtual is the address that transparent proxies dial a service at,
+	// which is the ClusterIP of its Kubernetes service.
+	TaggedAddressVirtual = "virtual"
+)
+
+// RequireServiceTaggedAddress waits up to a minute for all instances of service
+// in the catalog to have the tagged address key equal to expected, and fails the test if they don't.
+// There must be at least one instance of service.
+func RequireServiceTaggedAddress(t *testing.T, client *api.Client, service, key string, expected api.ServiceAddress, q *api.QueryOptions) {
+	t.Helper()
+
+	retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: checkPollInterval}, t, func(r *retry.R) {
+		instances, _, err := client.Catalog().Service(service, "", q)
+		require.NoError(r, err)
+		require.NoError(r, checkTaggedAddress(instances, key, expected))
+	})
+	logger.Logf(t, "service %s has the %s tagged address %s:%d", service, key, expected.Address, expected.Port)
+}
+
+// checkTaggedAddress returns an error if there are no instances or if any
+// of the instances doesn't have the tagged address key equal to expected.
+func checkTaggedAddress(instances []*api.CatalogService, key string, expected api.ServiceAddress) error {
+	if len(instances) == 0 {
+		return fmt.Errorf("no instances registered")
+	}
+	for _, instance := range instances {
+		actual, ok := instance.ServiceTaggedAddresses[key]
+		if !ok {
+			return fmt.Errorf("instance %s has no %s tagged address", instance.ServiceID, key)
+		}
+		if actual != expected {
+			return fmt.Errorf("instance %s has the %s tagged address %s:%d, expected %s:%d",
+				instance.ServiceID, key, actual.Address, actual.Port, expected.Address, expected.Port)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package consul
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTaggedAddress(t *testing.T) {
+	virtual := api.ServiceAddress{Address: "10.96.0.10", Port: 80}
+
+	cases := map[string]struct {
+		instances []*api.CatalogService
+		expErr    string
+	}{
+		"no instances": {
+			expErr: "no instances registered",
+		},
+		"all instances have the address": {
+			instances: []*api.CatalogService{
+				{ServiceID: "static-server-1", ServiceTaggedAddresses: map[string]api.ServiceAddress{TaggedAddressVirtual: virtual}},
+				{ServiceID: "static-server-2", ServiceTaggedAddresses: map[string]api.ServiceAddress{TaggedAddressVirtual: virtual}},
+			},
+		},
+		"an instance is missing the address": {
+			instances: []*api.CatalogService{
+				{ServiceID: "static-server-1", ServiceTaggedAddresses: map[string]api.ServiceAddress{TaggedAddressVirtual: virtual}},
+				{ServiceID: "static-server-2"},
+			},
+			expErr: "instance static-server-2 has no virtual tagged address",
+		},
+		"an instance has another address": {
+			instances: []*api.CatalogService{
+				{ServiceID: "static-server-1", ServiceTaggedAddresses: map[string]api.ServiceAddress{TaggedAddressVirtual: {Address: "10.96.0.10", Port: 8080}}},
+			},
+			expErr: "instance static-server-1 has the virtual tagged address 10.96.0.10:8080, expected 10.96.0.10:80",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := checkTaggedAddress(c.instances, TaggedAddressVirtual, virtual)
+			if c.expErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, c.expErr)
+			}
+		})
+	}
+}
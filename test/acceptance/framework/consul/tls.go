@@ -0,0 +1,56 @@
+package consul
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (h *HelmCluster) CACert(t *testing.T) []byte {
+	t.Helper()
+
+	secretName, secretKey := h.caCertSecret()
+	secret, err := h.kubernetesClient.CoreV1().Secrets(h.helmOptions.KubectlOptions.Namespace).Get(context.Background(), secretName, metav1.GetOptions{})
+	require.NoError(t, err)
+	caCert, ok := secret.Data[secretKey]
+	require.True(t, ok, "secret %s has no key %s", secretName, secretKey)
+	return caCert
+}
+
+// caCertSecret returns the name and key of the secret with the CA certificate.
+// It is the secret created by tls-init unless the CA is provided
+// with global.tls.caCert in the helm values.
+func (h *HelmCluster) caCertSecret() (string, string) {
+	secretName := h.helmOptions.SetValues["global.tls.caCert.secretName"]
+	if secretName == "" {
+		secretName = h.releaseName + "-consul-ca-cert"
+	}
+	secretKey := h.helmOptions.SetValues["global.tls.caCert.secretKey"]
+	if secretKey == "" {
+		secretKey = "tls.crt"
+	}
+	return secretName, secretKey
+}
+
+// WriteCACertFile writes the CA certificate of cluster to a temporary file
+// and returns its path, e.g. to pass it as -ca-file to the consul CLI or
+// to build a TLS config with api.SetupTLSConfig. The file is removed when the test finishes.
+func WriteCACertFile(t *testing.T, cluster Cluster) string {
+	t.Helper()
+
+	f, err := ioutil.TempFile("", "consul-ca-*.pem")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.Remove(f.Name())
+	})
+
+	_, err = f.Write(cluster.CACert(t))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	return f.Name()
+}
@@ -0,0 +1,49 @@
+package consul
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCACert(t *testing.T) {
+	cases := map[string]struct {
+		helmValues map[string]string
+		secret     *corev1.Secret
+	}{
+		"generated by tls-init": {
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-consul-ca-cert"},
+				Data:       map[string][]byte{"tls.crt": []byte("ca-cert")},
+			},
+		},
+		"provided in helm values": {
+			helmValues: map[string]string{
+				"global.tls.caCert.secretName": "custom-ca",
+				"global.tls.caCert.secretKey":  "ca.pem",
+			},
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "custom-ca"},
+				Data:       map[string][]byte{"ca.pem": []byte("ca-cert")},
+			},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cluster := NewHelmCluster(t, c.helmValues, &ctx{}, &config.TestConfig{}, "test").(*HelmCluster)
+			_, err := cluster.kubernetesClient.CoreV1().Secrets("").Create(context.Background(), c.secret, metav1.CreateOptions{})
+			require.NoError(t, err)
+
+			require.Equal(t, []byte("ca-cert"), cluster.CACert(t))
+
+			contents, err := ioutil.ReadFile(WriteCACertFile(t, cluster))
+			require.NoError(t, err)
+			require.Equal(t, []byte("ca-cert"), contents)
+		})
+	}
+}
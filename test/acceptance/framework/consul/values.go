@@ -0,0 +1,99 @@
+package consul
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+// WriteValuesFile writes nested helm values to a temporary YAML file
+// and returns its path so that it can be passed to NewHelmCluster.
+// This is useful for values that are hard to express with --set,
+// such as lists of maps or affinity blocks. The file is removed when the test finishes.
+func WriteValuesFile(t *testing.T, values map[string]interface{}) string {
+	t.Helper()
+
+	contents, err := yaml.Marshal(values)
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "values-*.yaml")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.Remove(f.Name())
+	})
+
+	_, err = f.Write(contents)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	return f.Name()
+}
+
+// valuesFileKeys returns the keys set by the values files in --set notation,
+// e.g. "server.replicas" or "server.extraVolumes[0].name".
+func valuesFileKeys(t *testing.T, valuesFiles []string) []string {
+	t.Helper()
+
+	var keys []string
+	for _, valuesFile := range valuesFiles {
+		contents, err := ioutil.ReadFile(valuesFile)
+		require.NoError(t, err)
+
+		var values map[string]interface{}
+		require.NoError(t, yaml.Unmarshal(contents, &values), "parsing values file %s", valuesFile)
+		keys = append(keys, flattenValueKeys("", values)...)
+	}
+	return keys
+}
+
+// flattenValueKeys returns the keys of the leaves of value in --set notation.
+func flattenValueKeys(prefix string, value interface{}) []string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && prefix != "" {
+			return []string{prefix}
+		}
+		var keys []string
+		for k, child := range v {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			keys = append(keys, flattenValueKeys(key, child)...)
+		}
+		return keys
+	case []interface{}:
+		if len(v) == 0 {
+			return []string{prefix}
+		}
+		var keys []string
+		for i, child := range v {
+			keys = append(keys, flattenValueKeys(fmt.Sprintf("%s[%d]", prefix, i), child)...)
+		}
+		return keys
+	default:
+		return []string{prefix}
+	}
+}
+
+// setByValuesFiles returns true if key, in --set notation, is set by one of
+// valuesFileKeys, either directly or because the values files set a parent
+// or a child of key.
+func setByValuesFiles(key string, valuesFileKeys []string) bool {
+	for _, fileKey := range valuesFileKeys {
+		if key == fileKey || isParentKey(fileKey, key) || isParentKey(key, fileKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// isParentKey returns true if child is nested under parent in --set notation.
+func isParentKey(parent, child string) bool {
+	return strings.HasPrefix(child, parent+".") || strings.HasPrefix(child, parent+"[")
+}
@@ -0,0 +1,61 @@
+package consul
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlattenValueKeys(t *testing.T) {
+	values := map[string]interface{}{
+		"global": map[string]interface{}{
+			"image": "consul",
+			"tls": map[string]interface{}{
+				"enabled": true,
+			},
+		},
+		"ingressGateways": map[string]interface{}{
+			"gateways": []interface{}{
+				map[string]interface{}{"name": "gateway1"},
+				map[string]interface{}{"name": "gateway2", "replicas": 2},
+			},
+		},
+		"server": map[string]interface{}{
+			"affinity":     nil,
+			"extraConfig":  map[string]interface{}{},
+			"extraVolumes": []interface{}{},
+		},
+	}
+
+	keys := flattenValueKeys("", values)
+	sort.Strings(keys)
+	require.Equal(t, []string{
+		"global.image",
+		"global.tls.enabled",
+		"ingressGateways.gateways[0].name",
+		"ingressGateways.gateways[1].name",
+		"ingressGateways.gateways[1].replicas",
+		"server.affinity",
+		"server.extraConfig",
+		"server.extraVolumes",
+	}, keys)
+}
+
+func TestSetByValuesFiles(t *testing.T) {
+	fileKeys := []string{"server.replicas", "client.extraVolumes[0].name", "connectInject.transparentProxy"}
+
+	cases := map[string]bool{
+		"server.replicas":        true,
+		"server.bootstrapExpect": false,
+		"server":                 true,
+		"client.extraVolumes":    true,
+		"connectInject.transparentProxy.defaultEnabled": true,
+		"connectInject.transparentProxyEnabled":         false,
+	}
+	for key, expected := range cases {
+		t.Run(key, func(t *testing.T) {
+			require.Equal(t, expected, setByValuesFiles(key, fileKeys))
+		})
+	}
+}
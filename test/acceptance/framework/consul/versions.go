@@ -0,0 +1,45 @@
+package consul
+
+import (
+	"hash/fnv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+)
+
+// RunForEachConsulImage runs fn as a subtest for each of the images in cfg.ConsulImages.
+// The config passed to fn is a copy of cfg with ConsulImage set to the image,
+// so that clusters created with it use that image. If cfg.ConsulImages is empty,
+// fn runs once, not as a subtest, with cfg.
+func RunForEachConsulImage(t *testing.T, cfg *config.TestConfig, fn func(t *testing.T, cfg *config.TestConfig)) {
+	t.Helper()
+
+	if len(cfg.ConsulImages) == 0 {
+		fn(t, cfg)
+		return
+	}
+
+	for _, image := range cfg.ConsulImages {
+		imageCfg := *cfg
+		imageCfg.ConsulImage = image
+		t.Run(imageTestName(image), func(t *testing.T) {
+			fn(t, &imageCfg)
+		})
+	}
+}
+
+// imageTestName returns the name of the subtest for image.
+// Slashes in test names create nested subtests, so they are replaced.
+func imageTestName(image string) string {
+	return strings.ReplaceAll(image, "/", "_")
+}
+
+// canaryImage returns the image out of images that the test testName installs
+// in version canary mode. The image only depends on the test name so that all
+// clusters of a test use the same image and reruns of a test use the same image.
+func canaryImage(images []string, testName string) string {
+	h := fnv.New32a()
+	h.Write([]byte(testName))
+	return images[h.Sum32()%uint32(len(images))]
+}
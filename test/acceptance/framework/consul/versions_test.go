@@ -0,0 +1,58 @@
+package consul
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunForEachConsulImage(t *testing.T) {
+	cfg := &config.TestConfig{
+		ConsulImage:  "hashicorp/consul:1.9.0",
+		ConsulImages: []string{"hashicorp/consul:1.9.5", "hashicorp/consul:1.10.0"},
+	}
+
+	var names, images []string
+	RunForEachConsulImage(t, cfg, func(t *testing.T, cfg *config.TestConfig) {
+		names = append(names, t.Name())
+		images = append(images, cfg.ConsulImage)
+	})
+	require.Equal(t, []string{
+		"TestRunForEachConsulImage/hashicorp_consul:1.9.5",
+		"TestRunForEachConsulImage/hashicorp_consul:1.10.0",
+	}, names)
+	require.Equal(t, []string{"hashicorp/consul:1.9.5", "hashicorp/consul:1.10.0"}, images)
+
+	// The original config is not modified.
+	require.Equal(t, "hashicorp/consul:1.9.0", cfg.ConsulImage)
+}
+
+func TestRunForEachConsulImage_NoImages(t *testing.T) {
+	cfg := &config.TestConfig{ConsulImage: "hashicorp/consul:1.9.0"}
+
+	var images []string
+	RunForEachConsulImage(t, cfg, func(subT *testing.T, cfg *config.TestConfig) {
+		require.Equal(t, t, subT)
+		images = append(images, cfg.ConsulImage)
+	})
+	require.Equal(t, []string{"hashicorp/consul:1.9.0"}, images)
+}
+
+func TestCanaryImage(t *testing.T) {
+	images := []string{"hashicorp/consul:1.10.0", "hashicorp/consul:1.9.5"}
+
+	counts := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		testName := fmt.Sprintf("TestExample/case_%d", i)
+		image := canaryImage(images, testName)
+		require.Equal(t, image, canaryImage(images, testName), "expected the same image for the same test")
+		counts[image]++
+	}
+
+	// Both images are used for a reasonable share of the tests.
+	for _, image := range images {
+		require.Greater(t, counts[image], 25, "image %s is used by %d out of 100 tests", image, counts[image])
+	}
+}
@@ -0,0 +1,66 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// AKSProvider creates AKS clusters using the Azure CLI. Credentials are read
+// by the Azure CLI from the logged in account or, for service principals,
+// from the AZURE_CLIENT_ID, AZURE_CLIENT_SECRET and AZURE_TENANT_ID environment
+// variables after running "az login --service-principal".
+type AKSProvider struct {
+	// ResourceGroup is the existing Azure resource group to create clusters in.
+	ResourceGroup string
+	// Location is the Azure location to create clusters in. If empty,
+	// the location of the resource group is used.
+	Location string
+	// Nodes is the number of nodes to create in the default node pool.
+	Nodes int
+}
+
+func (a *AKSProvider) CreateCluster(name, kubeconfigDir string) (*ProvisionedCluster, error) {
+	cluster := &ProvisionedCluster{
+		Name:           name,
+		KubeconfigPath: filepath.Join(kubeconfigDir, fmt.Sprintf("%s.kubeconfig", name)),
+		// az aks get-credentials names the context after the cluster.
+		ContextName: name,
+	}
+
+	args := []string{"aks", "create",
+		"--resource-group", a.ResourceGroup,
+		"--name", cluster.Name,
+		"--node-count", strconv.Itoa(a.Nodes),
+		"--generate-ssh-keys",
+	}
+	if a.Location != "" {
+		args = append(args, "--location", a.Location)
+	}
+	if err := runCommand("az", args...); err != nil {
+		// The cluster may have been partially created,
+		// so return it so that the caller can attempt to delete it.
+		return cluster, fmt.Errorf("creating AKS cluster %s: %s", name, err)
+	}
+
+	err := runCommand("az", "aks", "get-credentials",
+		"--resource-group", a.ResourceGroup,
+		"--name", cluster.Name,
+		"--file", cluster.KubeconfigPath)
+	if err != nil {
+		return cluster, fmt.Errorf("getting credentials for AKS cluster %s: %s", name, err)
+	}
+	return cluster, nil
+}
+
+func (a *AKSProvider) DeleteCluster(cluster *ProvisionedCluster) error {
+	err := runCommand("az", "aks", "delete",
+		"--resource-group", a.ResourceGroup,
+		"--name", cluster.Name,
+		"--yes")
+	if err != nil {
+		return fmt.Errorf("deleting AKS cluster %s: %s", cluster.Name, err)
+	}
+	return os.RemoveAll(cluster.KubeconfigPath)
+}
@@ -0,0 +1,63 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// EKSProvider creates EKS clusters using eksctl. AWS credentials are read
+// by eksctl from the environment, e.g. AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY,
+// or from the shared credentials file for the given Profile.
+type EKSProvider struct {
+	// Region is the AWS region to create clusters in.
+	Region string
+	// Profile is the AWS profile to use. If empty, the default credential chain is used.
+	Profile string
+	// Nodes is the number of nodes to create in the default node group.
+	Nodes int
+}
+
+func (e *EKSProvider) CreateCluster(name, kubeconfigDir string) (*ProvisionedCluster, error) {
+	cluster := &ProvisionedCluster{
+		Name:           name,
+		KubeconfigPath: filepath.Join(kubeconfigDir, fmt.Sprintf("%s.kubeconfig", name)),
+	}
+
+	args := []string{"create", "cluster", "--name", cluster.Name, "--kubeconfig", cluster.KubeconfigPath, "--set-kubeconfig-context",
+		"--nodes", strconv.Itoa(e.Nodes)}
+	args = append(args, e.commonArgs()...)
+	if err := runCommand("eksctl", args...); err != nil {
+		// eksctl can leave a partially created CloudFormation stack behind,
+		// so return the cluster so that the caller can attempt to delete it.
+		return cluster, fmt.Errorf("creating EKS cluster %s: %s", name, err)
+	}
+
+	contextName, err := currentContext(cluster.KubeconfigPath)
+	if err != nil {
+		return cluster, err
+	}
+	cluster.ContextName = contextName
+	return cluster, nil
+}
+
+func (e *EKSProvider) DeleteCluster(cluster *ProvisionedCluster) error {
+	args := []string{"delete", "cluster", "--name", cluster.Name, "--wait"}
+	args = append(args, e.commonArgs()...)
+	if err := runCommand("eksctl", args...); err != nil {
+		return fmt.Errorf("deleting EKS cluster %s: %s", cluster.Name, err)
+	}
+	return os.RemoveAll(cluster.KubeconfigPath)
+}
+
+func (e *EKSProvider) commonArgs() []string {
+	var args []string
+	if e.Region != "" {
+		args = append(args, "--region", e.Region)
+	}
+	if e.Profile != "" {
+		args = append(args, "--profile", e.Profile)
+	}
+	return args
+}
@@ -7,64 +7,67 @@ import (
 	"github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	frameworkk8s "github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
 	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
 const (
-	DefaultContextName   = "default"
-	SecondaryContextName = "secondary"
+	DefaultContextIndex   = 0
+	SecondaryContextIndex = 1
 )
 
 // TestEnvironment represents the infrastructure environment of the test,
 // such as the kubernetes cluster(s) the test is running against
 type TestEnvironment interface {
 	DefaultContext(t *testing.T) TestContext
-	Context(t *testing.T, name string) TestContext
+	// Context returns the context of the Kubernetes cluster at the given index.
+	// Index 0 is the default cluster and index 1 is the secondary cluster.
+	Context(t *testing.T, index int) TestContext
+	// ContextCount returns the number of Kubernetes clusters in the environment.
+	ContextCount() int
 }
 
 // TestContext represents a specific context a test needs,
 // for example, information about a specific Kubernetes cluster.
 type TestContext interface {
 	KubectlOptions(t *testing.T) *k8s.KubectlOptions
+	// KubectlOptionsForNamespace returns a copy of KubectlOptions
+	// with the namespace set to namespace.
+	KubectlOptionsForNamespace(t *testing.T, namespace string) *k8s.KubectlOptions
 	KubernetesClient(t *testing.T) kubernetes.Interface
 }
 
 type KubernetesEnvironment struct {
-	contexts map[string]*kubernetesContext
+	contexts []*kubernetesContext
 }
 
 func NewKubernetesEnvironmentFromConfig(config *config.TestConfig) *KubernetesEnvironment {
-	defaultContext := NewContext(config.KubeNamespace, config.Kubeconfig, config.KubeContext)
-
-	// Create a kubernetes environment with default context.
-	kenv := &KubernetesEnvironment{
-		contexts: map[string]*kubernetesContext{
-			DefaultContextName: defaultContext,
-		},
-	}
-
-	// Add secondary context if multi cluster tests are enabled.
-	if config.EnableMultiCluster {
-		kenv.contexts[SecondaryContextName] = NewContext(config.SecondaryKubeNamespace, config.SecondaryKubeconfig, config.SecondaryKubeContext)
+	// Create a kubernetes environment with a context for every configured cluster.
+	// The default context is always first, followed by the secondary and any additional
+	// contexts if multi cluster tests are enabled.
+	kenv := &KubernetesEnvironment{}
+	for _, env := range config.KubeEnvs() {
+		kenv.contexts = append(kenv.contexts, NewContext(env.KubeNamespace, env.Kubeconfig, env.KubeContext))
 	}
 
 	return kenv
 }
 
-func (k *KubernetesEnvironment) Context(t *testing.T, name string) TestContext {
-	ctx, ok := k.contexts[name]
-	require.Truef(t, ok, fmt.Sprintf("requested context %s not found", name))
+func (k *KubernetesEnvironment) Context(t *testing.T, index int) TestContext {
+	require.Truef(t, index >= 0 && index < len(k.contexts),
+		fmt.Sprintf("requested context %d not found; the environment has %d context(s)", index, len(k.contexts)))
 
-	return ctx
+	return k.contexts[index]
 }
 
-func (k *KubernetesEnvironment) DefaultContext(t *testing.T) TestContext {
-	ctx, ok := k.contexts[DefaultContextName]
-	require.Truef(t, ok, "default context not found")
+func (k *KubernetesEnvironment) ContextCount() int {
+	return len(k.contexts)
+}
 
-	return ctx
+func (k *KubernetesEnvironment) DefaultContext(t *testing.T) TestContext {
+	return k.Context(t, DefaultContextIndex)
 }
 
 type kubernetesContext struct {
@@ -108,6 +111,10 @@ func (k kubernetesContext) KubectlOptions(t *testing.T) *k8s.KubectlOptions {
 	return k.options
 }
 
+func (k kubernetesContext) KubectlOptionsForNamespace(t *testing.T, namespace string) *k8s.KubectlOptions {
+	return frameworkk8s.KubectlOptionsForNamespace(k.KubectlOptions(t), namespace)
+}
+
 func (k kubernetesContext) KubernetesClient(t *testing.T) kubernetes.Interface {
 	if k.client != nil {
 		return k.client
@@ -125,3 +132,20 @@ func NewContext(namespace, pathToKubeConfig, kubeContextName string) *kubernetes
 		kubeContextName:  kubeContextName,
 	}
 }
+
+// ContextForNamespace returns a copy of ctx that uses namespace by default,
+// e.g. to install a second Consul release next to the one in the namespace
+// of ctx, since the framework expects one release per namespace.
+// The namespace must exist before the context is used.
+func ContextForNamespace(ctx TestContext, namespace string) TestContext {
+	return namespacedContext{TestContext: ctx, namespace: namespace}
+}
+
+type namespacedContext struct {
+	TestContext
+	namespace string
+}
+
+func (n namespacedContext) KubectlOptions(t *testing.T) *k8s.KubectlOptions {
+	return n.TestContext.KubectlOptionsForNamespace(t, n.namespace)
+}
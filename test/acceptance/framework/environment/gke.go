@@ -0,0 +1,66 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// GKEProvider creates GKE clusters using gcloud. Credentials are read
+// by gcloud from the active account or GOOGLE_APPLICATION_CREDENTIALS.
+type GKEProvider struct {
+	// Project is the GCP project to create clusters in.
+	// It's also used to configure the workload identity pool.
+	Project string
+	// Zone is the GCP zone to create clusters in. If empty,
+	// the default zone from the gcloud config is used.
+	Zone string
+	// Nodes is the number of nodes to create in the default node pool.
+	Nodes int
+}
+
+func (g *GKEProvider) CreateCluster(name, kubeconfigDir string) (*ProvisionedCluster, error) {
+	cluster := &ProvisionedCluster{
+		Name:           name,
+		KubeconfigPath: filepath.Join(kubeconfigDir, fmt.Sprintf("%s.kubeconfig", name)),
+	}
+
+	args := []string{"container", "clusters", "create", cluster.Name,
+		"--num-nodes", strconv.Itoa(g.Nodes),
+		"--workload-pool", fmt.Sprintf("%s.svc.id.goog", g.Project),
+	}
+	args = append(args, g.commonArgs()...)
+	// gcloud writes the credentials of the new cluster to the file in KUBECONFIG.
+	env := []string{"KUBECONFIG=" + cluster.KubeconfigPath}
+	if err := runCommandWithEnv(env, "gcloud", args...); err != nil {
+		// The cluster may have been partially created,
+		// so return it so that the caller can attempt to delete it.
+		return cluster, fmt.Errorf("creating GKE cluster %s: %s", name, err)
+	}
+
+	contextName, err := currentContext(cluster.KubeconfigPath)
+	if err != nil {
+		return cluster, err
+	}
+	cluster.ContextName = contextName
+	return cluster, nil
+}
+
+func (g *GKEProvider) DeleteCluster(cluster *ProvisionedCluster) error {
+	args := []string{"container", "clusters", "delete", cluster.Name, "--quiet"}
+	args = append(args, g.commonArgs()...)
+	env := []string{"KUBECONFIG=" + cluster.KubeconfigPath}
+	if err := runCommandWithEnv(env, "gcloud", args...); err != nil {
+		return fmt.Errorf("deleting GKE cluster %s: %s", cluster.Name, err)
+	}
+	return os.RemoveAll(cluster.KubeconfigPath)
+}
+
+func (g *GKEProvider) commonArgs() []string {
+	args := []string{"--project", g.Project}
+	if g.Zone != "" {
+		args = append(args, "--zone", g.Zone)
+	}
+	return args
+}
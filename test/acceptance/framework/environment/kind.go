@@ -0,0 +1,42 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// KindProvider creates local kind clusters.
+type KindProvider struct {
+	// Images are docker images to load from the local docker daemon into
+	// all nodes of the cluster. This allows tests to use locally built images
+	// without pushing them to a registry.
+	Images []string
+}
+
+func (k *KindProvider) CreateCluster(name, kubeconfigDir string) (*ProvisionedCluster, error) {
+	cluster := &ProvisionedCluster{
+		Name:           name,
+		KubeconfigPath: filepath.Join(kubeconfigDir, fmt.Sprintf("%s.kubeconfig", name)),
+		ContextName:    "kind-" + name,
+	}
+
+	err := runCommand("kind", "create", "cluster", "--name", cluster.Name, "--kubeconfig", cluster.KubeconfigPath, "--wait", "5m")
+	if err != nil {
+		return nil, fmt.Errorf("creating kind cluster %s: %s", name, err)
+	}
+
+	for _, image := range k.Images {
+		if err := runCommand("kind", "load", "docker-image", image, "--name", cluster.Name); err != nil {
+			return cluster, fmt.Errorf("loading image %s into kind cluster %s: %s", image, cluster.Name, err)
+		}
+	}
+	return cluster, nil
+}
+
+func (k *KindProvider) DeleteCluster(cluster *ProvisionedCluster) error {
+	if err := runCommand("kind", "delete", "cluster", "--name", cluster.Name, "--kubeconfig", cluster.KubeconfigPath); err != nil {
+		return fmt.Errorf("deleting kind cluster %s: %s", cluster.Name, err)
+	}
+	return os.RemoveAll(cluster.KubeconfigPath)
+}
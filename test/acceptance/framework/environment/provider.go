@@ -0,0 +1,57 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Provider creates and deletes Kubernetes clusters
+// for the duration of a test run.
+type Provider interface {
+	// CreateCluster creates a cluster with the given name and writes
+	// its kubeconfig to a file in kubeconfigDir.
+	CreateCluster(name, kubeconfigDir string) (*ProvisionedCluster, error)
+	// DeleteCluster deletes a cluster previously created by CreateCluster.
+	DeleteCluster(cluster *ProvisionedCluster) error
+}
+
+// ProvisionedCluster is a Kubernetes cluster created by a Provider.
+type ProvisionedCluster struct {
+	// Name is the name of the cluster.
+	Name string
+	// KubeconfigPath is the path to the kubeconfig file
+	// that contains the credentials for the cluster.
+	KubeconfigPath string
+	// ContextName is the name of the Kubernetes context in the kubeconfig file
+	// to use for the cluster.
+	ContextName string
+}
+
+// currentContext returns the current context of the kubeconfig file at kubeconfigPath.
+// Providers that don't have a predictable context name use it to find the context
+// that their CLI wrote to a newly created kubeconfig file.
+func currentContext(kubeconfigPath string) (string, error) {
+	output, err := exec.Command("kubectl", "config", "current-context", "--kubeconfig", kubeconfigPath).Output()
+	if err != nil {
+		return "", fmt.Errorf("reading current context from %s: %s", kubeconfigPath, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// runCommand runs the command with the given args, streaming its output
+// so that progress is visible while clusters are created and deleted.
+func runCommand(command string, args ...string) error {
+	return runCommandWithEnv(nil, command, args...)
+}
+
+// runCommandWithEnv is the same as runCommand but it also sets
+// the given environment variables in addition to the current environment.
+func runCommandWithEnv(env []string, command string, args ...string) error {
+	cmd := exec.Command(command, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
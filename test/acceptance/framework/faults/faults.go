@@ -0,0 +1,192 @@
+// Package faults injects faults at fixed points of the framework's helpers, e.g.
+// a delay between installing the chart and deploying a workload, so that flaky
+// tests caused by races can be reproduced deterministically and turned into
+// regression tests.
+//
+// Faults are either configured for all tests with the CONSUL_HELM_TEST_FAULTS
+// environment variable, or by a test for itself and its subtests with Set.
+package faults
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+)
+
+// EnvVar is the environment variable that configures faults for all tests,
+// as a comma-separated list of <point>=<fault>, where fault is either
+// delay:<duration>, e.g. after-install=delay:1m, or fail.
+const EnvVar = "CONSUL_HELM_TEST_FAULTS"
+
+// The points of the helpers that faults can be injected at.
+const (
+	// BeforeInstall is before a Consul Helm release is installed.
+	BeforeInstall = "before-install"
+	// AfterInstall is after the pods of a Consul Helm release are ready.
+	AfterInstall = "after-install"
+	// BeforeUpgrade is before a Consul Helm release is upgraded.
+	BeforeUpgrade = "before-upgrade"
+	// AfterUpgrade is after the pods of an upgraded Consul Helm release are ready.
+	AfterUpgrade = "after-upgrade"
+	// BeforeDeploy is before a workload is deployed with k8s.Deploy or k8s.DeployKustomize.
+	BeforeDeploy = "before-deploy"
+	// AfterDeploy is after a workload deployed with k8s.Deploy or k8s.DeployKustomize is available.
+	AfterDeploy = "after-deploy"
+)
+
+// Points are the points that faults can be injected at.
+var Points = []string{BeforeInstall, AfterInstall, BeforeUpgrade, AfterUpgrade, BeforeDeploy, AfterDeploy}
+
+// Fault is a fault to inject at a point.
+type Fault struct {
+	// Delay is how long to sleep at the point.
+	Delay time.Duration
+	// Fail fails the test at the point, after the delay.
+	Fail bool
+}
+
+func (f Fault) String() string {
+	if f.Fail && f.Delay > 0 {
+		return fmt.Sprintf("delay %s, then fail", f.Delay)
+	}
+	if f.Fail {
+		return "fail"
+	}
+	return fmt.Sprintf("delay %s", f.Delay)
+}
+
+// testFault is a fault that a test has set for itself and its subtests.
+type testFault struct {
+	test  string
+	point string
+	fault Fault
+}
+
+var (
+	// envOnce parses EnvVar into envFaults, or envErr if it's invalid.
+	envOnce   sync.Once
+	envFaults map[string]Fault
+	envErr    error
+
+	// lock protects testFaults.
+	lock       sync.Mutex
+	testFaults []testFault
+)
+
+// Parse parses a comma-separated list of <point>=<fault> in the format of EnvVar.
+func Parse(spec string) (map[string]Fault, error) {
+	faults := make(map[string]Fault)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("fault %q must be in the format <point>=<fault>", entry)
+		}
+		point, action := parts[0], parts[1]
+		if !isPoint(point) {
+			return nil, fmt.Errorf("fault %q has unknown point %q, must be one of %s", entry, point, strings.Join(Points, ", "))
+		}
+
+		var fault Fault
+		switch {
+		case action == "fail":
+			fault.Fail = true
+		case strings.HasPrefix(action, "delay:"):
+			delay, err := time.ParseDuration(strings.TrimPrefix(action, "delay:"))
+			if err != nil {
+				return nil, fmt.Errorf("fault %q has invalid delay: %s", entry, err)
+			}
+			fault.Delay = delay
+		default:
+			return nil, fmt.Errorf("fault %q has unknown fault %q, must be delay:<duration> or fail", entry, action)
+		}
+		faults[point] = fault
+	}
+	return faults, nil
+}
+
+// Set injects fault at point in t and its subtests until t finishes,
+// in addition to any fault configured for point with EnvVar.
+func Set(t *testing.T, point string, fault Fault) {
+	t.Helper()
+
+	if !isPoint(point) {
+		t.Fatalf("unknown fault injection point %q, must be one of %s", point, strings.Join(Points, ", "))
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	f := testFault{test: t.Name(), point: point, fault: fault}
+	testFaults = append(testFaults, f)
+	t.Cleanup(func() {
+		lock.Lock()
+		defer lock.Unlock()
+		for i, other := range testFaults {
+			if other == f {
+				testFaults = append(testFaults[:i], testFaults[i+1:]...)
+				break
+			}
+		}
+	})
+}
+
+// Inject injects the faults that are configured for point in t, if any.
+// It fails the test if EnvVar is invalid.
+func Inject(t *testing.T, point string) {
+	t.Helper()
+
+	faults, err := faultsAt(t.Name(), point)
+	if err != nil {
+		t.Fatalf("invalid %s: %s", EnvVar, err)
+	}
+	for _, fault := range faults {
+		logger.Logf(t, "injecting fault at %s: %s", point, fault)
+		time.Sleep(fault.Delay)
+		if fault.Fail {
+			t.Fatalf("injected failure at %s", point)
+		}
+	}
+}
+
+// faultsAt returns the faults configured for point in the test with name:
+// first the fault configured with EnvVar, then the faults the test and its
+// parents have set, in the order they were set.
+func faultsAt(name, point string) ([]Fault, error) {
+	envOnce.Do(func() {
+		envFaults, envErr = Parse(os.Getenv(EnvVar))
+	})
+	if envErr != nil {
+		return nil, envErr
+	}
+
+	var faults []Fault
+	if fault, ok := envFaults[point]; ok {
+		faults = append(faults, fault)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	for _, f := range testFaults {
+		if f.point == point && (name == f.test || strings.HasPrefix(name, f.test+"/")) {
+			faults = append(faults, f.fault)
+		}
+	}
+	return faults, nil
+}
+
+// isPoint returns true if point is one of Points.
+func isPoint(point string) bool {
+	for _, p := range Points {
+		if p == point {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package faults
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]struct {
+		spec      string
+		expFaults map[string]Fault
+		expErr    string
+	}{
+		"empty": {
+			spec:      "",
+			expFaults: map[string]Fault{},
+		},
+		"delay and fail": {
+			spec: "after-install=delay:1m, before-deploy=fail",
+			expFaults: map[string]Fault{
+				AfterInstall: {Delay: time.Minute},
+				BeforeDeploy: {Fail: true},
+			},
+		},
+		"missing fault": {
+			spec:   "after-install",
+			expErr: `fault "after-install" must be in the format <point>=<fault>`,
+		},
+		"unknown point": {
+			spec:   "after-everything=fail",
+			expErr: `fault "after-everything=fail" has unknown point "after-everything"`,
+		},
+		"invalid delay": {
+			spec:   "after-install=delay:soon",
+			expErr: `fault "after-install=delay:soon" has invalid delay`,
+		},
+		"unknown fault": {
+			spec:   "after-install=panic",
+			expErr: `fault "after-install=panic" has unknown fault "panic"`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			faults, err := Parse(c.spec)
+			if c.expErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), c.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expFaults, faults)
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	delay := Fault{Delay: time.Millisecond}
+	t.Run("parent", func(t *testing.T) {
+		Set(t, AfterInstall, delay)
+
+		t.Run("subtest", func(t *testing.T) {
+			faults, err := faultsAt(t.Name(), AfterInstall)
+			require.NoError(t, err)
+			require.Equal(t, []Fault{delay}, faults)
+
+			// Faults are only injected at the points they're set for.
+			faults, err = faultsAt(t.Name(), AfterDeploy)
+			require.NoError(t, err)
+			require.Empty(t, faults)
+
+			// Injecting a delay doesn't fail the test.
+			Inject(t, AfterInstall)
+		})
+	})
+
+	t.Run("parent-sibling", func(t *testing.T) {
+		faults, err := faultsAt(t.Name(), AfterInstall)
+		require.NoError(t, err)
+		require.Empty(t, faults)
+	})
+
+	// The fault is removed when the test that set it finishes.
+	require.Empty(t, testFaults)
+}
@@ -3,11 +3,26 @@ package flags
 import (
 	"errors"
 	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
 )
 
+const (
+	kindProvider = "kind"
+	eksProvider  = "eks"
+	gkeProvider  = "gke"
+	aksProvider  = "aks"
+)
+
+// supportedProviders are the providers that can be passed to the -provider flag.
+var supportedProviders = []string{kindProvider, eksProvider, gkeProvider, aksProvider}
+
 type TestFlags struct {
 	flagKubeconfig  string
 	flagKubecontext string
@@ -18,9 +33,14 @@ type TestFlags struct {
 	flagSecondaryKubecontext string
 	flagSecondaryNamespace   string
 
+	flagAdditionalKubeconfigs  string
+	flagAdditionalKubecontexts string
+	flagAdditionalNamespaces   string
+
 	flagEnableEnterprise            bool
 	flagEnterpriseLicenseSecretName string
 	flagEnterpriseLicenseSecretKey  string
+	flagEnterpriseLicensePath       string
 
 	flagEnableOpenshift bool
 
@@ -28,13 +48,58 @@ type TestFlags struct {
 
 	flagConsulImage    string
 	flagConsulK8sImage string
+	flagEnvoyImage     string
+	flagConsulImages   string
+
+	flagConsulVersionCanary bool
+
+	flagUseLocalRegistry bool
+
+	flagHelmChartPath    string
+	flagHelmChartVersion string
+
+	flagUpgradeFromChartVersion string
 
 	flagNoCleanupOnFailure bool
 
 	flagDebugDirectory string
 
+	flagLogFormat string
+
+	flagStrict bool
+
+	flagReportDirectory string
+
+	flagRunTags string
+
+	flagMatrix string
+
+	flagEnableClusterStateCheck bool
+
+	flagForceDeleteStuckResources bool
+
+	flagUpdateGoldenFiles bool
+
+	flagInstallBenchmarkIterations int
+
+	flagMaxLeaderUnavailability time.Duration
+
+	flagResumeFile string
+	flagResume     bool
+
 	flagUseKind bool
 
+	flagProvisionKind bool
+	flagProvider      string
+	flagNodes         int
+	flagAWSRegion     string
+	flagAWSProfile    string
+	flagGCPProject    string
+	flagGCPZone       string
+
+	flagAzureResourceGroup string
+	flagAzureLocation      string
+
 	once sync.Once
 }
 
@@ -54,6 +119,28 @@ func (t *TestFlags) init() {
 
 	flag.StringVar(&t.flagConsulImage, "consul-image", "", "The Consul image to use for all tests.")
 	flag.StringVar(&t.flagConsulK8sImage, "consul-k8s-image", "", "The consul-k8s image to use for all tests.")
+	flag.StringVar(&t.flagEnvoyImage, "envoy-image", "", "The Envoy image to use for all tests.")
+	flag.StringVar(&t.flagConsulImages, "consul-images", "", "A comma-separated list of Consul images. "+
+		"Tests that support it, such as TestBasicInstallation, run against each of these images instead of the -consul-image. "+
+		"This is used for version skew testing. Upgrade tests, such as TestConnectInject_StaggeredServerUpgrade, "+
+		"upgrade from the first to the last of these images.")
+	flag.BoolVar(&t.flagConsulVersionCanary, "consul-version-canary", false, "If true, about half of the test cases "+
+		"install the current Consul image and the other half install the latest patch release of the previous minor version, "+
+		"which is looked up on Docker Hub. The current image is the -consul-image or the appVersion of the chart.")
+
+	flag.BoolVar(&t.flagUseLocalRegistry, "use-local-registry", false, "If true, the test suite deploys a container registry "+
+		"into each Kubernetes cluster before running the tests, pushes the -consul-k8s-image, -consul-image, -envoy-image, "+
+		"and -consul-images from the local docker daemon into it, and installs them from there. This allows testing "+
+		"unreleased images on any cluster. The container runtime of the nodes must allow pulling from localhost:5000 over HTTP.")
+
+	flag.StringVar(&t.flagHelmChartPath, "helm-chart-path", "", "The Helm chart to test. It can be a path to a chart directory "+
+		"or packaged chart, or a chart reference from a Helm repo added with helm repo add, e.g. hashicorp/consul. "+
+		"If this is blank, the chart in this repository will be used.")
+	flag.StringVar(&t.flagHelmChartVersion, "helm-chart-version", "", "The version of the chart to test "+
+		"when -helm-chart-path is a chart reference. If this is blank, the latest version will be used.")
+	flag.StringVar(&t.flagUpgradeFromChartVersion, "upgrade-from-chart-version", "", "The version of the released chart "+
+		"hashicorp/consul that the upgrade tests install before upgrading to the chart under test, usually the previous release. "+
+		"The HashiCorp Helm repo must be added as hashicorp with helm repo add. If this is blank, the upgrade tests are skipped.")
 
 	flag.BoolVar(&t.flagEnableMultiCluster, "enable-multi-cluster", false,
 		"If true, the tests that require multiple Kubernetes clusters will be run. "+
@@ -64,6 +151,13 @@ func (t *TestFlags) init() {
 		"If this is blank, the context set as the current context will be used by default.")
 	flag.StringVar(&t.flagSecondaryNamespace, "secondary-namespace", "", "The Kubernetes namespace to use in the secondary k8s cluster.")
 
+	flag.StringVar(&t.flagAdditionalKubeconfigs, "additional-kubeconfigs", "", "A comma-separated list of paths to kubeconfig files "+
+		"of any k8s clusters beyond the secondary cluster. Requires -enable-multi-cluster.")
+	flag.StringVar(&t.flagAdditionalKubecontexts, "additional-kubecontexts", "", "A comma-separated list of Kubernetes context names "+
+		"of any k8s clusters beyond the secondary cluster. Requires -enable-multi-cluster.")
+	flag.StringVar(&t.flagAdditionalNamespaces, "additional-namespaces", "", "A comma-separated list of Kubernetes namespaces "+
+		"to use in any k8s clusters beyond the secondary cluster. Requires -enable-multi-cluster.")
+
 	flag.BoolVar(&t.flagEnableEnterprise, "enable-enterprise", false,
 		"If true, the test suite will run tests for enterprise features. "+
 			"Note that some features may require setting the enterprise license flags below.")
@@ -71,9 +165,13 @@ func (t *TestFlags) init() {
 		"The name of the Kubernetes secret containing the enterprise license.")
 	flag.StringVar(&t.flagEnterpriseLicenseSecretKey, "enterprise-license-secret-key", "",
 		"The key of the Kubernetes secret containing the enterprise license.")
+	flag.StringVar(&t.flagEnterpriseLicensePath, "enterprise-license-path", "",
+		"The path to a file containing the enterprise license. If provided, the tests will create the enterprise license secret "+
+			"from this file in the namespace Consul is installed into. Cannot be used together with the enterprise license secret flags.")
 
 	flag.BoolVar(&t.flagEnableOpenshift, "enable-openshift", false,
-		"If true, the tests will automatically add Openshift Helm value for each Helm install.")
+		"If true, the tests will automatically add Openshift Helm value for each Helm install "+
+			"and create security context constraints that allow test fixtures to run on OpenShift.")
 
 	flag.BoolVar(&t.flagEnablePodSecurityPolicies, "enable-pod-security-policies", false,
 		"If true, the test suite will run tests with pod security policies enabled.")
@@ -85,17 +183,186 @@ func (t *TestFlags) init() {
 	flag.StringVar(&t.flagDebugDirectory, "debug-directory", "", "The directory where to write debug information about failed test runs, "+
 		"such as logs and pod definitions. If not provided, a temporary directory will be created by the tests.")
 
+	flag.StringVar(&t.flagLogFormat, "log-format", logger.FormatText, fmt.Sprintf("The format of the test logs. Supported formats: %s. "+
+		"In the json format, each log line is a JSON object with the time, test name, test phase (setup, test, or cleanup), and message, "+
+		"and kubectl commands are logged with the command line and their duration once they finish.", strings.Join(logger.Formats, ", ")))
+
+	flag.BoolVar(&t.flagStrict, "strict", false,
+		"If true, tests fail if the Consul servers, clients, or connect injector they install log errors once the installation is ready, "+
+			"unless the test expects them. Otherwise, such errors are only logged.")
+
+	flag.StringVar(&t.flagReportDirectory, "report-directory", "",
+		"The directory where to write a JUnit XML report and a JSON summary of the tests of each test package, "+
+			"named after the package, with the duration, retries, and debug artifacts of each test. "+
+			"Only tests that request a Kubernetes cluster are reported. If blank, no reports are written.")
+
+	flag.StringVar(&t.flagRunTags, "run-tags", "", fmt.Sprintf("A comma-separated list of tags. If set, only the tests and test cases "+
+		"that are tagged with all of these tags run, and the others are skipped. Supported tags: %s. "+
+		"For example, -run-tags=secure,namespaces runs the secure test cases of Consul namespaces.", strings.Join(config.Tags, ", ")))
+
+	flag.StringVar(&t.flagMatrix, "matrix", "", fmt.Sprintf("A comma-separated list of <dimension>=<value> that selects the test cases "+
+		"of table-driven tests to run by the values of their dimensions, where value is true, false, or any. Supported dimensions: %s. "+
+		"For example, -matrix=secure=true,tproxy=any only runs the secure test cases. Tests without these dimensions aren't affected.",
+		strings.Join(config.MatrixDimensions, ", ")))
+
+	flag.BoolVar(&t.flagEnableClusterStateCheck, "enable-cluster-state-check", false,
+		"If true, the tests will record cluster-scoped resources (CRDs, webhooks, cluster roles, persistent volumes, etc.) "+
+			"before and after each Consul installation and fail if they differ.")
+
+	flag.BoolVar(&t.flagForceDeleteStuckResources, "force-delete-stuck-resources", false,
+		"If true, resources of a Consul installation that are not deleted within 2 minutes of uninstalling it, "+
+			"e.g. because of finalizers or failed helm hooks, will be force-deleted. Otherwise, they fail the test that installed it.")
+
+	flag.BoolVar(&t.flagUpdateGoldenFiles, "update-golden-files", false,
+		"If true, tests that compare results against golden files will overwrite those files with the actual results.")
+
+	flag.IntVar(&t.flagInstallBenchmarkIterations, "install-benchmark-iterations", 0,
+		"The number of times TestInstallBenchmark installs each of its Helm values profiles to measure the time "+
+			"until the installation is ready. The timings are shown in the test report. If 0, the benchmark is skipped.")
+
+	flag.DurationVar(&t.flagMaxLeaderUnavailability, "max-leader-unavailability", 15*time.Second,
+		"The longest time that the Consul servers may be without a leader, or fail to serve catalog queries, "+
+			"while tests roll the servers during an upgrade.")
+
+	flag.StringVar(&t.flagResumeFile, "resume-file", "",
+		"The absolute path to a file where the tests that pass are recorded, together with a fingerprint of the flags that change "+
+			"what they test, such as the images and -enable-enterprise. The file is shared by all test packages.")
+	flag.BoolVar(&t.flagResume, "resume", false,
+		"If true, tests recorded in the -resume-file as passed with the same flags are skipped, so that an interrupted "+
+			"test run can be continued. Tests are recorded when they request a Kubernetes cluster, so tests that fail "+
+			"or don't use a cluster always run. Requires -resume-file.")
+
 	flag.BoolVar(&t.flagUseKind, "use-kind", false,
 		"If true, the tests will assume they are running against a local kind cluster(s).")
+
+	flag.BoolVar(&t.flagProvisionKind, "provision-kind", false,
+		"If true, the test suite will create kind cluster(s) before running the tests and delete them afterwards. "+
+			"A second cluster is created if -enable-multi-cluster is set. The image provided by -consul-k8s-image "+
+			"is loaded into the clusters so that locally built images can be used. Implies -use-kind. Equivalent to -provider=kind.")
+
+	flag.StringVar(&t.flagProvider, "provider", "",
+		fmt.Sprintf("The provider to use to create Kubernetes cluster(s) before running the tests and delete them afterwards. "+
+			"A second cluster is created if -enable-multi-cluster is set. Supported providers: %s. "+
+			"If blank, the tests run against existing clusters.", strings.Join(supportedProviders, ", ")))
+	flag.StringVar(&t.flagAWSRegion, "aws-region", "", "The AWS region to create EKS clusters in when -provider=eks is set. "+
+		"If blank, the region is read from the AWS environment variables or config.")
+	flag.StringVar(&t.flagAWSProfile, "aws-profile", "", "The AWS profile to use to create EKS clusters when -provider=eks is set. "+
+		"If blank, credentials are read from the AWS environment variables or the default profile.")
+	flag.StringVar(&t.flagGCPProject, "gcp-project", "", "The GCP project to create GKE clusters in when -provider=gke is set. "+
+		"It is also used to configure workload identity on the clusters.")
+	flag.StringVar(&t.flagGCPZone, "gcp-zone", "", "The GCP zone to create GKE clusters in when -provider=gke is set. "+
+		"If blank, the default zone from the gcloud config is used.")
+	flag.StringVar(&t.flagAzureResourceGroup, "azure-resource-group", "", "The existing Azure resource group to create AKS clusters in "+
+		"when -provider=aks is set.")
+	flag.StringVar(&t.flagAzureLocation, "azure-location", "", "The Azure location to create AKS clusters in when -provider=aks is set. "+
+		"If blank, the location of the resource group is used.")
+	flag.IntVar(&t.flagNodes, "nodes", 3, "The number of nodes to create in each cluster created by -provider. "+
+		"Ignored by the kind provider, which creates single-node clusters.")
 }
 
 func (t *TestFlags) Validate() error {
-	if t.flagEnableMultiCluster {
+	if t.flagProvider != "" && !sliceContains(supportedProviders, t.flagProvider) {
+		return fmt.Errorf("-provider must be one of: %s", strings.Join(supportedProviders, ", "))
+	}
+	if t.flagLogFormat != "" && !sliceContains(logger.Formats, t.flagLogFormat) {
+		return fmt.Errorf("-log-format must be one of: %s", strings.Join(logger.Formats, ", "))
+	}
+
+	for _, tag := range splitList(t.flagRunTags) {
+		if !sliceContains(config.Tags, tag) {
+			return fmt.Errorf("-run-tags must only contain: %s", strings.Join(config.Tags, ", "))
+		}
+	}
+
+	if _, err := config.ParseMatrix(t.flagMatrix); err != nil {
+		return fmt.Errorf("-matrix is invalid: %s", err)
+	}
+
+	if t.flagProvisionKind && t.flagProvider != "" && t.flagProvider != kindProvider {
+		return errors.New("-provision-kind cannot be used together with -provider other than kind")
+	}
+
+	if t.flagProvider == gkeProvider && t.flagGCPProject == "" {
+		return errors.New("-gcp-project must be provided if -provider=gke is set")
+	}
+	if t.flagProvider == aksProvider && t.flagAzureResourceGroup == "" {
+		return errors.New("-azure-resource-group must be provided if -provider=aks is set")
+	}
+	if t.provider() != "" && t.provider() != kindProvider && t.flagNodes < 1 {
+		return errors.New("-nodes must be at least 1")
+	}
+
+	if t.provider() != "" {
+		if t.flagKubeconfig != "" || t.flagKubecontext != "" || t.flagSecondaryKubeconfig != "" || t.flagSecondaryKubecontext != "" ||
+			t.flagAdditionalKubeconfigs != "" || t.flagAdditionalKubecontexts != "" {
+			return errors.New("-provider or -provision-kind cannot be used together with flags that configure the kubeconfig or kube context")
+		}
+	}
+
+	if t.flagEnableMultiCluster && t.provider() == "" {
 		if t.flagSecondaryKubecontext == "" && t.flagSecondaryKubeconfig == "" {
 			return errors.New("at least one of -secondary-kubecontext or -secondary-kubeconfig flags must be provided if -enable-multi-cluster is set")
 		}
 	}
 
+	additionalKubeconfigs := splitList(t.flagAdditionalKubeconfigs)
+	additionalKubecontexts := splitList(t.flagAdditionalKubecontexts)
+	additionalNamespaces := splitList(t.flagAdditionalNamespaces)
+	if len(additionalKubeconfigs) > 0 || len(additionalKubecontexts) > 0 || len(additionalNamespaces) > 0 {
+		if !t.flagEnableMultiCluster {
+			return errors.New("-enable-multi-cluster must be set if any of -additional-kubeconfigs, -additional-kubecontexts, or -additional-namespaces are provided")
+		}
+		if len(additionalKubeconfigs) == 0 && len(additionalKubecontexts) == 0 {
+			return errors.New("at least one of -additional-kubecontexts or -additional-kubeconfigs flags must be provided if -additional-namespaces is set")
+		}
+		count := len(additionalKubeconfigs)
+		if count == 0 {
+			count = len(additionalKubecontexts)
+		}
+		for _, list := range [][]string{additionalKubeconfigs, additionalKubecontexts, additionalNamespaces} {
+			if len(list) > 0 && len(list) != count {
+				return errors.New("-additional-kubeconfigs, -additional-kubecontexts, and -additional-namespaces must have the same number of elements when provided")
+			}
+		}
+	}
+
+	if t.flagConsulVersionCanary && t.flagConsulImages != "" {
+		return errors.New("-consul-version-canary cannot be used together with -consul-images")
+	}
+
+	if t.flagInstallBenchmarkIterations < 0 {
+		return errors.New("-install-benchmark-iterations must not be negative")
+	}
+
+	if t.flagMaxLeaderUnavailability < 0 {
+		return errors.New("-max-leader-unavailability must not be negative")
+	}
+
+	if t.flagResume && t.flagResumeFile == "" {
+		return errors.New("-resume-file must be provided if -resume is set")
+	}
+	// Each test package runs in its own directory.
+	if t.flagResumeFile != "" && !filepath.IsAbs(t.flagResumeFile) {
+		return errors.New("-resume-file must be an absolute path")
+	}
+
+	if t.flagUseLocalRegistry && t.flagConsulK8sImage == "" && t.flagConsulImage == "" && t.flagEnvoyImage == "" && t.flagConsulImages == "" {
+		return errors.New("at least one of -consul-k8s-image, -consul-image, -envoy-image, or -consul-images must be provided if -use-local-registry is set")
+	}
+
+	if t.flagHelmChartVersion != "" && t.flagHelmChartPath == "" {
+		return errors.New("-helm-chart-path must be provided if -helm-chart-version is set")
+	}
+
+	if t.flagEnterpriseLicensePath != "" {
+		if !t.flagEnableEnterprise {
+			return errors.New("-enable-enterprise must be set if -enterprise-license-path is provided")
+		}
+		if t.flagEnterpriseLicenseSecretName != "" || t.flagEnterpriseLicenseSecretKey != "" {
+			return errors.New("-enterprise-license-path cannot be used together with -enterprise-license-secret-name or -enterprise-license-secret-key")
+		}
+	}
+
 	onlyEntSecretNameSet := t.flagEnterpriseLicenseSecretName != "" && t.flagEnterpriseLicenseSecretKey == ""
 	onlyEntSecretKeySet := t.flagEnterpriseLicenseSecretName == "" && t.flagEnterpriseLicenseSecretKey != ""
 	if onlyEntSecretNameSet || onlyEntSecretKeySet {
@@ -108,6 +375,17 @@ func (t *TestFlags) Validate() error {
 func (t *TestFlags) TestConfigFromFlags() *config.TestConfig {
 	tempDir := t.flagDebugDirectory
 
+	// The license secret is created by the tests from the license file.
+	entLicenseSecretName := t.flagEnterpriseLicenseSecretName
+	entLicenseSecretKey := t.flagEnterpriseLicenseSecretKey
+	if t.flagEnterpriseLicensePath != "" {
+		entLicenseSecretName = config.LicenseSecretName
+		entLicenseSecretKey = config.LicenseSecretKey
+	}
+
+	// The matrix has been validated by Validate.
+	matrix, _ := config.ParseMatrix(t.flagMatrix)
+
 	return &config.TestConfig{
 		Kubeconfig:    t.flagKubeconfig,
 		KubeContext:   t.flagKubecontext,
@@ -118,9 +396,12 @@ func (t *TestFlags) TestConfigFromFlags() *config.TestConfig {
 		SecondaryKubeContext:   t.flagSecondaryKubecontext,
 		SecondaryKubeNamespace: t.flagSecondaryNamespace,
 
+		AdditionalKubeEnvs: t.additionalKubeEnvs(),
+
 		EnableEnterprise:            t.flagEnableEnterprise,
-		EnterpriseLicenseSecretName: t.flagEnterpriseLicenseSecretName,
-		EnterpriseLicenseSecretKey:  t.flagEnterpriseLicenseSecretKey,
+		EnterpriseLicenseSecretName: entLicenseSecretName,
+		EnterpriseLicenseSecretKey:  entLicenseSecretKey,
+		EnterpriseLicensePath:       t.flagEnterpriseLicensePath,
 
 		EnableOpenshift: t.flagEnableOpenshift,
 
@@ -128,9 +409,114 @@ func (t *TestFlags) TestConfigFromFlags() *config.TestConfig {
 
 		ConsulImage:    t.flagConsulImage,
 		ConsulK8SImage: t.flagConsulK8sImage,
+		EnvoyImage:     t.flagEnvoyImage,
+		ConsulImages:   splitList(t.flagConsulImages),
+
+		ConsulVersionCanary: t.flagConsulVersionCanary,
+
+		UseLocalRegistry: t.flagUseLocalRegistry,
+
+		HelmChartPath:    t.flagHelmChartPath,
+		HelmChartVersion: t.flagHelmChartVersion,
+
+		UpgradeFromChartVersion: t.flagUpgradeFromChartVersion,
 
 		NoCleanupOnFailure: t.flagNoCleanupOnFailure,
 		DebugDirectory:     tempDir,
-		UseKind:            t.flagUseKind,
+
+		LogFormat: t.flagLogFormat,
+
+		Strict: t.flagStrict,
+
+		ReportDirectory: t.flagReportDirectory,
+
+		RunTags: splitList(t.flagRunTags),
+		Matrix:  matrix,
+
+		EnableClusterStateCheck: t.flagEnableClusterStateCheck,
+
+		ForceDeleteStuckResources: t.flagForceDeleteStuckResources,
+
+		UpdateGoldenFiles: t.flagUpdateGoldenFiles,
+
+		InstallBenchmarkIterations: t.flagInstallBenchmarkIterations,
+
+		MaxLeaderUnavailability: t.flagMaxLeaderUnavailability,
+
+		ResumeFile: t.flagResumeFile,
+		Resume:     t.flagResume,
+
+		UseKind: t.flagUseKind || t.provider() == kindProvider,
+
+		Provider:   t.provider(),
+		Nodes:      t.flagNodes,
+		AWSRegion:  t.flagAWSRegion,
+		AWSProfile: t.flagAWSProfile,
+		GCPProject: t.flagGCPProject,
+		GCPZone:    t.flagGCPZone,
+
+		AzureResourceGroup: t.flagAzureResourceGroup,
+		AzureLocation:      t.flagAzureLocation,
+	}
+}
+
+// provider returns the name of the provider to use to create clusters.
+// -provision-kind is a shorthand for -provider=kind.
+func (t *TestFlags) provider() string {
+	if t.flagProvisionKind {
+		return kindProvider
+	}
+	return t.flagProvider
+}
+
+// additionalKubeEnvs returns the configuration of any Kubernetes clusters
+// beyond the secondary cluster. It assumes that the flags have been validated.
+func (t *TestFlags) additionalKubeEnvs() []config.KubeEnv {
+	kubeconfigs := splitList(t.flagAdditionalKubeconfigs)
+	kubecontexts := splitList(t.flagAdditionalKubecontexts)
+	namespaces := splitList(t.flagAdditionalNamespaces)
+
+	count := len(kubeconfigs)
+	if len(kubecontexts) > count {
+		count = len(kubecontexts)
+	}
+
+	var envs []config.KubeEnv
+	for i := 0; i < count; i++ {
+		var env config.KubeEnv
+		if i < len(kubeconfigs) {
+			env.Kubeconfig = kubeconfigs[i]
+		}
+		if i < len(kubecontexts) {
+			env.KubeContext = kubecontexts[i]
+		}
+		if i < len(namespaces) {
+			env.KubeNamespace = namespaces[i]
+		}
+		envs = append(envs, env)
+	}
+	return envs
+}
+
+// splitList splits a comma-separated list, ignoring whitespace around elements.
+// It returns nil for an empty string.
+func splitList(list string) []string {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	var result []string
+	for _, elem := range strings.Split(list, ",") {
+		result = append(result, strings.TrimSpace(elem))
+	}
+	return result
+}
+
+// sliceContains returns true if s contains target.
+func sliceContains(s []string, target string) bool {
+	for _, elem := range s {
+		if elem == target {
+			return true
+		}
 	}
+	return false
 }
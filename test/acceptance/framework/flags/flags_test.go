@@ -2,17 +2,43 @@ package flags
 
 import (
 	"testing"
+	"time"
 
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/stretchr/testify/require"
 )
 
 func TestFlags_validate(t *testing.T) {
 	type fields struct {
-		flagEnableMultiCluster   bool
-		flagSecondaryKubeconfig  string
-		flagSecondaryKubecontext string
-		flagEntLicenseSecretName string
-		flagEntLicenseSecretKey  string
+		flagKubecontext            string
+		flagEnableMultiCluster     bool
+		flagSecondaryKubeconfig    string
+		flagSecondaryKubecontext   string
+		flagAdditionalKubeconfigs  string
+		flagAdditionalKubecontexts string
+		flagAdditionalNamespaces   string
+		flagEntLicenseSecretName   string
+		flagEntLicenseSecretKey    string
+		flagProvisionKind          bool
+		flagProvider               string
+		flagNodes                  int
+		flagGCPProject             string
+		flagAzureResourceGroup     string
+		flagHelmChartPath          string
+		flagHelmChartVersion       string
+		flagConsulImages           string
+		flagConsulVersionCanary    bool
+		flagEnableEnterprise       bool
+		flagEntLicensePath         string
+		flagInstallBenchIterations int
+		flagLeaderUnavailability   time.Duration
+		flagUseLocalRegistry       bool
+		flagConsulK8sImage         string
+		flagResumeFile             string
+		flagResume                 bool
+		flagLogFormat              string
+		flagRunTags                string
+		flagMatrix                 string
 	}
 	tests := []struct {
 		name       string
@@ -80,6 +106,128 @@ func TestFlags_validate(t *testing.T) {
 			false,
 			"",
 		},
+		{
+			"additional clusters: errors when multi cluster is disabled",
+			fields{
+				flagAdditionalKubecontexts: "foo",
+			},
+			true,
+			"-enable-multi-cluster must be set if any of -additional-kubeconfigs, -additional-kubecontexts, or -additional-namespaces are provided",
+		},
+		{
+			"additional clusters: errors when only namespaces are provided",
+			fields{
+				flagEnableMultiCluster:   true,
+				flagSecondaryKubecontext: "foo",
+				flagAdditionalNamespaces: "ns1,ns2",
+			},
+			true,
+			"at least one of -additional-kubecontexts or -additional-kubeconfigs flags must be provided if -additional-namespaces is set",
+		},
+		{
+			"additional clusters: errors when lists have different lengths",
+			fields{
+				flagEnableMultiCluster:     true,
+				flagSecondaryKubecontext:   "foo",
+				flagAdditionalKubecontexts: "ctx1,ctx2",
+				flagAdditionalNamespaces:   "ns1",
+			},
+			true,
+			"-additional-kubeconfigs, -additional-kubecontexts, and -additional-namespaces must have the same number of elements when provided",
+		},
+		{
+			"additional clusters: no error when lists have the same length",
+			fields{
+				flagEnableMultiCluster:     true,
+				flagSecondaryKubecontext:   "foo",
+				flagAdditionalKubeconfigs:  "cfg1,cfg2",
+				flagAdditionalKubecontexts: "ctx1,ctx2",
+			},
+			false,
+			"",
+		},
+		{
+			"provision kind: errors when a kube context is provided",
+			fields{
+				flagProvisionKind: true,
+				flagKubecontext:   "foo",
+			},
+			true,
+			"-provider or -provision-kind cannot be used together with flags that configure the kubeconfig or kube context",
+		},
+		{
+			"provision kind: no error when multi cluster is enabled without a secondary kube context",
+			fields{
+				flagProvisionKind:      true,
+				flagEnableMultiCluster: true,
+			},
+			false,
+			"",
+		},
+		{
+			"provider: errors when the provider is not supported",
+			fields{
+				flagProvider: "foo",
+			},
+			true,
+			"-provider must be one of: kind, eks, gke, aks",
+		},
+		{
+			"provider: errors when used with -provision-kind and a different provider",
+			fields{
+				flagProvisionKind: true,
+				flagProvider:      "eks",
+			},
+			true,
+			"-provision-kind cannot be used together with -provider other than kind",
+		},
+		{
+			"provider: errors when a secondary kube context is provided",
+			fields{
+				flagProvider:             "eks",
+				flagNodes:                3,
+				flagEnableMultiCluster:   true,
+				flagSecondaryKubecontext: "foo",
+			},
+			true,
+			"-provider or -provision-kind cannot be used together with flags that configure the kubeconfig or kube context",
+		},
+		{
+			"provider: errors when gke is used without a project",
+			fields{
+				flagProvider: "gke",
+				flagNodes:    3,
+			},
+			true,
+			"-gcp-project must be provided if -provider=gke is set",
+		},
+		{
+			"provider: errors when aks is used without a resource group",
+			fields{
+				flagProvider: "aks",
+				flagNodes:    3,
+			},
+			true,
+			"-azure-resource-group must be provided if -provider=aks is set",
+		},
+		{
+			"provider: errors when the number of nodes is less than 1",
+			fields{
+				flagProvider: "eks",
+			},
+			true,
+			"-nodes must be at least 1",
+		},
+		{
+			"provider: no error with a supported provider",
+			fields{
+				flagProvider:   "gke",
+				flagNodes:      3,
+				flagGCPProject: "project",
+			},
+			false,
+			"",
+		},
 		{
 			"enterprise license: error when only -enterprise-license-secret-name is provided",
 			fields{
@@ -105,15 +253,206 @@ func TestFlags_validate(t *testing.T) {
 			false,
 			"",
 		},
+		{
+			"helm chart: error when -helm-chart-version is provided without -helm-chart-path",
+			fields{
+				flagHelmChartVersion: "0.31.1",
+			},
+			true,
+			"-helm-chart-path must be provided if -helm-chart-version is set",
+		},
+		{
+			"helm chart: no error when both -helm-chart-path and -helm-chart-version are provided",
+			fields{
+				flagHelmChartPath:    "hashicorp/consul",
+				flagHelmChartVersion: "0.31.1",
+			},
+			false,
+			"",
+		},
+		{
+			"local registry: error when -use-local-registry is provided without any images",
+			fields{
+				flagUseLocalRegistry: true,
+			},
+			true,
+			"at least one of -consul-k8s-image, -consul-image, -envoy-image, or -consul-images must be provided if -use-local-registry is set",
+		},
+		{
+			"local registry: no error when -use-local-registry and -consul-k8s-image are provided",
+			fields{
+				flagUseLocalRegistry: true,
+				flagConsulK8sImage:   "consul-k8s-dev",
+			},
+			false,
+			"",
+		},
+		{
+			"install benchmark: error when -install-benchmark-iterations is negative",
+			fields{
+				flagInstallBenchIterations: -1,
+			},
+			true,
+			"-install-benchmark-iterations must not be negative",
+		},
+		{
+			"leader unavailability: error when -max-leader-unavailability is negative",
+			fields{
+				flagLeaderUnavailability: -time.Second,
+			},
+			true,
+			"-max-leader-unavailability must not be negative",
+		},
+		{
+			"resume: error when -resume is provided without -resume-file",
+			fields{
+				flagResume: true,
+			},
+			true,
+			"-resume-file must be provided if -resume is set",
+		},
+		{
+			"resume: no error when -resume and -resume-file are provided",
+			fields{
+				flagResume:     true,
+				flagResumeFile: "/tmp/resume-state",
+			},
+			false,
+			"",
+		},
+		{
+			"resume: error when -resume-file is a relative path",
+			fields{
+				flagResumeFile: "resume-state",
+			},
+			true,
+			"-resume-file must be an absolute path",
+		},
+		{
+			"log format: error when -log-format is not supported",
+			fields{
+				flagLogFormat: "xml",
+			},
+			true,
+			"-log-format must be one of: text, json",
+		},
+		{
+			"log format: no error when -log-format is json",
+			fields{
+				flagLogFormat: "json",
+			},
+			false,
+			"",
+		},
+		{
+			"run tags: error when -run-tags contains an unsupported tag",
+			fields{
+				flagRunTags: "secure,fast",
+			},
+			true,
+			"-run-tags must only contain: secure, enterprise, namespaces, slow",
+		},
+		{
+			"run tags: no error when -run-tags contains supported tags",
+			fields{
+				flagRunTags: "secure, namespaces",
+			},
+			false,
+			"",
+		},
+		{
+			"matrix: error when -matrix contains an unsupported dimension",
+			fields{
+				flagMatrix: "secure=true,namespaces=true",
+			},
+			true,
+			`-matrix is invalid: "namespaces=true" has unknown dimension "namespaces", must be one of: secure, auto-encrypt, tproxy`,
+		},
+		{
+			"matrix: error when -matrix contains an invalid value",
+			fields{
+				flagMatrix: "secure=yes",
+			},
+			true,
+			`-matrix is invalid: "secure=yes" has invalid value "yes", must be true, false, or any`,
+		},
+		{
+			"matrix: no error when -matrix is valid",
+			fields{
+				flagMatrix: "secure=true, tproxy=any",
+			},
+			false,
+			"",
+		},
+		{
+			"consul versions: error when -consul-version-canary and -consul-images are provided",
+			fields{
+				flagConsulImages:        "hashicorp/consul:1.9.5",
+				flagConsulVersionCanary: true,
+			},
+			true,
+			"-consul-version-canary cannot be used together with -consul-images",
+		},
+		{
+			"enterprise license: error when -enterprise-license-path is provided without -enable-enterprise",
+			fields{
+				flagEntLicensePath: "license.hclic",
+			},
+			true,
+			"-enable-enterprise must be set if -enterprise-license-path is provided",
+		},
+		{
+			"enterprise license: error when -enterprise-license-path and -enterprise-license-secret-name are provided",
+			fields{
+				flagEnableEnterprise:     true,
+				flagEntLicensePath:       "license.hclic",
+				flagEntLicenseSecretName: "secret",
+			},
+			true,
+			"-enterprise-license-path cannot be used together with -enterprise-license-secret-name or -enterprise-license-secret-key",
+		},
+		{
+			"enterprise license: no error when -enterprise-license-path and -enable-enterprise are provided",
+			fields{
+				flagEnableEnterprise: true,
+				flagEntLicensePath:   "license.hclic",
+			},
+			false,
+			"",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tf := &TestFlags{
+				flagKubecontext:                 tt.fields.flagKubecontext,
 				flagEnableMultiCluster:          tt.fields.flagEnableMultiCluster,
 				flagSecondaryKubeconfig:         tt.fields.flagSecondaryKubeconfig,
 				flagSecondaryKubecontext:        tt.fields.flagSecondaryKubecontext,
+				flagAdditionalKubeconfigs:       tt.fields.flagAdditionalKubeconfigs,
+				flagAdditionalKubecontexts:      tt.fields.flagAdditionalKubecontexts,
+				flagAdditionalNamespaces:        tt.fields.flagAdditionalNamespaces,
 				flagEnterpriseLicenseSecretName: tt.fields.flagEntLicenseSecretName,
 				flagEnterpriseLicenseSecretKey:  tt.fields.flagEntLicenseSecretKey,
+				flagProvisionKind:               tt.fields.flagProvisionKind,
+				flagProvider:                    tt.fields.flagProvider,
+				flagNodes:                       tt.fields.flagNodes,
+				flagGCPProject:                  tt.fields.flagGCPProject,
+				flagAzureResourceGroup:          tt.fields.flagAzureResourceGroup,
+				flagHelmChartPath:               tt.fields.flagHelmChartPath,
+				flagHelmChartVersion:            tt.fields.flagHelmChartVersion,
+				flagConsulImages:                tt.fields.flagConsulImages,
+				flagConsulVersionCanary:         tt.fields.flagConsulVersionCanary,
+				flagEnableEnterprise:            tt.fields.flagEnableEnterprise,
+				flagEnterpriseLicensePath:       tt.fields.flagEntLicensePath,
+				flagInstallBenchmarkIterations:  tt.fields.flagInstallBenchIterations,
+				flagMaxLeaderUnavailability:     tt.fields.flagLeaderUnavailability,
+				flagUseLocalRegistry:            tt.fields.flagUseLocalRegistry,
+				flagConsulK8sImage:              tt.fields.flagConsulK8sImage,
+				flagResumeFile:                  tt.fields.flagResumeFile,
+				flagResume:                      tt.fields.flagResume,
+				flagLogFormat:                   tt.fields.flagLogFormat,
+				flagRunTags:                     tt.fields.flagRunTags,
+				flagMatrix:                      tt.fields.flagMatrix,
 			}
 			err := tf.Validate()
 			if tt.wantErr {
@@ -124,3 +463,40 @@ func TestFlags_validate(t *testing.T) {
 		})
 	}
 }
+
+func TestFlags_additionalKubeEnvs(t *testing.T) {
+	tf := &TestFlags{
+		flagAdditionalKubeconfigs:  "cfg1, cfg2",
+		flagAdditionalKubecontexts: "ctx1,ctx2",
+	}
+	require.Equal(t, []config.KubeEnv{
+		{Kubeconfig: "cfg1", KubeContext: "ctx1"},
+		{Kubeconfig: "cfg2", KubeContext: "ctx2"},
+	}, tf.additionalKubeEnvs())
+
+	require.Nil(t, (&TestFlags{}).additionalKubeEnvs())
+}
+
+func TestFlags_TestConfigFromFlags_Strict(t *testing.T) {
+	require.True(t, (&TestFlags{flagStrict: true}).TestConfigFromFlags().Strict)
+	require.False(t, (&TestFlags{}).TestConfigFromFlags().Strict)
+}
+
+func TestFlags_TestConfigFromFlags_UpgradeFromChartVersion(t *testing.T) {
+	require.Equal(t, "0.31.1", (&TestFlags{flagUpgradeFromChartVersion: "0.31.1"}).TestConfigFromFlags().UpgradeFromChartVersion)
+}
+
+func TestFlags_TestConfigFromFlags_EnterpriseLicensePath(t *testing.T) {
+	tf := &TestFlags{
+		flagEnableEnterprise:      true,
+		flagEnterpriseLicensePath: "license.hclic",
+	}
+	cfg := tf.TestConfigFromFlags()
+	require.Equal(t, "license.hclic", cfg.EnterpriseLicensePath)
+	require.Equal(t, config.LicenseSecretName, cfg.EnterpriseLicenseSecretName)
+	require.Equal(t, config.LicenseSecretKey, cfg.EnterpriseLicenseSecretKey)
+}
+
+func TestFlags_TestConfigFromFlags_MaxLeaderUnavailability(t *testing.T) {
+	require.Equal(t, 5*time.Second, (&TestFlags{flagMaxLeaderUnavailability: 5 * time.Second}).TestConfigFromFlags().MaxLeaderUnavailability)
+}
@@ -0,0 +1,110 @@
+package helpers
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+)
+
+// Backoff configures how Eventually retries a function.
+type Backoff struct {
+	// Initial is the delay after the first failed attempt.
+	Initial time.Duration
+	// Max is the maximum delay between attempts.
+	Max time.Duration
+	// Multiplier is the factor by which the delay grows after each failed attempt.
+	Multiplier float64
+	// Jitter is the fraction of the delay that is randomized, e.g. 0.2 means +/- 20%.
+	Jitter float64
+	// Timeout is the total time after which Eventually stops retrying.
+	Timeout time.Duration
+	// OnTimeout, if set, is called with the last error when Eventually gives up,
+	// e.g. to dump diagnostic information about the cluster before the test fails.
+	OnTimeout func(lastErr error)
+}
+
+// DefaultBackoff returns a Backoff that starts at 1s, doubles up to 30s
+// with 20% jitter, and times out after 5 minutes.
+func DefaultBackoff() Backoff {
+	return Backoff{
+		Initial:    1 * time.Second,
+		Max:        30 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.2,
+		Timeout:    5 * time.Minute,
+	}
+}
+
+// Eventually calls fn until it returns nil, backing off exponentially with jitter
+// between attempts. It fails the test if fn doesn't succeed before the backoff timeout
+// elapses or ctx is cancelled, calling backoff.OnTimeout first if it's set.
+// Each failed attempt is logged when tests are run with -v.
+//
+// It's meant for waiting on cluster state that can take minutes to converge, where
+// backing off keeps the load on the cluster low and OnTimeout can collect diagnostics.
+// Short polls that assert with require on a retry.R can keep using retry.Run.
+func Eventually(t *testing.T, ctx context.Context, backoff Backoff, fn func() error) {
+	t.Helper()
+
+	start := time.Now()
+	logf := func(format string, args ...interface{}) {
+		if testing.Verbose() {
+			logger.Logf(t, format, args...)
+		}
+	}
+	attempts, err := eventually(ctx, backoff, fn, logf, rand.Float64)
+	if err != nil {
+		t.Fatalf("function did not succeed after %d attempts in %s: %s", attempts, time.Since(start), err)
+	}
+}
+
+// eventually implements Eventually. It returns the number of attempts and,
+// if fn didn't succeed, its last error. random returns numbers in [0, 1)
+// that are used to apply the jitter.
+func eventually(ctx context.Context, backoff Backoff, fn func() error, logf func(string, ...interface{}), random func() float64) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, backoff.Timeout)
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		lastErr := fn()
+		if lastErr == nil {
+			return attempt + 1, nil
+		}
+
+		delay := nextDelay(backoff, attempt, random())
+		logf("attempt %d failed, retrying in %s: %s", attempt+1, delay, lastErr)
+
+		select {
+		case <-ctx.Done():
+			if backoff.OnTimeout != nil {
+				backoff.OnTimeout(lastErr)
+			}
+			return attempt + 1, lastErr
+		case <-time.After(delay):
+		}
+	}
+}
+
+// nextDelay returns the delay before the next attempt given the number of
+// the attempt that just failed (starting at 0) and a random number in [0, 1)
+// used to apply the jitter.
+func nextDelay(backoff Backoff, attempt int, random float64) time.Duration {
+	multiplier := backoff.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(backoff.Initial) * math.Pow(multiplier, float64(attempt))
+	if backoff.Max > 0 && delay > float64(backoff.Max) {
+		delay = float64(backoff.Max)
+	}
+
+	// Spread the delay evenly in [delay - jitter*delay, delay + jitter*delay).
+	delay += backoff.Jitter * delay * (2*random - 1)
+
+	return time.Duration(delay)
+}
@@ -0,0 +1,163 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextDelay(t *testing.T) {
+	backoff := Backoff{
+		Initial:    1 * time.Second,
+		Max:        10 * time.Second,
+		Multiplier: 2,
+	}
+
+	cases := map[string]struct {
+		backoff  Backoff
+		attempt  int
+		random   float64
+		expDelay time.Duration
+	}{
+		"first attempt": {
+			backoff:  backoff,
+			attempt:  0,
+			random:   0.5,
+			expDelay: 1 * time.Second,
+		},
+		"grows exponentially": {
+			backoff:  backoff,
+			attempt:  3,
+			random:   0.5,
+			expDelay: 8 * time.Second,
+		},
+		"capped at max": {
+			backoff:  backoff,
+			attempt:  10,
+			random:   0.5,
+			expDelay: 10 * time.Second,
+		},
+		"multiplier less than 1 is treated as constant backoff": {
+			backoff:  Backoff{Initial: 1 * time.Second},
+			attempt:  5,
+			random:   0.5,
+			expDelay: 1 * time.Second,
+		},
+		"jitter lower bound": {
+			backoff:  Backoff{Initial: 10 * time.Second, Multiplier: 1, Jitter: 0.2},
+			attempt:  0,
+			random:   0,
+			expDelay: 8 * time.Second,
+		},
+		"jitter upper bound": {
+			backoff:  Backoff{Initial: 10 * time.Second, Multiplier: 1, Jitter: 0.2},
+			attempt:  0,
+			random:   1,
+			expDelay: 12 * time.Second,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, c.expDelay, nextDelay(c.backoff, c.attempt, c.random))
+		})
+	}
+}
+
+func TestEventually_SucceedsAfterRetries(t *testing.T) {
+	attempts := 0
+	Eventually(t, context.Background(), Backoff{Initial: 1 * time.Millisecond, Multiplier: 2, Timeout: 5 * time.Second}, func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	require.Equal(t, 3, attempts)
+}
+
+func TestEventually_Fails(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cases := map[string]struct {
+		ctx     context.Context
+		backoff Backoff
+	}{
+		"times out": {
+			ctx:     context.Background(),
+			backoff: Backoff{Initial: 10 * time.Millisecond, Multiplier: 1, Timeout: 100 * time.Millisecond},
+		},
+		"context is cancelled": {
+			ctx:     cancelled,
+			backoff: Backoff{Initial: 1 * time.Minute, Timeout: 1 * time.Minute},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var onTimeoutErr error
+			c.backoff.OnTimeout = func(lastErr error) {
+				onTimeoutErr = lastErr
+			}
+			calls := 0
+			var delays []string
+
+			start := time.Now()
+			attempts, err := eventually(c.ctx, c.backoff, func() error {
+				calls++
+				return fmt.Errorf("attempt %d", calls)
+			}, func(format string, args ...interface{}) {
+				delays = append(delays, fmt.Sprintf(format, args...))
+			}, func() float64 { return 0.5 })
+
+			require.Less(t, int64(time.Since(start)), int64(c.backoff.Timeout+time.Second))
+			require.Equal(t, calls, attempts)
+			require.EqualError(t, err, fmt.Sprintf("attempt %d", calls))
+			// OnTimeout is called with the last error.
+			require.Equal(t, err, onTimeoutErr)
+			require.Len(t, delays, calls)
+		})
+	}
+}
+
+func TestEventually_BackoffGrows(t *testing.T) {
+	backoff := Backoff{Initial: 1 * time.Millisecond, Max: 4 * time.Millisecond, Multiplier: 2, Jitter: 0.5, Timeout: 5 * time.Second}
+	var logged []string
+	calls := 0
+	attempts, err := eventually(context.Background(), backoff, func() error {
+		calls++
+		if calls < 5 {
+			return errors.New("not yet")
+		}
+		return nil
+	}, func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}, func() float64 { return 0.5 })
+
+	require.NoError(t, err)
+	require.Equal(t, 5, attempts)
+	require.Equal(t, []string{
+		"attempt 1 failed, retrying in 1ms: not yet",
+		"attempt 2 failed, retrying in 2ms: not yet",
+		"attempt 3 failed, retrying in 4ms: not yet",
+		"attempt 4 failed, retrying in 4ms: not yet",
+	}, logged)
+}
+
+func TestNextDelay_JitterBounds(t *testing.T) {
+	backoff := Backoff{Initial: 1 * time.Second, Max: 8 * time.Second, Multiplier: 2, Jitter: 0.2}
+	for attempt := 0; attempt < 6; attempt++ {
+		base := nextDelay(Backoff{Initial: backoff.Initial, Max: backoff.Max, Multiplier: backoff.Multiplier}, attempt, 0)
+		for i := 0; i < 100; i++ {
+			delay := nextDelay(backoff, attempt, rand.Float64())
+			require.GreaterOrEqual(t, int64(delay), int64(float64(base)*0.8))
+			require.Less(t, int64(delay), int64(float64(base)*1.2))
+		}
+	}
+}
@@ -3,15 +3,18 @@ package helpers
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
 
 	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
 	"github.com/hashicorp/consul/sdk/testutil/retry"
 	"github.com/stretchr/testify/require"
@@ -57,7 +60,7 @@ func WaitForAllPodsToBeReady(t *testing.T, client kubernetes.Interface, namespac
 // Sets up a goroutine that will wait for interrupt signals
 // and call cleanup function when it catches it.
 func SetupInterruptHandler(cleanup func()) {
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
@@ -80,6 +83,7 @@ func Cleanup(t *testing.T, noCleanupOnFailure bool, cleanup func()) {
 	// We need to wrap the cleanup function because t that is passed in to this function
 	// might not have the information on whether the test has failed yet.
 	wrappedCleanupFunc := func() {
+		logger.SetPhase(t, logger.PhaseCleanup)
 		if !(noCleanupOnFailure && t.Failed()) {
 			logger.Logf(t, "cleaning up resources for %s", t.Name())
 			cleanup()
@@ -91,6 +95,97 @@ func Cleanup(t *testing.T, noCleanupOnFailure bool, cleanup func()) {
 	t.Cleanup(wrappedCleanupFunc)
 }
 
+// taggedTests are the names of the tests that SkipUnlessTag has let run.
+var taggedTests = struct {
+	sync.Mutex
+	names map[string]bool
+}{names: make(map[string]bool)}
+
+// SkipUnlessTag skips t unless tags include all the tags that the tests
+// are run with, see config.TestConfig.RunTags. Table-driven tests call it
+// in each case with tags that depend on the case, see TagIf.
+// It has to be called before the test requests a Kubernetes cluster,
+// see SkipUnlessTagged.
+func SkipUnlessTag(t *testing.T, cfg *config.TestConfig, tags ...string) {
+	t.Helper()
+
+	if !cfg.HasRunTags(tags...) {
+		t.Skipf("skipping this test because it isn't tagged with all of -run-tags %s", strings.Join(cfg.RunTags, ","))
+	}
+
+	taggedTests.Lock()
+	defer taggedTests.Unlock()
+	taggedTests.names[t.Name()] = true
+}
+
+// SkipUnlessTagged skips t if the tests are run with -run-tags and neither t
+// nor any of its parents has called SkipUnlessTag, so that untagged tests
+// don't run when tests are selected by tags. The test suite calls it when
+// a test requests a Kubernetes cluster.
+func SkipUnlessTagged(t *testing.T, cfg *config.TestConfig) {
+	t.Helper()
+
+	if len(cfg.RunTags) == 0 || isTagged(t.Name()) {
+		return
+	}
+	t.Skipf("skipping this test because it isn't tagged and -run-tags %s is set", strings.Join(cfg.RunTags, ","))
+}
+
+// isTagged returns true if the test with name or any of its parents has called SkipUnlessTag.
+func isTagged(name string) bool {
+	taggedTests.Lock()
+	defer taggedTests.Unlock()
+	for {
+		if taggedTests.names[name] {
+			return true
+		}
+		i := strings.LastIndex(name, "/")
+		if i < 0 {
+			return false
+		}
+		name = name[:i]
+	}
+}
+
+// TagIf returns tag if cond is true. Otherwise, it returns
+// an empty tag, which never matches any run tags.
+func TagIf(cond bool, tag string) string {
+	if cond {
+		return tag
+	}
+	return ""
+}
+
+// SkipUnlessInMatrix skips t unless values, the values of the dimensions of its
+// test case, are the ones selected with -matrix, see config.TestConfig.Matrix.
+func SkipUnlessInMatrix(t *testing.T, cfg *config.TestConfig, values config.Matrix) {
+	t.Helper()
+
+	if !cfg.InMatrix(values) {
+		t.Skip("skipping this test case because it isn't selected by -matrix")
+	}
+}
+
+// ReadGoldenFile returns the contents of goldenFile. If update is true,
+// it first overwrites goldenFile with actual so that golden files can be
+// regenerated by running the tests with the -update-golden-files flag.
+func ReadGoldenFile(t *testing.T, goldenFile string, actual []byte, update bool) []byte {
+	t.Helper()
+
+	if update {
+		logger.Logf(t, "updating golden file %s", goldenFile)
+		require.NoError(t, ioutil.WriteFile(goldenFile, actual, 0644))
+	}
+
+	expected, err := ioutil.ReadFile(goldenFile)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden file %s doesn't exist; run the test with -update-golden-files to create it", goldenFile)
+	}
+	require.NoError(t, err)
+
+	return expected
+}
+
 // KubernetesClientFromOptions takes KubectlOptions and returns Kubernetes API client.
 func KubernetesClientFromOptions(t *testing.T, options *terratestk8s.KubectlOptions) kubernetes.Interface {
 	configPath, err := options.GetConfigPath(t)
@@ -0,0 +1,36 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipUnlessTagged(t *testing.T) {
+	cfg := &config.TestConfig{RunTags: []string{config.TagSecure}}
+	var ran []string
+
+	t.Run("untagged", func(t *testing.T) {
+		SkipUnlessTagged(t, cfg)
+		ran = append(ran, "untagged")
+	})
+	t.Run("tagged", func(t *testing.T) {
+		SkipUnlessTag(t, cfg, config.TagSecure)
+		// Subtests of tagged tests, e.g. the attempts of RunWithRetries, are tagged too.
+		t.Run("subtest", func(t *testing.T) {
+			SkipUnlessTagged(t, cfg)
+			ran = append(ran, "tagged/subtest")
+		})
+	})
+	t.Run("tagged with other tags", func(t *testing.T) {
+		SkipUnlessTag(t, cfg, config.TagSlow)
+		ran = append(ran, "tagged with other tags")
+	})
+	t.Run("without run tags", func(t *testing.T) {
+		SkipUnlessTagged(t, &config.TestConfig{})
+		ran = append(ran, "without run tags")
+	})
+
+	require.Equal(t, []string{"tagged/subtest", "without run tags"}, ran)
+}
@@ -0,0 +1,38 @@
+package helpers
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// objectOptions are the options that RequireObjectMatches always uses.
+// They ignore the fields of Kubernetes objects that the API server sets and that
+// differ between installations, i.e. managed fields, UIDs, resource versions,
+// generations, and all timestamps, and compare resource quantities by value.
+var objectOptions = []cmp.Option{
+	cmpopts.IgnoreFields(metav1.ObjectMeta{}, "ManagedFields", "UID", "ResourceVersion", "Generation", "SelfLink"),
+	cmpopts.IgnoreTypes(metav1.Time{}, &metav1.Time{}, metav1.MicroTime{}, &metav1.MicroTime{}),
+	cmpopts.EquateEmpty(),
+	cmp.Comparer(func(x, y resource.Quantity) bool {
+		return x.Cmp(y) == 0
+	}),
+}
+
+// RequireObjectMatches fails the test if obj doesn't match want, ignoring managed fields,
+// UIDs, resource versions, generations, timestamps, and any other fields ignored by opts, e.g.
+// cmpopts.IgnoreFields(corev1.Container{}, "Image"). Unlike require.Equal, the failure
+// message only shows the fields that differ, as a diff from want to obj.
+// Empty and nil slices and maps are considered equal.
+func RequireObjectMatches(t require.TestingT, obj, want interface{}, opts ...cmp.Option) {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	options := append(append([]cmp.Option{}, objectOptions...), opts...)
+	if diff := cmp.Diff(want, obj, options...); diff != "" {
+		require.FailNow(t, "object doesn't match", "(-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,72 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// recordingT records the failure of a require assertion.
+type recordingT struct {
+	failed  bool
+	message string
+}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.message = fmt.Sprintf(format, args...)
+}
+
+func (r *recordingT) FailNow() {
+	r.failed = true
+}
+
+func TestRequireObjectMatches(t *testing.T) {
+	want := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "static-server", Labels: map[string]string{"app": "static-server"}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "static-server",
+				Image: "hashicorp/http-echo:latest",
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("100Mi")},
+				},
+			}},
+		},
+	}
+
+	// Fields set by the API server, equivalent quantities, and empty
+	// instead of nil collections don't make objects differ.
+	got := *want.DeepCopy()
+	got.UID = "d6e2f3c4"
+	got.ResourceVersion = "1234"
+	got.CreationTimestamp = metav1.NewTime(time.Now())
+	got.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}
+	got.Annotations = map[string]string{}
+	got.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory] = resource.MustParse("104857600")
+	got.Status.StartTime = &metav1.Time{Time: time.Now()}
+
+	r := &recordingT{}
+	RequireObjectMatches(r, got, want)
+	require.False(t, r.failed, r.message)
+
+	// Other differences fail with a diff of just the differing fields.
+	got.Spec.Containers[0].Image = "hashicorp/http-echo:0.2.3"
+	r = &recordingT{}
+	RequireObjectMatches(r, got, want)
+	require.True(t, r.failed)
+	require.Contains(t, r.message, "hashicorp/http-echo:latest")
+	require.Contains(t, r.message, "hashicorp/http-echo:0.2.3")
+	require.Equal(t, 2, strings.Count(r.message, "Image"), r.message)
+
+	// Extra options ignore more fields.
+	r = &recordingT{}
+	RequireObjectMatches(r, got, want, cmpopts.IgnoreFields(corev1.Container{}, "Image"))
+	require.False(t, r.failed, r.message)
+}
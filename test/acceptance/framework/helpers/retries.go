@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/reporting"
+)
+
+// retries records the attempts of the tests run with RunWithRetries.
+var retries = newRetryRegistry()
+
+// RunWithRetries runs fn as a subtest of t named after the attempt, attempt-1 the first
+// time t runs. It's meant for tests that intermittently fail on slow clusters; fn has
+// to set up everything it needs, e.g. its own Consul cluster, so that each attempt
+// starts from scratch.
+//
+// The testing package fails t as soon as an attempt fails, so the attempts can't run
+// one after another within t. Instead, if an attempt fails and t has attempts left,
+// the test suite runs the tests again once all of them have run, skipping the tests
+// that passed, and fn runs as attempt-2 of t, and so on, see RetryableFailures.
+// If a later attempt passes, go test passes, and the failed attempts are marked
+// as flaky in the test report written with -report-directory.
+// The debug artifacts of each attempt are written to the directory of its subtest,
+// so the artifacts of the failed attempts are kept.
+func RunWithRetries(t *testing.T, attempts int, fn func(t *testing.T)) {
+	t.Helper()
+
+	attempt := retries.start(t.Name())
+	if attempt > 1 {
+		logger.Logf(t, "retrying: attempt %d of %d", attempt, attempts)
+	}
+	passed := t.Run(attemptName(attempt), fn)
+	if flaky := retries.finish(t.Name(), attempt, attempts, passed); len(flaky) > 0 {
+		logger.Logf(t, "test is flaky: %d of %d attempts failed before an attempt passed", len(flaky), attempt)
+		reporting.MarkFlaky(flaky...)
+	}
+}
+
+// RetryableFailures returns the names of the tests run with RunWithRetries that
+// have failed since it was last called and have attempts left. The test suite
+// runs the tests again if these are the only tests that failed.
+func RetryableFailures() []string {
+	return retries.retryableFailures()
+}
+
+// retryRegistry records the attempts of the tests run with RunWithRetries
+// across the runs of the tests of a package.
+type retryRegistry struct {
+	lock sync.Mutex
+	// attempts are the numbers of the last attempts
+	// of the tests that are waiting to be retried.
+	attempts map[string]int
+	// pending are the tests that have failed with attempts
+	// left since retryableFailures was last called.
+	pending []string
+}
+
+func newRetryRegistry() *retryRegistry {
+	return &retryRegistry{attempts: make(map[string]int)}
+}
+
+// start returns the number of the attempt of the test with name that is starting.
+func (r *retryRegistry) start(name string) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.attempts[name] + 1
+}
+
+// finish records the result of attempt of the test with name. If the attempt
+// passed after earlier attempts failed, it returns the names of their subtests.
+// The attempts of a test start over once an attempt passes or it has no attempts
+// left, e.g. when it runs again because of go test -count.
+func (r *retryRegistry) finish(name string, attempt, attempts int, passed bool) (flaky []string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !passed && attempt < attempts {
+		r.attempts[name] = attempt
+		r.pending = append(r.pending, name)
+		return nil
+	}
+	delete(r.attempts, name)
+	if passed {
+		for failed := 1; failed < attempt; failed++ {
+			flaky = append(flaky, fmt.Sprintf("%s/%s", name, attemptName(failed)))
+		}
+	}
+	return flaky
+}
+
+func (r *retryRegistry) retryableFailures() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	pending := r.pending
+	r.pending = nil
+	return pending
+}
+
+// attemptName returns the name of the subtest of an attempt of RunWithRetries.
+func attemptName(attempt int) string {
+	return fmt.Sprintf("attempt-%d", attempt)
+}
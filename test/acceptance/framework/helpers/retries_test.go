@@ -0,0 +1,61 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryRegistry(t *testing.T) {
+	cases := map[string]struct {
+		attempts     int
+		results      []bool
+		expFlaky     []string
+		expRetryable []string
+	}{
+		"passes on the first attempt": {
+			attempts: 3,
+			results:  []bool{true},
+		},
+		"passes on a retry": {
+			attempts:     3,
+			results:      []bool{false, false, true},
+			expFlaky:     []string{"TestA/attempt-1", "TestA/attempt-2"},
+			expRetryable: []string{"TestA", "TestA"},
+		},
+		"fails all attempts": {
+			attempts:     2,
+			results:      []bool{false, false},
+			expRetryable: []string{"TestA"},
+		},
+		"isn't retried with a single attempt": {
+			attempts: 1,
+			results:  []bool{false},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := newRetryRegistry()
+			var flaky, retryable []string
+			for i, passed := range c.results {
+				require.Equal(t, i+1, r.start("TestA"))
+				flaky = append(flaky, r.finish("TestA", i+1, c.attempts, passed)...)
+				retryable = append(retryable, r.retryableFailures()...)
+			}
+			require.Equal(t, c.expFlaky, flaky)
+			require.Equal(t, c.expRetryable, retryable)
+			// The attempts start over once the test has passed or has no attempts left.
+			require.Equal(t, 1, r.start("TestA"))
+		})
+	}
+}
+
+func TestRunWithRetries_PassesFirstAttempt(t *testing.T) {
+	var names []string
+	RunWithRetries(t, 3, func(t *testing.T) {
+		names = append(names, t.Name())
+	})
+	require.Equal(t, []string{"TestRunWithRetries_PassesFirstAttempt/attempt-1"}, names)
+	require.Empty(t, RetryableFailures())
+}
@@ -0,0 +1,110 @@
+package k8s
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// clusterScopedResourceTypes are the cluster-scoped resources that tests
+// could leak if cleanup doesn't work correctly.
+var clusterScopedResourceTypes = []string{
+	"customresourcedefinitions",
+	"mutatingwebhookconfigurations",
+	"validatingwebhookconfigurations",
+	"clusterroles",
+	"clusterrolebindings",
+	"persistentvolumes",
+}
+
+// ClusterScopedResources returns a sorted list of all cluster-scoped resources
+// in the cluster in the <type>/<name> format, e.g. clusterrole.rbac.authorization.k8s.io/foo.
+func ClusterScopedResources(t *testing.T, options *k8s.KubectlOptions) []string {
+	t.Helper()
+
+	resources, err := ClusterScopedResourcesE(t, options)
+	require.NoError(t, err)
+	return resources
+}
+
+// ClusterScopedResourcesE is like ClusterScopedResources but returns an error
+// instead of failing the test, e.g. so that it can be retried.
+func ClusterScopedResourcesE(t *testing.T, options *k8s.KubectlOptions) ([]string, error) {
+	t.Helper()
+
+	// Pass the discard logger because the output is very long and isn't useful in the test logs.
+	output, err := RunKubectlAndGetOutputWithLoggerE(t, options, terratestLogger.Discard, "get", strings.Join(clusterScopedResourceTypes, ","), "-o", "name")
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			resources = append(resources, line)
+		}
+	}
+	sort.Strings(resources)
+
+	return resources, nil
+}
+
+// CheckClusterStateUnchangedOnCleanup records the set of cluster-scoped resources
+// and registers a cleanup function that fails the test if that set has changed
+// by the time the test finishes. Because cleanup functions run in the reverse order,
+// this function should be called before any resources are created by the test.
+func CheckClusterStateUnchangedOnCleanup(t *testing.T, options *k8s.KubectlOptions) {
+	t.Helper()
+
+	before := ClusterScopedResources(t, options)
+
+	t.Cleanup(func() {
+		logger.Log(t, "checking that cluster-scoped resources are unchanged")
+
+		// Some resources, such as persistent volumes, are deleted asynchronously
+		// so we need to give them some time to go away.
+		retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 5 * time.Second}, t, func(r *retry.R) {
+			after, err := ClusterScopedResourcesE(t, options)
+			require.NoError(r, err)
+			added, removed := diffResources(before, after)
+			if len(added) > 0 || len(removed) > 0 {
+				r.Errorf("cluster-scoped resources changed during the test:\nadded: %s\nremoved: %s",
+					strings.Join(added, ", "), strings.Join(removed, ", "))
+			}
+		})
+	})
+}
+
+// diffResources returns the elements that are in after but not in before (added)
+// and the elements that are in before but not in after (removed).
+func diffResources(before, after []string) (added, removed []string) {
+	beforeSet := make(map[string]struct{}, len(before))
+	for _, r := range before {
+		beforeSet[r] = struct{}{}
+	}
+	afterSet := make(map[string]struct{}, len(after))
+	for _, r := range after {
+		afterSet[r] = struct{}{}
+	}
+
+	for _, r := range after {
+		if _, ok := beforeSet[r]; !ok {
+			added = append(added, r)
+		}
+	}
+	for _, r := range before {
+		if _, ok := afterSet[r]; !ok {
+			removed = append(removed, r)
+		}
+	}
+
+	return added, removed
+}
@@ -0,0 +1,45 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffResources(t *testing.T) {
+	cases := map[string]struct {
+		before     []string
+		after      []string
+		expAdded   []string
+		expRemoved []string
+	}{
+		"no changes": {
+			before: []string{"clusterrole/foo", "persistentvolume/bar"},
+			after:  []string{"clusterrole/foo", "persistentvolume/bar"},
+		},
+		"added resources": {
+			before:   []string{"clusterrole/foo"},
+			after:    []string{"clusterrole/foo", "persistentvolume/bar"},
+			expAdded: []string{"persistentvolume/bar"},
+		},
+		"removed resources": {
+			before:     []string{"clusterrole/foo", "persistentvolume/bar"},
+			after:      []string{"clusterrole/foo"},
+			expRemoved: []string{"persistentvolume/bar"},
+		},
+		"added and removed resources": {
+			before:     []string{"clusterrole/foo"},
+			after:      []string{"clusterrole/bar"},
+			expAdded:   []string{"clusterrole/bar"},
+			expRemoved: []string{"clusterrole/foo"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			added, removed := diffResources(c.before, c.after)
+			require.Equal(t, c.expAdded, added)
+			require.Equal(t, c.expRemoved, removed)
+		})
+	}
+}
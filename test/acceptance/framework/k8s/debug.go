@@ -4,20 +4,33 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gruntwork-io/terratest/modules/k8s"
 	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/portallocator"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// envoySidecarContainer is the name of the Envoy container of injected pods.
+	envoySidecarContainer = "envoy-sidecar"
+	// envoyAdminPort is the port of the Envoy admin API of injected pods and gateways.
+	envoyAdminPort = 19000
+)
+
 // WritePodsDebugInfoIfFailed calls kubectl describe and kubectl logs --all-containers
-// on pods filtered by the labelSelector and writes it to the debugDirectory.
+// on pods filtered by the labelSelector and writes it to the debugDirectory, along with
+// the Envoy config dump and clusters of the injected pods among them and of any mesh gateways,
+// and the events of the namespace.
 func WritePodsDebugInfoIfFailed(t *testing.T, kubectlOptions *k8s.KubectlOptions, debugDirectory, labelSelector string) {
 	t.Helper()
 
@@ -31,7 +44,7 @@ func WritePodsDebugInfoIfFailed(t *testing.T, kubectlOptions *k8s.KubectlOptions
 		testDebugDirectory := filepath.Join(debugDirectory, t.Name(), contextName)
 		require.NoError(t, os.MkdirAll(testDebugDirectory, 0755))
 
-		logger.Logf(t, "dumping logs, pod info, and envoy config for %s to %s", labelSelector, testDebugDirectory)
+		logger.Logf(t, "dumping logs, pod info, envoy config, and events for %s to %s", labelSelector, testDebugDirectory)
 
 		// Describe and get logs for any pods.
 		pods, err := client.CoreV1().Pods(kubectlOptions.Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: labelSelector})
@@ -52,30 +65,25 @@ func WritePodsDebugInfoIfFailed(t *testing.T, kubectlOptions *k8s.KubectlOptions
 			writeResourceInfoToFile(t, pod.Name, "pod", testDebugDirectory, kubectlOptions)
 		}
 
-		// Get envoy configuration from the mesh gateways, if there are any.
+		// Get envoy configuration from the injected pods and the mesh gateways, if there are any.
+		// Mesh gateways are included even if they don't match the label selector
+		// because connect failures across datacenters often originate in them.
 		meshGatewayPods, err := client.CoreV1().Pods(kubectlOptions.Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "component=mesh-gateway"})
 		require.NoError(t, err)
 
-		for _, mpod := range meshGatewayPods.Items {
-			// Get configdump from mesh gateway, passing the discard logger since we only need these logs written to the file (below).
-			configDump, err := RunKubectlAndGetOutputWithLoggerE(t, kubectlOptions, terratestLogger.Discard, "exec", mpod.Name, "-c", "consul-sidecar", "--", "curl", "-s", "localhost:19000/config_dump?format=json")
-			if err != nil {
-				configDump = fmt.Sprintf("Error getting config_dump: %s: %s", err, configDump)
+		dumped := make(map[string]bool)
+		for _, pod := range append(pods.Items, meshGatewayPods.Items...) {
+			if dumped[pod.Name] || !hasEnvoyAdmin(pod) {
+				continue
 			}
-			// Get cluster config from mesh gateway, passing the discard logger since we only need these logs written to the file (below).
-			clusters, err := RunKubectlAndGetOutputWithLoggerE(t, kubectlOptions, terratestLogger.Discard, "exec", mpod.Name, "-c", "consul-sidecar", "--", "curl", "-s", "localhost:19000/clusters?format=json")
-			if err != nil {
-				clusters = fmt.Sprintf("Error getting clusters: %s: %s", err, clusters)
-			}
-
-			// Write config/clusters or err to file name <pod.Name>-envoy-[configdump/clusters].json
-			configDumpFilename := filepath.Join(testDebugDirectory, fmt.Sprintf("%s-envoy-configdump.json", mpod.Name))
-			clustersFilename := filepath.Join(testDebugDirectory, fmt.Sprintf("%s-envoy-clusters.json", mpod.Name))
-			require.NoError(t, ioutil.WriteFile(configDumpFilename, []byte(configDump), 0600))
-			require.NoError(t, ioutil.WriteFile(clustersFilename, []byte(clusters), 0600))
-
+			dumped[pod.Name] = true
+			writeEnvoyConfigToFiles(t, pod.Name, testDebugDirectory, kubectlOptions)
 		}
 
+		// Write the events of the namespace, which include the events of objects that don't
+		// match the label selector or don't exist anymore, e.g. pods that the injector rejected.
+		writeEventsToFile(t, testDebugDirectory, kubectlOptions)
+
 		// Describe any stateful sets.
 		statefulSets, err := client.AppsV1().StatefulSets(kubectlOptions.Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: labelSelector})
 		for _, statefulSet := range statefulSets.Items {
@@ -90,6 +98,13 @@ func WritePodsDebugInfoIfFailed(t *testing.T, kubectlOptions *k8s.KubectlOptions
 			writeResourceInfoToFile(t, daemonSet.Name, "daemonset", testDebugDirectory, kubectlOptions)
 		}
 
+		// Describe any replica sets, whose events show why their pods couldn't be created.
+		replicaSets, err := client.AppsV1().ReplicaSets(kubectlOptions.Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: labelSelector})
+		for _, replicaSet := range replicaSets.Items {
+			// Describe replica set and write it to a file.
+			writeResourceInfoToFile(t, replicaSet.Name, "replicaset", testDebugDirectory, kubectlOptions)
+		}
+
 		// Describe any deployments.
 		deployments, err := client.AppsV1().Deployments(kubectlOptions.Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: labelSelector})
 		for _, deployment := range deployments.Items {
@@ -99,6 +114,89 @@ func WritePodsDebugInfoIfFailed(t *testing.T, kubectlOptions *k8s.KubectlOptions
 	}
 }
 
+// hasEnvoyAdmin returns true if pod runs Envoy with the admin API on envoyAdminPort,
+// i.e. it's an injected pod or a gateway.
+func hasEnvoyAdmin(pod corev1.Pod) bool {
+	switch pod.Labels["component"] {
+	case "mesh-gateway", "ingress-gateway", "terminating-gateway":
+		return true
+	}
+	for _, container := range pod.Spec.Containers {
+		if container.Name == envoySidecarContainer {
+			return true
+		}
+	}
+	return false
+}
+
+// writeEnvoyConfigToFiles writes the config dump and the clusters of the Envoy
+// admin API of pod to <pod>-envoy-configdump.json and <pod>-envoy-clusters.json
+// or the error getting them. The admin API is reached through a port-forward
+// because the Envoy containers don't necessarily have curl.
+func writeEnvoyConfigToFiles(t *testing.T, podName, testDebugDirectory string, kubectlOptions *k8s.KubectlOptions) {
+	configDump, clusters := "", ""
+
+	localPort := portallocator.Allocate(t)
+	tunnel := k8s.NewTunnelWithLogger(kubectlOptions, k8s.ResourceTypePod, podName, localPort, envoyAdminPort, terratestLogger.Discard)
+	if err := tunnel.ForwardPortE(t); err != nil {
+		configDump = fmt.Sprintf("Error port-forwarding to the Envoy admin API: %s", err)
+		clusters = configDump
+	} else {
+		defer tunnel.Close()
+		configDump = envoyAdminGet(tunnel.Endpoint(), "/config_dump?format=json")
+		clusters = envoyAdminGet(tunnel.Endpoint(), "/clusters?format=json")
+	}
+
+	configDumpFilename := filepath.Join(testDebugDirectory, fmt.Sprintf("%s-envoy-configdump.json", podName))
+	clustersFilename := filepath.Join(testDebugDirectory, fmt.Sprintf("%s-envoy-clusters.json", podName))
+	require.NoError(t, ioutil.WriteFile(configDumpFilename, []byte(configDump), 0600))
+	require.NoError(t, ioutil.WriteFile(clustersFilename, []byte(clusters), 0600))
+}
+
+// envoyAdminGet returns the body of the response to a GET request for path
+// from the Envoy admin API at addr, or the error making the request.
+func envoyAdminGet(addr, path string) string {
+	body, err := envoyAdminGetE(addr, path)
+	if err != nil {
+		return fmt.Sprintf("Error getting %s: %s", path, err)
+	}
+	return body
+}
+
+// envoyAdminGetE returns the body of the response to a GET request
+// for path from the Envoy admin API at addr.
+func envoyAdminGetE(addr, path string) (string, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://%s%s", addr, path))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading the response: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	return string(body), nil
+}
+
+// writeEventsToFile writes the events of the namespace of kubectlOptions,
+// sorted by when they last happened, to <namespace>-events.txt or the error getting them.
+func writeEventsToFile(t *testing.T, testDebugDirectory string, kubectlOptions *k8s.KubectlOptions) {
+	events, err := RunKubectlAndGetOutputWithLoggerE(t, kubectlOptions, terratestLogger.Discard, "get", "events", "--sort-by=.lastTimestamp")
+	if err != nil {
+		events = fmt.Sprintf("Error getting events: %s: %s", err, events)
+	}
+	namespace := kubectlOptions.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	eventsFilename := filepath.Join(testDebugDirectory, fmt.Sprintf("%s-events.txt", namespace))
+	require.NoError(t, ioutil.WriteFile(eventsFilename, []byte(events), 0600))
+}
+
 // writeResourceInfoToFile takes a Kubernetes resource name, resource type (e.g. pod, deployment, statefulset etc),
 // runs 'kubectl describe' with that resource name and type and writes the output of it to a file or errors.
 // Note that the resource type has to be compatible with the one you could use with a kubectl describe command,
@@ -114,3 +212,52 @@ func writeResourceInfoToFile(t *testing.T, resourceName, resourceType, testDebug
 	descFilename := filepath.Join(testDebugDirectory, fmt.Sprintf("%s-%s.txt", resourceName, resourceType))
 	require.NoError(t, ioutil.WriteFile(descFilename, []byte(desc), 0600))
 }
+
+// WriteConsulDebugArchiveIfFailed runs 'consul debug' inside the Consul server pod given by podName
+// and copies the resulting archive into the debugDirectory. The archive is left in the format
+// produced by 'consul debug' so that it can be attached as is to support escalations.
+// If ACLs are enabled, the token is passed to the 'consul debug' command;
+// otherwise it should be left empty.
+func WriteConsulDebugArchiveIfFailed(t *testing.T, kubectlOptions *k8s.KubectlOptions, debugDirectory, podName, token string) {
+	t.Helper()
+
+	if t.Failed() {
+		contextName := helpers.KubernetesContextFromOptions(t, kubectlOptions)
+
+		// Create a directory for the test.
+		testDebugDirectory := filepath.Join(debugDirectory, t.Name(), contextName)
+		require.NoError(t, os.MkdirAll(testDebugDirectory, 0755))
+
+		logger.Logf(t, "capturing consul debug archive from %s to %s", podName, testDebugDirectory)
+
+		// 'consul debug' appends the .tar.gz extension to the output path itself.
+		remoteOutput := fmt.Sprintf("/tmp/consul-debug-%d", time.Now().Unix())
+		archiveName := fmt.Sprintf("%s-consul-debug.tar.gz", podName)
+
+		args := []string{"exec", podName, "-c", "consul", "--", "consul", "debug", "-duration=30s", "-interval=10s", "-output=" + remoteOutput}
+		if token != "" {
+			args = append(args, "-token="+token)
+		}
+
+		// Pass the discard logger so that the ACL token is not printed to test logs.
+		output, err := RunKubectlAndGetOutputWithLoggerE(t, kubectlOptions, terratestLogger.Discard, args...)
+		if err != nil {
+			// Write the error into the debug directory instead of the archive so that
+			// it's clear why the archive is missing.
+			errFilename := filepath.Join(testDebugDirectory, fmt.Sprintf("%s-consul-debug-error.txt", podName))
+			require.NoError(t, ioutil.WriteFile(errFilename, []byte(fmt.Sprintf("Error running consul debug: %s: %s", err, output)), 0600))
+			return
+		}
+
+		// Copy the archive out of the pod. kubectl cp requires the tar binary in the container,
+		// which is present in the official Consul images.
+		_, err = RunKubectlAndGetOutputWithLoggerE(t, kubectlOptions, terratestLogger.Discard,
+			"cp", "-c", "consul", fmt.Sprintf("%s:%s.tar.gz", podName, remoteOutput), filepath.Join(testDebugDirectory, archiveName))
+		if err != nil {
+			logger.Logf(t, "failed to copy consul debug archive from %s: %s", podName, err)
+		}
+
+		// Clean up the archive in the pod so that it doesn't take up space on subsequent captures.
+		_, _ = RunKubectlAndGetOutputWithLoggerE(t, kubectlOptions, terratestLogger.Discard, "exec", podName, "-c", "consul", "--", "rm", "-f", remoteOutput+".tar.gz")
+	}
+}
@@ -0,0 +1,58 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHasEnvoyAdmin(t *testing.T) {
+	cases := map[string]struct {
+		pod      corev1.Pod
+		expected bool
+	}{
+		"injected pod": {
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "static-server"}, {Name: "envoy-sidecar"}},
+				},
+			},
+			expected: true,
+		},
+		"mesh gateway": {
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"component": "mesh-gateway"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "mesh-gateway"}},
+				},
+			},
+			expected: true,
+		},
+		"ingress gateway": {
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"component": "ingress-gateway"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "ingress-gateway"}},
+				},
+			},
+			expected: true,
+		},
+		"pod that isn't injected": {
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"component": "server"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "consul"}},
+				},
+			},
+			expected: false,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, c.expected, hasEnvoyAdmin(c.pod))
+		})
+	}
+}
@@ -1,19 +1,25 @@
 package k8s
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/faults"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
 	"github.com/hashicorp/consul/sdk/testutil/retry"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
 )
 
 // Deploy creates a Kubernetes deployment by applying configuration stored at filepath,
@@ -21,6 +27,7 @@ import (
 func Deploy(t *testing.T, options *k8s.KubectlOptions, noCleanupOnFailure bool, debugDirectory string, filepath string) {
 	t.Helper()
 
+	faults.Inject(t, faults.BeforeDeploy)
 	KubectlApply(t, options, filepath)
 
 	file, err := os.Open(filepath)
@@ -39,7 +46,10 @@ func Deploy(t *testing.T, options *k8s.KubectlOptions, noCleanupOnFailure bool,
 		KubectlDelete(t, options, filepath)
 	})
 
+	StreamPodLogs(t, options, debugDirectory, labelMapToString(deployment.GetLabels()))
+
 	RunKubectl(t, options, "wait", "--for=condition=available", fmt.Sprintf("deploy/%s", deployment.Name))
+	faults.Inject(t, faults.AfterDeploy)
 }
 
 // DeployKustomize creates a Kubernetes deployment by applying the kustomize directory stored at kustomizeDir,
@@ -47,6 +57,7 @@ func Deploy(t *testing.T, options *k8s.KubectlOptions, noCleanupOnFailure bool,
 func DeployKustomize(t *testing.T, options *k8s.KubectlOptions, noCleanupOnFailure bool, debugDirectory string, kustomizeDir string) {
 	t.Helper()
 
+	faults.Inject(t, faults.BeforeDeploy)
 	KubectlApplyK(t, options, kustomizeDir)
 
 	output, err := RunKubectlAndGetOutputE(t, options, "kustomize", kustomizeDir)
@@ -65,8 +76,11 @@ func DeployKustomize(t *testing.T, options *k8s.KubectlOptions, noCleanupOnFailu
 		KubectlDeleteK(t, options, kustomizeDir)
 	})
 
+	StreamPodLogs(t, options, debugDirectory, labelMapToString(deployment.GetLabels()))
+
 	// The timeout to allow for connect-init to wait for services to be registered by the endpoints controller.
 	RunKubectl(t, options, "wait", "--for=condition=available", "--timeout=5m", fmt.Sprintf("deploy/%s", deployment.Name))
+	faults.Inject(t, faults.AfterDeploy)
 }
 
 // CheckStaticServerConnection execs into a pod of the deployment given by deploymentName
@@ -154,6 +168,49 @@ func CheckStaticServerConnectionFailing(t *testing.T, options *k8s.KubectlOption
 		curlArgs...)
 }
 
+// DenialMode is how a connection that intentions deny fails,
+// which depends on the protocol of the destination service.
+type DenialMode int
+
+const (
+	// DeniedL4 is the denial of a connection to a service with the tcp protocol:
+	// Envoy closes the connection without a response.
+	DeniedL4 DenialMode = iota
+	// DeniedL7 is the denial of a request to a service with an HTTP protocol:
+	// Envoy responds with 403 Forbidden.
+	DeniedL7
+)
+
+func (m DenialMode) String() string {
+	switch m {
+	case DeniedL4:
+		return "L4"
+	case DeniedL7:
+		return "L7"
+	default:
+		return fmt.Sprintf("DenialMode(%d)", int(m))
+	}
+}
+
+// failureMessages returns the curl errors of a connection denied with mode.
+func (m DenialMode) failureMessages() []string {
+	switch m {
+	case DeniedL7:
+		return []string{"curl: (22) The requested URL returned error: 403"}
+	default:
+		return []string{"curl: (52) Empty reply from server", "curl: (56) Recv failure: Connection reset by peer"}
+	}
+}
+
+// CheckDeniedAs is like CheckStaticServerConnectionFailing, but it expects the connection
+// to be denied by intentions as mode, e.g. with a 403 rather than a closed connection for L7,
+// so that a test doesn't pass because the connection fails for another reason.
+func CheckDeniedAs(t *testing.T, options *k8s.KubectlOptions, deploymentName string, mode DenialMode, curlArgs ...string) {
+	t.Helper()
+	logger.Logf(t, "checking that the connection is denied by intentions at %s", mode)
+	CheckStaticServerConnection(t, options, false, deploymentName, mode.failureMessages(), curlArgs...)
+}
+
 // labelMapToString takes a label map[string]string
 // and returns the string-ified version of, e.g app=foo,env=dev.
 func labelMapToString(labelMap map[string]string) string {
@@ -164,3 +221,99 @@ func labelMapToString(labelMap map[string]string) string {
 
 	return strings.Join(labels, ",")
 }
+
+// maxParallelNamespaceDeploys is the maximum number of namespaces
+// that DeployAcrossNamespaces deploys to at the same time.
+const maxParallelNamespaceDeploys = 5
+
+// DeployAcrossNamespaces creates nsCount namespaces and deploys the kustomize directory
+// stored at kustomizeDir into each of them, deploying into at most maxParallelNamespaceDeploys
+// namespaces concurrently. It waits for the deployment to become available in every namespace
+// and returns the names of the created namespaces. The namespaces are deleted when the test finishes.
+func DeployAcrossNamespaces(t *testing.T, options *k8s.KubectlOptions, noCleanupOnFailure bool, debugDirectory string, nsCount int, kustomizeDir string) []string {
+	t.Helper()
+
+	client := helpers.KubernetesClientFromOptions(t, options)
+
+	output, err := RunKubectlAndGetOutputE(t, options, "kustomize", kustomizeDir)
+	require.NoError(t, err)
+
+	deployment := v1.Deployment{}
+	err = yaml.NewYAMLOrJSONDecoder(strings.NewReader(output), 1024).Decode(&deployment)
+	require.NoError(t, err)
+
+	namespacePrefix := helpers.RandomName()
+	namespaces := make([]string, nsCount)
+	for i := range namespaces {
+		namespaces[i] = fmt.Sprintf("%s-%d", namespacePrefix, i)
+	}
+
+	helpers.Cleanup(t, noCleanupOnFailure, func() {
+		deleteNamespaces(t, client, options, debugDirectory, labelMapToString(deployment.GetLabels()), namespaces)
+	})
+
+	logger.Logf(t, "deploying %s into %d namespaces", kustomizeDir, nsCount)
+	start := time.Now()
+
+	errs := deployConcurrently(namespaces, maxParallelNamespaceDeploys, func(ns string) error {
+		return deployToNamespace(t, client, options, ns, kustomizeDir, deployment.Name)
+	})
+	for i, err := range errs {
+		require.NoError(t, err, "failed to deploy %s into namespace %s", kustomizeDir, namespaces[i])
+	}
+	logger.Logf(t, "took %s to deploy %s into %d namespaces", time.Since(start), kustomizeDir, nsCount)
+
+	return namespaces
+}
+
+// deployConcurrently calls deploy for each of namespaces, with at most limit calls running
+// at the same time, and returns the error of each call in the order of namespaces.
+// Note: we can't fail the test from the goroutines below,
+// so we collect the errors and let the caller fail the test once all of them are done.
+func deployConcurrently(namespaces []string, limit int, deploy func(ns string) error) []error {
+	errs := make([]error, len(namespaces))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, ns := range namespaces {
+		wg.Add(1)
+		go func(i int, ns string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			errs[i] = deploy(ns)
+		}(i, ns)
+	}
+	wg.Wait()
+	return errs
+}
+
+// deleteNamespaces writes the debug info of the pods matching labelSelector
+// in each of namespaces if the test failed and then deletes the namespaces.
+func deleteNamespaces(t *testing.T, client kubernetes.Interface, options *k8s.KubectlOptions, debugDirectory, labelSelector string, namespaces []string) {
+	for _, ns := range namespaces {
+		WritePodsDebugInfoIfFailed(t, KubectlOptionsForNamespace(options, ns), debugDirectory, labelSelector)
+
+		// Ignore errors because the namespace may not have been created if the deploy failed.
+		_ = client.CoreV1().Namespaces().Delete(context.Background(), ns, metav1.DeleteOptions{})
+	}
+}
+
+// deployToNamespace creates namespace ns, applies the kustomize directory into it
+// and waits for the deployment given by deploymentName to become available.
+func deployToNamespace(t *testing.T, client kubernetes.Interface, options *k8s.KubectlOptions, ns, kustomizeDir, deploymentName string) error {
+	_, err := client.CoreV1().Namespaces().Create(context.Background(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}}, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+
+	nsOptions := KubectlOptionsForNamespace(options, ns)
+	if output, err := RunKubectlAndGetOutputE(t, nsOptions, "apply", "-k", kustomizeDir); err != nil {
+		return fmt.Errorf("%s: %s", err, output)
+	}
+	if output, err := RunKubectlAndGetOutputE(t, nsOptions, "wait", "--for=condition=available", "--timeout=5m", fmt.Sprintf("deploy/%s", deploymentName)); err != nil {
+		return fmt.Errorf("%s: %s", err, output)
+	}
+
+	return nil
+}
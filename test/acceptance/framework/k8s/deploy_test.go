@@ -0,0 +1,85 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Test that the failure messages of the denial modes don't overlap
+// so that CheckDeniedAs can tell them apart.
+func TestDenialMode_FailureMessages(t *testing.T) {
+	for _, l4 := range DeniedL4.failureMessages() {
+		for _, l7 := range DeniedL7.failureMessages() {
+			require.False(t, strings.Contains(l4, l7) || strings.Contains(l7, l4), "%q and %q overlap", l4, l7)
+		}
+	}
+	require.Equal(t, "L4", DeniedL4.String())
+	require.Equal(t, "L7", DeniedL7.String())
+}
+
+// Test that deployConcurrently deploys into every namespace without exceeding
+// the concurrency limit and returns the errors in the order of the namespaces.
+func TestDeployConcurrently(t *testing.T) {
+	var namespaces []string
+	for i := 0; i < 12; i++ {
+		namespaces = append(namespaces, fmt.Sprintf("ns-%d", i))
+	}
+
+	var running, maxRunning, deployed int32
+	errs := deployConcurrently(namespaces, 3, func(ns string) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&deployed, 1)
+
+		if ns == "ns-5" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	require.Equal(t, int32(len(namespaces)), deployed)
+	require.LessOrEqual(t, maxRunning, int32(3))
+	require.Len(t, errs, len(namespaces))
+	for i, err := range errs {
+		if i == 5 {
+			require.EqualError(t, err, "failed")
+		} else {
+			require.NoError(t, err)
+		}
+	}
+}
+
+// Test that deleteNamespaces deletes every namespace,
+// including when some of them were never created.
+func TestDeleteNamespaces(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	for _, ns := range []string{"ns-0", "ns-2", "other"} {
+		_, err := client.CoreV1().Namespaces().Create(context.Background(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	deleteNamespaces(t, client, &k8s.KubectlOptions{}, t.TempDir(), "app=static-server", []string{"ns-0", "ns-1", "ns-2"})
+
+	namespaces, err := client.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, namespaces.Items, 1)
+	require.Equal(t, "other", namespaces.Items[0].Name)
+}
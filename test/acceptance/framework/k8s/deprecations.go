@@ -0,0 +1,134 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// DeprecationWarnings returns the deprecation warnings that the API server of the
+// cluster of options returns for the API versions of the objects in manifest, e.g.
+// the rendered manifest of a release, so that tests notice when a chart uses APIs
+// that are deprecated in the Kubernetes version they run against.
+// The warnings are recorded with a client-go warning handler while listing the objects
+// of each API version and kind in the manifest, and are returned by the API version and
+// kind they were returned for, sorted and deduplicated. Kinds without warnings are omitted.
+func DeprecationWarnings(t *testing.T, options *k8s.KubectlOptions, manifest string) map[schema.GroupVersionKind][]string {
+	t.Helper()
+
+	gvks, err := manifestGroupVersionKinds(manifest)
+	require.NoError(t, err)
+
+	configPath, err := options.GetConfigPath(t)
+	require.NoError(t, err)
+	config, err := k8s.LoadApiClientConfigE(configPath, options.ContextName)
+	require.NoError(t, err)
+	recorder := &warningRecorder{}
+	config.WarningHandler = recorder
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	require.NoError(t, err)
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+	dynamicClient, err := dynamic.NewForConfig(config)
+	require.NoError(t, err)
+
+	warnings := make(map[schema.GroupVersionKind][]string)
+	for _, gvk := range gvks {
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		require.NoError(t, err)
+		// Discard the warnings of discovery so that only
+		// the warnings of listing gvk are attributed to it.
+		recorder.take()
+
+		var resource dynamic.ResourceInterface = dynamicClient.Resource(mapping.Resource)
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			resource = dynamicClient.Resource(mapping.Resource).Namespace(options.Namespace)
+		}
+		_, err = resource.List(context.Background(), metav1.ListOptions{Limit: 1})
+		require.NoError(t, err, "listing %s", gvk)
+		if gvkWarnings := recorder.take(); len(gvkWarnings) > 0 {
+			warnings[gvk] = gvkWarnings
+		}
+	}
+
+	return warnings
+}
+
+// manifestGroupVersionKinds returns the distinct API versions and kinds
+// of the objects in manifest in the order in which they first appear.
+func manifestGroupVersionKinds(manifest string) ([]schema.GroupVersionKind, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+	seen := make(map[schema.GroupVersionKind]bool)
+	var gvks []schema.GroupVersionKind
+	for {
+		var obj unstructured.Unstructured
+		err := decoder.Decode(&obj.Object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding manifest: %s", err)
+		}
+		// Empty documents, e.g. templates that aren't rendered, decode to nothing.
+		if len(obj.Object) == 0 {
+			continue
+		}
+		gvk := obj.GroupVersionKind()
+		if gvk.Kind == "" || seen[gvk] {
+			continue
+		}
+		seen[gvk] = true
+		gvks = append(gvks, gvk)
+	}
+	return gvks, nil
+}
+
+// warningRecorder is a client-go warning handler that records
+// the warnings that the API server returns.
+type warningRecorder struct {
+	lock     sync.Mutex
+	warnings map[string]bool
+}
+
+// HandleWarningHeader records message if it's a warning,
+// which the API server returns with code 299.
+func (r *warningRecorder) HandleWarningHeader(code int, _ string, message string) {
+	if code != 299 || message == "" {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.warnings == nil {
+		r.warnings = make(map[string]bool)
+	}
+	r.warnings[message] = true
+}
+
+// take returns the sorted warnings recorded since it was last called.
+func (r *warningRecorder) take() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var warnings []string
+	for warning := range r.warnings {
+		warnings = append(warnings, warning)
+	}
+	sort.Strings(warnings)
+	r.warnings = nil
+	return warnings
+}
@@ -0,0 +1,53 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestManifestGroupVersionKinds(t *testing.T) {
+	manifest := `---
+# Source: consul/templates/server-podsecuritypolicy.yaml
+apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+metadata:
+  name: consul-server
+---
+# Source: consul/templates/empty.yaml
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: consul-server
+---
+apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+metadata:
+  name: consul-client
+`
+	gvks, err := manifestGroupVersionKinds(manifest)
+	require.NoError(t, err)
+	require.Equal(t, []schema.GroupVersionKind{
+		{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"},
+		{Group: "", Version: "v1", Kind: "ServiceAccount"},
+	}, gvks)
+}
+
+func TestWarningRecorder(t *testing.T) {
+	r := &warningRecorder{}
+	r.HandleWarningHeader(299, "-", "policy/v1beta1 PodSecurityPolicy is deprecated in v1.21+, unavailable in v1.25+")
+	r.HandleWarningHeader(299, "-", "policy/v1beta1 PodSecurityPolicy is deprecated in v1.21+, unavailable in v1.25+")
+	r.HandleWarningHeader(299, "-", "extensions/v1beta1 Ingress is deprecated in v1.14+, unavailable in v1.22+")
+	// Only warnings are recorded.
+	r.HandleWarningHeader(199, "-", "miscellaneous warning")
+	r.HandleWarningHeader(299, "-", "")
+
+	require.Equal(t, []string{
+		"extensions/v1beta1 Ingress is deprecated in v1.14+, unavailable in v1.22+",
+		"policy/v1beta1 PodSecurityPolicy is deprecated in v1.21+, unavailable in v1.25+",
+	}, r.take())
+	// The warnings are reset once they're taken.
+	require.Empty(t, r.take())
+}
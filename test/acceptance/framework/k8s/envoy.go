@@ -0,0 +1,105 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/portallocator"
+	"github.com/stretchr/testify/require"
+)
+
+// EnvoyCluster is an Envoy cluster from a config dump
+// with the settings that tests check.
+type EnvoyCluster struct {
+	Name string `json:"name"`
+	// ConnectTimeout is a duration in the JSON format of protobuf, e.g. "2.500s".
+	ConnectTimeout   string                `json:"connect_timeout"`
+	CircuitBreakers  EnvoyCircuitBreakers  `json:"circuit_breakers"`
+	OutlierDetection EnvoyOutlierDetection `json:"outlier_detection"`
+}
+
+// EnvoyCircuitBreakers are the circuit breakers of an Envoy cluster.
+type EnvoyCircuitBreakers struct {
+	Thresholds []EnvoyThresholds `json:"thresholds"`
+}
+
+// EnvoyThresholds are the thresholds of a circuit breaker of an Envoy cluster.
+// Unset thresholds are nil.
+type EnvoyThresholds struct {
+	MaxConnections     *int `json:"max_connections"`
+	MaxPendingRequests *int `json:"max_pending_requests"`
+	MaxRequests        *int `json:"max_requests"`
+}
+
+// EnvoyOutlierDetection is the passive health checking of an Envoy cluster.
+// Unset settings are empty or nil.
+type EnvoyOutlierDetection struct {
+	Interval       string `json:"interval"`
+	Consecutive5xx *int   `json:"consecutive_5xx"`
+}
+
+// EnvoyConfigDump returns the config dump of the Envoy admin API of the pod
+// podName, reached through a port-forward. It fails the test if it can't get it.
+func EnvoyConfigDump(t *testing.T, options *k8s.KubectlOptions, podName string) []byte {
+	t.Helper()
+
+	configDump, err := EnvoyConfigDumpE(t, options, podName)
+	require.NoError(t, err)
+	return configDump
+}
+
+// EnvoyConfigDumpE returns the config dump of the Envoy admin API of the pod
+// podName, reached through a port-forward, or an error if it can't get it,
+// e.g. so that it can be retried. The port-forward is closed before it returns.
+func EnvoyConfigDumpE(t *testing.T, options *k8s.KubectlOptions, podName string) ([]byte, error) {
+	localPort, err := portallocator.AllocateE()
+	if err != nil {
+		return nil, err
+	}
+	defer portallocator.Release(localPort)
+
+	tunnel := k8s.NewTunnelWithLogger(options, k8s.ResourceTypePod, podName, localPort, envoyAdminPort, terratestLogger.Discard)
+	// It's okay to pass t to ForwardPortE since it only uses it for logging.
+	if err := tunnel.ForwardPortE(t); err != nil {
+		return nil, err
+	}
+	defer tunnel.Close()
+
+	configDump, err := envoyAdminGetE(tunnel.Endpoint(), "/config_dump?format=json")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(configDump), nil
+}
+
+// EnvoyClusters returns the dynamic active clusters of an Envoy config dump,
+// which include the clusters of the upstreams of Connect sidecars.
+func EnvoyClusters(configDump []byte) ([]EnvoyCluster, error) {
+	var dump struct {
+		Configs []struct {
+			Type            string `json:"@type"`
+			DynamicClusters []struct {
+				Cluster EnvoyCluster `json:"cluster"`
+			} `json:"dynamic_active_clusters"`
+		} `json:"configs"`
+	}
+	if err := json.Unmarshal(configDump, &dump); err != nil {
+		return nil, fmt.Errorf("parsing the config dump: %s", err)
+	}
+
+	var clusters []EnvoyCluster
+	for _, config := range dump.Configs {
+		// The version of the type depends on the version of Envoy.
+		if !strings.HasSuffix(config.Type, ".ClustersConfigDump") {
+			continue
+		}
+		for _, dynamicCluster := range config.DynamicClusters {
+			clusters = append(clusters, dynamicCluster.Cluster)
+		}
+	}
+	return clusters, nil
+}
@@ -0,0 +1,70 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvoyClusters(t *testing.T) {
+	configDump := `{
+  "configs": [
+    {
+      "@type": "type.googleapis.com/envoy.admin.v3.BootstrapConfigDump",
+      "bootstrap": {}
+    },
+    {
+      "@type": "type.googleapis.com/envoy.admin.v3.ClustersConfigDump",
+      "static_clusters": [
+        {"cluster": {"name": "local_agent"}}
+      ],
+      "dynamic_active_clusters": [
+        {
+          "version_info": "abc",
+          "cluster": {
+            "@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+            "name": "static-server.default.dc1.internal.11111111-2222-3333-4444-555555555555.consul",
+            "connect_timeout": "2.500s",
+            "circuit_breakers": {
+              "thresholds": [
+                {"max_connections": 11, "max_pending_requests": 12, "max_requests": 13}
+              ]
+            },
+            "outlier_detection": {
+              "interval": "2s",
+              "consecutive_5xx": 7
+            }
+          }
+        },
+        {
+          "cluster": {
+            "name": "local_app",
+            "connect_timeout": "5s"
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+	clusters, err := EnvoyClusters([]byte(configDump))
+	require.NoError(t, err)
+	require.Len(t, clusters, 2)
+
+	upstream := clusters[0]
+	require.Equal(t, "static-server.default.dc1.internal.11111111-2222-3333-4444-555555555555.consul", upstream.Name)
+	require.Equal(t, "2.500s", upstream.ConnectTimeout)
+	require.Len(t, upstream.CircuitBreakers.Thresholds, 1)
+	require.Equal(t, 11, *upstream.CircuitBreakers.Thresholds[0].MaxConnections)
+	require.Equal(t, 12, *upstream.CircuitBreakers.Thresholds[0].MaxPendingRequests)
+	require.Equal(t, 13, *upstream.CircuitBreakers.Thresholds[0].MaxRequests)
+	require.Equal(t, "2s", upstream.OutlierDetection.Interval)
+	require.Equal(t, 7, *upstream.OutlierDetection.Consecutive5xx)
+
+	localApp := clusters[1]
+	require.Empty(t, localApp.CircuitBreakers.Thresholds)
+	require.Nil(t, localApp.OutlierDetection.Consecutive5xx)
+
+	_, err = EnvoyClusters([]byte("not json"))
+	require.Error(t, err)
+}
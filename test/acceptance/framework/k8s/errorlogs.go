@@ -0,0 +1,65 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// errorLogPattern matches the lines that Consul and consul-k8s log at the ERROR level.
+// Consul and most of consul-k8s use hclog, and the controller-runtime
+// parts of consul-k8s use zap, which separates the level with tabs.
+var errorLogPattern = regexp.MustCompile(`\[ERROR\]|\tERROR\t`)
+
+// errorLogLine is a line that a container logged at the ERROR level.
+type errorLogLine struct {
+	// time is when the container logged the line according to the kubelet.
+	time      time.Time
+	pod       string
+	labels    map[string]string
+	container string
+	line      string
+}
+
+// ErrorLogs returns the lines that the containers of the pods matching labelSelector
+// have logged at the ERROR level since since, except the lines that match any of allowed.
+// It includes the lines of container instances that have restarted or pods that have been
+// deleted since, as long as they were streamed. Each line is prefixed with the name of its
+// pod and container.
+func (l *LogStream) ErrorLogs(t *testing.T, labelSelector string, since time.Time, allowed []*regexp.Regexp) []string {
+	t.Helper()
+
+	selector, err := labels.Parse(labelSelector)
+	require.NoError(t, err)
+
+	l.streamer.lock.Lock()
+	defer l.streamer.lock.Unlock()
+	return filterErrorLogs(l.streamer.errorLines, selector, since, allowed)
+}
+
+// filterErrorLogs returns the lines of the pods matching selector that
+// were logged since since, except the lines that match any of allowed.
+func filterErrorLogs(lines []errorLogLine, selector labels.Selector, since time.Time, allowed []*regexp.Regexp) []string {
+	var errorLines []string
+	for _, l := range lines {
+		if l.time.Before(since) || !selector.Matches(labels.Set(l.labels)) || matchesAny(l.line, allowed) {
+			continue
+		}
+		errorLines = append(errorLines, fmt.Sprintf("%s/%s: %s", l.pod, l.container, l.line))
+	}
+	return errorLines
+}
+
+// matchesAny returns true if s matches any of patterns.
+func matchesAny(s string, patterns []*regexp.Regexp) bool {
+	for _, p := range patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
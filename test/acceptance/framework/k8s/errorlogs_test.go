@@ -0,0 +1,67 @@
+package k8s
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestFilterErrorLogs(t *testing.T) {
+	since := time.Date(2021, 4, 1, 10, 0, 1, 0, time.UTC)
+	serverLabels := map[string]string{"component": "server"}
+	lines := []errorLogLine{
+		{time: since.Add(-time.Second), pod: "server-0", labels: serverLabels, container: "consul", line: `[ERROR] agent: Coordinate update error: error="No cluster leader"`},
+		{time: since, pod: "server-0", labels: serverLabels, container: "consul", line: `[ERROR] agent.server.rpc: RPC failed: error="rpc error: No cluster leader"`},
+		{time: since.Add(time.Second), pod: "server-0", labels: serverLabels, container: "consul", line: `[ERROR] agent: failed to sync changes: error="permission denied"`},
+		{time: since.Add(time.Second), pod: "injector", labels: map[string]string{"component": "connect-injector"}, container: "sidecar-injector", line: "ERROR\tcontroller-runtime.controller\tReconciler error"},
+		{time: since.Add(time.Second), pod: "other", labels: map[string]string{"component": "other"}, container: "other", line: "[ERROR] other error"},
+	}
+	selector, err := labels.Parse("component in (server,connect-injector)")
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		allowed  []*regexp.Regexp
+		expected []string
+	}{
+		"no allowed errors": {
+			expected: []string{
+				`server-0/consul: [ERROR] agent.server.rpc: RPC failed: error="rpc error: No cluster leader"`,
+				`server-0/consul: [ERROR] agent: failed to sync changes: error="permission denied"`,
+				"injector/sidecar-injector: ERROR\tcontroller-runtime.controller\tReconciler error",
+			},
+		},
+		"allowed errors": {
+			allowed: []*regexp.Regexp{regexp.MustCompile("No cluster leader"), regexp.MustCompile("Reconciler error")},
+			expected: []string{
+				`server-0/consul: [ERROR] agent: failed to sync changes: error="permission denied"`,
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, c.expected, filterErrorLogs(lines, selector, since, c.allowed))
+		})
+	}
+
+	require.Empty(t, filterErrorLogs(nil, selector, since, nil))
+}
+
+func TestErrorLogPattern(t *testing.T) {
+	require.True(t, errorLogPattern.MatchString(`2021-04-01T10:00:01.000Z [ERROR] agent.server.rpc: RPC failed`))
+	require.True(t, errorLogPattern.MatchString("2021-04-01T10:00:04.000Z\tERROR\tcontroller-runtime.controller\tReconciler error"))
+	require.False(t, errorLogPattern.MatchString(`2021-04-01T10:00:03.000Z [WARN]  agent: ERROR in the message doesn't make it an error`))
+}
+
+func TestSplitLogTimestamp(t *testing.T) {
+	logged, line := splitLogTimestamp("2021-04-01T10:00:01.123456789Z [ERROR] agent: error\n")
+	require.Equal(t, time.Date(2021, 4, 1, 10, 0, 1, 123456789, time.UTC), logged)
+	require.Equal(t, "[ERROR] agent: error\n", line)
+
+	// Lines without a timestamp are kept as is.
+	_, line = splitLogTimestamp("fake logs")
+	require.Equal(t, "fake logs", line)
+}
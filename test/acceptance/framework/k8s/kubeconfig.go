@@ -0,0 +1,193 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clientcmdv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// KubeconfigSecretKey is the key of the kubeconfig in the secret created by CreateKubeconfigSecret.
+	KubeconfigSecretKey = "kubeconfig"
+	// KubeconfigSecretHostKey is the key of the Kubernetes API server address in the secret created by CreateKubeconfigSecret.
+	KubeconfigSecretHostKey = "host"
+	// KubeconfigSecretCACertKey is the key of the Kubernetes API server CA certificate in the secret created by CreateKubeconfigSecret.
+	KubeconfigSecretCACertKey = "caCert"
+	// KubeconfigSecretTokenKey is the key of the service account token in the secret created by CreateKubeconfigSecret.
+	KubeconfigSecretTokenKey = "token"
+)
+
+// ClusterCredentials are the credentials for a service account
+// that can be used to talk to a Kubernetes API server from another cluster.
+type ClusterCredentials struct {
+	Host   string
+	CACert []byte
+	Token  string
+}
+
+// CreateKubeconfigSecret creates a service account named name in the source cluster
+// given by sourceOptions that is allowed to review tokens (i.e. bound to the system:auth-delegator cluster role),
+// and stores its credentials and a kubeconfig in a secret named name in the destination cluster
+// given by destOptions. This is what Consul components in a secondary datacenter
+// need to reach the primary's Kubernetes API, for example, for the auth method to validate
+// service account tokens. The resources are deleted when the test finishes.
+func CreateKubeconfigSecret(t *testing.T, sourceOptions, destOptions *k8s.KubectlOptions, name string, noCleanupOnFailure bool) ClusterCredentials {
+	t.Helper()
+
+	sourceClient := helpers.KubernetesClientFromOptions(t, sourceOptions)
+	destClient := helpers.KubernetesClientFromOptions(t, destOptions)
+
+	creds := createTokenReviewerServiceAccount(t, sourceClient, sourceOptions, name, noCleanupOnFailure)
+
+	kubeconfig, err := kubeconfigFromCredentials(creds, name)
+	require.NoError(t, err)
+
+	logger.Logf(t, "creating kubeconfig secret %s/%s", destOptions.Namespace, name)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Data: map[string][]byte{
+			KubeconfigSecretKey:       kubeconfig,
+			KubeconfigSecretHostKey:   []byte(creds.Host),
+			KubeconfigSecretCACertKey: creds.CACert,
+			KubeconfigSecretTokenKey:  []byte(creds.Token),
+		},
+	}
+	_, err = destClient.CoreV1().Secrets(destOptions.Namespace).Create(context.Background(), secret, metav1.CreateOptions{})
+	require.NoError(t, err)
+	helpers.Cleanup(t, noCleanupOnFailure, func() {
+		_ = destClient.CoreV1().Secrets(destOptions.Namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	})
+
+	return creds
+}
+
+// createTokenReviewerServiceAccount creates a service account, a cluster role binding
+// to the system:auth-delegator cluster role, and a token secret for the service account,
+// and returns the credentials to talk to the API server as that service account.
+func createTokenReviewerServiceAccount(t *testing.T, client kubernetes.Interface, options *k8s.KubectlOptions, name string, noCleanupOnFailure bool) ClusterCredentials {
+	t.Helper()
+
+	namespace := options.Namespace
+
+	logger.Logf(t, "creating service account %s/%s", namespace, name)
+	_, err := client.CoreV1().ServiceAccounts(namespace).Create(context.Background(), &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	_, err = client.RbacV1().ClusterRoleBindings().Create(context.Background(), &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      name,
+				Namespace: namespace,
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     "system:auth-delegator",
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	// Create the token secret explicitly because newer Kubernetes versions
+	// don't create it automatically for service accounts.
+	_, err = client.CoreV1().Secrets(namespace).Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name + "-token",
+			Annotations: map[string]string{corev1.ServiceAccountNameKey: name},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	helpers.Cleanup(t, noCleanupOnFailure, func() {
+		_ = client.CoreV1().Secrets(namespace).Delete(context.Background(), name+"-token", metav1.DeleteOptions{})
+		_ = client.RbacV1().ClusterRoleBindings().Delete(context.Background(), name, metav1.DeleteOptions{})
+		_ = client.CoreV1().ServiceAccounts(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	})
+
+	// Wait for the token controller to populate the token.
+	var token []byte
+	retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: 1 * time.Second}, t, func(r *retry.R) {
+		secret, err := client.CoreV1().Secrets(namespace).Get(context.Background(), name+"-token", metav1.GetOptions{})
+		require.NoError(r, err)
+		token = secret.Data[corev1.ServiceAccountTokenKey]
+		require.NotEmpty(r, token, "token for service account %s has not been populated yet", name)
+	})
+
+	configPath, err := options.GetConfigPath(t)
+	require.NoError(t, err)
+	restConfig, err := k8s.LoadApiClientConfigE(configPath, options.ContextName)
+	require.NoError(t, err)
+
+	caCert := restConfig.CAData
+	if len(caCert) == 0 && restConfig.CAFile != "" {
+		caCert, err = ioutil.ReadFile(restConfig.CAFile)
+		require.NoError(t, err)
+	}
+
+	return ClusterCredentials{
+		Host:   restConfig.Host,
+		CACert: caCert,
+		Token:  string(token),
+	}
+}
+
+// kubeconfigFromCredentials returns a kubeconfig with a single cluster, user and context
+// named name that uses creds to talk to the API server.
+func kubeconfigFromCredentials(creds ClusterCredentials, name string) ([]byte, error) {
+	if creds.Host == "" {
+		return nil, fmt.Errorf("API server host must not be empty")
+	}
+
+	config := clientcmdv1.Config{
+		APIVersion: "v1",
+		Kind:       "Config",
+		Clusters: []clientcmdv1.NamedCluster{
+			{
+				Name: name,
+				Cluster: clientcmdv1.Cluster{
+					Server:                   creds.Host,
+					CertificateAuthorityData: creds.CACert,
+				},
+			},
+		},
+		AuthInfos: []clientcmdv1.NamedAuthInfo{
+			{
+				Name:     name,
+				AuthInfo: clientcmdv1.AuthInfo{Token: creds.Token},
+			},
+		},
+		Contexts: []clientcmdv1.NamedContext{
+			{
+				Name: name,
+				Context: clientcmdv1.Context{
+					Cluster:  name,
+					AuthInfo: name,
+				},
+			},
+		},
+		CurrentContext: name,
+	}
+
+	return yaml.Marshal(config)
+}
@@ -0,0 +1,37 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	clientcmdv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestKubeconfigFromCredentials(t *testing.T) {
+	creds := ClusterCredentials{
+		Host:   "https://1.2.3.4:443",
+		CACert: []byte("ca-cert"),
+		Token:  "token",
+	}
+
+	kubeconfig, err := kubeconfigFromCredentials(creds, "primary")
+	require.NoError(t, err)
+
+	var config clientcmdv1.Config
+	require.NoError(t, yaml.Unmarshal(kubeconfig, &config))
+	require.Equal(t, "primary", config.CurrentContext)
+	require.Len(t, config.Clusters, 1)
+	require.Equal(t, creds.Host, config.Clusters[0].Cluster.Server)
+	require.Equal(t, creds.CACert, config.Clusters[0].Cluster.CertificateAuthorityData)
+	require.Len(t, config.AuthInfos, 1)
+	require.Equal(t, creds.Token, config.AuthInfos[0].AuthInfo.Token)
+	require.Len(t, config.Contexts, 1)
+	require.Equal(t, "primary", config.Contexts[0].Context.Cluster)
+	require.Equal(t, "primary", config.Contexts[0].Context.AuthInfo)
+}
+
+func TestKubeconfigFromCredentials_EmptyHost(t *testing.T) {
+	_, err := kubeconfigFromCredentials(ClusterCredentials{}, "primary")
+	require.EqualError(t, err, "API server host must not be empty")
+}
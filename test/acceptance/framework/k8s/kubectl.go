@@ -1,6 +1,10 @@
 package k8s
 
 import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
 	"strings"
 	"testing"
 	"time"
@@ -24,6 +28,14 @@ var kubeAPIConnectErrs = []string{
 // we're re-implementing them because we don't want to use their default logger
 // as it logs everything regardless of verbosity level set via go test -v flags.
 
+// KubectlOptionsForNamespace returns a copy of options
+// with the namespace set to namespace.
+func KubectlOptionsForNamespace(options *k8s.KubectlOptions, namespace string) *k8s.KubectlOptions {
+	nsOptions := *options
+	nsOptions.Namespace = namespace
+	return &nsOptions
+}
+
 // RunKubectlAndGetOutputE runs an arbitrary kubectl command provided via args
 // and returns its output and error.
 func RunKubectlAndGetOutputE(t *testing.T, options *k8s.KubectlOptions, args ...string) (string, error) {
@@ -33,24 +45,14 @@ func RunKubectlAndGetOutputE(t *testing.T, options *k8s.KubectlOptions, args ...
 // RunKubectlAndGetOutputWithLoggerE is the same as RunKubectlAndGetOutputE but
 // it also allows you to provide a custom logger. This is useful if the command output
 // contains sensitive information, for example, when you can pass logger.Discard.
-func RunKubectlAndGetOutputWithLoggerE(t *testing.T, options *k8s.KubectlOptions, logger *terratestLogger.Logger, args ...string) (string, error) {
-	var cmdArgs []string
-	if options.ContextName != "" {
-		cmdArgs = append(cmdArgs, "--context", options.ContextName)
-	}
-	if options.ConfigPath != "" {
-		cmdArgs = append(cmdArgs, "--kubeconfig", options.ConfigPath)
-	}
-	if options.Namespace != "" && !sliceContains(args, "-n") && !sliceContains(args, "--namespace") {
-		cmdArgs = append(cmdArgs, "--namespace", options.Namespace)
-	}
-	cmdArgs = append(cmdArgs, args...)
+func RunKubectlAndGetOutputWithLoggerE(t *testing.T, options *k8s.KubectlOptions, cmdLogger *terratestLogger.Logger, args ...string) (string, error) {
 	command := shell.Command{
 		Command: "kubectl",
-		Args:    cmdArgs,
+		Args:    kubectlArgs(options, args),
 		Env:     options.Env,
-		Logger:  logger,
+		Logger:  cmdLogger,
 	}
+	start := time.Now()
 
 	counter := &retry.Counter{
 		Count: 3,
@@ -71,9 +73,73 @@ func RunKubectlAndGetOutputWithLoggerE(t *testing.T, options *k8s.KubectlOptions
 			}
 		}
 	})
+	if cmdLogger != terratestLogger.Discard {
+		logger.LogCommand(t, strings.Join(append([]string{command.Command}, command.Args...), " "), time.Since(start))
+	}
 	return output, err
 }
 
+// RunKubectlAndGetStdoutStderrE runs an arbitrary kubectl command provided via args
+// and returns its stdout and stderr separately. Neither the command nor its output
+// are logged, so it's safe to use with commands that contain sensitive information.
+func RunKubectlAndGetStdoutStderrE(t *testing.T, options *k8s.KubectlOptions, args ...string) (string, string, error) {
+	return RunKubectlWithInputAndGetStdoutStderrE(t, options, nil, args...)
+}
+
+// RunKubectlWithInputAndGetStdoutStderrE is the same as RunKubectlAndGetStdoutStderrE
+// but it also passes input to the standard input of kubectl, e.g. to copy a file
+// into a pod with kubectl exec -i.
+func RunKubectlWithInputAndGetStdoutStderrE(t *testing.T, options *k8s.KubectlOptions, input []byte, args ...string) (string, string, error) {
+	counter := &retry.Counter{
+		Count: 3,
+		Wait:  1 * time.Second,
+	}
+	var stdout, stderr bytes.Buffer
+	var err error
+	retry.RunWith(counter, t, func(r *retry.R) {
+		stdout.Reset()
+		stderr.Reset()
+		cmd := exec.Command("kubectl", kubectlArgs(options, args)...)
+		cmd.Env = os.Environ()
+		for key, value := range options.Env {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+		}
+		if input != nil {
+			cmd.Stdin = bytes.NewReader(input)
+		}
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err = cmd.Run()
+		if err != nil {
+			// Want to retry on errors connecting to actual Kube API because
+			// these are intermittent.
+			for _, connectionErr := range kubeAPIConnectErrs {
+				if strings.Contains(stderr.String(), connectionErr) {
+					r.Errorf(stderr.String())
+					return
+				}
+			}
+		}
+	})
+	return stdout.String(), stderr.String(), err
+}
+
+// kubectlArgs returns the arguments to pass to kubectl to run
+// the command in args with options.
+func kubectlArgs(options *k8s.KubectlOptions, args []string) []string {
+	var cmdArgs []string
+	if options.ContextName != "" {
+		cmdArgs = append(cmdArgs, "--context", options.ContextName)
+	}
+	if options.ConfigPath != "" {
+		cmdArgs = append(cmdArgs, "--kubeconfig", options.ConfigPath)
+	}
+	if options.Namespace != "" && !sliceContains(args, "-n") && !sliceContains(args, "--namespace") {
+		cmdArgs = append(cmdArgs, "--namespace", options.Namespace)
+	}
+	return append(cmdArgs, args...)
+}
+
 // KubectlApply takes a path to a Kubernetes YAML file and
 // applies it to the cluster by running 'kubectl apply -f'.
 // If there's an error applying the file, fail the test.
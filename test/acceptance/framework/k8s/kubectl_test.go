@@ -0,0 +1,26 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKubectlOptionsForNamespace(t *testing.T) {
+	options := &k8s.KubectlOptions{
+		ContextName: "kind-dc1",
+		ConfigPath:  "/tmp/kubeconfig",
+		Namespace:   "default",
+	}
+
+	nsOptions := KubectlOptionsForNamespace(options, "ns1")
+	require.Equal(t, &k8s.KubectlOptions{
+		ContextName: "kind-dc1",
+		ConfigPath:  "/tmp/kubeconfig",
+		Namespace:   "ns1",
+	}, nsOptions)
+
+	// The original options are unchanged.
+	require.Equal(t, "default", options.Namespace)
+}
@@ -0,0 +1,31 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodLogs returns the logs of all containers of pods matching the labelSelector
+// keyed by pod name. Logs are not printed to the test output because they
+// could contain sensitive information.
+func PodLogs(t *testing.T, kubectlOptions *k8s.KubectlOptions, labelSelector string) map[string]string {
+	t.Helper()
+
+	client := helpers.KubernetesClientFromOptions(t, kubectlOptions)
+	pods, err := client.CoreV1().Pods(kubectlOptions.Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: labelSelector})
+	require.NoError(t, err)
+
+	logs := make(map[string]string)
+	for _, pod := range pods.Items {
+		output, err := RunKubectlAndGetOutputWithLoggerE(t, kubectlOptions, terratestLogger.Discard, "logs", "--all-containers=true", pod.Name)
+		require.NoError(t, err)
+		logs[pod.Name] = output
+	}
+	return logs
+}
@@ -0,0 +1,260 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// logStreamInterval is how often StreamPodLogs looks for
+// containers that have started since it last looked.
+const logStreamInterval = 2 * time.Second
+
+// StreamPodLogs streams the logs of every container of the pods matching labelSelector
+// into <pod>-<container>.log files in the logs directory of the test's debug directory
+// until the test finishes, including pods and containers that start later. When a container
+// restarts, the logs of the new instance are appended to the same file, so the logs of a
+// crash loop are kept even if the container has recovered by the time the test fails,
+// unlike the logs that WritePodsDebugInfoIfFailed captures.
+// The streams can be stopped earlier with LogStream.Stop, e.g. before the pods are
+// deleted and recreated, so that the logs of a later stream aren't interleaved with them.
+// The files, and the directories of the test that are left empty, are removed
+// when the test finishes if it has passed.
+func StreamPodLogs(t *testing.T, options *k8s.KubectlOptions, debugDirectory, labelSelector string) *LogStream {
+	t.Helper()
+
+	contextName := helpers.KubernetesContextFromOptions(t, options)
+	dir := filepath.Join(debugDirectory, t.Name(), contextName, "logs")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	streamer := newLogStreamer(helpers.KubernetesClientFromOptions(t, options), options.Namespace, labelSelector, debugDirectory, dir)
+	go streamer.run()
+
+	// This doesn't use helpers.Cleanup because the streams
+	// have to be stopped even if the test is left running.
+	t.Cleanup(func() {
+		streamer.stop()
+		if !t.Failed() {
+			streamer.removeFiles()
+		}
+	})
+	return &LogStream{streamer: streamer}
+}
+
+// LogStream is a stream of the logs of pods started by StreamPodLogs.
+type LogStream struct {
+	streamer *logStreamer
+}
+
+// Stop stops streaming and waits for the streams to finish. The files are kept
+// until the test finishes. Stopping a stream more than once is a no-op.
+func (l *LogStream) Stop() {
+	l.streamer.stop()
+}
+
+// containerInstance identifies a run of a container, which
+// changes every time the container restarts.
+type containerInstance struct {
+	podUID       types.UID
+	container    string
+	restartCount int32
+}
+
+// logStreamer streams the logs of containers to files in dir.
+type logStreamer struct {
+	client    kubernetes.Interface
+	namespace string
+	selector  string
+	// root is the debug directory that dir is in.
+	root string
+	dir  string
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	streams  sync.WaitGroup
+	stopOnce sync.Once
+
+	// lock protects streamed, files, and errorLines.
+	lock sync.Mutex
+	// streamed are the container instances whose logs have been streamed.
+	streamed map[containerInstance]bool
+	// files are the files that logs have been written to.
+	files map[string]bool
+	// errorLines are the lines that have been logged at the ERROR level.
+	errorLines []errorLogLine
+}
+
+func newLogStreamer(client kubernetes.Interface, namespace, selector, root, dir string) *logStreamer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &logStreamer{
+		client:    client,
+		namespace: namespace,
+		selector:  selector,
+		root:      filepath.Clean(root),
+		dir:       dir,
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		streamed:  make(map[containerInstance]bool),
+		files:     make(map[string]bool),
+	}
+}
+
+// run starts streaming the logs of new container instances
+// every logStreamInterval until the streamer is stopped.
+func (s *logStreamer) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(logStreamInterval)
+	defer ticker.Stop()
+	for {
+		s.poll()
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll starts streaming the logs of the container instances that have started
+// since the last poll. Errors listing the pods are ignored because the next poll retries.
+func (s *logStreamer) poll() {
+	pods, err := s.client.CoreV1().Pods(s.namespace).List(s.ctx, metav1.ListOptions{LabelSelector: s.selector})
+	if err != nil {
+		return
+	}
+
+	for _, pod := range pods.Items {
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			// Containers that are waiting to start don't have logs yet.
+			if status.State.Running == nil && status.State.Terminated == nil {
+				continue
+			}
+			instance := containerInstance{podUID: pod.UID, container: status.Name, restartCount: status.RestartCount}
+
+			s.lock.Lock()
+			if s.streamed[instance] {
+				s.lock.Unlock()
+				continue
+			}
+			s.streamed[instance] = true
+			s.lock.Unlock()
+
+			s.streams.Add(1)
+			go func(podName string, podLabels map[string]string, instance containerInstance) {
+				defer s.streams.Done()
+				s.stream(podName, podLabels, instance)
+			}(pod.Name, pod.Labels, instance)
+		}
+	}
+}
+
+// stream appends the logs of the container instance to its file until
+// the container exits or the streamer is stopped, and records the lines
+// that it logs at the ERROR level for LogStream.ErrorLogs.
+func (s *logStreamer) stream(podName string, podLabels map[string]string, instance containerInstance) {
+	path := filepath.Join(s.dir, fmt.Sprintf("%s-%s.log", podName, instance.container))
+	s.lock.Lock()
+	s.files[path] = true
+	s.lock.Unlock()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	if instance.restartCount > 0 {
+		fmt.Fprintf(f, "--- restart %d ---\n", instance.restartCount)
+	}
+
+	// The kubelet prefixes each line with the time it was logged, which
+	// ErrorLogs filters by. The prefix is stripped from the files.
+	logs, err := s.client.CoreV1().Pods(s.namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Container:  instance.container,
+		Follow:     true,
+		Timestamps: true,
+	}).Stream(s.ctx)
+	if err != nil {
+		fmt.Fprintf(f, "Error streaming logs: %s\n", err)
+		return
+	}
+	defer logs.Close()
+
+	reader := bufio.NewReader(logs)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			logged, text := splitLogTimestamp(line)
+			f.WriteString(text)
+			if errorLogPattern.MatchString(text) {
+				s.lock.Lock()
+				s.errorLines = append(s.errorLines, errorLogLine{
+					time:      logged,
+					pod:       podName,
+					labels:    podLabels,
+					container: instance.container,
+					line:      strings.TrimSuffix(text, "\n"),
+				})
+				s.lock.Unlock()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// splitLogTimestamp splits the timestamp that the kubelet prefixes a log line with
+// from the line. Lines without a timestamp are returned as is with the current time.
+func splitLogTimestamp(line string) (time.Time, string) {
+	if i := strings.IndexByte(line, ' '); i > 0 {
+		if logged, err := time.Parse(time.RFC3339Nano, line[:i]); err == nil {
+			return logged, line[i+1:]
+		}
+	}
+	return time.Now(), line
+}
+
+// stop stops streaming and waits for the streams to finish.
+func (s *logStreamer) stop() {
+	s.stopOnce.Do(func() {
+		s.cancel()
+		<-s.done
+		s.streams.Wait()
+	})
+}
+
+// removeFiles removes the files that logs have been written to, and the directories
+// between them and the debug directory that are left empty.
+func (s *logStreamer) removeFiles() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for path := range s.files {
+		os.Remove(path)
+	}
+	// Other streamers and the debug info of the test can share the directories,
+	// so they're only removed once they're empty, which os.Remove checks.
+	for dir := s.dir; dir != s.root && strings.HasPrefix(dir, s.root); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
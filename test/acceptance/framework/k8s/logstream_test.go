@@ -0,0 +1,102 @@
+package k8s
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// The fake clientset returns "fake logs" as the logs of every container.
+func TestLogStreamer(t *testing.T) {
+	cases := map[string]struct {
+		failed        bool
+		expectedFiles bool
+	}{
+		"test failed": {failed: true, expectedFiles: true},
+		"test passed": {failed: false, expectedFiles: false},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "logstream")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+			logsDir := filepath.Join(dir, "TestA", "kind", "logs")
+			require.NoError(t, os.MkdirAll(logsDir, 0755))
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "server-0", Namespace: "default", UID: "uid", Labels: map[string]string{"app": "consul"}},
+				Status: corev1.PodStatus{
+					InitContainerStatuses: []corev1.ContainerStatus{
+						{Name: "init", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}},
+					},
+					ContainerStatuses: []corev1.ContainerStatus{
+						{Name: "consul", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+						{Name: "sidecar", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{}}},
+					},
+				},
+			}
+			otherPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default", UID: "other-uid"},
+				Status: corev1.PodStatus{
+					ContainerStatuses: []corev1.ContainerStatus{
+						{Name: "other", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+					},
+				},
+			}
+			client := fake.NewSimpleClientset(pod, otherPod)
+
+			streamer := newLogStreamer(client, "default", "app=consul", dir, logsDir)
+			streamer.poll()
+			streamer.streams.Wait()
+
+			// Polling again doesn't stream the same container instances again.
+			streamer.poll()
+			streamer.streams.Wait()
+
+			// The consul container restarts.
+			pod.Status.ContainerStatuses[0].RestartCount = 1
+			_, err = client.CoreV1().Pods("default").UpdateStatus(context.Background(), pod, metav1.UpdateOptions{})
+			require.NoError(t, err)
+			streamer.poll()
+			streamer.streams.Wait()
+
+			requireFileContents(t, filepath.Join(logsDir, "server-0-init.log"), "fake logs")
+			requireFileContents(t, filepath.Join(logsDir, "server-0-consul.log"), "fake logs--- restart 1 ---\nfake logs")
+			require.NoFileExists(t, filepath.Join(logsDir, "server-0-sidecar.log"))
+			require.NoFileExists(t, filepath.Join(logsDir, "other-other.log"))
+
+			// run exits straight away because the streamer is already stopped.
+			streamer.cancel()
+			go streamer.run()
+			streamer.stop()
+			// Stopping again is a no-op.
+			streamer.stop()
+			if !c.failed {
+				streamer.removeFiles()
+			}
+
+			if c.expectedFiles {
+				require.FileExists(t, filepath.Join(logsDir, "server-0-consul.log"))
+			} else {
+				require.NoDirExists(t, filepath.Join(dir, "TestA"))
+				require.DirExists(t, dir)
+			}
+		})
+	}
+}
+
+func requireFileContents(t *testing.T, path, expected string) {
+	t.Helper()
+
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(contents))
+}
@@ -0,0 +1,295 @@
+package k8s
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/portallocator"
+)
+
+const (
+	// tunnelAttempts is how many times a port-forward to a pod is attempted
+	// before a connection through a PortForwarder fails.
+	tunnelAttempts = 5
+	// tunnelRetryWait is how long to wait between attempts to create a port-forward.
+	tunnelRetryWait = 2 * time.Second
+	// tunnelDialTimeout bounds how long it takes to connect to a port-forward.
+	tunnelDialTimeout = 5 * time.Second
+)
+
+// PortForwarder manages port-forwards to pods for tests.
+// Each forward listens on a stable local address and proxies every connection
+// through a port-forward to the pod. If the port-forward dies, for example because
+// the pod was restarted or the connection to the API server broke, it is recreated
+// on the next connection, so clients that hold on to the local address keep working
+// for the whole test. Forwards are reused within a test and closed when the test finishes.
+type PortForwarder struct {
+	options *k8s.KubectlOptions
+	logger  terratestLogger.TestLogger
+
+	forwards     map[portForwardKey]*portForward
+	forwardsLock sync.Mutex
+}
+
+// portForwardKey identifies a forward of a PortForwarder.
+type portForwardKey struct {
+	testName   string
+	pod        string
+	remotePort int
+}
+
+// NewPortForwarder returns a PortForwarder for pods in the namespace of options.
+// The logger is used for the port-forwards, so pass terratestLogger.Discard
+// to keep them out of the test logs.
+func NewPortForwarder(options *k8s.KubectlOptions, logger terratestLogger.TestLogger) *PortForwarder {
+	return &PortForwarder{
+		options:  options,
+		logger:   logger,
+		forwards: make(map[portForwardKey]*portForward),
+	}
+}
+
+// Forward returns a local address, e.g. 127.0.0.1:51234, that forwards to
+// remotePort of pod until t finishes. Calls from the same test for the same
+// pod and port return the same address.
+func (p *PortForwarder) Forward(t *testing.T, pod string, remotePort int) string {
+	t.Helper()
+
+	key := portForwardKey{testName: t.Name(), pod: pod, remotePort: remotePort}
+
+	p.forwardsLock.Lock()
+	defer p.forwardsLock.Unlock()
+
+	if forward, ok := p.forwards[key]; ok {
+		return forward.addr()
+	}
+
+	forward, err := newPortForward(func() (tunnel, error) {
+		return p.openTunnel(t, pod, remotePort)
+	})
+	if err != nil {
+		t.Fatalf("forwarding to port %d of pod %s: %s", remotePort, pod, err)
+	}
+	p.forwards[key] = forward
+
+	t.Cleanup(func() {
+		p.forwardsLock.Lock()
+		defer p.forwardsLock.Unlock()
+		delete(p.forwards, key)
+		forward.close()
+	})
+
+	return forward.addr()
+}
+
+// openTunnel creates a port-forward to remotePort of pod on a local port
+// from the port allocator, which is released when the port-forward is closed.
+func (p *PortForwarder) openTunnel(t *testing.T, pod string, remotePort int) (tunnel, error) {
+	localPort, err := portallocator.AllocateE()
+	if err != nil {
+		return nil, err
+	}
+	tun := k8s.NewTunnelWithLogger(p.options, k8s.ResourceTypePod, pod, localPort, remotePort, p.logger)
+	// It's okay to pass t to ForwardPortE since it only uses it for logging.
+	if err := tun.ForwardPortE(t); err != nil {
+		portallocator.Release(localPort)
+		return nil, err
+	}
+	return allocatedTunnel{Tunnel: tun, localPort: localPort}, nil
+}
+
+// allocatedTunnel is a port-forward on a port from the port allocator.
+type allocatedTunnel struct {
+	*k8s.Tunnel
+	localPort int
+}
+
+// Close closes the port-forward and releases its local port.
+func (a allocatedTunnel) Close() {
+	a.Tunnel.Close()
+	portallocator.Release(a.localPort)
+}
+
+// tunnel is a single port-forward. It's implemented by *k8s.Tunnel.
+type tunnel interface {
+	// Endpoint returns the local address of the port-forward.
+	Endpoint() string
+	Close()
+}
+
+// portForward listens on a local address and proxies connections
+// to the current tunnel, recreating it when it has died.
+type portForward struct {
+	listener   net.Listener
+	openTunnel func() (tunnel, error)
+
+	// lock protects tunnel, closed, and conns.
+	lock   sync.Mutex
+	tunnel tunnel
+	closed bool
+	// conns are the open connections so that they can be closed with the forward.
+	conns map[net.Conn]struct{}
+}
+
+// newPortForward opens a tunnel with openTunnel and starts proxying
+// connections on a free local port to it.
+func newPortForward(openTunnel func() (tunnel, error)) (*portForward, error) {
+	tun, err := openTunnel()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tun.Close()
+		return nil, err
+	}
+
+	forward := &portForward{
+		listener:   listener,
+		openTunnel: openTunnel,
+		tunnel:     tun,
+		conns:      make(map[net.Conn]struct{}),
+	}
+	go forward.serve()
+	return forward, nil
+}
+
+// addr returns the local address of the forward.
+func (f *portForward) addr() string {
+	return f.listener.Addr().String()
+}
+
+// serve accepts connections until the forward is closed.
+func (f *portForward) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.proxy(conn)
+	}
+}
+
+// proxy copies data between conn and a connection through the tunnel.
+func (f *portForward) proxy(conn net.Conn) {
+	defer conn.Close()
+
+	upstream, err := f.dial()
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	if !f.track(conn, upstream) {
+		return
+	}
+	defer f.untrack(conn, upstream)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, upstream)
+		done <- struct{}{}
+	}()
+	// Closing both connections once either direction is done
+	// unblocks the other copy.
+	<-done
+}
+
+// dial connects to the current tunnel. If that fails, the tunnel has died,
+// so it is replaced with a new one, retrying a few times in case the pod is restarting.
+func (f *portForward) dial() (net.Conn, error) {
+	var lastErr error
+	for attempt := 0; attempt < tunnelAttempts; attempt++ {
+		f.lock.Lock()
+		if f.closed {
+			f.lock.Unlock()
+			return nil, fmt.Errorf("port-forward is closed")
+		}
+		tun := f.tunnel
+		f.lock.Unlock()
+
+		if tun != nil {
+			conn, err := net.DialTimeout("tcp", tun.Endpoint(), tunnelDialTimeout)
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+
+		if err := f.reconnect(tun); err != nil {
+			lastErr = err
+			time.Sleep(tunnelRetryWait)
+		}
+	}
+	return nil, lastErr
+}
+
+// reconnect replaces the tunnel broken with a new one, unless another
+// connection has already replaced it.
+func (f *portForward) reconnect(broken tunnel) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed || f.tunnel != broken {
+		return nil
+	}
+	if f.tunnel != nil {
+		f.tunnel.Close()
+		f.tunnel = nil
+	}
+	tun, err := f.openTunnel()
+	if err != nil {
+		return err
+	}
+	f.tunnel = tun
+	return nil
+}
+
+// track records the open connections so that close can close them.
+// It returns false if the forward has been closed in the meantime.
+func (f *portForward) track(conns ...net.Conn) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.closed {
+		return false
+	}
+	for _, conn := range conns {
+		f.conns[conn] = struct{}{}
+	}
+	return true
+}
+
+func (f *portForward) untrack(conns ...net.Conn) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+		delete(f.conns, conn)
+	}
+}
+
+// close stops the forward, its open connections, and the tunnel.
+func (f *portForward) close() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.closed = true
+	f.listener.Close()
+	for conn := range f.conns {
+		conn.Close()
+	}
+	if f.tunnel != nil {
+		f.tunnel.Close()
+		f.tunnel = nil
+	}
+}
@@ -0,0 +1,131 @@
+package k8s
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTunnel stands in for a port-forward. Each fake tunnel is
+// an echo server that prefixes replies with its id.
+type fakeTunnel struct {
+	id       int
+	listener net.Listener
+}
+
+func newFakeTunnel(t *testing.T, id int) *fakeTunnel {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					fmt.Fprintf(conn, "%d: %s\n", id, scanner.Text())
+				}
+			}()
+		}
+	}()
+	return &fakeTunnel{id: id, listener: listener}
+}
+
+func (f *fakeTunnel) Endpoint() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeTunnel) Close() {
+	f.listener.Close()
+}
+
+// fakeTunnels opens fake tunnels and records them.
+type fakeTunnels struct {
+	t       *testing.T
+	lock    sync.Mutex
+	tunnels []*fakeTunnel
+	failing bool
+}
+
+func (f *fakeTunnels) open() (tunnel, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.failing {
+		return nil, errors.New("pod is not running")
+	}
+	tun := newFakeTunnel(f.t, len(f.tunnels))
+	f.tunnels = append(f.tunnels, tun)
+	return tun, nil
+}
+
+func (f *fakeTunnels) count() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.tunnels)
+}
+
+func TestPortForward_Reconnects(t *testing.T) {
+	tunnels := &fakeTunnels{t: t}
+	forward, err := newPortForward(tunnels.open)
+	require.NoError(t, err)
+	defer forward.close()
+
+	require.Equal(t, "0: hello", roundTrip(t, forward.addr(), "hello"))
+	require.Equal(t, "0: again", roundTrip(t, forward.addr(), "again"))
+	require.Equal(t, 1, tunnels.count())
+
+	// Kill the tunnel. The next connection uses a new one on the same local address.
+	tunnels.tunnels[0].Close()
+	require.Equal(t, "1: hello", roundTrip(t, forward.addr(), "hello"))
+	require.Equal(t, 2, tunnels.count())
+}
+
+func TestPortForward_Close(t *testing.T) {
+	tunnels := &fakeTunnels{t: t}
+	forward, err := newPortForward(tunnels.open)
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", forward.addr())
+	require.NoError(t, err)
+	defer conn.Close()
+	fmt.Fprintln(conn, "hello")
+	reader := bufio.NewReader(conn)
+	_, err = reader.ReadString('\n')
+	require.NoError(t, err)
+
+	forward.close()
+
+	// Open connections are closed and no new connections are accepted.
+	_, err = reader.ReadString('\n')
+	require.Error(t, err)
+	_, err = net.Dial("tcp", forward.addr())
+	require.Error(t, err)
+}
+
+func TestNewPortForward_Error(t *testing.T) {
+	tunnels := &fakeTunnels{t: t, failing: true}
+	_, err := newPortForward(tunnels.open)
+	require.EqualError(t, err, "pod is not running")
+}
+
+// roundTrip sends line to addr and returns the reply.
+func roundTrip(t *testing.T, addr, line string) string {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	fmt.Fprintln(conn, line)
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	return reply[:len(reply)-1]
+}
@@ -0,0 +1,68 @@
+package k8s
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+)
+
+// connectionProbeInterval is the time between the requests of a ConnectionProbe.
+const connectionProbeInterval = 500 * time.Millisecond
+
+// ConnectionProbe continuously sends requests from a deployment
+// until it's stopped and records the ones that fail, so that tests
+// can assert that traffic isn't interrupted while they change the cluster.
+type ConnectionProbe struct {
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+
+	// requests is the number of requests sent so far.
+	requests int
+	// failures are the errors of the requests that failed.
+	failures []string
+}
+
+// StartConnectionProbe starts sending requests to url with curl from the container
+// of the deployment deploymentName with the same name, e.g. from the static-client
+// to the static-server, until Stop is called. The probe is stopped when t finishes
+// if the test doesn't stop it first, e.g. because it failed.
+func StartConnectionProbe(t *testing.T, options *k8s.KubectlOptions, deploymentName, url string) *ConnectionProbe {
+	p := &ConnectionProbe{stop: make(chan struct{})}
+	t.Cleanup(func() {
+		p.Stop()
+	})
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			select {
+			case <-p.stop:
+				return
+			default:
+			}
+			out, err := RunKubectlAndGetOutputE(t, options, "exec", "deploy/"+deploymentName, "-c", deploymentName,
+				"--", "curl", "-sSf", "--max-time", "10", url)
+			if err != nil {
+				p.failures = append(p.failures, fmt.Sprintf("%s %s: %s", time.Now().Format(time.RFC3339), err, out))
+			}
+			p.requests++
+			time.Sleep(connectionProbeInterval)
+		}
+	}()
+	return p
+}
+
+// Stop stops the probe and returns the number of requests
+// it sent and the errors of the ones that failed.
+// Stopping a probe more than once is a no-op.
+func (p *ConnectionProbe) Stop() (requests int, failures []string) {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+		p.wg.Wait()
+	})
+	return p.requests, p.failures
+}
@@ -0,0 +1,177 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// WaitForRolloutsToComplete waits until the rollouts of all stateful sets, daemon sets
+// and deployments matching labelSelector are complete, i.e. all of their pods have been
+// updated to the latest spec and are available. Unlike waiting for pods to be ready,
+// this doesn't succeed while the old pods of a rollout that hasn't started yet are still ready.
+func WaitForRolloutsToComplete(t *testing.T, client kubernetes.Interface, namespace, labelSelector string) {
+	t.Helper()
+
+	logger.Logf(t, "waiting for rollouts of %s to complete", labelSelector)
+
+	listOptions := metav1.ListOptions{LabelSelector: labelSelector}
+	retry.RunWith(&retry.Timer{Timeout: 15 * time.Minute, Wait: 5 * time.Second}, t, func(r *retry.R) {
+		var incomplete []string
+
+		statefulSets, err := client.AppsV1().StatefulSets(namespace).List(context.Background(), listOptions)
+		require.NoError(r, err)
+		for _, statefulSet := range statefulSets.Items {
+			if err := statefulSetRolloutComplete(statefulSet); err != nil {
+				incomplete = append(incomplete, fmt.Sprintf("statefulset/%s: %s", statefulSet.Name, err))
+			}
+		}
+
+		daemonSets, err := client.AppsV1().DaemonSets(namespace).List(context.Background(), listOptions)
+		require.NoError(r, err)
+		for _, daemonSet := range daemonSets.Items {
+			if err := daemonSetRolloutComplete(daemonSet); err != nil {
+				incomplete = append(incomplete, fmt.Sprintf("daemonset/%s: %s", daemonSet.Name, err))
+			}
+		}
+
+		deployments, err := client.AppsV1().Deployments(namespace).List(context.Background(), listOptions)
+		require.NoError(r, err)
+		for _, deployment := range deployments.Items {
+			if err := deploymentRolloutComplete(deployment); err != nil {
+				incomplete = append(incomplete, fmt.Sprintf("deployment/%s: %s", deployment.Name, err))
+			}
+		}
+
+		if len(incomplete) > 0 {
+			r.Errorf("%d rollouts are not complete: %s", len(incomplete), strings.Join(incomplete, "; "))
+		}
+	})
+}
+
+// statefulSetRolloutComplete returns an error if the rollout of the stateful set
+// is not complete. It follows the same logic as `kubectl rollout status`:
+// when a partition is set, only the pods with an ordinal greater than
+// or equal to the partition are expected to be updated.
+func statefulSetRolloutComplete(statefulSet appsv1.StatefulSet) error {
+	if statefulSet.Status.ObservedGeneration < statefulSet.Generation {
+		return fmt.Errorf("update not observed yet")
+	}
+
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	if statefulSet.Status.ReadyReplicas < replicas {
+		return fmt.Errorf("%d of %d pods are ready", statefulSet.Status.ReadyReplicas, replicas)
+	}
+
+	// Pods of stateful sets with the OnDelete strategy are only updated
+	// when they are deleted, so there's no rollout to wait for.
+	if statefulSet.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return nil
+	}
+
+	if rollingUpdate := statefulSet.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil && rollingUpdate.Partition != nil && *rollingUpdate.Partition > 0 {
+		expectedUpdated := replicas - *rollingUpdate.Partition
+		if statefulSet.Status.UpdatedReplicas < expectedUpdated {
+			return fmt.Errorf("%d of %d pods are updated", statefulSet.Status.UpdatedReplicas, expectedUpdated)
+		}
+		return nil
+	}
+
+	if statefulSet.Status.UpdateRevision != statefulSet.Status.CurrentRevision {
+		return fmt.Errorf("%d of %d pods are updated", statefulSet.Status.UpdatedReplicas, replicas)
+	}
+	return nil
+}
+
+// daemonSetRolloutComplete returns an error if the rollout of the daemon set is not complete.
+func daemonSetRolloutComplete(daemonSet appsv1.DaemonSet) error {
+	if daemonSet.Status.ObservedGeneration < daemonSet.Generation {
+		return fmt.Errorf("update not observed yet")
+	}
+
+	desired := daemonSet.Status.DesiredNumberScheduled
+	if daemonSet.Spec.UpdateStrategy.Type == appsv1.RollingUpdateDaemonSetStrategyType && daemonSet.Status.UpdatedNumberScheduled < desired {
+		return fmt.Errorf("%d of %d pods are updated", daemonSet.Status.UpdatedNumberScheduled, desired)
+	}
+	if daemonSet.Status.NumberAvailable < desired {
+		return fmt.Errorf("%d of %d pods are available", daemonSet.Status.NumberAvailable, desired)
+	}
+	return nil
+}
+
+// deploymentRolloutComplete returns an error if the rollout of the deployment is not complete.
+func deploymentRolloutComplete(deployment appsv1.Deployment) error {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return fmt.Errorf("update not observed yet")
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	if deployment.Status.UpdatedReplicas < replicas {
+		return fmt.Errorf("%d of %d pods are updated", deployment.Status.UpdatedReplicas, replicas)
+	}
+	// Old pods are still terminating.
+	if deployment.Status.Replicas > deployment.Status.UpdatedReplicas {
+		return fmt.Errorf("%d old pods are pending termination", deployment.Status.Replicas-deployment.Status.UpdatedReplicas)
+	}
+	if deployment.Status.AvailableReplicas < replicas {
+		return fmt.Errorf("%d of %d pods are available", deployment.Status.AvailableReplicas, replicas)
+	}
+	return nil
+}
+
+// DeleteStuckStatefulSetPods deletes the pods of the stateful sets matching labelSelector
+// that are not ready and don't run the latest revision of their stateful set.
+// A stateful set doesn't replace such pods on its own when it is rolled back
+// from a broken revision, e.g. one with a bad image tag.
+// See https://kubernetes.io/docs/concepts/workloads/controllers/statefulset/#forced-rollback.
+func DeleteStuckStatefulSetPods(t *testing.T, client kubernetes.Interface, namespace, labelSelector string) {
+	t.Helper()
+
+	statefulSets, err := client.AppsV1().StatefulSets(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: labelSelector})
+	require.NoError(t, err)
+
+	for _, statefulSet := range statefulSets.Items {
+		// Wait for the stateful set controller to observe the latest spec so that
+		// the update revision in the status is the one we are rolling out.
+		var updateRevision string
+		retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: 1 * time.Second}, t, func(r *retry.R) {
+			current, err := client.AppsV1().StatefulSets(namespace).Get(context.Background(), statefulSet.Name, metav1.GetOptions{})
+			require.NoError(r, err)
+			require.GreaterOrEqual(r, current.Status.ObservedGeneration, current.Generation)
+			updateRevision = current.Status.UpdateRevision
+		})
+
+		selector, err := metav1.LabelSelectorAsSelector(statefulSet.Spec.Selector)
+		require.NoError(t, err)
+		pods, err := client.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: selector.String()})
+		require.NoError(t, err)
+
+		for _, pod := range pods.Items {
+			if pod.Labels[appsv1.StatefulSetRevisionLabel] == updateRevision || helpers.IsReady(pod) {
+				continue
+			}
+			logger.Logf(t, "deleting pod %s because it is stuck on a previous revision of statefulset %s", pod.Name, statefulSet.Name)
+			err := client.CoreV1().Pods(namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+			if !errors.IsNotFound(err) {
+				require.NoError(t, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStatefulSetRolloutComplete(t *testing.T) {
+	three := int32(3)
+	one := int32(1)
+
+	rollingUpdate := func(partition *int32) appsv1.StatefulSetUpdateStrategy {
+		return appsv1.StatefulSetUpdateStrategy{
+			Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: partition},
+		}
+	}
+
+	cases := map[string]struct {
+		spec     appsv1.StatefulSetSpec
+		status   appsv1.StatefulSetStatus
+		complete bool
+	}{
+		"update not observed": {
+			spec:   appsv1.StatefulSetSpec{Replicas: &three, UpdateStrategy: rollingUpdate(nil)},
+			status: appsv1.StatefulSetStatus{ObservedGeneration: 1, ReadyReplicas: 3, CurrentRevision: "a", UpdateRevision: "a"},
+		},
+		"pods not ready": {
+			spec:   appsv1.StatefulSetSpec{Replicas: &three, UpdateStrategy: rollingUpdate(nil)},
+			status: appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 2, CurrentRevision: "b", UpdateRevision: "b"},
+		},
+		"rollout in progress": {
+			spec:   appsv1.StatefulSetSpec{Replicas: &three, UpdateStrategy: rollingUpdate(nil)},
+			status: appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 3, UpdatedReplicas: 1, CurrentRevision: "a", UpdateRevision: "b"},
+		},
+		"rollout complete": {
+			spec:     appsv1.StatefulSetSpec{Replicas: &three, UpdateStrategy: rollingUpdate(nil)},
+			status:   appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 3, UpdatedReplicas: 3, CurrentRevision: "b", UpdateRevision: "b"},
+			complete: true,
+		},
+		"partitioned rollout in progress": {
+			spec:   appsv1.StatefulSetSpec{Replicas: &three, UpdateStrategy: rollingUpdate(&one)},
+			status: appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 3, UpdatedReplicas: 1, CurrentRevision: "a", UpdateRevision: "b"},
+		},
+		"partitioned rollout complete": {
+			spec:     appsv1.StatefulSetSpec{Replicas: &three, UpdateStrategy: rollingUpdate(&one)},
+			status:   appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 3, UpdatedReplicas: 2, CurrentRevision: "a", UpdateRevision: "b"},
+			complete: true,
+		},
+		"on delete": {
+			spec:     appsv1.StatefulSetSpec{Replicas: &three, UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType}},
+			status:   appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 3, CurrentRevision: "a", UpdateRevision: "b"},
+			complete: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			statefulSet := appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       c.spec,
+				Status:     c.status,
+			}
+			err := statefulSetRolloutComplete(statefulSet)
+			if c.complete {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestDaemonSetRolloutComplete(t *testing.T) {
+	cases := map[string]struct {
+		status   appsv1.DaemonSetStatus
+		complete bool
+	}{
+		"update not observed": {
+			status: appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3},
+		},
+		"rollout in progress": {
+			status: appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 2, NumberAvailable: 3},
+		},
+		"updated pods not available": {
+			status: appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 2},
+		},
+		"rollout complete": {
+			status:   appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3},
+			complete: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			daemonSet := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DaemonSetSpec{UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.RollingUpdateDaemonSetStrategyType}},
+				Status:     c.status,
+			}
+			err := daemonSetRolloutComplete(daemonSet)
+			if c.complete {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestDeploymentRolloutComplete(t *testing.T) {
+	two := int32(2)
+
+	cases := map[string]struct {
+		status   appsv1.DeploymentStatus
+		complete bool
+	}{
+		"update not observed": {
+			status: appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+		},
+		"rollout in progress": {
+			status: appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 2},
+		},
+		"old pods terminating": {
+			status: appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 2},
+		},
+		"rollout complete": {
+			status:   appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			complete: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			deployment := appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: &two},
+				Status:     c.status,
+			}
+			err := deploymentRolloutComplete(deployment)
+			if c.complete {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ServiceExternalAddress returns the host:port where port of the service
+// can be reached from outside the cluster. For LoadBalancer services, that's
+// the load balancer, which it waits to be provisioned. For NodePort services,
+// it's the node port on the external IP of a node, or the internal IP if
+// the nodes don't have external IPs, as is the case for kind clusters.
+func ServiceExternalAddress(t *testing.T, client kubernetes.Interface, namespace, name string, port int32) string {
+	t.Helper()
+
+	var address string
+	retry.RunWith(&retry.Timer{Timeout: 5 * time.Minute, Wait: 5 * time.Second}, t, func(r *retry.R) {
+		svc, err := client.CoreV1().Services(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		require.NoError(r, err)
+
+		var nodes []corev1.Node
+		if svc.Spec.Type == corev1.ServiceTypeNodePort {
+			nodeList, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+			require.NoError(r, err)
+			nodes = nodeList.Items
+		}
+
+		address, err = externalAddress(svc, nodes, port)
+		require.NoError(r, err)
+	})
+	logger.Logf(t, "port %d of service %s is reachable at %s", port, name, address)
+	return address
+}
+
+// externalAddress returns the address outside the cluster of port of svc,
+// using nodes for NodePort services.
+func externalAddress(svc *corev1.Service, nodes []corev1.Node, port int32) (string, error) {
+	var servicePort *corev1.ServicePort
+	for i := range svc.Spec.Ports {
+		if svc.Spec.Ports[i].Port == port {
+			servicePort = &svc.Spec.Ports[i]
+			break
+		}
+	}
+	if servicePort == nil {
+		return "", fmt.Errorf("service %s has no port %d", svc.Name, port)
+	}
+
+	switch svc.Spec.Type {
+	case corev1.ServiceTypeLoadBalancer:
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" {
+				return net.JoinHostPort(ingress.IP, strconv.Itoa(int(port))), nil
+			}
+			if ingress.Hostname != "" {
+				return net.JoinHostPort(ingress.Hostname, strconv.Itoa(int(port))), nil
+			}
+		}
+		return "", fmt.Errorf("load balancer of service %s is not provisioned yet", svc.Name)
+	case corev1.ServiceTypeNodePort:
+		if servicePort.NodePort == 0 {
+			return "", fmt.Errorf("port %d of service %s has no node port yet", port, svc.Name)
+		}
+		nodePort := strconv.Itoa(int(servicePort.NodePort))
+		for _, addressType := range []corev1.NodeAddressType{corev1.NodeExternalIP, corev1.NodeInternalIP} {
+			for _, node := range nodes {
+				for _, address := range node.Status.Addresses {
+					if address.Type == addressType && address.Address != "" {
+						return net.JoinHostPort(address.Address, nodePort), nil
+					}
+				}
+			}
+		}
+		return "", errors.New("no node has an IP address")
+	default:
+		return "", fmt.Errorf("service %s of type %s isn't reachable from outside the cluster", svc.Name, svc.Spec.Type)
+	}
+}
@@ -0,0 +1,92 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExternalAddress(t *testing.T) {
+	service := func(serviceType corev1.ServiceType, ingress ...corev1.LoadBalancerIngress) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "ingress-gateway"},
+			Spec: corev1.ServiceSpec{
+				Type:  serviceType,
+				Ports: []corev1.ServicePort{{Port: 8080, NodePort: 30080}},
+			},
+			Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: ingress}},
+		}
+	}
+	node := func(addresses ...corev1.NodeAddress) corev1.Node {
+		return corev1.Node{Status: corev1.NodeStatus{Addresses: addresses}}
+	}
+
+	cases := map[string]struct {
+		svc      *corev1.Service
+		nodes    []corev1.Node
+		port     int32
+		expected string
+		err      string
+	}{
+		"load balancer IP": {
+			svc:      service(corev1.ServiceTypeLoadBalancer, corev1.LoadBalancerIngress{IP: "1.2.3.4"}),
+			port:     8080,
+			expected: "1.2.3.4:8080",
+		},
+		"load balancer hostname": {
+			svc:      service(corev1.ServiceTypeLoadBalancer, corev1.LoadBalancerIngress{Hostname: "lb.example.com"}),
+			port:     8080,
+			expected: "lb.example.com:8080",
+		},
+		"load balancer not provisioned": {
+			svc:  service(corev1.ServiceTypeLoadBalancer),
+			port: 8080,
+			err:  "load balancer of service ingress-gateway is not provisioned yet",
+		},
+		"node port prefers external IPs": {
+			svc: service(corev1.ServiceTypeNodePort),
+			nodes: []corev1.Node{
+				node(corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}),
+				node(corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.2"}, corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "5.6.7.8"}),
+			},
+			port:     8080,
+			expected: "5.6.7.8:30080",
+		},
+		"node port on internal IP": {
+			svc:      service(corev1.ServiceTypeNodePort),
+			nodes:    []corev1.Node{node(corev1.NodeAddress{Type: corev1.NodeHostName, Address: "kind-control-plane"}, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "172.18.0.2"})},
+			port:     8080,
+			expected: "172.18.0.2:30080",
+		},
+		"node port without node IPs": {
+			svc:   service(corev1.ServiceTypeNodePort),
+			nodes: []corev1.Node{node(corev1.NodeAddress{Type: corev1.NodeHostName, Address: "kind-control-plane"})},
+			port:  8080,
+			err:   "no node has an IP address",
+		},
+		"unknown port": {
+			svc:  service(corev1.ServiceTypeLoadBalancer, corev1.LoadBalancerIngress{IP: "1.2.3.4"}),
+			port: 8443,
+			err:  "service ingress-gateway has no port 8443",
+		},
+		"cluster IP": {
+			svc:  service(corev1.ServiceTypeClusterIP),
+			port: 8080,
+			err:  "service ingress-gateway of type ClusterIP isn't reachable from outside the cluster",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			address, err := externalAddress(c.svc, c.nodes, c.port)
+			if c.err != "" {
+				require.EqualError(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, address)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// helmHookAnnotation is the annotation that marks a resource as a helm hook.
+const helmHookAnnotation = "helm.sh/hook"
+
+// StuckResource is a resource that still exists after it should have been deleted.
+type StuckResource struct {
+	Kind string
+	Name string
+	// Deleting is true if the resource has been marked for deletion
+	// but has not been deleted yet, usually because of its finalizers.
+	Deleting   bool
+	Finalizers []string
+	// HelmHook is the value of the helm.sh/hook annotation if the resource
+	// is a helm hook. Hooks are left behind if their delete policy doesn't
+	// match the outcome of the hook, e.g. failed jobs with hook-succeeded.
+	HelmHook string
+}
+
+func (r StuckResource) String() string {
+	var reasons []string
+	if r.Deleting {
+		reasons = append(reasons, "deletion in progress")
+	}
+	if len(r.Finalizers) > 0 {
+		reasons = append(reasons, fmt.Sprintf("finalizers: %s", strings.Join(r.Finalizers, ", ")))
+	}
+	if r.HelmHook != "" {
+		reasons = append(reasons, fmt.Sprintf("helm hook: %s", r.HelmHook))
+	}
+	if len(reasons) == 0 {
+		return fmt.Sprintf("%s/%s", strings.ToLower(r.Kind), r.Name)
+	}
+	return fmt.Sprintf("%s/%s (%s)", strings.ToLower(r.Kind), r.Name, strings.Join(reasons, "; "))
+}
+
+// WaitForResourcesDeleted waits up to timeout for all resources of the given kinds
+// matching labelSelector to be deleted. It returns the resources that still exist
+// after the timeout, or nil if all of them have been deleted.
+func WaitForResourcesDeleted(t *testing.T, options *k8s.KubectlOptions, kinds []string, labelSelector string, timeout time.Duration) []StuckResource {
+	t.Helper()
+
+	// This doesn't use retry because running out of time is not a test failure,
+	// the caller decides what to do with the remaining resources.
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining, err := getResources(t, options, kinds, labelSelector)
+		if err != nil {
+			logger.Logf(t, "failed to list resources matching %s: %s", labelSelector, err)
+		} else if len(remaining) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return remaining
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// ForceDeleteResources removes the finalizers of resources and deletes them
+// without waiting for graceful termination.
+func ForceDeleteResources(t *testing.T, options *k8s.KubectlOptions, resources []StuckResource) {
+	t.Helper()
+
+	for _, resource := range resources {
+		name := fmt.Sprintf("%s/%s", strings.ToLower(resource.Kind), resource.Name)
+		logger.Logf(t, "force deleting %s", resource)
+		if len(resource.Finalizers) > 0 {
+			out, err := RunKubectlAndGetOutputE(t, options, "patch", name, "--type=merge", "-p", `{"metadata":{"finalizers":null}}`)
+			if err != nil {
+				logger.Logf(t, "failed to remove finalizers of %s: %s: %s", name, err, out)
+			}
+		}
+		out, err := RunKubectlAndGetOutputE(t, options, "delete", name, "--ignore-not-found", "--grace-period=0", "--force", "--wait=false")
+		if err != nil {
+			logger.Logf(t, "failed to delete %s: %s: %s", name, err, out)
+		}
+	}
+}
+
+// getResources returns the resources of the given kinds matching labelSelector.
+func getResources(t *testing.T, options *k8s.KubectlOptions, kinds []string, labelSelector string) ([]StuckResource, error) {
+	out, err := RunKubectlAndGetOutputWithLoggerE(t, options, terratestLogger.Discard, "get", strings.Join(kinds, ","), "-l", labelSelector, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, out)
+	}
+	return parseResources(out)
+}
+
+// parseResources parses the output of kubectl get -o json.
+func parseResources(out string) ([]StuckResource, error) {
+	var list struct {
+		Items []struct {
+			Kind     string            `json:"kind"`
+			Metadata metav1.ObjectMeta `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		return nil, err
+	}
+
+	var resources []StuckResource
+	for _, item := range list.Items {
+		resources = append(resources, StuckResource{
+			Kind:       item.Kind,
+			Name:       item.Metadata.Name,
+			Deleting:   item.Metadata.DeletionTimestamp != nil,
+			Finalizers: item.Metadata.Finalizers,
+			HelmHook:   item.Metadata.Annotations[helmHookAnnotation],
+		})
+	}
+	return resources, nil
+}
+
+// ReportStuckResources marks the test as failed and lists all stuck resources if there are any.
+// It doesn't stop the test so that it can be used in cleanup functions.
+func ReportStuckResources(t *testing.T, resources []StuckResource, msg string) {
+	t.Helper()
+
+	if len(resources) == 0 {
+		return
+	}
+	var lines []string
+	for _, resource := range resources {
+		lines = append(lines, "\t"+resource.String())
+	}
+	t.Errorf("%s:\n%s", msg, strings.Join(lines, "\n"))
+}
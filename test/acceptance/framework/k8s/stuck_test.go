@@ -0,0 +1,56 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResources(t *testing.T) {
+	out := `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {
+      "kind": "PersistentVolumeClaim",
+      "metadata": {
+        "name": "data-default-consul-server-0",
+        "deletionTimestamp": "2021-03-01T00:00:00Z",
+        "finalizers": ["kubernetes.io/pvc-protection"]
+      }
+    },
+    {
+      "kind": "Job",
+      "metadata": {
+        "name": "consul-tls-init-cleanup",
+        "annotations": {"helm.sh/hook": "pre-delete"}
+      }
+    }
+  ]
+}`
+
+	resources, err := parseResources(out)
+	require.NoError(t, err)
+	require.Equal(t, []StuckResource{
+		{
+			Kind:       "PersistentVolumeClaim",
+			Name:       "data-default-consul-server-0",
+			Deleting:   true,
+			Finalizers: []string{"kubernetes.io/pvc-protection"},
+		},
+		{
+			Kind:     "Job",
+			Name:     "consul-tls-init-cleanup",
+			HelmHook: "pre-delete",
+		},
+	}, resources)
+
+	require.Equal(t, "persistentvolumeclaim/data-default-consul-server-0 (deletion in progress; finalizers: kubernetes.io/pvc-protection)", resources[0].String())
+	require.Equal(t, "job/consul-tls-init-cleanup (helm hook: pre-delete)", resources[1].String())
+}
+
+func TestParseResources_Empty(t *testing.T) {
+	resources, err := parseResources(`{"apiVersion": "v1", "kind": "List", "items": []}`)
+	require.NoError(t, err)
+	require.Empty(t, resources)
+}
@@ -0,0 +1,79 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// syncedServiceLabelKey and syncedServiceLabelValue make up the label
+	// that catalog sync adds to the Kubernetes services it creates from Consul services.
+	// Catalog sync only updates and deletes services with this label.
+	syncedServiceLabelKey   = "consul"
+	syncedServiceLabelValue = "true"
+
+	// serviceSyncAnnotation is the annotation that controls whether a Kubernetes service
+	// is synced to Consul. Catalog sync sets it to "false" on the services it creates
+	// so that they are not synced back to Consul.
+	serviceSyncAnnotation = "consul.hashicorp.com/service-sync"
+)
+
+// WaitForSyncedService waits for catalog sync to create the Kubernetes service
+// for the Consul service with the given name and returns it.
+// The service needs to be synced into namespace, which is the release namespace by default.
+func WaitForSyncedService(t *testing.T, client kubernetes.Interface, namespace, name string) *corev1.Service {
+	t.Helper()
+
+	logger.Logf(t, "waiting for service %s to be synced from Consul", name)
+	var service *corev1.Service
+	retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: 1 * time.Second}, t, func(r *retry.R) {
+		var err error
+		service, err = client.CoreV1().Services(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		require.NoError(r, err)
+		require.Equal(r, syncedServiceLabelValue, service.Labels[syncedServiceLabelKey], "service %s exists but was not created by catalog sync", name)
+	})
+	return service
+}
+
+// WaitForSyncedServiceDeleted waits for catalog sync to delete the Kubernetes service
+// with the given name, e.g. after the Consul service it was created from is deregistered.
+func WaitForSyncedServiceDeleted(t *testing.T, client kubernetes.Interface, namespace, name string) {
+	t.Helper()
+
+	logger.Logf(t, "waiting for synced service %s to be deleted", name)
+	retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: 1 * time.Second}, t, func(r *retry.R) {
+		_, err := client.CoreV1().Services(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		require.True(r, errors.IsNotFound(err), "expected service %s to be deleted, got: %v", name, err)
+	})
+}
+
+// RequireSyncedFromConsul checks that the Kubernetes service was created by catalog sync
+// for the Consul service consulName, i.e. that it is an ExternalName service
+// pointing to the Consul DNS name of the service in domain, that it has the ownership label
+// of catalog sync and that it is annotated to not be synced back to Consul.
+func RequireSyncedFromConsul(t require.TestingT, service *corev1.Service, consulName, domain string) {
+	require.Equal(t, corev1.ServiceTypeExternalName, service.Spec.Type, "service %s", service.Name)
+	require.Equal(t, fmt.Sprintf("%s.service.%s", consulName, domain), service.Spec.ExternalName, "service %s", service.Name)
+	require.Equal(t, syncedServiceLabelValue, service.Labels[syncedServiceLabelKey], "service %s", service.Name)
+	require.Equal(t, "false", service.Annotations[serviceSyncAnnotation], "service %s", service.Name)
+}
+
+// RequireNotOwnedBySync checks that the Kubernetes service doesn't have the ownership
+// label of catalog sync, so catalog sync will never update or delete it, and that
+// it has the given type. This is useful to check that catalog sync didn't take over
+// a user-created service with the same name as a Consul service.
+func RequireNotOwnedBySync(t require.TestingT, service *corev1.Service, serviceType corev1.ServiceType) {
+	_, ok := service.Labels[syncedServiceLabelKey]
+	require.False(t, ok, "service %s has the catalog sync label", service.Name)
+	require.Equal(t, serviceType, service.Spec.Type, "service %s", service.Name)
+}
@@ -0,0 +1,90 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// mockT records whether a require assertion failed.
+type mockT struct {
+	failed bool
+}
+
+func (m *mockT) Errorf(string, ...interface{}) { m.failed = true }
+func (m *mockT) FailNow()                      { m.failed = true }
+
+// syncedService returns a service as created by catalog sync for the Consul service foo.
+func syncedService() *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Labels:      map[string]string{"consul": "true"},
+			Annotations: map[string]string{"consul.hashicorp.com/service-sync": "false"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: "foo.service.consul",
+		},
+	}
+}
+
+func TestRequireSyncedFromConsul(t *testing.T) {
+	cases := map[string]struct {
+		modify func(*corev1.Service)
+		domain string
+		fail   bool
+	}{
+		"synced service": {
+			modify: func(*corev1.Service) {},
+			domain: "consul",
+		},
+		"different domain": {
+			modify: func(*corev1.Service) {},
+			domain: "example",
+			fail:   true,
+		},
+		"missing label": {
+			modify: func(s *corev1.Service) { s.Labels = nil },
+			domain: "consul",
+			fail:   true,
+		},
+		"missing annotation": {
+			modify: func(s *corev1.Service) { s.Annotations = nil },
+			domain: "consul",
+			fail:   true,
+		},
+		"cluster IP service": {
+			modify: func(s *corev1.Service) { s.Spec = corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP} },
+			domain: "consul",
+			fail:   true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			service := syncedService()
+			c.modify(service)
+			mt := &mockT{}
+			RequireSyncedFromConsul(mt, service, "foo", c.domain)
+			require.Equal(t, c.fail, mt.failed)
+		})
+	}
+}
+
+func TestRequireNotOwnedBySync(t *testing.T) {
+	userService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP},
+	}
+
+	mt := &mockT{}
+	RequireNotOwnedBySync(mt, userService, corev1.ServiceTypeClusterIP)
+	require.False(t, mt.failed)
+
+	mt = &mockT{}
+	RequireNotOwnedBySync(mt, syncedService(), corev1.ServiceTypeExternalName)
+	require.True(t, mt.failed)
+}
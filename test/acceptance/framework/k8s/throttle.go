@@ -0,0 +1,131 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// flowControlAPIVersion is the API version of API Priority and Fairness
+// that ThrottleServiceAccounts uses. It's enabled by default since Kubernetes 1.20.
+const flowControlAPIVersion = "flowcontrol.apiserver.k8s.io/v1beta1"
+
+// ThrottleServiceAccounts makes the API server throttle the requests of the service accounts
+// in the namespace of options, as if the API server were overloaded. It creates a priority level
+// with the lowest possible concurrency that rejects requests it can't handle immediately with
+// 429 Too Many Requests, and a flow schema named name that assigns all the requests of the
+// service accounts to it. Long-running requests such as watches are not throttled.
+//
+// It returns a function that removes the throttling, which is also called when the test finishes.
+// The test is skipped if the cluster doesn't support API Priority and Fairness.
+func ThrottleServiceAccounts(t *testing.T, options *k8s.KubectlOptions, noCleanupOnFailure bool, name string, serviceAccounts ...string) func() {
+	t.Helper()
+
+	apiVersions, err := RunKubectlAndGetOutputE(t, options, "api-versions")
+	require.NoError(t, err)
+	if !sliceContains(strings.Fields(apiVersions), flowControlAPIVersion) {
+		t.Skipf("skipping this test because the cluster doesn't support %s", flowControlAPIVersion)
+	}
+
+	logger.Logf(t, "throttling API requests of service accounts %s", strings.Join(serviceAccounts, ", "))
+	manifest := throttleManifest(name, options.Namespace, serviceAccounts)
+	_, stderr, err := RunKubectlWithInputAndGetStdoutStderrE(t, options, []byte(manifest), "apply", "-f", "-")
+	require.NoError(t, err, stderr)
+
+	unthrottle := func() {
+		logger.Logf(t, "removing the throttling of API requests of service accounts %s", strings.Join(serviceAccounts, ", "))
+		_, stderr, err := RunKubectlWithInputAndGetStdoutStderrE(t, options, []byte(manifest), "delete", "--ignore-not-found", "-f", "-")
+		require.NoError(t, err, stderr)
+	}
+	helpers.Cleanup(t, noCleanupOnFailure, unthrottle)
+	return unthrottle
+}
+
+// RejectedRequests returns the number of requests that the API server has rejected
+// because of the throttling that ThrottleServiceAccounts set up with name, according
+// to the API Priority and Fairness metrics of the API server. This lets tests check
+// that the components were actually throttled.
+func RejectedRequests(t *testing.T, options *k8s.KubectlOptions, name string) int {
+	t.Helper()
+
+	// The metrics are long and aren't useful in the test logs.
+	metrics, err := RunKubectlAndGetOutputWithLoggerE(t, options, terratestLogger.Discard, "get", "--raw", "/metrics")
+	require.NoError(t, err)
+	rejected, err := rejectedRequests(metrics, name)
+	require.NoError(t, err)
+	return rejected
+}
+
+// rejectedRequests returns the sum of the apiserver_flowcontrol_rejected_requests_total
+// metrics of the priority level name in metrics, in the Prometheus text format.
+func rejectedRequests(metrics, name string) (int, error) {
+	label := fmt.Sprintf(`priority_level="%s"`, name)
+	rejected := 0
+	for _, line := range strings.Split(metrics, "\n") {
+		if !strings.HasPrefix(line, "apiserver_flowcontrol_rejected_requests_total{") || !strings.Contains(line, label) {
+			continue
+		}
+		fields := strings.Fields(line)
+		value, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing metric %q: %s", line, err)
+		}
+		rejected += int(value)
+	}
+	return rejected, nil
+}
+
+// throttleManifest returns the priority level and flow schema
+// that throttle the service accounts in namespace.
+func throttleManifest(name, namespace string, serviceAccounts []string) string {
+	var subjects strings.Builder
+	for _, sa := range serviceAccounts {
+		fmt.Fprintf(&subjects, `        - kind: ServiceAccount
+          serviceAccount:
+            name: %s
+            namespace: %s
+`, sa, namespace)
+	}
+
+	return fmt.Sprintf(`apiVersion: %[1]s
+kind: PriorityLevelConfiguration
+metadata:
+  name: %[2]s
+spec:
+  type: Limited
+  limited:
+    assuredConcurrencyShares: 1
+    limitResponse:
+      type: Reject
+---
+apiVersion: %[1]s
+kind: FlowSchema
+metadata:
+  name: %[2]s
+spec:
+  priorityLevelConfiguration:
+    name: %[2]s
+  # Lower than the default flow schemas for service accounts so that this one takes precedence.
+  matchingPrecedence: 500
+  distinguisherMethod:
+    type: ByUser
+  rules:
+    - subjects:
+%[3]s      resourceRules:
+        - verbs: ["*"]
+          apiGroups: ["*"]
+          resources: ["*"]
+          clusterScope: true
+          namespaces: ["*"]
+      nonResourceRules:
+        - verbs: ["*"]
+          nonResourceURLs: ["*"]
+`, flowControlAPIVersion, name, subjects.String())
+}
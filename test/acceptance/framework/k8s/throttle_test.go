@@ -0,0 +1,77 @@
+package k8s
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+func TestThrottleManifest(t *testing.T) {
+	manifest := throttleManifest("throttled", "consul", []string{"controller", "injector"})
+
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 1024)
+	var objects []unstructured.Unstructured
+	for {
+		var obj unstructured.Unstructured
+		err := decoder.Decode(&obj.Object)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		objects = append(objects, obj)
+	}
+	require.Len(t, objects, 2)
+
+	priorityLevel := objects[0]
+	require.Equal(t, "PriorityLevelConfiguration", priorityLevel.GetKind())
+	require.Equal(t, "throttled", priorityLevel.GetName())
+	limitResponse, _, err := unstructured.NestedString(priorityLevel.Object, "spec", "limited", "limitResponse", "type")
+	require.NoError(t, err)
+	require.Equal(t, "Reject", limitResponse)
+
+	flowSchema := objects[1]
+	require.Equal(t, "FlowSchema", flowSchema.GetKind())
+	priorityLevelName, _, err := unstructured.NestedString(flowSchema.Object, "spec", "priorityLevelConfiguration", "name")
+	require.NoError(t, err)
+	require.Equal(t, "throttled", priorityLevelName)
+
+	rules, _, err := unstructured.NestedSlice(flowSchema.Object, "spec", "rules")
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	subjects, _, err := unstructured.NestedSlice(rules[0].(map[string]interface{}), "subjects")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{
+		map[string]interface{}{
+			"kind":           "ServiceAccount",
+			"serviceAccount": map[string]interface{}{"name": "controller", "namespace": "consul"},
+		},
+		map[string]interface{}{
+			"kind":           "ServiceAccount",
+			"serviceAccount": map[string]interface{}{"name": "injector", "namespace": "consul"},
+		},
+	}, subjects)
+}
+
+func TestRejectedRequests(t *testing.T) {
+	metrics := `# HELP apiserver_flowcontrol_rejected_requests_total [ALPHA] Number of requests rejected by API Priority and Fairness system
+# TYPE apiserver_flowcontrol_rejected_requests_total counter
+apiserver_flowcontrol_rejected_requests_total{flow_schema="throttled",priority_level="throttled",reason="queue-full"} 12
+apiserver_flowcontrol_rejected_requests_total{flow_schema="throttled",priority_level="throttled",reason="time-out"} 3
+apiserver_flowcontrol_rejected_requests_total{flow_schema="global-default",priority_level="global-default",reason="queue-full"} 7
+apiserver_flowcontrol_dispatched_requests_total{flow_schema="throttled",priority_level="throttled"} 40
+`
+	rejected, err := rejectedRequests(metrics, "throttled")
+	require.NoError(t, err)
+	require.Equal(t, 15, rejected)
+
+	rejected, err = rejectedRequests(metrics, "other")
+	require.NoError(t, err)
+	require.Zero(t, rejected)
+
+	_, err = rejectedRequests(`apiserver_flowcontrol_rejected_requests_total{priority_level="throttled"} NaNa`, "throttled")
+	require.Error(t, err)
+}
@@ -0,0 +1,192 @@
+package k8s
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// triageFilename is the file in the debug directory of a test
+// that the hints of WriteTriageHints are written to.
+const triageFilename = "triage.txt"
+
+// triageSignature is an error signature that is commonly found in the
+// diagnostics of failed tests along with its probable cause.
+type triageSignature struct {
+	name    string
+	pattern *regexp.Regexp
+	cause   string
+}
+
+// triageSignatures are the known error signatures, in the order that their hints are reported.
+var triageSignatures = []triageSignature{
+	{
+		name:    "image pull",
+		pattern: regexp.MustCompile(`ImagePullBackOff|ErrImagePull`),
+		cause: "an image couldn't be pulled. Check the image names and tags in the helm values and the " +
+			"-consul-image, -consul-k8s-image and -envoy-image flags, and whether the registry rate limits pulls.",
+	},
+	{
+		name: "ACL permission denied",
+		// Consul reports ACL denials as "Permission denied" in RPC errors and 403 responses.
+		// Filesystem and exec errors say "permission denied" too, so they aren't matched.
+		pattern: regexp.MustCompile(`rpc error.*Permission denied|403 \(Permission denied\)|ACL not found`),
+		cause: "a request was denied by Consul ACLs. Check that the component got its token from " +
+			"server-acl-init and that the policy of the token allows the request.",
+	},
+	{
+		name:    "webhook timeout",
+		pattern: regexp.MustCompile(`failed calling webhook.*(context deadline exceeded|i/o timeout|Client.Timeout)`),
+		cause: "the Kubernetes API server couldn't reach a webhook in time. Check that the connect injector or " +
+			"controller is ready, and that no firewall blocks the API server from reaching the webhook port, " +
+			"e.g. on private GKE clusters.",
+	},
+	{
+		name:    "PVC pending",
+		pattern: regexp.MustCompile(`unbound immediate PersistentVolumeClaims|ProvisioningFailed|no persistent volumes available`),
+		cause: "a persistent volume claim of the servers is pending. Check that the cluster has a default " +
+			"storage class or that server.storageClass names one that can provision volumes.",
+	},
+}
+
+// TriageHint is the probable cause of a test failure
+// that matched a known error signature in its diagnostics.
+type TriageHint struct {
+	// Name is the name of the error signature.
+	Name string
+	// Cause is a human-readable explanation of the probable cause.
+	Cause string
+	// Evidence is the first line that matched the signature,
+	// prefixed with the path of its file relative to the debug directory.
+	Evidence string
+}
+
+func (h TriageHint) String() string {
+	return fmt.Sprintf("probable cause (%s): %s\n  evidence: %s", h.Name, h.Cause, h.Evidence)
+}
+
+var (
+	// triaged are the tests that WriteTriageHintsOnFailure has been called for.
+	triaged     = make(map[*testing.T]bool)
+	triagedLock sync.Mutex
+)
+
+// WriteTriageHintsOnFailure calls WriteTriageHints for the debug directory of t
+// once t has finished if it failed. The hints are written after the cleanups that
+// t registers later, such as the ones writing the diagnostics, have run, so call it
+// before creating any resources. Calling it again for the same test is a no-op.
+func WriteTriageHintsOnFailure(t *testing.T, debugDirectory string) {
+	triagedLock.Lock()
+	defer triagedLock.Unlock()
+
+	if triaged[t] {
+		return
+	}
+	triaged[t] = true
+
+	t.Cleanup(func() {
+		triagedLock.Lock()
+		delete(triaged, t)
+		triagedLock.Unlock()
+
+		if t.Failed() {
+			WriteTriageHints(t, filepath.Join(debugDirectory, t.Name()))
+		}
+	})
+}
+
+// WriteTriageHints matches the diagnostics in testDebugDirectory against
+// known error signatures and logs a hint about the probable cause of the failure
+// for each one that matches. The hints are also written to triage.txt so that
+// they show up among the artifacts of the test.
+func WriteTriageHints(t *testing.T, testDebugDirectory string) {
+	t.Helper()
+
+	hints, err := triage(testDebugDirectory)
+	if os.IsNotExist(err) {
+		// No diagnostics have been written, e.g. because the test failed before creating any resources.
+		return
+	}
+	if err != nil {
+		logger.Logf(t, "failed to triage the diagnostics in %s: %s", testDebugDirectory, err)
+		return
+	}
+	if len(hints) == 0 {
+		return
+	}
+
+	var out strings.Builder
+	for _, hint := range hints {
+		logger.Log(t, hint.String())
+		fmt.Fprintln(&out, hint.String())
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(testDebugDirectory, triageFilename), []byte(out.String()), 0600))
+}
+
+// triage returns a hint for each of triageSignatures that matches a line
+// of the logs and text files in dir and its subdirectories.
+func triage(dir string) ([]TriageHint, error) {
+	evidence := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || info.Name() == triageFilename {
+			return nil
+		}
+		if ext := filepath.Ext(path); ext != ".log" && ext != ".txt" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return scanSignatures(path, rel, evidence)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var hints []TriageHint
+	for _, signature := range triageSignatures {
+		if line, ok := evidence[signature.name]; ok {
+			hints = append(hints, TriageHint{Name: signature.name, Cause: signature.cause, Evidence: line})
+		}
+	}
+	return hints, nil
+}
+
+// scanSignatures records the first line of the file at path that matches each of
+// triageSignatures in evidence, unless evidence already has a line for it.
+func scanSignatures(path, rel string, evidence map[string]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	// Envoy config dumps and some log lines are longer than the default limit.
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for _, signature := range triageSignatures {
+			if _, ok := evidence[signature.name]; ok {
+				continue
+			}
+			if signature.pattern.MatchString(line) {
+				evidence[signature.name] = fmt.Sprintf("%s: %s", rel, strings.TrimSpace(line))
+			}
+		}
+	}
+	return scanner.Err()
+}
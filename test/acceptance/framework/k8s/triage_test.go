@@ -0,0 +1,60 @@
+package k8s
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "triage")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"static-client-pod.txt": "  Warning  Failed  10s  kubelet  Error: ImagePullBackOff\n",
+		"default-events.txt": "Warning FailedCreate replicaset/static-client Error creating: Internal error occurred: " +
+			"failed calling webhook \"consul-connect-injector.consul.hashicorp.com\": Post \"https://injector:443/mutate\": context deadline exceeded\n",
+		// Filesystem errors aren't mistaken for ACL denials.
+		"logs/consul-client-abcde-consul.log": "2021-05-04T09:59:59Z [ERROR] agent: open /consul/data/node-id: permission denied\n",
+		"logs/consul-server-0-consul.log": "2021-05-04T10:00:00Z [ERROR] agent: rpc error: Permission denied\n" +
+			"2021-05-04T10:00:01Z [ERROR] agent: rpc error: Permission denied again\n",
+		// Files other than logs and text files aren't scanned.
+		"consul-server-0-consul-debug.tar.gz": "unbound immediate PersistentVolumeClaims",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+
+	hints, err := triage(dir)
+	require.NoError(t, err)
+	require.Len(t, hints, 3)
+	// Hints are in the order of the signatures.
+	require.Equal(t, "image pull", hints[0].Name)
+	require.Equal(t, "static-client-pod.txt: Warning  Failed  10s  kubelet  Error: ImagePullBackOff", hints[0].Evidence)
+	require.Equal(t, "ACL permission denied", hints[1].Name)
+	require.Equal(t, "logs/consul-server-0-consul.log: 2021-05-04T10:00:00Z [ERROR] agent: rpc error: Permission denied", hints[1].Evidence)
+	require.Equal(t, "webhook timeout", hints[2].Name)
+	require.Contains(t, hints[2].Evidence, "default-events.txt: ")
+}
+
+func TestTriage_NoMatches(t *testing.T) {
+	dir, err := ioutil.TempDir("", "triage")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "static-server.log"), []byte("listening on :8080\n"), 0644))
+
+	hints, err := triage(dir)
+	require.NoError(t, err)
+	require.Empty(t, hints)
+}
+
+// Test that tests that fail before writing any diagnostics don't fail triage.
+func TestWriteTriageHints_NoDiagnostics(t *testing.T) {
+	WriteTriageHints(t, filepath.Join(t.TempDir(), "TestMissing"))
+}
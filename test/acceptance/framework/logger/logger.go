@@ -1,13 +1,77 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	terratestTesting "github.com/gruntwork-io/terratest/modules/testing"
 )
 
+// The formats of the log lines, see SetFormat.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Formats are the supported log formats.
+var Formats = []string{FormatText, FormatJSON}
+
+// The phases of a test that JSON log entries are attributed to, see SetPhase.
+const (
+	PhaseSetup   = "setup"
+	PhaseTest    = "test"
+	PhaseCleanup = "cleanup"
+)
+
+var (
+	// format is the format of the log lines.
+	format = FormatText
+
+	// phases are the current phases of the tests
+	// that have set one. The default is PhaseTest.
+	phases     = make(map[*testing.T]string)
+	phasesLock sync.Mutex
+)
+
+// SetFormat sets the format of all log lines, either FormatText
+// or FormatJSON. Any other format is treated as FormatText.
+func SetFormat(f string) {
+	format = f
+}
+
+// SetPhase sets the phase of t that its JSON log entries are attributed to.
+func SetPhase(t *testing.T, phase string) {
+	phasesLock.Lock()
+	defer phasesLock.Unlock()
+	phases[t] = phase
+}
+
+// phase returns the current phase of t.
+func phase(t *testing.T) string {
+	phasesLock.Lock()
+	defer phasesLock.Unlock()
+	if p, ok := phases[t]; ok {
+		return p
+	}
+	return PhaseTest
+}
+
+// Entry is a log line in the JSON format.
+type Entry struct {
+	Time    string `json:"time"`
+	Test    string `json:"test"`
+	Phase   string `json:"phase"`
+	Message string `json:"message"`
+
+	// Command and Duration are only set for the entries of commands, see LogCommand.
+	Command  string  `json:"command,omitempty"`
+	Duration float64 `json:"duration_seconds,omitempty"`
+}
+
 // TestLogger implements terratest's TestLogger interface
 // so that we can pass it to terratest objects to have consistent logging
 // across all tests.
@@ -34,10 +98,59 @@ func Logf(t *testing.T, format string, args ...interface{}) {
 }
 
 // Log calls t.Log, adding an RFC3339 timestamp to the beginning of the log line.
+// In the JSON format, the line is an Entry instead.
 func Log(t *testing.T, args ...interface{}) {
 	t.Helper()
 
+	if format == FormatJSON {
+		t.Log(jsonEntry(t, Entry{Message: strings.TrimSuffix(fmt.Sprintln(args...), "\n")}))
+		return
+	}
+
 	allArgs := []interface{}{time.Now().Format(time.RFC3339)}
 	allArgs = append(allArgs, args...)
 	t.Log(allArgs...)
 }
+
+// LogCommand logs that command has finished after duration. It's only logged in the
+// JSON format because the text format already has a line for when the command started.
+func LogCommand(t *testing.T, command string, duration time.Duration) {
+	t.Helper()
+
+	if format != FormatJSON {
+		return
+	}
+	t.Log(jsonEntry(t, Entry{
+		Message:  "command finished",
+		Command:  command,
+		Duration: duration.Seconds(),
+	}))
+}
+
+// ParseEntry returns the Entry of a line of test output logged in the JSON format.
+// The file and line number that t.Log adds to the beginning of the line are skipped.
+// It returns false if the line isn't an Entry, e.g. because it was logged in the text format.
+func ParseEntry(line string) (Entry, bool) {
+	var entry Entry
+	i := strings.Index(line, ": {")
+	if i < 0 {
+		return entry, false
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(line[i+2:])), &entry); err != nil {
+		return entry, false
+	}
+	return entry, true
+}
+
+// jsonEntry fills in the test fields of entry and returns it as JSON.
+func jsonEntry(t *testing.T, entry Entry) string {
+	entry.Time = time.Now().Format(time.RFC3339Nano)
+	entry.Test = t.Name()
+	entry.Phase = phase(t)
+	line, err := json.Marshal(entry)
+	if err != nil {
+		// Entries only contain strings and numbers, so this can't happen.
+		panic(err)
+	}
+	return string(line)
+}
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONEntry(t *testing.T) {
+	var entry Entry
+	require.NoError(t, json.Unmarshal([]byte(jsonEntry(t, Entry{Message: "hello"})), &entry))
+	require.NotEmpty(t, entry.Time)
+	require.Equal(t, "TestJSONEntry", entry.Test)
+	require.Equal(t, PhaseTest, entry.Phase)
+	require.Equal(t, "hello", entry.Message)
+
+	SetPhase(t, PhaseCleanup)
+	line := jsonEntry(t, Entry{Message: "command finished", Command: "kubectl get pods", Duration: 1.5})
+	require.Contains(t, line, `"phase":"cleanup"`)
+	require.Contains(t, line, `"command":"kubectl get pods"`)
+	require.Contains(t, line, `"duration_seconds":1.5`)
+
+	// The command fields are omitted from other entries.
+	require.NotContains(t, jsonEntry(t, Entry{Message: "hello"}), "command")
+}
+
+func TestParseEntry(t *testing.T) {
+	entry, ok := ParseEntry(`    logger.go:106: {"time":"2021-04-01T10:00:00Z","test":"TestA","phase":"test","message":"install benchmark: minimal 1m0s"}` + "\n")
+	require.True(t, ok)
+	require.Equal(t, "TestA", entry.Test)
+	require.Equal(t, "install benchmark: minimal 1m0s", entry.Message)
+
+	_, ok = ParseEntry("    logger.go:19: 2021-04-01T10:00:00Z install benchmark: minimal 1m0s\n")
+	require.False(t, ok)
+}
@@ -0,0 +1,101 @@
+// Package portallocator hands out local ports for port-forwards.
+// A port-forward can only be created on a port that is free, but checking that
+// a port is free by listening on it and closing the listener again leaves a window
+// in which another test running in parallel can pick the same port. Ports handed
+// out by an allocator are not handed out again until they are released, which
+// prevents these collisions between the tests of a package.
+package portallocator
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// allocateAttempts is how many free ports Allocate tries
+// before giving up because they have all been handed out.
+const allocateAttempts = 10
+
+// Allocator hands out free local ports that it hasn't handed out already.
+type Allocator struct {
+	// freePort returns a local port that is currently free.
+	freePort func() (int, error)
+
+	// lock protects allocated.
+	lock      sync.Mutex
+	allocated map[int]bool
+}
+
+// New returns an Allocator for ports on 127.0.0.1.
+func New() *Allocator {
+	return &Allocator{
+		freePort:  freePort,
+		allocated: make(map[int]bool),
+	}
+}
+
+// Allocate returns a local port that is free and that a hasn't handed out
+// before, or that has been released since.
+func (a *Allocator) Allocate() (int, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for attempt := 0; attempt < allocateAttempts; attempt++ {
+		port, err := a.freePort()
+		if err != nil {
+			return 0, err
+		}
+		if !a.allocated[port] {
+			a.allocated[port] = true
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port that hasn't been allocated after %d attempts", allocateAttempts)
+}
+
+// Release makes port available to be handed out again.
+func (a *Allocator) Release(port int) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	delete(a.allocated, port)
+}
+
+// defaultAllocator is shared by all tests of a package, which run in the same process.
+var defaultAllocator = New()
+
+// Allocate returns a port from the allocator shared by the tests of the package,
+// which is released when t finishes. It fails the test if there's no free port.
+func Allocate(t *testing.T) int {
+	t.Helper()
+
+	port, err := AllocateE()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		Release(port)
+	})
+	return port
+}
+
+// AllocateE returns a port from the allocator shared by the tests of the package.
+// The caller must release it with Release once it's no longer used.
+func AllocateE() (int, error) {
+	return defaultAllocator.Allocate()
+}
+
+// Release releases a port returned by AllocateE.
+func Release(port int) {
+	defaultAllocator.Release(port)
+}
+
+// freePort returns a local port that is currently free.
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
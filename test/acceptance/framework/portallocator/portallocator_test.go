@@ -0,0 +1,80 @@
+package portallocator
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test that ports are not handed out twice until they are released,
+// even if the system reports them as free again.
+func TestAllocator(t *testing.T) {
+	ports := []int{1000, 1000, 1001, 1000}
+	a := New()
+	a.freePort = func() (int, error) {
+		port := ports[0]
+		if len(ports) > 1 {
+			ports = ports[1:]
+		}
+		return port, nil
+	}
+
+	first, err := a.Allocate()
+	require.NoError(t, err)
+	require.Equal(t, 1000, first)
+
+	// 1000 is skipped because it has been allocated.
+	second, err := a.Allocate()
+	require.NoError(t, err)
+	require.Equal(t, 1001, second)
+
+	// Only 1000 is free from now on, and it's still allocated.
+	_, err = a.Allocate()
+	require.Error(t, err)
+
+	a.Release(first)
+	third, err := a.Allocate()
+	require.NoError(t, err)
+	require.Equal(t, 1000, third)
+}
+
+// Test that concurrent allocations of real ports are unique.
+func TestAllocate_Concurrent(t *testing.T) {
+	const count = 50
+
+	// Allocate from goroutines, but assert on the test goroutine
+	// because require can't stop the test from other goroutines.
+	type result struct {
+		port int
+		err  error
+	}
+	results := make(chan result, count)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			port, err := AllocateE()
+			results <- result{port: port, err: err}
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	seen := make(map[int]bool)
+	var errs []error
+	for res := range results {
+		if res.err != nil {
+			errs = append(errs, res.err)
+			continue
+		}
+		// Release the ports even if the test fails below.
+		defer Release(res.port)
+		if seen[res.port] {
+			t.Errorf("port %d was allocated twice", res.port)
+		}
+		seen[res.port] = true
+	}
+	require.Empty(t, errs)
+}
@@ -0,0 +1,238 @@
+package registry
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/portallocator"
+)
+
+const (
+	// namespace is the Kubernetes namespace the registry is deployed into.
+	namespace = "consul-test-registry"
+
+	// Host is the address the registry is reachable at from every node of
+	// the cluster. Images pushed with Push are referenced with this host.
+	Host = "localhost:5000"
+
+	// portForwardTimeout is how long to wait for the port-forward
+	// to the registry to be ready.
+	portForwardTimeout = 1 * time.Minute
+)
+
+// manifest deploys the registry and a proxy on every node that exposes it on
+// port 5000 of the node, so that the container runtime of any node can pull
+// images from localhost:5000. Container runtimes pull from localhost over plain
+// HTTP, so the registry doesn't need TLS. This is the same approach as
+// the registry addon of minikube.
+const manifest = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: consul-test-registry
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: registry
+  namespace: consul-test-registry
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: registry
+  template:
+    metadata:
+      labels:
+        app: registry
+    spec:
+      containers:
+        - name: registry
+          image: registry:2
+          ports:
+            - containerPort: 5000
+          readinessProbe:
+            httpGet:
+              path: /v2/
+              port: 5000
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: registry
+  namespace: consul-test-registry
+spec:
+  selector:
+    app: registry
+  ports:
+    - port: 5000
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: registry-proxy
+  namespace: consul-test-registry
+spec:
+  selector:
+    matchLabels:
+      app: registry-proxy
+  template:
+    metadata:
+      labels:
+        app: registry-proxy
+    spec:
+      containers:
+        - name: registry-proxy
+          image: alpine/socat
+          args:
+            - tcp-listen:5000,fork,reuseaddr
+            - tcp-connect:registry.consul-test-registry.svc.cluster.local:5000
+          ports:
+            - containerPort: 5000
+              hostPort: 5000
+`
+
+// Registry is a container registry deployed into a Kubernetes cluster.
+// It allows tests to install locally built images, e.g. unreleased consul-k8s
+// builds, on any cluster without pushing them to a public registry.
+type Registry struct {
+	kubeconfig  string
+	kubeContext string
+}
+
+// Deploy deploys a registry into the cluster of the given kubeconfig and
+// context and waits for it to be ready. If kubeconfig or kubeContext are empty,
+// the defaults of kubectl are used.
+func Deploy(kubeconfig, kubeContext string) (*Registry, error) {
+	r := &Registry{kubeconfig: kubeconfig, kubeContext: kubeContext}
+
+	apply := r.kubectl("apply", "-f", "-")
+	apply.Stdin = strings.NewReader(manifest)
+	if err := run(apply); err != nil {
+		return nil, fmt.Errorf("deploying registry: %s", err)
+	}
+	for _, workload := range []string{"deployment/registry", "daemonset/registry-proxy"} {
+		if err := run(r.kubectl("rollout", "status", workload, "--namespace", namespace, "--timeout", "5m")); err != nil {
+			return r, fmt.Errorf("waiting for %s to be ready: %s", workload, err)
+		}
+	}
+	return r, nil
+}
+
+// Push pushes image from the local docker daemon to the registry
+// and returns the reference to use for it in the cluster.
+func (r *Registry) Push(image string) (string, error) {
+	localPort, err := portallocator.AllocateE()
+	if err != nil {
+		return "", err
+	}
+	defer portallocator.Release(localPort)
+	stop, err := r.portForward(localPort)
+	if err != nil {
+		return "", err
+	}
+	defer stop()
+
+	// Docker pushes to registries on localhost over plain HTTP, so push through
+	// the port-forward and reference the image with Host in the cluster.
+	pushRef := fmt.Sprintf("localhost:%d/%s", localPort, repository(image))
+	if err := run(exec.Command("docker", "tag", image, pushRef)); err != nil {
+		return "", fmt.Errorf("tagging %s: %s", image, err)
+	}
+	defer exec.Command("docker", "rmi", pushRef).Run()
+	if err := run(exec.Command("docker", "push", pushRef)); err != nil {
+		return "", fmt.Errorf("pushing %s: %s", image, err)
+	}
+	return Image(image), nil
+}
+
+// Delete deletes the registry and the images pushed to it.
+func (r *Registry) Delete() error {
+	if err := run(r.kubectl("delete", "namespace", namespace, "--ignore-not-found")); err != nil {
+		return fmt.Errorf("deleting registry: %s", err)
+	}
+	return nil
+}
+
+// Image returns the reference of image in the cluster once it has been pushed
+// to the registry. The registry host of image is replaced with Host, e.g.
+// hashicorp/consul-k8s:dev becomes localhost:5000/hashicorp/consul-k8s:dev.
+func Image(image string) string {
+	return fmt.Sprintf("%s/%s", Host, repository(image))
+}
+
+// repository returns image without its registry host, if it has one.
+// Like docker, it treats the first component of image as a registry host
+// if it contains a dot or a colon or is localhost.
+func repository(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[1]
+	}
+	return image
+}
+
+// portForward forwards localPort to the registry service. It returns
+// once the port-forward is ready and a function to stop it.
+func (r *Registry) portForward(localPort int) (func(), error) {
+	cmd := r.kubectl("port-forward", "service/registry", "--namespace", namespace, fmt.Sprintf("%d:5000", localPort))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("port-forwarding to registry: %s", err)
+	}
+	stop := func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+
+	// kubectl prints a line starting with "Forwarding from" once it's ready.
+	ready := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "Forwarding from") {
+				close(ready)
+				break
+			}
+		}
+		// Keep draining the output so that kubectl doesn't block.
+		io.Copy(ioutil.Discard, stdout)
+	}()
+
+	select {
+	case <-ready:
+		return stop, nil
+	case <-time.After(portForwardTimeout):
+		stop()
+		return nil, fmt.Errorf("port-forward to registry wasn't ready after %s", portForwardTimeout)
+	}
+}
+
+// kubectl returns a kubectl command with args for the cluster of the registry.
+func (r *Registry) kubectl(args ...string) *exec.Cmd {
+	var cmdArgs []string
+	if r.kubeContext != "" {
+		cmdArgs = append(cmdArgs, "--context", r.kubeContext)
+	}
+	if r.kubeconfig != "" {
+		cmdArgs = append(cmdArgs, "--kubeconfig", r.kubeconfig)
+	}
+	return exec.Command("kubectl", append(cmdArgs, args...)...)
+}
+
+// run runs cmd, streaming its output so that progress is visible.
+func run(cmd *exec.Cmd) error {
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
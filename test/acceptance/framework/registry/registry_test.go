@@ -0,0 +1,22 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImage(t *testing.T) {
+	cases := map[string]string{
+		"consul-k8s-dev":                         "localhost:5000/consul-k8s-dev",
+		"hashicorp/consul-k8s:dev":               "localhost:5000/hashicorp/consul-k8s:dev",
+		"docker.io/hashicorp/consul:1.9.5":       "localhost:5000/hashicorp/consul:1.9.5",
+		"localhost/consul-k8s:dev":               "localhost:5000/consul-k8s:dev",
+		"registry.example.com:5000/envoy:1.16.0": "localhost:5000/envoy:1.16.0",
+	}
+	for image, expected := range cases {
+		t.Run(image, func(t *testing.T) {
+			require.Equal(t, expected, Image(image))
+		})
+	}
+}
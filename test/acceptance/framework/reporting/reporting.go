@@ -0,0 +1,340 @@
+// Package reporting records the results of the tests of a package and writes
+// them as a JUnit XML report and a JSON summary, so that CI systems can show
+// them without parsing the output of go test.
+package reporting
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The statuses of a test.
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+	// StatusFlaky is the status of a test that failed, but passed when it
+	// was retried, see MarkFlaky.
+	StatusFlaky = "flaky"
+)
+
+var (
+	// flakyLock protects flakyTests.
+	flakyLock  sync.Mutex
+	flakyTests = make(map[string]bool)
+)
+
+// MarkFlaky marks the tests with names as flaky, i.e. they failed, but were
+// retried until they passed, e.g. by helpers.RunWithRetries. Recorders report
+// these tests with StatusFlaky instead of StatusFail.
+func MarkFlaky(names ...string) {
+	flakyLock.Lock()
+	defer flakyLock.Unlock()
+	for _, name := range names {
+		flakyTests[name] = true
+	}
+}
+
+// isFlaky returns true if the test with name has been marked as flaky.
+func isFlaky(name string) bool {
+	flakyLock.Lock()
+	defer flakyLock.Unlock()
+	return flakyTests[name]
+}
+
+// Result is the result of a test.
+type Result struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Duration float64 `json:"duration_seconds"`
+	// Retries is the number of times the test has run
+	// before its last run, e.g. with go test -count.
+	Retries int `json:"retries"`
+	// Artifacts are the paths of the debug artifacts of the test,
+	// relative to the directory of the report.
+	Artifacts []string `json:"artifacts,omitempty"`
+
+	start time.Time
+}
+
+// Summary is the JSON summary of the tests of a package.
+type Summary struct {
+	Package  string    `json:"package"`
+	Start    time.Time `json:"start"`
+	Duration float64   `json:"duration_seconds"`
+	Passed   int       `json:"passed"`
+	Failed   int       `json:"failed"`
+	Skipped  int       `json:"skipped"`
+	Flaky    int       `json:"flaky"`
+	Tests    []*Result `json:"tests"`
+}
+
+// Recorder records the results of the tests of a package.
+type Recorder struct {
+	pkg            string
+	debugDirectory string
+	start          time.Time
+
+	// lock protects results and tracked.
+	lock    sync.Mutex
+	results map[string]*Result
+	// tracked are the runs of tests that Track has been called for.
+	tracked map[*testing.T]bool
+}
+
+// NewRecorder returns a Recorder for the tests of the package in the current
+// directory that write their debug artifacts to debugDirectory.
+func NewRecorder(debugDirectory string) (*Recorder, error) {
+	// The tests of a package run in its directory.
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return newRecorder(filepath.Base(dir), debugDirectory), nil
+}
+
+func newRecorder(pkg, debugDirectory string) *Recorder {
+	return &Recorder{
+		pkg:            pkg,
+		debugDirectory: debugDirectory,
+		start:          time.Now(),
+		results:        make(map[string]*Result),
+		tracked:        make(map[*testing.T]bool),
+	}
+}
+
+// Track records the result of t when it finishes. The duration of the test
+// is measured from the first time it's tracked. Tracking a test more than once is a no-op.
+func (r *Recorder) Track(t *testing.T) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.tracked[t] {
+		return
+	}
+	r.tracked[t] = true
+
+	start := time.Now()
+	// Cleanup functions run in reverse order, so this runs after the cleanup of
+	// anything the test sets up later, which can also fail the test.
+	t.Cleanup(func() {
+		status := StatusPass
+		if t.Skipped() {
+			status = StatusSkip
+		} else if t.Failed() {
+			status = StatusFail
+		}
+		r.record(t.Name(), status, start, time.Since(start))
+	})
+}
+
+// record records the result of a run of the test with name.
+func (r *Recorder) record(name, status string, start time.Time, duration time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	result, ok := r.results[name]
+	if ok {
+		result.Retries++
+	} else {
+		result = &Result{Name: name, start: start}
+		r.results[name] = result
+	}
+	result.Status = status
+	result.Duration = duration.Seconds()
+}
+
+// Write writes the JUnit XML report and the JSON summary of the tests that have
+// finished to <package>.xml and <package>.json in dir, creating dir if necessary.
+func (r *Recorder) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	summary, err := r.summary(dir)
+	if err != nil {
+		return err
+	}
+
+	summaryJSON, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, r.pkg+".json"), summaryJSON, 0644); err != nil {
+		return err
+	}
+
+	junitXML, err := xml.MarshalIndent(junitReport(summary), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, r.pkg+".xml"), append([]byte(xml.Header), junitXML...), 0644)
+}
+
+// summary returns the summary of the tests that have finished, ordered by the time
+// they started, with their artifacts relative to reportDir.
+func (r *Recorder) summary(reportDir string) (*Summary, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	summary := &Summary{
+		Package:  r.pkg,
+		Start:    r.start,
+		Duration: time.Since(r.start).Seconds(),
+	}
+	for _, result := range r.results {
+		artifacts, err := r.artifacts(result.Name, reportDir)
+		if err != nil {
+			return nil, err
+		}
+		result.Artifacts = artifacts
+		// A test is marked as flaky once it passes on a retry,
+		// which can be after its failed run has been recorded.
+		if result.Status == StatusFail && isFlaky(result.Name) {
+			result.Status = StatusFlaky
+		}
+
+		summary.Tests = append(summary.Tests, result)
+		switch result.Status {
+		case StatusPass:
+			summary.Passed++
+		case StatusFail:
+			summary.Failed++
+		case StatusSkip:
+			summary.Skipped++
+		case StatusFlaky:
+			summary.Flaky++
+		}
+	}
+	sort.Slice(summary.Tests, func(i, j int) bool {
+		return summary.Tests[i].start.Before(summary.Tests[j].start)
+	})
+	return summary, nil
+}
+
+// artifacts returns the paths of the debug artifacts of the test with name relative
+// to reportDir. The tests write their artifacts to <debug directory>/<test name>/<kube context>/,
+// so the directories of subtests, which are next to the context directories, are skipped.
+func (r *Recorder) artifacts(name, reportDir string) ([]string, error) {
+	testDir := filepath.Join(r.debugDirectory, filepath.FromSlash(name))
+	entries, err := ioutil.ReadDir(testDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	absReportDir, err := filepath.Abs(reportDir)
+	if err != nil {
+		return nil, err
+	}
+	var artifacts []string
+	for _, entry := range entries {
+		if !entry.IsDir() || r.results[name+"/"+entry.Name()] != nil {
+			continue
+		}
+		err := filepath.Walk(filepath.Join(testDir, entry.Name()), func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			link, err := filepath.Rel(absReportDir, absPath)
+			if err != nil {
+				return err
+			}
+			artifacts = append(artifacts, filepath.ToSlash(link))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return artifacts, nil
+}
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName  string          `xml:"classname,attr"`
+	Name       string          `xml:"name,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitMessage   `xml:"failure,omitempty"`
+	Skipped    *junitMessage   `xml:"skipped,omitempty"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// junitReport converts summary to a JUnit XML report with a test suite for the package.
+// The retries and artifacts of each test are properties of its test case, and the
+// artifacts are also attachments in its output, which some CI systems link to.
+// JUnit XML has no status for flaky tests, so they pass with a flaky property.
+func junitReport(summary *Summary) junitTestSuites {
+	suite := junitTestSuite{
+		Name:      summary.Package,
+		Tests:     len(summary.Tests),
+		Failures:  summary.Failed,
+		Skipped:   summary.Skipped,
+		Time:      junitTime(summary.Duration),
+		Timestamp: summary.Start.Format(time.RFC3339),
+	}
+	for _, result := range summary.Tests {
+		testCase := junitTestCase{
+			ClassName:  summary.Package,
+			Name:       result.Name,
+			Time:       junitTime(result.Duration),
+			Properties: []junitProperty{{Name: "retries", Value: fmt.Sprint(result.Retries)}},
+		}
+		for _, artifact := range result.Artifacts {
+			testCase.Properties = append(testCase.Properties, junitProperty{Name: "artifact", Value: artifact})
+			testCase.SystemOut += fmt.Sprintf("[[ATTACHMENT|%s]]\n", artifact)
+		}
+		switch result.Status {
+		case StatusFail:
+			testCase.Failure = &junitMessage{Message: "test failed, see the test output and debug artifacts"}
+		case StatusSkip:
+			testCase.Skipped = &junitMessage{Message: "test skipped"}
+		case StatusFlaky:
+			testCase.Properties = append(testCase.Properties, junitProperty{Name: "flaky", Value: "true"})
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	return junitTestSuites{Suites: []junitTestSuite{suite}}
+}
+
+// junitTime formats seconds the way JUnit XML reports do.
+func junitTime(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
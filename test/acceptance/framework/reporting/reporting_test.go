@@ -0,0 +1,114 @@
+package reporting
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reporting")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	debugDirectory := filepath.Join(dir, "debug")
+	reportDirectory := filepath.Join(dir, "report")
+
+	// The passing test has debug artifacts in a context directory, including
+	// streamed logs, and a subtest with artifacts of its own.
+	writeFile(t, filepath.Join(debugDirectory, "TestRecorder", "passes", "kind-dc1", "pods.txt"))
+	writeFile(t, filepath.Join(debugDirectory, "TestRecorder", "passes", "kind-dc1", "logs", "server-0-consul.log"))
+	writeFile(t, filepath.Join(debugDirectory, "TestRecorder", "passes", "subtest", "kind-dc1", "events.txt"))
+
+	r := newRecorder("reporting", debugDirectory)
+	t.Run("passes", func(t *testing.T) {
+		r.Track(t)
+		// Tracking the same test again is a no-op.
+		r.Track(t)
+		t.Run("subtest", func(t *testing.T) {
+			r.Track(t)
+		})
+	})
+	t.Run("skips", func(t *testing.T) {
+		r.Track(t)
+		t.Skip("skipped by the test")
+	})
+	// A failed test can't be simulated without failing this test,
+	// so the results of a flaky test are recorded directly.
+	start := r.start
+	r.record("TestRecorder/flaky", StatusFail, start, 0)
+	r.record("TestRecorder/flaky", StatusPass, start, 0)
+	r.record("TestRecorder/fails", StatusFail, start, 0)
+	// The failed attempt of a test retried with helpers.RunWithRetries is
+	// marked as flaky after it's recorded, once a later attempt passes.
+	r.record("TestRecorder/retried/attempt-1", StatusFail, start, 0)
+	r.record("TestRecorder/retried/attempt-2", StatusPass, start, 0)
+	MarkFlaky("TestRecorder/retried/attempt-1")
+
+	require.NoError(t, r.Write(reportDirectory))
+
+	summaryJSON, err := ioutil.ReadFile(filepath.Join(reportDirectory, "reporting.json"))
+	require.NoError(t, err)
+	var summary Summary
+	require.NoError(t, json.Unmarshal(summaryJSON, &summary))
+	require.Equal(t, "reporting", summary.Package)
+	require.Equal(t, 4, summary.Passed)
+	require.Equal(t, 1, summary.Failed)
+	require.Equal(t, 1, summary.Skipped)
+	require.Equal(t, 1, summary.Flaky)
+
+	results := make(map[string]*Result)
+	for _, result := range summary.Tests {
+		results[result.Name] = result
+	}
+	require.Len(t, results, 7)
+	require.Equal(t, StatusPass, results["TestRecorder/passes"].Status)
+	require.ElementsMatch(t, []string{
+		"../debug/TestRecorder/passes/kind-dc1/pods.txt",
+		"../debug/TestRecorder/passes/kind-dc1/logs/server-0-consul.log",
+	}, results["TestRecorder/passes"].Artifacts)
+	require.Equal(t, []string{"../debug/TestRecorder/passes/subtest/kind-dc1/events.txt"}, results["TestRecorder/passes/subtest"].Artifacts)
+	require.Equal(t, StatusSkip, results["TestRecorder/skips"].Status)
+	require.Equal(t, StatusPass, results["TestRecorder/flaky"].Status)
+	require.Equal(t, 1, results["TestRecorder/flaky"].Retries)
+	require.Equal(t, 0, results["TestRecorder/fails"].Retries)
+	require.Equal(t, StatusFail, results["TestRecorder/fails"].Status)
+	require.Equal(t, StatusFlaky, results["TestRecorder/retried/attempt-1"].Status)
+	require.Equal(t, StatusPass, results["TestRecorder/retried/attempt-2"].Status)
+
+	junitXML, err := ioutil.ReadFile(filepath.Join(reportDirectory, "reporting.xml"))
+	require.NoError(t, err)
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(junitXML, &report))
+	require.Len(t, report.Suites, 1)
+	suite := report.Suites[0]
+	require.Equal(t, "reporting", suite.Name)
+	require.Equal(t, 7, suite.Tests)
+	require.Equal(t, 1, suite.Failures)
+	require.Equal(t, 1, suite.Skipped)
+
+	testCases := make(map[string]junitTestCase)
+	for _, testCase := range suite.TestCases {
+		testCases[testCase.Name] = testCase
+	}
+	require.NotNil(t, testCases["TestRecorder/fails"].Failure)
+	require.Nil(t, testCases["TestRecorder/fails"].Skipped)
+	require.NotNil(t, testCases["TestRecorder/skips"].Skipped)
+	require.Nil(t, testCases["TestRecorder/retried/attempt-1"].Failure)
+	require.Contains(t, testCases["TestRecorder/retried/attempt-1"].Properties, junitProperty{Name: "flaky", Value: "true"})
+	require.Contains(t, testCases["TestRecorder/flaky"].Properties, junitProperty{Name: "retries", Value: "1"})
+	require.Contains(t, testCases["TestRecorder/passes/subtest"].Properties,
+		junitProperty{Name: "artifact", Value: "../debug/TestRecorder/passes/subtest/kind-dc1/events.txt"})
+	require.Equal(t, "[[ATTACHMENT|../debug/TestRecorder/passes/subtest/kind-dc1/events.txt]]\n", testCases["TestRecorder/passes/subtest"].SystemOut)
+}
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte("artifact"), 0644))
+}
@@ -0,0 +1,133 @@
+// Package resume records the tests that have passed in a state file so that
+// an interrupted test run can be resumed without running them again.
+package resume
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// State is the set of tests that have passed with a test configuration,
+// backed by a state file. Each line of the file is a test that has passed,
+// prefixed with the fingerprint of the configuration it passed with and the
+// name of its package, since test names are only unique within a package.
+// The test packages run in separate processes that append to the same file.
+type State struct {
+	path        string
+	fingerprint string
+	pkg         string
+	// skip is true if completed tests should be skipped.
+	skip bool
+
+	// lock protects completed and tracked.
+	lock      sync.Mutex
+	completed map[string]bool
+	// tracked are the tests that Track has been called for.
+	tracked map[string]bool
+}
+
+// Load reads the tests that have passed with the configuration identified by
+// fingerprint from the state file at path. The file doesn't need to exist.
+// If skip is true, Track skips tests that have passed; otherwise, it only records
+// the tests that pass so that a later run can skip them.
+func Load(path, fingerprint string, skip bool) (*State, error) {
+	// The tests of a package run in its directory.
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &State{
+		path:        path,
+		fingerprint: fingerprint,
+		pkg:         filepath.Base(dir),
+		skip:        skip,
+		completed:   make(map[string]bool),
+		tracked:     make(map[string]bool),
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		s.completed[strings.TrimSpace(scanner.Text())] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %s", path, err)
+	}
+	return s, nil
+}
+
+// Track skips t if it has passed before and resuming is enabled. Otherwise,
+// it records t in the state file if t passes. Tracking a test more than once is a no-op.
+func (s *State) Track(t *testing.T) {
+	t.Helper()
+
+	s.lock.Lock()
+	if s.tracked[t.Name()] {
+		s.lock.Unlock()
+		return
+	}
+	s.tracked[t.Name()] = true
+	completed := s.completed[s.key(t.Name())]
+	s.lock.Unlock()
+
+	if completed && s.skip {
+		t.Skipf("skipping this test because it passed in a previous run recorded in %s", s.path)
+	}
+
+	// Cleanup functions run in reverse order, so this runs after the cleanup of
+	// anything the test sets up later, which can also fail the test.
+	t.Cleanup(func() {
+		if t.Failed() || t.Skipped() {
+			return
+		}
+		if err := s.record(t.Name()); err != nil {
+			t.Logf("failed to record test in %s: %s", s.path, err)
+		}
+	})
+}
+
+// record appends the test with name to the state file.
+func (s *State) record(name string) error {
+	key := s.key(name)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.completed[key] {
+		return nil
+	}
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	// A single write of a line is atomic in append mode, so processes
+	// of other packages can append to the file at the same time.
+	if _, err := f.WriteString(key + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	s.completed[key] = true
+	return nil
+}
+
+// key returns the line for the test with name in the state file.
+func (s *State) key(name string) string {
+	return fmt.Sprintf("%s %s.%s", s.fingerprint, s.pkg, name)
+}
@@ -0,0 +1,61 @@
+package resume
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestState_Track(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resume")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state")
+
+	// run runs a subtest tracked with state and returns whether its body ran.
+	run := func(state *State, name string, skip bool) bool {
+		ran := false
+		t.Run(name, func(t *testing.T) {
+			state.Track(t)
+			// Tracking the same test again is a no-op.
+			state.Track(t)
+			ran = true
+			if skip {
+				t.Skip("skipped by the test")
+			}
+		})
+		return ran
+	}
+
+	// The first run records the tests that pass, but not the skipped ones.
+	state, err := Load(path, "abc", false)
+	require.NoError(t, err)
+	require.True(t, run(state, "passes", false))
+	require.True(t, run(state, "skips", true))
+
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "abc resume.TestState_Track/passes\n", string(contents))
+
+	// Record tests from a previous run, one of them with a different configuration.
+	previous := "abc resume.TestState_Track/rerun\nabc resume.TestState_Track/resumed\ndef resume.TestState_Track/other-config\n"
+	require.NoError(t, ioutil.WriteFile(path, []byte(previous), 0644))
+
+	// Without resuming, recorded tests run again and aren't recorded twice.
+	state, err = Load(path, "abc", false)
+	require.NoError(t, err)
+	require.True(t, run(state, "rerun", false))
+
+	// Resuming skips the recorded tests, but not tests recorded with another configuration.
+	state, err = Load(path, "abc", true)
+	require.NoError(t, err)
+	require.False(t, run(state, "resumed", false))
+	require.True(t, run(state, "other-config", false))
+
+	contents, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, previous+"abc resume.TestState_Track/other-config\n", string(contents))
+}
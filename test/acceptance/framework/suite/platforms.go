@@ -0,0 +1,149 @@
+package suite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkImagePlatforms returns an error if any of the images configured with flags
+// isn't built for the OS and architecture of every node in the Kubernetes clusters.
+// Otherwise, pods of such images crash with exec format errors that are hard to
+// trace back to the image. Images whose platforms can't be determined with docker,
+// e.g. because docker isn't installed, are skipped with a warning.
+func (s *suite) checkImagePlatforms() error {
+	images := s.configuredImages()
+	if len(images) == 0 {
+		return nil
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		fmt.Println("Warning: not checking the platforms of the images because docker isn't installed")
+		return nil
+	}
+
+	nodePlatforms := make(map[string]bool)
+	for _, env := range s.cfg.KubeEnvs() {
+		client, err := kubernetesClient(env.Kubeconfig, env.KubeContext)
+		if err != nil {
+			return err
+		}
+		nodes, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes.Items {
+			nodePlatforms[platform(node.Status.NodeInfo.OperatingSystem, node.Status.NodeInfo.Architecture)] = true
+		}
+	}
+
+	for _, image := range images {
+		platforms, err := imagePlatforms(image)
+		if err != nil {
+			fmt.Printf("Warning: not checking the platforms of image %s: %s\n", image, err)
+			continue
+		}
+		if missing := missingPlatforms(platforms, nodePlatforms); len(missing) > 0 {
+			return fmt.Errorf("image %s is built for %s but the clusters have nodes running %s",
+				image, strings.Join(platforms, ", "), strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
+
+// configuredImages returns the images set with flags.
+func (s *suite) configuredImages() []string {
+	candidates := []string{s.cfg.ConsulImage, s.cfg.ConsulK8SImage, s.cfg.EnvoyImage}
+	candidates = append(candidates, s.cfg.ConsulImages...)
+	candidates = append(candidates, s.cfg.CanaryImages...)
+
+	var images []string
+	for _, image := range candidates {
+		if image != "" {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// imagePlatforms returns the platforms, e.g. linux/amd64, that image is built for
+// according to its manifest in its registry, or the local docker daemon if it's
+// not in a registry, such as locally built images loaded into kind clusters.
+func imagePlatforms(image string) ([]string, error) {
+	out, err := exec.Command("docker", "manifest", "inspect", "--verbose", image).Output()
+	if err == nil {
+		return parseManifestPlatforms(out)
+	}
+
+	out, localErr := exec.Command("docker", "image", "inspect", "--format", "{{.Os}}/{{.Architecture}}", image).Output()
+	if localErr != nil {
+		return nil, fmt.Errorf("image isn't in a registry or the local docker daemon: %s", err)
+	}
+	return []string{strings.TrimSpace(string(out))}, nil
+}
+
+// parseManifestPlatforms returns the platforms in the output of docker manifest inspect --verbose,
+// which is a list of manifests for multi-platform images and a single manifest otherwise.
+func parseManifestPlatforms(out []byte) ([]string, error) {
+	type manifest struct {
+		Descriptor struct {
+			Platform struct {
+				Architecture string `json:"architecture"`
+				OS           string `json:"os"`
+			} `json:"platform"`
+		}
+	}
+
+	var manifests []manifest
+	out = bytes.TrimSpace(out)
+	if bytes.HasPrefix(out, []byte("[")) {
+		if err := json.Unmarshal(out, &manifests); err != nil {
+			return nil, err
+		}
+	} else {
+		var m manifest
+		if err := json.Unmarshal(out, &m); err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+
+	var platforms []string
+	for _, m := range manifests {
+		// Attestation manifests have an unknown platform.
+		if m.Descriptor.Platform.OS == "" || m.Descriptor.Platform.OS == "unknown" {
+			continue
+		}
+		platforms = append(platforms, platform(m.Descriptor.Platform.OS, m.Descriptor.Platform.Architecture))
+	}
+	if len(platforms) == 0 {
+		return nil, fmt.Errorf("manifest has no platforms")
+	}
+	return platforms, nil
+}
+
+// missingPlatforms returns the sorted node platforms that aren't in imagePlatforms.
+func missingPlatforms(imagePlatforms []string, nodePlatforms map[string]bool) []string {
+	supported := make(map[string]bool)
+	for _, p := range imagePlatforms {
+		supported[p] = true
+	}
+	var missing []string
+	for p := range nodePlatforms {
+		if !supported[p] {
+			missing = append(missing, p)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// platform returns the platform string for os and arch, e.g. linux/amd64.
+func platform(os, arch string) string {
+	return fmt.Sprintf("%s/%s", os, arch)
+}
@@ -0,0 +1,52 @@
+package suite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseManifestPlatforms(t *testing.T) {
+	cases := map[string]struct {
+		out       string
+		platforms []string
+		err       string
+	}{
+		"single manifest": {
+			out:       `{"Ref": "docker.io/hashicorp/consul:1.9.5", "Descriptor": {"platform": {"architecture": "amd64", "os": "linux"}}}`,
+			platforms: []string{"linux/amd64"},
+		},
+		"manifest list": {
+			out: `[
+  {"Ref": "docker.io/hashicorp/consul:1.9.5@sha256:1", "Descriptor": {"platform": {"architecture": "amd64", "os": "linux"}}},
+  {"Ref": "docker.io/hashicorp/consul:1.9.5@sha256:2", "Descriptor": {"platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}}},
+  {"Ref": "docker.io/hashicorp/consul:1.9.5@sha256:3", "Descriptor": {"platform": {"architecture": "unknown", "os": "unknown"}}}
+]`,
+			platforms: []string{"linux/amd64", "linux/arm64"},
+		},
+		"no platforms": {
+			out: `{"Ref": "docker.io/hashicorp/consul:1.9.5", "Descriptor": {}}`,
+			err: "manifest has no platforms",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			platforms, err := parseManifestPlatforms([]byte(c.out))
+			if c.err != "" {
+				require.EqualError(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.platforms, platforms)
+		})
+	}
+}
+
+func TestMissingPlatforms(t *testing.T) {
+	nodePlatforms := map[string]bool{"linux/amd64": true, "linux/arm64": true}
+
+	require.Empty(t, missingPlatforms([]string{"linux/amd64", "linux/arm64", "linux/s390x"}, nodePlatforms))
+	require.Equal(t, []string{"linux/arm64"}, missingPlatforms([]string{"linux/amd64"}, nodePlatforms))
+	require.Equal(t, []string{"linux/amd64", "linux/arm64"}, missingPlatforms([]string{"windows/amd64"}, nodePlatforms))
+}
@@ -0,0 +1,110 @@
+package suite
+
+import (
+	"flag"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// retryState records the results of the tests that request a Kubernetes cluster
+// so that the tests of a package can be run again to retry the tests that failed
+// an attempt of helpers.RunWithRetries, without running the tests that passed again.
+type retryState struct {
+	lock sync.Mutex
+	// retrying is true once the tests run again.
+	retrying bool
+	// failFast is true if -failfast was set when the tests first ran.
+	failFast bool
+	// passed are the tests that have passed in any run.
+	passed map[string]bool
+	// failed are the tests that have failed in the current run.
+	failed map[string]bool
+	// tracked are the runs of tests that Track has been called for.
+	tracked map[*testing.T]bool
+}
+
+func newRetryState() *retryState {
+	return &retryState{
+		passed:  make(map[string]bool),
+		failed:  make(map[string]bool),
+		tracked: make(map[*testing.T]bool),
+	}
+}
+
+// Track records the result of t when it finishes. When the tests run again,
+// it skips t if it has passed before, or if another test has failed and -failfast
+// is set. Tracking a test more than once is a no-op.
+func (s *retryState) Track(t *testing.T) {
+	t.Helper()
+
+	s.lock.Lock()
+	if s.tracked[t] {
+		s.lock.Unlock()
+		return
+	}
+	s.tracked[t] = true
+	passed := s.retrying && s.passed[t.Name()]
+	stopped := s.retrying && s.failFast && len(s.failed) > 0
+	s.lock.Unlock()
+
+	if passed {
+		t.Skip("skipping this test because it passed before the failed tests were retried")
+	}
+	if stopped {
+		t.Skip("skipping this test because a test has failed and -failfast is set")
+	}
+
+	t.Cleanup(func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if t.Failed() {
+			s.failed[t.Name()] = true
+		} else if !t.Skipped() {
+			s.passed[t.Name()] = true
+		}
+	})
+}
+
+// retry returns true if the tests should run again because tests run with
+// helpers.RunWithRetries have failed with attempts left, and the tests that
+// have failed in the current run are retryable, or subtests or parents of them.
+func (s *retryState) retry(retryable []string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(retryable) == 0 {
+		return false
+	}
+	for name := range s.failed {
+		if !retriedBy(name, retryable) {
+			return false
+		}
+	}
+
+	if !s.retrying {
+		// The testing package counts the failed attempts for -failfast until the
+		// process exits, so it would skip all tests when they run again.
+		// Track stops running tests after a failure instead.
+		if f := flag.Lookup("test.failfast"); f != nil {
+			s.failFast = f.Value.String() == "true"
+			if err := f.Value.Set("false"); err != nil {
+				return false
+			}
+		}
+		s.retrying = true
+	}
+	s.failed = make(map[string]bool)
+	return true
+}
+
+// retriedBy returns true if the test with name is one of the retryable
+// tests, or a subtest or parent of one of them.
+func retriedBy(name string, retryable []string) bool {
+	for _, retried := range retryable {
+		if name == retried || strings.HasPrefix(name, retried+"/") || strings.HasPrefix(retried, name+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package suite
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryState_retry(t *testing.T) {
+	cases := map[string]struct {
+		failed    []string
+		retryable []string
+		expRetry  bool
+	}{
+		"no retryable tests": {
+			failed: []string{"TestA/attempt-1"},
+		},
+		"only retryable tests failed": {
+			failed:    []string{"TestA", "TestA/attempt-1", "TestA/attempt-1/secure"},
+			retryable: []string{"TestA"},
+			expRetry:  true,
+		},
+		"retryable subtest failed": {
+			failed:    []string{"TestA", "TestA/secure", "TestA/secure/attempt-1"},
+			retryable: []string{"TestA/secure"},
+			expRetry:  true,
+		},
+		"another test failed": {
+			failed:    []string{"TestA/attempt-1", "TestB"},
+			retryable: []string{"TestA"},
+		},
+		"test with a similar name failed": {
+			failed:    []string{"TestA/attempt-1", "TestAB"},
+			retryable: []string{"TestA"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := newRetryState()
+			for _, failed := range c.failed {
+				s.failed[failed] = true
+			}
+			require.Equal(t, c.expRetry, s.retry(c.retryable))
+			require.Equal(t, c.expRetry, s.retrying)
+			if c.expRetry {
+				require.Empty(t, s.failed)
+			}
+		})
+	}
+}
+
+func TestRetryState_Track(t *testing.T) {
+	s := newRetryState()
+	var ran []string
+	run := func(name string) {
+		t.Run(name, func(t *testing.T) {
+			s.Track(t)
+			ran = append(ran, name)
+		})
+	}
+
+	run("passes")
+	require.True(t, s.passed[t.Name()+"/passes"])
+	// A failed test can't be simulated without failing this test,
+	// so the failure of a retryable test is recorded directly.
+	s.failed[t.Name()+"/flaky"] = true
+	require.True(t, s.retry([]string{t.Name() + "/flaky"}))
+
+	// When the tests run again, the tests that passed are skipped.
+	// Subtests with the same name get a suffix, so the passed test is recorded directly.
+	s.passed[t.Name()+"/passed-before"] = true
+	ran = nil
+	run("passed-before")
+	run("flaky")
+	require.Equal(t, []string{"flaky"}, ran)
+
+	// With -failfast, the tests after a failure are skipped.
+	s.failFast = true
+	s.failed[t.Name()+"/fails"] = true
+	run("skipped")
+	require.Equal(t, []string{"flaky"}, ran)
+}
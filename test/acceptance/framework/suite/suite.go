@@ -1,27 +1,70 @@
 package suite
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/flags"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/registry"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/reporting"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/resume"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 type suite struct {
-	m     *testing.M
+	m     runner
 	env   *environment.KubernetesEnvironment
 	cfg   *config.TestConfig
 	flags *flags.TestFlags
+
+	// resume records the tests that pass if -resume-file is set.
+	resume *resume.State
+	// report records the results of the tests if -report-directory is set.
+	report *reporting.Recorder
+	// retries records the results of the tests to retry the ones
+	// that failed an attempt of helpers.RunWithRetries.
+	retries *retryState
+
+	minNodes int
+
+	beforeAll []func() error
+	afterAll  []func() error
+}
+
+// runner runs the tests. It's implemented by *testing.M.
+type runner interface {
+	Run() int
 }
 
 type Suite interface {
 	Run() int
 	Environment() environment.TestEnvironment
 	Config() *config.TestConfig
+	// RequireMinimumNodes makes Run fail before running any tests
+	// if any of the Kubernetes clusters has fewer than count nodes.
+	// Kind clusters are exempt because they are single-node.
+	RequireMinimumNodes(count int)
+	// BeforeAll registers hook to run once before any tests of the suite run,
+	// after the clusters and images have been set up, e.g. to create fixtures
+	// that all tests share. Hooks run in the order they are registered.
+	// If a hook fails, Run fails without running the tests.
+	BeforeAll(hook func() error)
+	// AfterAll registers hook to run once after all tests of the suite have run,
+	// e.g. to delete shared fixtures. Hooks run in the reverse order they are registered.
+	// They run even if a BeforeAll hook has failed, so they must tolerate setup
+	// that didn't happen. If a hook fails, Run fails.
+	AfterAll(hook func() error)
 }
 
 func NewSuite(m *testing.M) Suite {
@@ -30,12 +73,14 @@ func NewSuite(m *testing.M) Suite {
 	flag.Parse()
 
 	testConfig := flags.TestConfigFromFlags()
+	logger.SetFormat(testConfig.LogFormat)
 
 	return &suite{
-		m:     m,
-		env:   environment.NewKubernetesEnvironmentFromConfig(testConfig),
-		cfg:   testConfig,
-		flags: flags,
+		m:       m,
+		env:     environment.NewKubernetesEnvironmentFromConfig(testConfig),
+		cfg:     testConfig,
+		flags:   flags,
+		retries: newRetryState(),
 	}
 }
 
@@ -56,11 +101,297 @@ func (s *suite) Run() int {
 		}
 	}
 
-	return s.m.Run()
+	if s.cfg.ResumeFile != "" {
+		s.resume, err = resume.Load(s.cfg.ResumeFile, s.cfg.Fingerprint(), s.cfg.Resume)
+		if err != nil {
+			fmt.Printf("Failed to load the resume state: %s\n", err)
+			return 1
+		}
+	}
+
+	if s.cfg.ReportDirectory != "" {
+		s.report, err = reporting.NewRecorder(s.cfg.DebugDirectory)
+		if err != nil {
+			fmt.Printf("Failed to set up the test report: %s\n", err)
+			return 1
+		}
+		defer func() {
+			if err := s.report.Write(s.cfg.ReportDirectory); err != nil {
+				fmt.Printf("Failed to write the test report: %s\n", err)
+			}
+		}()
+	}
+
+	if s.cfg.ConsulVersionCanary {
+		s.cfg.CanaryImages, err = s.cfg.ResolveCanaryImages()
+		if err != nil {
+			fmt.Printf("Failed to resolve Consul images for the version canary: %s\n", err)
+			return 1
+		}
+		fmt.Printf("Running version canary with Consul images %s\n", strings.Join(s.cfg.CanaryImages, ", "))
+	}
+
+	if s.cfg.Provider != "" {
+		provider := s.clusterProvider()
+		clusters, err := s.provisionClusters(provider)
+		// Delete any clusters that have been created, even if some of them failed.
+		defer func() {
+			for _, cluster := range clusters {
+				if err := provider.DeleteCluster(cluster); err != nil {
+					fmt.Printf("Failed to delete cluster: %s\n", err)
+				}
+			}
+		}()
+		if err != nil {
+			fmt.Printf("Failed to provision clusters: %s\n", err)
+			return 1
+		}
+	}
+
+	// Check the images before they are replaced with copies in the local registry.
+	if err := s.checkImagePlatforms(); err != nil {
+		fmt.Printf("Images don't support the cluster nodes: %s\n", err)
+		return 1
+	}
+
+	if s.cfg.UseLocalRegistry {
+		registries, err := s.deployRegistries()
+		defer func() {
+			for _, r := range registries {
+				if err := r.Delete(); err != nil {
+					fmt.Printf("Failed to delete registry: %s\n", err)
+				}
+			}
+		}()
+		if err != nil {
+			fmt.Printf("Failed to set up local registry: %s\n", err)
+			return 1
+		}
+	}
+
+	if s.minNodes > 0 && !s.cfg.UseKind {
+		if err := s.checkMinimumNodes(); err != nil {
+			fmt.Printf("Cluster is too small to run the tests: %s\n", err)
+			return 1
+		}
+	}
+
+	return s.runTests()
+}
+
+// runTests runs the BeforeAll hooks, the tests, and the AfterAll hooks
+// and returns the exit code. If tests run with helpers.RunWithRetries fail
+// with attempts left, and no other tests fail, the tests run again to retry
+// them, skipping the tests that have passed.
+func (s *suite) runTests() (code int) {
+	defer func() {
+		for i := len(s.afterAll) - 1; i >= 0; i-- {
+			if err := s.afterAll[i](); err != nil {
+				fmt.Printf("AfterAll hook failed: %s\n", err)
+				code = 1
+			}
+		}
+	}()
+
+	for _, hook := range s.beforeAll {
+		if err := hook(); err != nil {
+			fmt.Printf("BeforeAll hook failed: %s\n", err)
+			return 1
+		}
+	}
+
+	code = s.m.Run()
+	for code != 0 && s.retries.retry(helpers.RetryableFailures()) {
+		fmt.Println("Running the tests again to retry the failed tests that have attempts left")
+		code = s.m.Run()
+	}
+	return code
+}
+
+func (s *suite) RequireMinimumNodes(count int) {
+	s.minNodes = count
+}
+
+func (s *suite) BeforeAll(hook func() error) {
+	s.beforeAll = append(s.beforeAll, hook)
+}
+
+func (s *suite) AfterAll(hook func() error) {
+	s.afterAll = append(s.afterAll, hook)
+}
+
+// checkMinimumNodes returns an error if any of the Kubernetes clusters
+// in the environment has fewer than s.minNodes nodes.
+func (s *suite) checkMinimumNodes() error {
+	for i, env := range s.cfg.KubeEnvs() {
+		client, err := kubernetesClient(env.Kubeconfig, env.KubeContext)
+		if err != nil {
+			return err
+		}
+
+		nodes, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		if len(nodes.Items) < s.minNodes {
+			return fmt.Errorf("cluster %d (context %q) has %d node(s) but these tests require at least %d; "+
+				"use a larger cluster or set -nodes if the cluster is created with -provider", i, env.KubeContext, len(nodes.Items), s.minNodes)
+		}
+	}
+	return nil
+}
+
+// kubernetesClient returns a client for the cluster of kubeContext in kubeconfig.
+func kubernetesClient(kubeconfig, kubeContext string) (kubernetes.Interface, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// clusterProvider returns the provider configured by the -provider flag.
+// The flag is validated before this is called.
+func (s *suite) clusterProvider() environment.Provider {
+	switch s.cfg.Provider {
+	case "eks":
+		return &environment.EKSProvider{Region: s.cfg.AWSRegion, Profile: s.cfg.AWSProfile, Nodes: s.cfg.Nodes}
+	case "gke":
+		return &environment.GKEProvider{Project: s.cfg.GCPProject, Zone: s.cfg.GCPZone, Nodes: s.cfg.Nodes}
+	case "aks":
+		return &environment.AKSProvider{ResourceGroup: s.cfg.AzureResourceGroup, Location: s.cfg.AzureLocation, Nodes: s.cfg.Nodes}
+	default:
+		// Load the consul-k8s image into kind clusters so that locally built images can be used.
+		var images []string
+		if s.cfg.ConsulK8SImage != "" {
+			images = append(images, s.cfg.ConsulK8SImage)
+		}
+		return &environment.KindProvider{Images: images}
+	}
+}
+
+// provisionClusters creates a cluster for the default context and,
+// if multi cluster tests are enabled, for the secondary context
+// and points the test environment at them. It returns the clusters that
+// have been created even if it fails so that they can be cleaned up.
+func (s *suite) provisionClusters(provider environment.Provider) ([]*environment.ProvisionedCluster, error) {
+	count := 1
+	if s.cfg.EnableMultiCluster {
+		count = 2
+	}
+
+	kubeconfigDir, err := ioutil.TempDir("", "consul-test-kubeconfig")
+	if err != nil {
+		return nil, err
+	}
+
+	namePrefix := helpers.RandomName()
+	var clusters []*environment.ProvisionedCluster
+	for i := 0; i < count; i++ {
+		cluster, err := provider.CreateCluster(fmt.Sprintf("%s-%d", namePrefix, i), kubeconfigDir)
+		if cluster != nil {
+			clusters = append(clusters, cluster)
+		}
+		if err != nil {
+			return clusters, err
+		}
+	}
+
+	s.cfg.ClusterName = clusters[0].Name
+	s.cfg.Kubeconfig = clusters[0].KubeconfigPath
+	s.cfg.KubeContext = clusters[0].ContextName
+	if s.cfg.EnableMultiCluster {
+		s.cfg.SecondaryKubeconfig = clusters[1].KubeconfigPath
+		s.cfg.SecondaryKubeContext = clusters[1].ContextName
+	}
+	s.env = environment.NewKubernetesEnvironmentFromConfig(s.cfg)
+
+	return clusters, nil
+}
+
+// deployRegistries deploys a registry into each Kubernetes cluster, pushes the
+// images from the config into them, and points the config at the pushed images.
+// It returns the registries that have been deployed even if it fails
+// so that they can be cleaned up.
+func (s *suite) deployRegistries() ([]*registry.Registry, error) {
+	images := []*string{&s.cfg.ConsulK8SImage, &s.cfg.ConsulImage, &s.cfg.EnvoyImage}
+	for i := range s.cfg.ConsulImages {
+		images = append(images, &s.cfg.ConsulImages[i])
+	}
+
+	var registries []*registry.Registry
+	for _, env := range s.cfg.KubeEnvs() {
+		r, err := registry.Deploy(env.Kubeconfig, env.KubeContext)
+		if r != nil {
+			registries = append(registries, r)
+		}
+		if err != nil {
+			return registries, err
+		}
+		for _, image := range images {
+			if *image == "" {
+				continue
+			}
+			if _, err := r.Push(*image); err != nil {
+				return registries, err
+			}
+		}
+	}
+
+	// The images have the same name in every registry.
+	for _, image := range images {
+		if *image != "" {
+			*image = registry.Image(*image)
+		}
+	}
+	return registries, nil
 }
 
 func (s *suite) Environment() environment.TestEnvironment {
-	return s.env
+	// Tests that aren't tagged are skipped first when tests are selected by tags,
+	// so that they aren't tracked at all. The retries are tracked next so that the
+	// tests that they skip because they passed before aren't recorded again.
+	// The report is tracked next so that it also records the tests that resuming skips.
+	// Triage hints are written once the diagnostics of a failed test have been collected,
+	// i.e. after the cleanups that the test registers once it has the environment.
+	trackers := []func(t *testing.T){
+		func(t *testing.T) { helpers.SkipUnlessTagged(t, s.cfg) },
+		s.retries.Track,
+		func(t *testing.T) { k8s.WriteTriageHintsOnFailure(t, s.cfg.DebugDirectory) },
+	}
+	if s.report != nil {
+		trackers = append(trackers, s.report.Track)
+	}
+	if s.resume != nil {
+		trackers = append(trackers, s.resume.Track)
+	}
+	return &trackingEnvironment{TestEnvironment: s.env, trackers: trackers}
+}
+
+// trackingEnvironment tracks the tests that request a Kubernetes cluster,
+// e.g. with the resume state.
+type trackingEnvironment struct {
+	environment.TestEnvironment
+	trackers []func(t *testing.T)
+}
+
+func (e *trackingEnvironment) DefaultContext(t *testing.T) environment.TestContext {
+	e.track(t)
+	return e.TestEnvironment.DefaultContext(t)
+}
+
+func (e *trackingEnvironment) Context(t *testing.T, index int) environment.TestContext {
+	e.track(t)
+	return e.TestEnvironment.Context(t, index)
+}
+
+func (e *trackingEnvironment) track(t *testing.T) {
+	for _, track := range e.trackers {
+		track(t)
+	}
 }
 
 func (s *suite) Config() *config.TestConfig {
@@ -0,0 +1,70 @@
+package suite
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRunner records that the tests have run and returns code.
+type fakeRunner struct {
+	calls *[]string
+	code  int
+}
+
+func (f fakeRunner) Run() int {
+	*f.calls = append(*f.calls, "tests")
+	return f.code
+}
+
+func TestSuite_runTests(t *testing.T) {
+	hook := func(calls *[]string, name string, err error) func() error {
+		return func() error {
+			*calls = append(*calls, name)
+			return err
+		}
+	}
+
+	cases := map[string]struct {
+		testsCode   int
+		beforeErr   error
+		afterErr    error
+		expCalls    []string
+		expExitCode int
+	}{
+		"hooks run around the tests in order": {
+			expCalls:    []string{"before 1", "before 2", "tests", "after 2", "after 1"},
+			expExitCode: 0,
+		},
+		"failing tests": {
+			testsCode:   1,
+			expCalls:    []string{"before 1", "before 2", "tests", "after 2", "after 1"},
+			expExitCode: 1,
+		},
+		"failing BeforeAll hook skips the tests but runs the AfterAll hooks": {
+			beforeErr:   errors.New("failed"),
+			expCalls:    []string{"before 1", "after 2", "after 1"},
+			expExitCode: 1,
+		},
+		"failing AfterAll hook fails the suite and runs the other hooks": {
+			afterErr:    errors.New("failed"),
+			expCalls:    []string{"before 1", "before 2", "tests", "after 2", "after 1"},
+			expExitCode: 1,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var calls []string
+			s := &suite{m: fakeRunner{calls: &calls, code: c.testsCode}, retries: newRetryState()}
+			s.BeforeAll(hook(&calls, "before 1", c.beforeErr))
+			s.BeforeAll(hook(&calls, "before 2", nil))
+			s.AfterAll(hook(&calls, "after 1", nil))
+			s.AfterAll(hook(&calls, "after 2", c.afterErr))
+
+			require.Equal(t, c.expExitCode, s.runTests())
+			require.Equal(t, c.expCalls, calls)
+		})
+	}
+}
@@ -3,6 +3,8 @@ module github.com/hashicorp/consul-helm/test/acceptance
 go 1.14
 
 require (
+	github.com/evanphx/json-patch v4.9.0+incompatible
+	github.com/google/go-cmp v0.4.0
 	github.com/gruntwork-io/terratest v0.31.2
 	github.com/hashicorp/consul/api v1.4.1-0.20210504212756-347f3d212843
 	github.com/hashicorp/consul/sdk v0.7.0
@@ -11,4 +13,5 @@ require (
 	k8s.io/api v0.19.3
 	k8s.io/apimachinery v0.19.3
 	k8s.io/client-go v0.19.3
+	sigs.k8s.io/yaml v1.2.0
 )
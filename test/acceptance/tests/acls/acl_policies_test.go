@@ -0,0 +1,85 @@
+package acls
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that the ACL tokens created by server-acl-init for each component
+// are linked to exactly one policy and that the rules of that policy
+// match the documented rules in testdata/policies.
+// To update the golden files, run this test with the -update-golden-files flag.
+func TestACLPolicies_ComponentTokens(t *testing.T) {
+	cfg := suite.Config()
+	helpers.SkipUnlessTag(t, cfg, config.TagSecure)
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"global.acls.manageSystemACLs": "true",
+		"global.tls.enabled":           "true",
+
+		"connectInject.enabled": "true",
+		"syncCatalog.enabled":   "true",
+
+		"meshGateway.enabled":  "true",
+		"meshGateway.replicas": "1",
+	}
+
+	// The snapshot agent is an enterprise feature.
+	components := []string{"client", "connect-inject", "catalog-sync", "mesh-gateway"}
+	if cfg.EnableEnterprise {
+		helmValues["client.snapshotAgent.enabled"] = "true"
+		components = append(components, "client-snapshot-agent")
+	}
+
+	if cfg.UseKind {
+		helmValues["meshGateway.service.type"] = "NodePort"
+		helmValues["meshGateway.service.nodePort"] = "30000"
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+	consulCluster.Create(t)
+
+	// This client uses the bootstrap token.
+	consulClient := consulCluster.SetupConsulClient(t, true)
+
+	for _, component := range components {
+		t.Run(component, func(t *testing.T) {
+			secretName := fmt.Sprintf("%s-consul-%s-acl-token", releaseName, component)
+			logger.Logf(t, "reading ACL token from secret %s", secretName)
+			secret, err := ctx.KubernetesClient(t).CoreV1().Secrets(ctx.KubectlOptions(t).Namespace).Get(context.Background(), secretName, metav1.GetOptions{})
+			require.NoError(t, err)
+
+			token, _, err := consulClient.ACL().TokenReadSelf(&api.QueryOptions{Token: string(secret.Data["token"])})
+			require.NoError(t, err)
+			require.Len(t, token.Policies, 1, "expected token for %s to have exactly one policy", component)
+			require.Empty(t, token.Roles, "expected token for %s to have no roles", component)
+
+			policy, _, err := consulClient.ACL().PolicyRead(token.Policies[0].ID, nil)
+			require.NoError(t, err)
+
+			expected := helpers.ReadGoldenFile(t, filepath.Join("testdata", "policies", component+".hcl"), []byte(policy.Rules), cfg.UpdateGoldenFiles)
+			require.Equal(t, normalizeRules(string(expected)), normalizeRules(policy.Rules),
+				"rules of policy %s don't match the golden file", policy.Name)
+		})
+	}
+}
+
+// normalizeRules removes all whitespace from the rules so that
+// the comparison doesn't depend on the formatting of the rules.
+func normalizeRules(rules string) string {
+	return strings.Join(strings.Fields(rules), "")
+}
@@ -0,0 +1,115 @@
+package acls
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+const staticClientNamespace = "ns1"
+
+// Test that with Consul Enterprise namespaces enabled, the auth method
+// created by the Helm chart issues tokens in the Consul namespace that
+// the service would be registered in, i.e. either the Kubernetes namespace
+// of the service account when mirroring is enabled, or the destination namespace.
+func TestAuthMethodNamespaces_LoginLogout(t *testing.T) {
+	cfg := suite.Config()
+	if !cfg.EnableEnterprise {
+		t.Skipf("skipping this test because -enable-enterprise is not set")
+	}
+
+	consulDestNS := "consul-dest"
+	cases := []struct {
+		name                 string
+		destinationNamespace string
+		mirrorK8S            bool
+	}{
+		{
+			"single destination namespace",
+			consulDestNS,
+			false,
+		},
+		{
+			"mirror k8s namespaces",
+			consulDestNS,
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, config.TagNamespaces, config.TagSecure)
+			ctx := suite.Environment().DefaultContext(t)
+
+			helmValues := map[string]string{
+				"global.enableConsulNamespaces": "true",
+				"global.acls.manageSystemACLs":  "true",
+				"global.tls.enabled":            "true",
+
+				"connectInject.enabled": "true",
+				// When mirroringK8S is set, this setting is ignored.
+				"connectInject.consulNamespaces.consulDestinationNamespace": c.destinationNamespace,
+				"connectInject.consulNamespaces.mirroringK8S":               strconv.FormatBool(c.mirrorK8S),
+			}
+
+			releaseName := helpers.RandomName()
+			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+			consulCluster.Create(t)
+
+			logger.Logf(t, "creating namespace %s", staticClientNamespace)
+			k8s.RunKubectl(t, ctx.KubectlOptions(t), "create", "ns", staticClientNamespace)
+			helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+				k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "ns", staticClientNamespace)
+			})
+
+			staticClientOpts := ctx.KubectlOptionsForNamespace(t, staticClientNamespace)
+
+			logger.Log(t, "creating static-client deployment")
+			k8s.DeployKustomize(t, staticClientOpts, cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/static-client")
+
+			// Pass the discard logger so that the JWT is not printed to the test logs.
+			jwt, err := k8s.RunKubectlAndGetOutputWithLoggerE(t, staticClientOpts, terratestLogger.Discard,
+				"exec", "deploy/"+staticClientName, "-c", staticClientName, "--", "cat", "/var/run/secrets/kubernetes.io/serviceaccount/token")
+			require.NoError(t, err)
+			jwt = strings.TrimSpace(jwt)
+
+			consulClient := consulCluster.SetupConsulClient(t, true)
+			authMethodName := fmt.Sprintf("%s-consul-k8s-auth-method", releaseName)
+
+			// The auth method is always created in the default Consul namespace.
+			logger.Logf(t, "logging in with auth method %s", authMethodName)
+			token, _, err := consulClient.ACL().Login(&api.ACLLoginParams{
+				AuthMethod:  authMethodName,
+				BearerToken: jwt,
+			}, nil)
+			require.NoError(t, err)
+
+			expectedConsulNS := staticClientNamespace
+			if !c.mirrorK8S {
+				expectedConsulNS = c.destinationNamespace
+			}
+			require.Equal(t, expectedConsulNS, token.Namespace)
+			require.Len(t, token.ServiceIdentities, 1)
+			require.Equal(t, staticClientName, token.ServiceIdentities[0].ServiceName)
+
+			logger.Log(t, "logging out")
+			_, err = consulClient.ACL().Logout(&api.WriteOptions{Token: token.SecretID})
+			require.NoError(t, err)
+
+			_, _, err = consulCluster.ConsulClientForNamespace(t, true, expectedConsulNS).ACL().TokenRead(token.AccessorID, nil)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "ACL not found")
+		})
+	}
+}
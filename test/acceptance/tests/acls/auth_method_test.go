@@ -0,0 +1,81 @@
+package acls
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+const staticClientName = "static-client"
+
+// Test that a service account JWT of a pod can be used to log in
+// with the auth method created by the Helm chart directly, i.e. without
+// going through the connect injector, and that the issued token
+// has the expected service identity and can be logged out.
+func TestAuthMethod_LoginLogout(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"global.acls.manageSystemACLs": "true",
+		"global.tls.enabled":           "true",
+		"connectInject.enabled":        "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+	consulCluster.Create(t)
+
+	// Deploy the static-client without injection so that it's not logged in
+	// by the connect injector and we only get its service account token.
+	logger.Log(t, "creating static-client deployment")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/static-client")
+
+	// Pass the discard logger so that the JWT is not printed to the test logs.
+	jwt, err := k8s.RunKubectlAndGetOutputWithLoggerE(t, ctx.KubectlOptions(t), terratestLogger.Discard,
+		"exec", "deploy/"+staticClientName, "-c", staticClientName, "--", "cat", "/var/run/secrets/kubernetes.io/serviceaccount/token")
+	require.NoError(t, err)
+	jwt = strings.TrimSpace(jwt)
+
+	consulClient := consulCluster.SetupConsulClient(t, true)
+	authMethodName := fmt.Sprintf("%s-consul-k8s-auth-method", releaseName)
+
+	logger.Logf(t, "logging in with auth method %s", authMethodName)
+	token, _, err := consulClient.ACL().Login(&api.ACLLoginParams{
+		AuthMethod:  authMethodName,
+		BearerToken: jwt,
+		Meta:        map[string]string{"pod": fmt.Sprintf("%s/%s", ctx.KubectlOptions(t).Namespace, staticClientName)},
+	}, nil)
+	require.NoError(t, err)
+
+	// The binding rule created by the chart binds the service account name to a service identity.
+	require.Equal(t, authMethodName, token.AuthMethod)
+	require.True(t, token.Local, "expected tokens created by the auth method to be local")
+	require.Empty(t, token.Policies)
+	require.Empty(t, token.Roles)
+	require.Len(t, token.ServiceIdentities, 1)
+	require.Equal(t, staticClientName, token.ServiceIdentities[0].ServiceName)
+
+	// Check that the token is usable.
+	tokenSelf, _, err := consulClient.ACL().TokenReadSelf(&api.QueryOptions{Token: token.SecretID})
+	require.NoError(t, err)
+	require.Equal(t, token.AccessorID, tokenSelf.AccessorID)
+
+	logger.Log(t, "logging out")
+	_, err = consulClient.ACL().Logout(&api.WriteOptions{Token: token.SecretID})
+	require.NoError(t, err)
+
+	// The token should be deleted after logout.
+	_, _, err = consulClient.ACL().TokenRead(token.AccessorID, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "ACL not found")
+}
@@ -0,0 +1,59 @@
+package acls
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that the bootstrap token of the release is a management token
+// that can be used to create fine-grained tokens, and that those tokens
+// only grant the permissions of their policies.
+func TestACLs_BootstrapToken(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"global.acls.manageSystemACLs": "true",
+		"global.tls.enabled":           "true",
+	}
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	consulClient := consulCluster.SetupConsulClient(t, true)
+	bootstrapToken := consulCluster.BootstrapToken(t)
+	managementOpts := &api.WriteOptions{Token: bootstrapToken}
+
+	self, _, err := consulClient.ACL().TokenReadSelf(&api.QueryOptions{Token: bootstrapToken})
+	require.NoError(t, err)
+	require.Len(t, self.Policies, 1)
+	require.Equal(t, "global-management", self.Policies[0].Name)
+
+	logger.Log(t, "creating a token that can only read the KV store")
+	policy, _, err := consulClient.ACL().PolicyCreate(&api.ACLPolicy{
+		Name:  "kv-read",
+		Rules: `key_prefix "" { policy = "read" }`,
+	}, managementOpts)
+	require.NoError(t, err)
+	token, _, err := consulClient.ACL().TokenCreate(&api.ACLToken{
+		Policies: []*api.ACLTokenPolicyLink{{ID: policy.ID}},
+	}, managementOpts)
+	require.NoError(t, err)
+
+	_, err = consulClient.KV().Put(&api.KVPair{Key: "foo", Value: []byte("bar")}, managementOpts)
+	require.NoError(t, err)
+
+	kv, _, err := consulClient.KV().Get("foo", &api.QueryOptions{Token: token.SecretID})
+	require.NoError(t, err)
+	require.NotNil(t, kv)
+	require.Equal(t, []byte("bar"), kv.Value)
+
+	_, err = consulClient.KV().Put(&api.KVPair{Key: "foo", Value: []byte("baz")}, &api.WriteOptions{Token: token.SecretID})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Permission denied")
+}
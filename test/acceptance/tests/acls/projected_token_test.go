@@ -0,0 +1,142 @@
+package acls
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// projectedTokenMinMinorVersion is the first Kubernetes 1.x minor version
+// where the TokenRequest API that issues bound service account tokens is GA.
+const projectedTokenMinMinorVersion = 20
+
+// Test that the auth method created by the Helm chart accepts bound service
+// account tokens issued by the TokenRequest API, which are the tokens projected
+// into pods on recent Kubernetes versions, as long as they are issued for
+// the audience of the Kubernetes API server, and that it rejects tokens issued
+// for any other audience.
+func TestAuthMethod_ProjectedTokenAudience(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	minor := kubernetesMinorVersion(t, ctx)
+	if minor < projectedTokenMinMinorVersion {
+		t.Skipf("skipping this test because Kubernetes 1.%d doesn't have GA bound service account tokens", minor)
+	}
+
+	helmValues := map[string]string{
+		"global.acls.manageSystemACLs": "true",
+		"global.tls.enabled":           "true",
+		"connectInject.enabled":        "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	namespace := ctx.KubectlOptions(t).Namespace
+	serviceAccounts := ctx.KubernetesClient(t).CoreV1().ServiceAccounts(namespace)
+	_, err := serviceAccounts.Create(context.Background(), &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: staticClientName},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		serviceAccounts.Delete(context.Background(), staticClientName, metav1.DeleteOptions{})
+	})
+
+	consulClient := consulCluster.SetupConsulClient(t, true)
+	authMethodName := fmt.Sprintf("%s-consul-k8s-auth-method", releaseName)
+
+	// Without audiences, the token is issued for the audiences of the API server.
+	logger.Log(t, "logging in with a projected token for the default audiences")
+	defaultToken := requestToken(t, ctx, nil)
+	login(t, consulClient, authMethodName, defaultToken)
+
+	audiences := tokenAudiences(t, defaultToken)
+	require.NotEmpty(t, audiences)
+	logger.Logf(t, "logging in with a projected token for the API server audiences %v", audiences)
+	login(t, consulClient, authMethodName, requestToken(t, ctx, audiences))
+
+	logger.Log(t, "checking that a projected token for another audience is rejected")
+	_, _, err = consulClient.ACL().Login(&api.ACLLoginParams{
+		AuthMethod:  authMethodName,
+		BearerToken: requestToken(t, ctx, []string{"consul-acceptance-test"}),
+	}, nil)
+	require.Error(t, err)
+}
+
+// requestToken returns a bound token for the static-client service account
+// issued for audiences.
+func requestToken(t *testing.T, ctx environment.TestContext, audiences []string) string {
+	t.Helper()
+
+	expiration := int64(600)
+	tokenRequest, err := ctx.KubernetesClient(t).CoreV1().ServiceAccounts(ctx.KubectlOptions(t).Namespace).CreateToken(context.Background(), staticClientName,
+		&authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				Audiences:         audiences,
+				ExpirationSeconds: &expiration,
+			},
+		}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	return tokenRequest.Status.Token
+}
+
+// login logs in with jwt, checks that the token has the service identity
+// of the static-client, and logs out again.
+func login(t *testing.T, consulClient *api.Client, authMethodName, jwt string) {
+	t.Helper()
+
+	token, _, err := consulClient.ACL().Login(&api.ACLLoginParams{
+		AuthMethod:  authMethodName,
+		BearerToken: jwt,
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, token.ServiceIdentities, 1)
+	require.Equal(t, staticClientName, token.ServiceIdentities[0].ServiceName)
+
+	_, err = consulClient.ACL().Logout(&api.WriteOptions{Token: token.SecretID})
+	require.NoError(t, err)
+}
+
+// tokenAudiences returns the aud claim of jwt without verifying it.
+func tokenAudiences(t *testing.T, jwt string) []string {
+	t.Helper()
+
+	parts := strings.Split(jwt, ".")
+	require.Len(t, parts, 3, "expected a JWT")
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+
+	var claims struct {
+		Audiences []string `json:"aud"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	return claims.Audiences
+}
+
+// kubernetesMinorVersion returns the minor version of the Kubernetes API server.
+// Managed clusters report versions such as 21+, so anything after the digits is ignored.
+func kubernetesMinorVersion(t *testing.T, ctx environment.TestContext) int {
+	t.Helper()
+
+	version, err := ctx.KubernetesClient(t).Discovery().ServerVersion()
+	require.NoError(t, err)
+	minor, err := strconv.Atoi(strings.TrimRight(version.Minor, "+"))
+	require.NoError(t, err, "unexpected Kubernetes minor version %q", version.Minor)
+	return minor
+}
@@ -0,0 +1,163 @@
+package acls
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that re-running server-acl-init after the ACL tokens of the components
+// have been deleted, both from Consul and from their Kubernetes secrets, creates
+// working tokens for them again without any other manual intervention than
+// restarting the components, which read their tokens when they start.
+// The server-acl-init job is deleted once it completes, so a helm upgrade
+// recreates it. Running it again reuses the policies it already created.
+func TestServerACLInit_RerunRestoresComponentTokens(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"global.acls.manageSystemACLs": "true",
+		"global.tls.enabled":           "true",
+
+		"connectInject.enabled": "true",
+		"syncCatalog.enabled":   "true",
+
+		"meshGateway.enabled":  "true",
+		"meshGateway.replicas": "1",
+	}
+	if cfg.UseKind {
+		helmValues["meshGateway.service.type"] = "NodePort"
+		helmValues["meshGateway.service.nodePort"] = "30000"
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+	// The components log ACL errors until they are restarted with their new tokens.
+	consulCluster.AllowErrorLogs(t, "ACL not found", "[Pp]ermission denied", "rpc error")
+
+	consulClient := consulCluster.SetupConsulClient(t, true)
+	secrets := ctx.KubernetesClient(t).CoreV1().Secrets(ctx.KubectlOptions(t).Namespace)
+
+	// The workload of each component that reads its token from the secret.
+	components := map[string]string{
+		"client":         "daemonset/" + releaseName + "-consul",
+		"connect-inject": "deploy/" + releaseName + "-consul-connect-injector-webhook-deployment",
+		"catalog-sync":   "deploy/" + releaseName + "-consul-sync-catalog",
+		"mesh-gateway":   "deploy/" + releaseName + "-consul-mesh-gateway",
+	}
+
+	policiesBefore := make(map[string][]string)
+	for component := range components {
+		secretName := fmt.Sprintf("%s-consul-%s-acl-token", releaseName, component)
+		secret, err := secrets.Get(context.Background(), secretName, metav1.GetOptions{})
+		require.NoError(t, err)
+		token, _, err := consulClient.ACL().TokenReadSelf(&api.QueryOptions{Token: string(secret.Data["token"])})
+		require.NoError(t, err)
+		policiesBefore[component] = tokenPolicyIDs(token)
+
+		logger.Logf(t, "deleting the ACL token of %s and its secret", component)
+		_, err = consulClient.ACL().TokenDelete(token.AccessorID, nil)
+		require.NoError(t, err)
+		require.NoError(t, secrets.Delete(context.Background(), secretName, metav1.DeleteOptions{}))
+	}
+
+	logger.Log(t, "re-running server-acl-init")
+	consulCluster.Upgrade(t, nil)
+
+	for component := range components {
+		secretName := fmt.Sprintf("%s-consul-%s-acl-token", releaseName, component)
+		secret, err := secrets.Get(context.Background(), secretName, metav1.GetOptions{})
+		require.NoError(t, err, "server-acl-init didn't recreate the secret of %s", component)
+		token, _, err := consulClient.ACL().TokenReadSelf(&api.QueryOptions{Token: string(secret.Data["token"])})
+		require.NoError(t, err)
+		require.Equal(t, policiesBefore[component], tokenPolicyIDs(token), "the new token of %s has different policies", component)
+	}
+
+	for component, workload := range components {
+		logger.Logf(t, "restarting %s so that it reads its new token", component)
+		k8s.RunKubectl(t, ctx.KubectlOptions(t), "rollout", "restart", workload)
+		k8s.RunKubectl(t, ctx.KubectlOptions(t), "rollout", "status", "--timeout=5m", workload)
+	}
+
+	// The clients use their token to register their node in the catalog, so check that each
+	// of the restarted clients has synced its node info and that none were blocked by ACLs.
+	t.Run("client", func(t *testing.T) {
+		retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+			pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(),
+				metav1.ListOptions{LabelSelector: fmt.Sprintf("release=%s,component=client", releaseName)})
+			require.NoError(r, err)
+			require.NotEmpty(r, pods.Items)
+			for _, pod := range pods.Items {
+				// Pods of the previous rollout may still be terminating.
+				if pod.DeletionTimestamp != nil {
+					continue
+				}
+				logs, err := k8s.RunKubectlAndGetOutputWithLoggerE(t, ctx.KubectlOptions(t), terratestLogger.Discard, "logs", pod.Name, "-c", "consul")
+				require.NoError(r, err)
+				require.NotContains(r, logs, "blocked by ACLs", "client %s couldn't sync with its token", pod.Name)
+				require.Contains(r, logs, "Synced node info", "client %s hasn't synced its node info yet", pod.Name)
+			}
+		})
+	})
+
+	t.Run("catalog-sync", func(t *testing.T) {
+		logger.Log(t, "creating static-server deployment to be synced")
+		k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/static-server")
+		retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+			services, _, err := consulClient.Catalog().Service("static-server", "k8s", nil)
+			require.NoError(r, err)
+			require.Len(r, services, 1)
+		})
+	})
+
+	t.Run("connect-inject", func(t *testing.T) {
+		logger.Log(t, "creating static-client deployment to be injected")
+		k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+		retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+			for _, name := range []string{"static-client", "static-client-sidecar-proxy"} {
+				services, _, err := consulClient.Catalog().Service(name, "", nil)
+				require.NoError(r, err)
+				require.Len(r, services, 1, "%s is not registered", name)
+			}
+		})
+	})
+
+	t.Run("mesh-gateway", func(t *testing.T) {
+		retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+			gateways, _, err := consulClient.Health().Service("mesh-gateway", "", true, nil)
+			require.NoError(r, err)
+			require.Len(r, gateways, 1)
+		})
+	})
+
+	// Running server-acl-init again must not have created policies that already existed.
+	policies, _, err := consulClient.ACL().PolicyList(nil)
+	require.NoError(t, err)
+	policyNames := make(map[string]bool)
+	for _, policy := range policies {
+		require.False(t, policyNames[policy.Name], "duplicate policy %s", policy.Name)
+		policyNames[policy.Name] = true
+	}
+}
+
+// tokenPolicyIDs returns the IDs of the policies linked to token.
+func tokenPolicyIDs(token *api.ACLToken) []string {
+	var ids []string
+	for _, policy := range token.Policies {
+		ids = append(ids, policy.ID)
+	}
+	return ids
+}
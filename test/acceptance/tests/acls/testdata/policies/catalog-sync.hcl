@@ -0,0 +1,9 @@
+node "k8s-sync" {
+  policy = "write"
+}
+node_prefix "" {
+  policy = "read"
+}
+service_prefix "" {
+  policy = "write"
+}
@@ -0,0 +1,6 @@
+node_prefix "" {
+  policy = "write"
+}
+service_prefix "" {
+  policy = "read"
+}
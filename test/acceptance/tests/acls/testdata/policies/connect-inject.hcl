@@ -0,0 +1,7 @@
+node_prefix "" {
+  policy = "write"
+}
+acl = "write"
+service_prefix "" {
+  policy = "write"
+}
@@ -0,0 +1,12 @@
+agent_prefix "" {
+  policy = "read"
+}
+service "mesh-gateway" {
+  policy = "write"
+}
+node_prefix "" {
+  policy = "read"
+}
+service_prefix "" {
+  policy = "read"
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
@@ -15,7 +16,12 @@ import (
 // Test that the basic installation, i.e. just
 // servers and clients, works by creating a kv entry
 // and subsequently reading it from Consul.
+// It runs against each of the images passed with -consul-images.
 func TestBasicInstallation(t *testing.T) {
+	consul.RunForEachConsulImage(t, suite.Config(), testBasicInstallation)
+}
+
+func testBasicInstallation(t *testing.T, cfg *config.TestConfig) {
 	cases := []struct {
 		secure      bool
 		autoEncrypt bool
@@ -37,13 +43,14 @@ func TestBasicInstallation(t *testing.T) {
 	for _, c := range cases {
 		name := fmt.Sprintf("secure: %t, auto-encrypt: %t", c.secure, c.autoEncrypt)
 		t.Run(name, func(t *testing.T) {
+			helpers.SkipUnlessInMatrix(t, cfg, config.Matrix{config.MatrixSecure: c.secure, config.MatrixAutoEncrypt: c.autoEncrypt})
 			releaseName := helpers.RandomName()
 			helmValues := map[string]string{
 				"global.acls.manageSystemACLs": strconv.FormatBool(c.secure),
 				"global.tls.enabled":           strconv.FormatBool(c.secure),
 				"global.tls.enableAutoEncrypt": strconv.FormatBool(c.autoEncrypt),
 			}
-			consulCluster := consul.NewHelmCluster(t, helmValues, suite.Environment().DefaultContext(t), suite.Config(), releaseName)
+			consulCluster := consul.NewHelmCluster(t, helmValues, suite.Environment().DefaultContext(t), cfg, releaseName)
 
 			consulCluster.Create(t)
 
@@ -0,0 +1,56 @@
+package basic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that a service whose health check becomes critical is automatically
+// deregistered once DeregisterCriticalServiceAfter has passed, and not before.
+// The service is registered with the agent of a Consul server directly rather than
+// through an injected or synced pod because consul-k8s doesn't set
+// DeregisterCriticalServiceAfter on the services it registers. It deregisters
+// them itself when their pods or Kubernetes services are deleted instead.
+func TestDeregisterCriticalServiceAfter(t *testing.T) {
+	const (
+		serviceID = "deregister-critical"
+		checkID   = "deregister-critical-ttl"
+		checkTTL  = 10 * time.Second
+		// This is the minimum value Consul allows.
+		deregisterAfter = 1 * time.Minute
+		// Consul agents reap critical services every 30s, so deregistration
+		// can happen up to that long after the threshold has passed.
+		reapInterval = 30 * time.Second
+	)
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, nil, suite.Environment().DefaultContext(t), suite.Config(), releaseName)
+
+	consulCluster.Create(t)
+
+	client := consulCluster.SetupConsulClient(t, false)
+
+	logger.Logf(t, "registering service %s with a TTL check that deregisters after %s", serviceID, deregisterAfter)
+	err := client.Agent().ServiceRegister(&api.AgentServiceRegistration{
+		ID:   serviceID,
+		Name: serviceID,
+		Check: &api.AgentServiceCheck{
+			CheckID:                        checkID,
+			TTL:                            checkTTL.String(),
+			Status:                         api.HealthPassing,
+			DeregisterCriticalServiceAfter: deregisterAfter.String(),
+		},
+	})
+	require.NoError(t, err)
+
+	// Stop updating the TTL check so that it becomes critical.
+	criticalSince := consul.WaitForCheckStatus(t, client, checkID, api.HealthCritical, checkTTL+30*time.Second)
+
+	consul.RequireServiceDeregisteredWithin(t, client, serviceID, criticalSince, deregisterAfter, deregisterAfter+reapInterval)
+}
@@ -0,0 +1,67 @@
+package basic
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that installing with an invalid enterprise license fails the install
+// within the Helm timeout instead of hanging, and that the reason for the
+// failure is surfaced in the logs of the license job.
+// This codifies the failure behavior users see when their license is
+// expired or malformed.
+func TestEnterpriseLicense_InvalidLicense(t *testing.T) {
+	cfg := suite.Config()
+	if !cfg.EnableEnterprise {
+		t.Skipf("skipping this test because -enable-enterprise is not set")
+	}
+	helpers.SkipUnlessTag(t, cfg, config.TagEnterprise)
+	ctx := suite.Environment().DefaultContext(t)
+
+	releaseName := helpers.RandomName()
+	licenseSecretName := fmt.Sprintf("%s-invalid-license", releaseName)
+	licenseSecretKey := "license"
+
+	logger.Log(t, "creating a secret with an invalid enterprise license")
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "create", "secret", "generic", licenseSecretName,
+		fmt.Sprintf("--from-literal=%s=%s", licenseSecretKey, "invalid-license"))
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		// The secret may have been deleted already because the release cleanup
+		// deletes the secrets that have the release name in their name.
+		k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "secret", licenseSecretName, "--ignore-not-found")
+	})
+
+	helmValues := map[string]string{
+		"server.enterpriseLicense.secretName": licenseSecretName,
+		"server.enterpriseLicense.secretKey":  licenseSecretKey,
+	}
+
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+	// The license job retries applying the license, so the install only
+	// fails once Helm times out waiting for the post-install hook.
+	// Use a short timeout so that the failure is surfaced quickly.
+	err := consulCluster.CreateE(t, 3*time.Minute)
+	require.Error(t, err, "expected install with an invalid license to fail")
+	require.Contains(t, err.Error(), "post-install")
+
+	logger.Log(t, "checking that the license job logs surface the invalid license")
+	jobLogs := k8s.PodLogs(t, ctx.KubectlOptions(t), fmt.Sprintf("release=%s,component=license", releaseName))
+	require.NotEmpty(t, jobLogs, "expected license job pods to exist")
+	for podName, logs := range jobLogs {
+		require.Contains(t, logs, "Error putting license", "expected license job pod %s logs to contain the license error", podName)
+		require.NotContains(t, logs, "License applied successfully")
+	}
+
+	// Servers should still be running even though the license couldn't be applied
+	// so that users can fix the license secret and re-run the license job.
+	helpers.WaitForAllPodsToBeReady(t, ctx.KubernetesClient(t), ctx.KubectlOptions(t).Namespace, fmt.Sprintf("release=%s,component=server", releaseName))
+}
@@ -0,0 +1,89 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that server.extraVolumes and client.extraVolumes are mounted into
+// the consul containers of servers and clients as documented, and that
+// configuration in volumes with load: true is loaded by the agents.
+//
+// The chart doesn't support server.extraContainers or client.extraContainers,
+// so only the extra volumes are covered here.
+func TestExtraVolumes(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	releaseName := helpers.RandomName()
+	configMapName := fmt.Sprintf("%s-extra-config", releaseName)
+
+	logger.Logf(t, "creating config map %s with extra agent config", configMapName)
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "create", "configmap", configMapName,
+		`--from-literal=node-meta.json={"node_meta": {"extra-volume": "loaded"}}`)
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "configmap", configMapName)
+	})
+
+	extraVolumes := []interface{}{
+		map[string]interface{}{
+			"type": "configMap",
+			"name": configMapName,
+			"load": true,
+		},
+	}
+	valuesFile := consul.WriteValuesFile(t, map[string]interface{}{
+		"server": map[string]interface{}{"extraVolumes": extraVolumes},
+		"client": map[string]interface{}{"extraVolumes": extraVolumes},
+	})
+
+	consulCluster := consul.NewHelmCluster(t, nil, ctx, cfg, releaseName, valuesFile)
+	consulCluster.Create(t)
+
+	for _, component := range []string{"server", "client"} {
+		pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(),
+			metav1.ListOptions{LabelSelector: fmt.Sprintf("release=%s,component=%s", releaseName, component)})
+		require.NoError(t, err)
+		require.NotEmpty(t, pods.Items)
+
+		for _, pod := range pods.Items {
+			container := consulContainer(t, pod)
+			require.Contains(t, container.VolumeMounts, corev1.VolumeMount{
+				Name:      "userconfig-" + configMapName,
+				ReadOnly:  true,
+				MountPath: "/consul/userconfig/" + configMapName,
+			}, "expected the extra volume to be mounted in pod %s", pod.Name)
+		}
+	}
+
+	logger.Log(t, "checking that all agents loaded the config from the extra volume")
+	client := consulCluster.SetupConsulClient(t, false)
+	nodes, _, err := client.Catalog().Nodes(nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, nodes)
+	for _, node := range nodes {
+		require.Equal(t, "loaded", node.Meta["extra-volume"], "expected node %s to have the node meta from the extra volume", node.Node)
+	}
+}
+
+// consulContainer returns the container named consul of pod.
+func consulContainer(t *testing.T, pod corev1.Pod) corev1.Container {
+	t.Helper()
+
+	for _, container := range pod.Spec.Containers {
+		if container.Name == "consul" {
+			return container
+		}
+	}
+	require.FailNow(t, fmt.Sprintf("pod %s has no consul container", pod.Name))
+	return corev1.Container{}
+}
@@ -0,0 +1,168 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test the documented workflow for rotating the gossip encryption key:
+// the new key is installed on and then used by all members with `consul keyring`,
+// the chart is upgraded to point global.gossipEncryption at a secret with the new key
+// so that restarted agents use it, and finally the old key is removed.
+// Servers and clients have to stay in the member list throughout.
+func TestGossipKeyRotation(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	releaseName := helpers.RandomName()
+	oldKey := consul.NewGossipKey(t)
+	newKey := consul.NewGossipKey(t)
+	oldSecret := createGossipKeySecret(t, ctx, cfg.NoCleanupOnFailure, releaseName+"-gossip-key-old", oldKey)
+	newSecret := createGossipKeySecret(t, ctx, cfg.NoCleanupOnFailure, releaseName+"-gossip-key-new", newKey)
+
+	helmValues := map[string]string{
+		"global.gossipEncryption.secretName": oldSecret,
+		"global.gossipEncryption.secretKey":  "key",
+	}
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+	// Agents that restart during the upgrade can't decrypt gossip until they have
+	// the new key, and the servers lose their leader while they roll.
+	consulCluster.AllowErrorLogs(t, "No installed keys could decrypt", "No cluster leader", "agent.server.raft", "agent.server.memberlist", "rpc error", "EOF")
+
+	consulClient := consulCluster.SetupConsulClient(t, false)
+	var memberNames []string
+	retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		memberNames = aliveMembers(r, consulClient)
+		require.NotEmpty(r, memberNames)
+	})
+	requireGossipKeys(t, consulClient, oldKey)
+
+	// Continuously check that all members stay in the member list.
+	// Members may briefly not be alive while the upgrade restarts them,
+	// and requests fail while the server behind the port-forward restarts.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	var stopOnce sync.Once
+	stopWatching := func() {
+		stopOnce.Do(func() {
+			close(stop)
+			wg.Wait()
+		})
+	}
+	// Stop watching if the test fails before the rotation is done too.
+	t.Cleanup(stopWatching)
+	var missingMembers []string
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(2 * time.Second):
+			}
+			members, err := consulClient.Agent().Members(false)
+			if err != nil {
+				continue
+			}
+			names := make(map[string]bool)
+			for _, member := range members {
+				names[member.Name] = true
+			}
+			for _, name := range memberNames {
+				if !names[name] {
+					missingMembers = append(missingMembers, fmt.Sprintf("%s at %s", name, time.Now().Format(time.RFC3339)))
+				}
+			}
+		}
+	}()
+
+	consulCluster.InstallGossipKey(t, newKey)
+	requireGossipKeys(t, consulClient, oldKey, newKey)
+	consulCluster.UseGossipKey(t, newKey)
+
+	logger.Log(t, "upgrading the release to use the secret with the new gossip key")
+	consulCluster.Upgrade(t, map[string]string{
+		"global.gossipEncryption.secretName": newSecret,
+	})
+
+	// Restarted clients have joined with only the new key, so they
+	// need to be alive again before the old key can be removed everywhere.
+	retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		require.Equal(r, memberNames, aliveMembers(r, consulClient))
+	})
+	consulCluster.RemoveGossipKey(t, oldKey)
+	requireGossipKeys(t, consulClient, newKey)
+
+	stopWatching()
+	require.Empty(t, missingMembers, "members left the member list during the gossip key rotation")
+
+	retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		require.Equal(r, memberNames, aliveMembers(r, consulClient))
+	})
+}
+
+// createGossipKeySecret creates a secret named name with key under the "key" key and returns its name.
+func createGossipKeySecret(t *testing.T, ctx environment.TestContext, noCleanupOnFailure bool, name, key string) string {
+	t.Helper()
+
+	secrets := ctx.KubernetesClient(t).CoreV1().Secrets(ctx.KubectlOptions(t).Namespace)
+	_, err := secrets.Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		StringData: map[string]string{"key": key},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	helpers.Cleanup(t, noCleanupOnFailure, func() {
+		secrets.Delete(context.Background(), name, metav1.DeleteOptions{})
+	})
+	return name
+}
+
+// aliveMembers returns the sorted names of the alive members of the LAN pool.
+func aliveMembers(r *retry.R, consulClient *api.Client) []string {
+	members, err := consulClient.Agent().Members(false)
+	require.NoError(r, err)
+
+	var names []string
+	for _, member := range members {
+		// A status of 1 means that the member is alive.
+		if member.Status == 1 {
+			names = append(names, member.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// requireGossipKeys checks that keys are exactly the gossip encryption keys
+// installed on all members of the LAN and WAN pools.
+func requireGossipKeys(t *testing.T, consulClient *api.Client, keys ...string) {
+	t.Helper()
+
+	retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		responses, err := consulClient.Operator().KeyringList(nil)
+		require.NoError(r, err)
+		require.NotEmpty(r, responses)
+		for _, response := range responses {
+			require.Len(r, response.Keys, len(keys), "keys of the WAN: %t pool", response.WAN)
+			for _, key := range keys {
+				require.Equal(r, response.NumNodes, response.Keys[key], "members with the key in the WAN: %t pool", response.WAN)
+			}
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package basic
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+)
+
+// installBenchmarkProfiles are the Helm values installed by TestInstallBenchmark.
+var installBenchmarkProfiles = []struct {
+	name   string
+	values map[string]string
+}{
+	{
+		name:   "minimal",
+		values: map[string]string{},
+	},
+	{
+		name: "secure",
+		values: map[string]string{
+			"global.acls.manageSystemACLs": "true",
+			"global.tls.enabled":           "true",
+		},
+	},
+	{
+		name: "full-mesh",
+		values: map[string]string{
+			"global.acls.manageSystemACLs": "true",
+			"global.tls.enabled":           "true",
+			"connectInject.enabled":        "true",
+			"controller.enabled":           "true",
+			"meshGateway.enabled":          "true",
+			"meshGateway.replicas":         "1",
+			"ingressGateways.enabled":      "true",
+			"terminatingGateways.enabled":  "true",
+		},
+	},
+}
+
+// Test how long it takes to install the chart with several values profiles
+// until all of its pods are ready, so that regressions in install time,
+// e.g. from slower hooks or probes, are noticed. Each profile is installed
+// -install-benchmark-iterations times and the install times are logged
+// for the test report, which shows them per profile.
+func TestInstallBenchmark(t *testing.T) {
+	cfg := suite.Config()
+	if cfg.InstallBenchmarkIterations == 0 {
+		t.Skipf("skipping this test because -install-benchmark-iterations is not set")
+	}
+
+	for _, profile := range installBenchmarkProfiles {
+		for i := 1; i <= cfg.InstallBenchmarkIterations; i++ {
+			profile := profile
+			t.Run(fmt.Sprintf("%s/%d", profile.name, i), func(t *testing.T) {
+				ctx := suite.Environment().DefaultContext(t)
+
+				consulCluster := consul.NewHelmCluster(t, profile.values, ctx, cfg, helpers.RandomName())
+
+				// The install time includes the fixed wait for the
+				// connect-inject webhook at the end of Create, which doesn't
+				// matter when comparing runs.
+				start := time.Now()
+				consulCluster.Create(t)
+				logger.Log(t, config.InstallBenchmarkResult(profile.name, time.Since(start)))
+			})
+		}
+	}
+}
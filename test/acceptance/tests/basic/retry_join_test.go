@@ -0,0 +1,153 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that clients can join the servers using the Kubernetes
+// cloud auto-join provider set in client.join. The clients need
+// permissions to list pods, which the chart doesn't grant,
+// so the test grants them before installing.
+func TestRetryJoin_KubernetesAutoJoin(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	releaseName := helpers.RandomName()
+	grantClientPodListPermissions(t, ctx, releaseName, cfg.NoCleanupOnFailure)
+
+	// Commas need to be escaped because Helm would otherwise split the value
+	// into multiple values.
+	joinString := fmt.Sprintf(`provider=k8s namespace=%s label_selector="app=consul\,component=server\,release=%s"`,
+		ctx.KubectlOptions(t).Namespace, releaseName)
+	helmValues := map[string]string{
+		"client.join[0]": joinString,
+	}
+
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	verifyClientsJoined(t, ctx, consulCluster, releaseName)
+}
+
+// Test that clients can join the servers using the AWS cloud auto-join provider,
+// which discovers the servers through the tags of the EC2 instances they run on.
+// The servers' gossip ports are exposed on their nodes so that they are reachable
+// on the addresses discovered by the provider.
+// This test only runs on EKS clusters created by the test suite because it needs
+// the name of the cluster to know the tags of its nodes.
+func TestRetryJoin_AWSAutoJoin(t *testing.T) {
+	cfg := suite.Config()
+	if cfg.Provider != "eks" {
+		t.Skipf("skipping this test because -provider is not set to eks")
+	}
+	ctx := suite.Environment().DefaultContext(t)
+
+	// EKS tags the instances of all node groups with the name of the cluster.
+	joinString := fmt.Sprintf("provider=aws tag_key=eks:cluster-name tag_value=%s", cfg.ClusterName)
+	if cfg.AWSRegion != "" {
+		joinString += fmt.Sprintf(" region=%s", cfg.AWSRegion)
+	}
+
+	releaseName := helpers.RandomName()
+	helmValues := map[string]string{
+		"server.exposeGossipAndRPCPorts": "true",
+		"client.join[0]":                 joinString,
+	}
+
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	verifyClientsJoined(t, ctx, consulCluster, releaseName)
+}
+
+// grantClientPodListPermissions allows the client agents of the release
+// to list pods so that they can use the Kubernetes cloud auto-join provider.
+func grantClientPodListPermissions(t *testing.T, ctx environment.TestContext, releaseName string, noCleanupOnFailure bool) {
+	t.Helper()
+
+	client := ctx.KubernetesClient(t)
+	namespace := ctx.KubectlOptions(t).Namespace
+	name := fmt.Sprintf("%s-consul-client-auto-join", releaseName)
+
+	_, err := client.RbacV1().Roles(namespace).Create(context.Background(), &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				Verbs:     []string{"list"},
+				APIGroups: []string{""},
+				Resources: []string{"pods"},
+			},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	// The service account doesn't exist until the chart is installed
+	// but it can already be bound to the role.
+	_, err = client.RbacV1().RoleBindings(namespace).Create(context.Background(), &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      fmt.Sprintf("%s-consul-client", releaseName),
+				Namespace: namespace,
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			Kind: "Role",
+			Name: name,
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	helpers.Cleanup(t, noCleanupOnFailure, func() {
+		client.RbacV1().RoleBindings(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+		client.RbacV1().Roles(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	})
+}
+
+// verifyClientsJoined checks that every client agent of the release
+// has joined the servers. Since client.join replaces the default retry-join
+// addresses, the clients can only have joined through the auto-join string.
+func verifyClientsJoined(t *testing.T, ctx environment.TestContext, consulCluster consul.Cluster, releaseName string) {
+	t.Helper()
+
+	clientPods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: fmt.Sprintf("app=consul,component=client,release=%s", releaseName)})
+	require.NoError(t, err)
+	require.NotEmpty(t, clientPods.Items)
+
+	consulClient := consulCluster.SetupConsulClient(t, false)
+
+	logger.Logf(t, "checking that all %d clients have joined the servers", len(clientPods.Items))
+	retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		members, err := consulClient.Agent().Members(false)
+		require.NoError(r, err)
+
+		var clientNodes []string
+		for _, member := range members {
+			// A status of 1 means that the member is alive.
+			if member.Tags[api.MemberTagKeyRole] != api.MemberTagValueRoleServer && member.Status == 1 {
+				clientNodes = append(clientNodes, member.Name)
+			}
+		}
+		require.Len(r, clientNodes, len(clientPods.Items), "alive clients: %s", strings.Join(clientNodes, ", "))
+	})
+}
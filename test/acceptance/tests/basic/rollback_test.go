@@ -0,0 +1,114 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Test that after an upgrade that fails because the servers use an image
+// that doesn't exist, rolling back to the previous revision brings the servers
+// back with the same data, i.e. without orphaning PVCs or ACL tokens.
+func TestRollback_AfterFailedUpgrade(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"global.acls.manageSystemACLs": "true",
+		"global.tls.enabled":           "true",
+	}
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	revisions := consulCluster.Revisions(t)
+	require.Len(t, revisions, 1)
+	installedRevision := revisions[0].Number
+
+	pvcsBefore := releasePVCs(t, ctx, releaseName)
+	require.NotEmpty(t, pvcsBefore)
+	tokensBefore := tokenAccessorIDs(t, consulCluster.SetupConsulClient(t, true))
+
+	// Upgrade outside of the cluster object because we expect this upgrade to fail.
+	// Reusing the values of the release keeps everything else the same.
+	logger.Log(t, "upgrading servers to an image that doesn't exist")
+	failedUpgradeOptions := &helm.Options{
+		SetValues: map[string]string{
+			"server.image": "docker.mirror.hashicorp.services/hashicorp/consul:does-not-exist",
+		},
+		Version:        cfg.HelmChartVersion,
+		KubectlOptions: ctx.KubectlOptions(t),
+		Logger:         terratestLogger.New(logger.TestLogger{}),
+		ExtraArgs: map[string][]string{
+			"upgrade": {"--reuse-values", "--wait", "--timeout", "2m"},
+		},
+	}
+	err := helm.UpgradeE(t, failedUpgradeOptions, cfg.ChartPath(), releaseName)
+	require.Error(t, err)
+
+	revisions = consulCluster.Revisions(t)
+	require.Len(t, revisions, 2)
+	require.Equal(t, "failed", revisions[1].Status)
+
+	consulCluster.Rollback(t, installedRevision)
+
+	revisions = consulCluster.Revisions(t)
+	require.Len(t, revisions, 3)
+	require.Equal(t, "deployed", revisions[2].Status)
+	require.Equal(t, fmt.Sprintf("Rollback to %d", installedRevision), revisions[2].Description)
+
+	require.Equal(t, pvcsBefore, releasePVCs(t, ctx, releaseName))
+
+	require.Equal(t, tokensBefore, tokenAccessorIDs(t, consulCluster.SetupConsulClient(t, true)))
+}
+
+// pvcIdentity identifies a persistent volume claim and the volume bound to it,
+// so that a claim that was deleted and recreated with the same name doesn't compare equal.
+type pvcIdentity struct {
+	Name       string
+	UID        types.UID
+	VolumeName string
+}
+
+// releasePVCs returns the persistent volume claims of the release sorted by name.
+func releasePVCs(t *testing.T, ctx environment.TestContext, releaseName string) []pvcIdentity {
+	t.Helper()
+
+	pvcs, err := ctx.KubernetesClient(t).CoreV1().PersistentVolumeClaims(ctx.KubectlOptions(t).Namespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: "release=" + releaseName})
+	require.NoError(t, err)
+
+	var identities []pvcIdentity
+	for _, pvc := range pvcs.Items {
+		identities = append(identities, pvcIdentity{Name: pvc.Name, UID: pvc.UID, VolumeName: pvc.Spec.VolumeName})
+	}
+	sort.Slice(identities, func(i, j int) bool { return identities[i].Name < identities[j].Name })
+	return identities
+}
+
+// tokenAccessorIDs returns the sorted accessor IDs of all ACL tokens.
+func tokenAccessorIDs(t *testing.T, client *api.Client) []string {
+	t.Helper()
+
+	tokens, _, err := client.ACL().TokenList(nil)
+	require.NoError(t, err)
+
+	var accessorIDs []string
+	for _, token := range tokens {
+		accessorIDs = append(accessorIDs, token.AccessorID)
+	}
+	sort.Strings(accessorIDs)
+	return accessorIDs
+}
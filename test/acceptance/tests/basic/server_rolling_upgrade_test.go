@@ -0,0 +1,199 @@
+package basic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	rollingUpgradeServerReplicas = 3
+	// leaderProbeInterval is the time between the queries
+	// that check that the servers have a leader.
+	leaderProbeInterval = 500 * time.Millisecond
+)
+
+// Test that an upgrade that restarts the servers one at a time keeps the cluster
+// available: a leader is elected and catalog queries succeed throughout, except for
+// leader elections that are shorter than -max-leader-unavailability.
+// The servers are restarted by changing server.extraConfig.
+func TestServerRollingUpgrade(t *testing.T) {
+	cfg := suite.Config()
+	helpers.SkipUnlessTag(t, cfg, config.TagSlow)
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"server.replicas":        strconv.Itoa(rollingUpgradeServerReplicas),
+		"server.bootstrapExpect": strconv.Itoa(rollingUpgradeServerReplicas),
+	}
+	// The servers' default anti-affinity requires a node per server,
+	// but kind clusters have a single node.
+	if cfg.UseKind {
+		helmValues["server.affinity"] = "null"
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+	// Restarting the servers causes leader elections and dropped connections.
+	consulCluster.AllowErrorLogs(t, "No cluster leader", "agent.server.raft", "agent.server.memberlist", "rpc error", "EOF")
+
+	// Query the servers through a client agent, which isn't restarted by changing the server
+	// config, so that restarts of the pod behind the port-forward aren't counted as unavailability.
+	consulClient := clientAgentConsulClient(t, ctx, releaseName)
+	serversBefore := serverPodUIDs(t, ctx, releaseName)
+
+	probe := startLeaderProbe(t, consulClient)
+	logger.Log(t, "upgrading the servers")
+	// The extraConfig value is quoted so that Helm doesn't parse it as a list.
+	consulCluster.Upgrade(t, map[string]string{
+		"server.extraConfig": `"{\"log_level\": \"DEBUG\"}"`,
+	})
+	requests, longestOutage, failures := probe.stop()
+
+	for _, failure := range failures {
+		logger.Log(t, failure)
+	}
+	logger.Logf(t, "the servers were unavailable for at most %s in %d queries during the upgrade", longestOutage, requests)
+	require.NotZero(t, requests)
+	require.LessOrEqualf(t, int64(longestOutage), int64(cfg.MaxLeaderUnavailability),
+		"the servers were unavailable for %s, longer than -max-leader-unavailability=%s", longestOutage, cfg.MaxLeaderUnavailability)
+
+	logger.Log(t, "checking that all servers have been restarted")
+	serversAfter := serverPodUIDs(t, ctx, releaseName)
+	require.Len(t, serversAfter, rollingUpgradeServerReplicas)
+	for pod, uid := range serversAfter {
+		require.NotEqualf(t, serversBefore[pod], uid, "server %s has not been restarted by the upgrade", pod)
+	}
+}
+
+// leaderProbe continuously checks that the servers have a leader and serve
+// catalog queries until it's stopped, and records the longest time they didn't.
+type leaderProbe struct {
+	done     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+
+	requests      int
+	longestOutage time.Duration
+	failures      []string
+}
+
+// startLeaderProbe starts a leaderProbe that is stopped
+// when t finishes if the test doesn't stop it first.
+func startLeaderProbe(t *testing.T, consulClient *api.Client) *leaderProbe {
+	p := &leaderProbe{done: make(chan struct{})}
+	t.Cleanup(func() {
+		p.stop()
+	})
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		// outageStart is the time of the first failed query of the current outage,
+		// or zero if the last query succeeded.
+		var outageStart time.Time
+		for {
+			select {
+			case <-p.done:
+				if !outageStart.IsZero() {
+					p.recordOutage(time.Since(outageStart))
+				}
+				return
+			case <-time.After(leaderProbeInterval):
+			}
+			now := time.Now()
+			err := checkLeader(consulClient)
+			p.requests++
+			if err != nil {
+				p.failures = append(p.failures, fmt.Sprintf("%s %s", now.Format(time.RFC3339), err))
+				if outageStart.IsZero() {
+					outageStart = now
+				}
+				continue
+			}
+			if !outageStart.IsZero() {
+				p.recordOutage(now.Sub(outageStart))
+				outageStart = time.Time{}
+			}
+		}
+	}()
+	return p
+}
+
+func (p *leaderProbe) recordOutage(outage time.Duration) {
+	if outage > p.longestOutage {
+		p.longestOutage = outage
+	}
+}
+
+// stop stops the probe and returns the number of queries it sent,
+// the longest outage, and the errors of the queries that failed.
+func (p *leaderProbe) stop() (requests int, longestOutage time.Duration, failures []string) {
+	p.stopOnce.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+	})
+	return p.requests, p.longestOutage, p.failures
+}
+
+// checkLeader returns an error if the servers don't have a leader
+// or fail to list the services in the catalog.
+func checkLeader(consulClient *api.Client) error {
+	leader, err := consulClient.Status().Leader()
+	if err != nil {
+		return err
+	}
+	if leader == "" {
+		return errors.New("no leader")
+	}
+	_, _, err = consulClient.Catalog().Services(nil)
+	return err
+}
+
+// clientAgentConsulClient returns a Consul client for the HTTP API
+// of the first client agent of the release.
+func clientAgentConsulClient(t *testing.T, ctx environment.TestContext, releaseName string) *api.Client {
+	t.Helper()
+
+	pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("release=%s,component=client", releaseName),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, pods.Items)
+
+	addr := k8s.NewPortForwarder(ctx.KubectlOptions(t), terratestLogger.Discard).Forward(t, pods.Items[0].Name, 8500)
+	consulClient, err := api.NewClient(&api.Config{Address: addr})
+	require.NoError(t, err)
+	return consulClient
+}
+
+// serverPodUIDs returns the UIDs of the server pods of the release by pod name.
+func serverPodUIDs(t *testing.T, ctx environment.TestContext, releaseName string) map[string]types.UID {
+	t.Helper()
+
+	pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("release=%s,component=server", releaseName),
+	})
+	require.NoError(t, err)
+	uids := make(map[string]types.UID)
+	for _, pod := range pods.Items {
+		uids[pod.Name] = pod.UID
+	}
+	return uids
+}
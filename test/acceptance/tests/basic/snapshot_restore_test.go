@@ -0,0 +1,46 @@
+package basic
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that the state of Consul survives uninstalling the release, including
+// its persistent volumes, when a snapshot taken before is restored
+// into a fresh installation.
+func TestSnapshotRestore(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, nil, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	consulClient := consulCluster.SetupConsulClient(t, false)
+	_, err := consulClient.KV().Put(&api.KVPair{Key: "snapshot-restore", Value: []byte("survived")}, nil)
+	require.NoError(t, err)
+
+	snapshot := consulCluster.Snapshot(t)
+	require.NotEmpty(t, snapshot)
+
+	logger.Log(t, "reinstalling consul from scratch")
+	consulCluster.Destroy(t)
+	consulCluster.Create(t)
+
+	// The client's port-forward reconnects to the new server pod.
+	kv, _, err := consulClient.KV().Get("snapshot-restore", nil)
+	require.NoError(t, err)
+	require.Nil(t, kv, "expected the new installation to be empty")
+
+	consulCluster.Restore(t, snapshot)
+
+	kv, _, err = consulClient.KV().Get("snapshot-restore", nil)
+	require.NoError(t, err)
+	require.NotNil(t, kv)
+	require.Equal(t, "survived", string(kv.Value))
+}
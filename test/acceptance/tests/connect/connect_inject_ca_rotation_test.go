@@ -0,0 +1,96 @@
+package connect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that rotating the root of the Connect CA doesn't interrupt traffic
+// from the static-client to the static-server. While the leaf certificates of
+// the sidecars are re-issued, the old and the new root are both trusted because
+// the new root is cross-signed by the old one, so every request should succeed.
+func TestConnectInject_CARotation(t *testing.T) {
+	cases := []bool{false, true}
+
+	for _, secure := range cases {
+		name := fmt.Sprintf("secure: %t", secure)
+		t.Run(name, func(t *testing.T) {
+			cfg := suite.Config()
+			helpers.SkipUnlessTag(t, cfg, helpers.TagIf(secure, config.TagSecure))
+			helpers.SkipUnlessInMatrix(t, cfg, config.Matrix{config.MatrixSecure: secure})
+			ctx := suite.Environment().DefaultContext(t)
+
+			helmValues := map[string]string{
+				"connectInject.enabled": "true",
+
+				"global.tls.enabled":           strconv.FormatBool(secure),
+				"global.acls.manageSystemACLs": strconv.FormatBool(secure),
+			}
+
+			releaseName := helpers.RandomName()
+			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+			consulCluster.Create(t)
+
+			logger.Log(t, "creating static-server and static-client deployments")
+			k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+			k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+			if secure {
+				logger.Log(t, "creating static-client => static-server intention")
+				consulClient := consulCluster.SetupConsulClient(t, true)
+				_, _, err := consulClient.Connect().IntentionCreate(&api.Intention{
+					SourceName:      staticClientName,
+					DestinationName: staticServerName,
+					Action:          api.IntentionActionAllow,
+				}, nil)
+				require.NoError(t, err)
+			}
+
+			logger.Log(t, "checking that connection is successful")
+			k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+
+			// Continuously send requests from the static-client to the static-server.
+			probe := k8s.StartConnectionProbe(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+
+			// Changing the private key type of the built-in provider makes the servers
+			// generate a new root that is cross-signed by the old one.
+			_, newRoot := consulCluster.RotateConnectCA(t, secure, "consul", map[string]interface{}{
+				"PrivateKeyType": "rsa",
+				"PrivateKeyBits": 2048,
+			})
+
+			// Keep sending requests until the sidecar of the static-client trusts the new
+			// root, which means that its configuration was updated after the rotation.
+			logger.Log(t, "waiting for the sidecar of the static-client to trust the new root")
+			clientPod := singlePod(t, ctx, "app=static-client")
+			rootLines := strings.Split(strings.TrimSpace(newRoot.RootCertPEM), "\n")
+			require.Greater(t, len(rootLines), 2)
+			retry.RunWith(&retry.Timer{Timeout: 3 * time.Minute, Wait: 5 * time.Second}, t, func(r *retry.R) {
+				configDump, err := k8s.EnvoyConfigDumpE(t, ctx.KubectlOptions(t), clientPod.Name)
+				require.NoError(r, err)
+				require.Contains(r, string(configDump), rootLines[1], "the sidecar doesn't trust the new root yet")
+			})
+			// Also cover the time it takes for the leaf certificates of both sidecars to be re-issued.
+			time.Sleep(30 * time.Second)
+			requests, failures := probe.Stop()
+
+			require.Empty(t, failures, "requests failed during the Connect CA rotation")
+			require.NotZero(t, requests)
+
+			logger.Log(t, "checking that connection is successful after the rotation")
+			k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package connect
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/portallocator"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const injectGoldenDir = "testdata/inject-golden"
+
+// injectorImageFlag matches the flags of the connect injector that set
+// the images of the containers it injects, e.g. -envoy-image="envoyproxy/envoy-alpine:v1.18.3".
+var injectorImageFlag = regexp.MustCompile(`-(consul|envoy|consul-k8s)-image="([^"]+)"`)
+
+// Test that the connect injector webhook returns the expected patches for a set of representative pods.
+// Instead of deploying workloads, this test sends AdmissionReview requests directly to the injector
+// and applies the returned JSON patch to the pod from the request. The full resulting pod is then
+// compared against the golden file for that pod in testdata/inject-golden, so that changes to
+// the injected containers, such as their commands, env, resources, probes, ports and volume
+// mounts, are caught. The values that change between installations, i.e. the release name,
+// namespace, and images, are replaced with placeholders, see normalizeInjectedPod.
+// To update the golden files, run this test with the -update-golden-files flag.
+func TestConnectInject_GoldenPatches(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+	consulCluster.Create(t)
+	placeholders := injectPlaceholders(t, ctx, releaseName)
+
+	localPort := portallocator.Allocate(t)
+	tunnel := terratestk8s.NewTunnelWithLogger(
+		ctx.KubectlOptions(t),
+		terratestk8s.ResourceTypeService,
+		fmt.Sprintf("%s-consul-connect-injector-svc", releaseName),
+		localPort,
+		443,
+		terratestLogger.New(logger.TestLogger{}))
+
+	// Retry creating the port forward since it can fail occasionally.
+	retry.RunWith(&retry.Counter{Wait: 1 * time.Second, Count: 3}, t, func(r *retry.R) {
+		// NOTE: It's okay to pass in `t` to ForwardPortE despite being in a retry
+		// because we're using ForwardPortE (not ForwardPort) so the `t` won't
+		// get used to fail the test, just for logging.
+		require.NoError(r, tunnel.ForwardPortE(t))
+	})
+	t.Cleanup(func() {
+		tunnel.Close()
+	})
+
+	// The webhook serves a certificate issued by the webhook-cert-manager, which we don't need to verify
+	// for local traffic.
+	httpClient := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+
+	podFiles, err := filepath.Glob(filepath.Join(injectGoldenDir, "*.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, podFiles)
+
+	for _, podFile := range podFiles {
+		name := strings.TrimSuffix(filepath.Base(podFile), ".yaml")
+		t.Run(name, func(t *testing.T) {
+			podBytes, err := ioutil.ReadFile(podFile)
+			require.NoError(t, err)
+
+			var pod corev1.Pod
+			require.NoError(t, yaml.NewYAMLOrJSONDecoder(bytes.NewReader(podBytes), 1024).Decode(&pod))
+			pod.Namespace = ctx.KubectlOptions(t).Namespace
+
+			var mutatedPod corev1.Pod
+			retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+				var err error
+				mutatedPod, err = sendAdmissionReview(httpClient, fmt.Sprintf("https://127.0.0.1:%d/mutate", localPort), pod)
+				require.NoError(r, err)
+			})
+
+			actual, err := normalizeInjectedPod(mutatedPod, placeholders)
+			require.NoError(t, err)
+
+			expected := helpers.ReadGoldenFile(t, filepath.Join(injectGoldenDir, name+".golden"), actual, cfg.UpdateGoldenFiles)
+			require.JSONEq(t, string(expected), string(actual))
+		})
+	}
+}
+
+// sendAdmissionReview sends an AdmissionReview request for the pod to the injector URL
+// and returns the result of applying the returned patch to the pod.
+func sendAdmissionReview(client *http.Client, url string, pod corev1.Pod) (corev1.Pod, error) {
+	podJSON, err := json.Marshal(pod)
+	if err != nil {
+		return corev1.Pod{}, err
+	}
+
+	review := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admission.k8s.io/v1",
+			Kind:       "AdmissionReview",
+		},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       types.UID(helpers.RandomName()),
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+			Namespace: pod.Namespace,
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: podJSON},
+		},
+	}
+	reviewJSON, err := json.Marshal(review)
+	if err != nil {
+		return corev1.Pod{}, err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(reviewJSON))
+	if err != nil {
+		return corev1.Pod{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return corev1.Pod{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return corev1.Pod{}, fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, body)
+	}
+
+	var reviewResponse admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &reviewResponse); err != nil {
+		return corev1.Pod{}, err
+	}
+	if reviewResponse.Response == nil {
+		return corev1.Pod{}, fmt.Errorf("admission review response is empty: %s", body)
+	}
+	if !reviewResponse.Response.Allowed {
+		return corev1.Pod{}, fmt.Errorf("pod was not allowed: %v", reviewResponse.Response.Result)
+	}
+
+	mutatedJSON := podJSON
+	if len(reviewResponse.Response.Patch) > 0 {
+		patch, err := jsonpatch.DecodePatch(reviewResponse.Response.Patch)
+		if err != nil {
+			return corev1.Pod{}, err
+		}
+		mutatedJSON, err = patch.Apply(podJSON)
+		if err != nil {
+			return corev1.Pod{}, err
+		}
+	}
+
+	var mutatedPod corev1.Pod
+	if err := json.Unmarshal(mutatedJSON, &mutatedPod); err != nil {
+		return corev1.Pod{}, err
+	}
+	return mutatedPod, nil
+}
+
+// injectPlaceholders returns the values of the release that change between installations,
+// i.e. its name, namespace, and the images that the connect injector injects, mapped to
+// the placeholders that replace them in the golden files.
+func injectPlaceholders(t *testing.T, ctx environment.TestContext, releaseName string) map[string]string {
+	t.Helper()
+
+	namespace := ctx.KubectlOptions(t).Namespace
+	deployment, err := ctx.KubernetesClient(t).AppsV1().Deployments(namespace).Get(context.Background(),
+		fmt.Sprintf("%s-consul-connect-injector-webhook-deployment", releaseName), metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Len(t, deployment.Spec.Template.Spec.Containers, 1)
+	command := strings.Join(deployment.Spec.Template.Spec.Containers[0].Command, " ")
+
+	placeholders := make(map[string]string)
+	for _, match := range injectorImageFlag.FindAllStringSubmatch(command, -1) {
+		placeholders[match[2]] = fmt.Sprintf("<%s-image>", match[1])
+	}
+	require.Len(t, placeholders, 3, "the connect injector doesn't set the consul, envoy, and consul-k8s images: %s", command)
+	placeholders[releaseName] = "<release-name>"
+	placeholders[namespace] = "<namespace>"
+	return placeholders
+}
+
+// normalizeInjectedPod returns the indented JSON of the pod with the values
+// of placeholders replaced with the placeholders. The namespace is only replaced
+// where it's a whole value or a DNS label, e.g. in <service>.<namespace>.svc,
+// because short namespace names such as "default" can appear in other values.
+func normalizeInjectedPod(pod corev1.Pod, placeholders map[string]string) ([]byte, error) {
+	pod.Namespace = ""
+	podJSON, err := json.MarshalIndent(pod, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// Longer values are replaced first because they can contain shorter ones,
+	// e.g. an image can contain the name of the namespace.
+	var values []string
+	for value := range placeholders {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	normalized := string(podJSON)
+	for _, value := range values {
+		placeholder := placeholders[value]
+		if placeholder == "<namespace>" {
+			namespace := regexp.MustCompile(`(["=.])` + regexp.QuoteMeta(value) + `(["\s.])`)
+			normalized = namespace.ReplaceAllString(normalized, "${1}"+placeholder+"${2}")
+			continue
+		}
+		normalized = strings.ReplaceAll(normalized, value, placeholder)
+	}
+	return []byte(normalized + "\n"), nil
+}
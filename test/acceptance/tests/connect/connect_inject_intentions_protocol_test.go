@@ -0,0 +1,69 @@
+package connect
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that traffic denied by intentions fails differently depending on the
+// protocol of the destination service: Envoy closes tcp connections,
+// but responds to HTTP requests with 403 Forbidden.
+func TestConnectInject_IntentionDenialByProtocol(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled":        "true",
+		"global.tls.enabled":           "true",
+		"global.acls.manageSystemACLs": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating static-server and static-client deployments")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+	consulClient := consulCluster.SetupConsulClient(t, true)
+
+	logger.Log(t, "creating an intention denying the static-client")
+	writeIntentionsWithAPI(t, consulClient, map[string][]intentionSource{
+		staticServerName: {{staticClientName, api.IntentionActionDeny}},
+	})
+	requireIntentionCheck(t, consulClient, false)
+
+	cases := []struct {
+		protocol string
+		mode     k8s.DenialMode
+	}{
+		{"tcp", k8s.DeniedL4},
+		{"http", k8s.DeniedL7},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("protocol: %s", c.protocol), func(t *testing.T) {
+			logger.Logf(t, "setting the protocol of the static-server to %s", c.protocol)
+			_, _, err := consulClient.ConfigEntries().Set(&api.ServiceConfigEntry{
+				Kind:     api.ServiceDefaults,
+				Name:     staticServerName,
+				Protocol: c.protocol,
+			}, nil)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				_, err := consulClient.ConfigEntries().Delete(api.ServiceDefaults, staticServerName, nil)
+				require.NoError(t, err)
+			})
+
+			k8s.CheckDeniedAs(t, ctx.KubectlOptions(t), staticClientName, c.mode, "http://localhost:1234")
+		})
+	}
+}
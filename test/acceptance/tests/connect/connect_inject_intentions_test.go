@@ -0,0 +1,201 @@
+package connect
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// intentionSource is a source of a service-intentions config entry.
+type intentionSource struct {
+	name   string
+	action api.IntentionAction
+}
+
+// intentionPrecedenceCases are overlapping intentions for traffic from the
+// static-client to the static-server, keyed by destination, and whether
+// that traffic should be allowed according to Consul's precedence rules:
+// an exact destination takes precedence over a wildcard destination,
+// and then an exact source takes precedence over a wildcard source.
+var intentionPrecedenceCases = []struct {
+	name       string
+	intentions map[string][]intentionSource
+	allowed    bool
+}{
+	{
+		name: "exact source allow overrides wildcard source deny",
+		intentions: map[string][]intentionSource{
+			staticServerName: {{"*", api.IntentionActionDeny}, {staticClientName, api.IntentionActionAllow}},
+		},
+		allowed: true,
+	},
+	{
+		name: "exact source deny overrides wildcard source allow",
+		intentions: map[string][]intentionSource{
+			staticServerName: {{"*", api.IntentionActionAllow}, {staticClientName, api.IntentionActionDeny}},
+		},
+		allowed: false,
+	},
+	{
+		name: "exact destination overrides wildcard destination",
+		intentions: map[string][]intentionSource{
+			"*":              {{staticClientName, api.IntentionActionDeny}},
+			staticServerName: {{"*", api.IntentionActionAllow}},
+		},
+		allowed: true,
+	},
+	{
+		name: "wildcard destination applies without an exact destination",
+		intentions: map[string][]intentionSource{
+			"*": {{staticClientName, api.IntentionActionAllow}},
+		},
+		allowed: true,
+	},
+}
+
+// Test that when intentions overlap, live traffic between injected services
+// is authorized according to Consul's intention precedence rules,
+// both when the intentions are written through the Consul API and
+// when they are written as ServiceIntentions custom resources.
+func TestConnectInject_IntentionPrecedence(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	// With ACLs enabled, traffic is denied unless an intention allows it.
+	helmValues := map[string]string{
+		"connectInject.enabled":        "true",
+		"controller.enabled":           "true",
+		"global.tls.enabled":           "true",
+		"global.acls.manageSystemACLs": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating static-server and static-client deployments")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+	consulClient := consulCluster.SetupConsulClient(t, true)
+
+	for _, method := range []string{"api", "crd"} {
+		for _, c := range intentionPrecedenceCases {
+			t.Run(fmt.Sprintf("%s: %s", method, c.name), func(t *testing.T) {
+				if method == "api" {
+					writeIntentionsWithAPI(t, consulClient, c.intentions)
+				} else {
+					writeIntentionsWithCRDs(t, ctx, cfg.NoCleanupOnFailure, c.intentions)
+				}
+
+				// Wait for Consul to evaluate the intentions as expected before checking
+				// live traffic so that a case expecting denied traffic doesn't pass
+				// just because the intentions haven't been written yet.
+				requireIntentionCheck(t, consulClient, c.allowed)
+
+				if c.allowed {
+					k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+				} else {
+					k8s.CheckStaticServerConnectionFailing(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+				}
+			})
+		}
+	}
+}
+
+// writeIntentionsWithAPI writes a service-intentions config entry per destination
+// and deletes them when the test finishes.
+func writeIntentionsWithAPI(t *testing.T, consulClient *api.Client, intentions map[string][]intentionSource) {
+	t.Helper()
+
+	for destination, sources := range intentions {
+		entry := &api.ServiceIntentionsConfigEntry{
+			Kind: api.ServiceIntentions,
+			Name: destination,
+		}
+		for _, source := range sources {
+			entry.Sources = append(entry.Sources, &api.SourceIntention{Name: source.name, Action: source.action})
+		}
+		_, _, err := consulClient.ConfigEntries().Set(entry, nil)
+		require.NoError(t, err)
+	}
+
+	t.Cleanup(func() {
+		for destination := range intentions {
+			_, err := consulClient.ConfigEntries().Delete(api.ServiceIntentions, destination, nil)
+			require.NoError(t, err)
+		}
+	})
+}
+
+// writeIntentionsWithCRDs applies a ServiceIntentions resource per destination
+// and deletes them when the test finishes. kubectl delete waits for the
+// controller to delete the config entries from Consul because of the
+// finalizer on the resources.
+func writeIntentionsWithCRDs(t *testing.T, ctx environment.TestContext, noCleanupOnFailure bool, intentions map[string][]intentionSource) {
+	t.Helper()
+
+	var resources []string
+	for destination, sources := range intentions {
+		// The wildcard isn't a valid resource name.
+		name := destination
+		if name == "*" {
+			name = "wildcard"
+		}
+		resource := fmt.Sprintf(`apiVersion: consul.hashicorp.com/v1alpha1
+kind: ServiceIntentions
+metadata:
+  name: %s
+spec:
+  destination:
+    name: %q
+  sources:
+`, name, destination)
+		for _, source := range sources {
+			resource += fmt.Sprintf("  - name: %q\n    action: %s\n", source.name, source.action)
+		}
+		resources = append(resources, resource)
+	}
+
+	dir, err := ioutil.TempDir("", "intentions")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	path := filepath.Join(dir, "intentions.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(strings.Join(resources, "---\n")), 0644))
+
+	k8s.KubectlApply(t, ctx.KubectlOptions(t), path)
+	helpers.Cleanup(t, noCleanupOnFailure, func() {
+		k8s.KubectlDelete(t, ctx.KubectlOptions(t), path)
+	})
+}
+
+// requireIntentionCheck waits until Consul reports that traffic from the
+// static-client to the static-server is allowed or denied as expected.
+func requireIntentionCheck(t *testing.T, consulClient *api.Client, allowed bool) {
+	t.Helper()
+
+	retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		actual, _, err := consulClient.Connect().IntentionCheck(&api.IntentionCheck{
+			Source:      staticClientName,
+			Destination: staticServerName,
+			SourceType:  api.IntentionSourceConsul,
+		}, nil)
+		require.NoError(r, err)
+		require.Equal(r, allowed, actual)
+	})
+}
@@ -0,0 +1,48 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that an application that only listens on localhost is reachable
+// through its sidecar proxy but not directly via its pod IP.
+// This is the recommended way to bind applications in the service mesh
+// because it ensures that all traffic to the application goes through the proxy.
+func TestConnectInject_LocalhostOnlyApp(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating static-server and static-client deployments")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject-localhost")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+	logger.Log(t, "checking that connection is successful through the sidecar proxy")
+	k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+
+	pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "app=static-server"})
+	require.NoError(t, err)
+	require.Len(t, pods.Items, 1)
+	podIP := pods.Items[0].Status.PodIP
+	require.NotEmpty(t, podIP)
+
+	logger.Log(t, "checking that connection directly to the pod IP fails")
+	k8s.CheckStaticServerConnectionFailing(t, ctx.KubectlOptions(t), staticClientName, fmt.Sprintf("http://%s:8080", podIP))
+}
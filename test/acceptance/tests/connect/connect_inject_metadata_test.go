@@ -0,0 +1,93 @@
+package connect
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that injected containers receive pod metadata via the downward API
+// and that service registrations include the pod metadata in their Meta.
+// External tooling relies on both to map Consul services back to Kubernetes pods.
+func TestConnectInject_PodMetadata(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating static-server deployment")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+
+	pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "app=static-server"})
+	require.NoError(t, err)
+	require.Len(t, pods.Items, 1)
+	pod := pods.Items[0]
+
+	logger.Log(t, "checking that injected containers receive pod metadata from the downward API")
+	initContainer := findContainer(t, pod.Spec.InitContainers, "consul-connect-inject-init")
+	requireFieldRefEnv(t, initContainer, "POD_NAME", "metadata.name")
+	requireFieldRefEnv(t, initContainer, "POD_NAMESPACE", "metadata.namespace")
+	requireFieldRefEnv(t, initContainer, "HOST_IP", "status.hostIP")
+
+	sidecar := findContainer(t, pod.Spec.Containers, "envoy-sidecar")
+	requireFieldRefEnv(t, sidecar, "HOST_IP", "status.hostIP")
+
+	// Check the resolved value as well to make sure the env var is usable at runtime.
+	hostIP, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "exec", pod.Name, "-c", "envoy-sidecar", "--", "printenv", "HOST_IP")
+	require.NoError(t, err)
+	require.Equal(t, pod.Status.HostIP, strings.TrimSpace(hostIP))
+
+	logger.Log(t, "checking that the service registration includes pod metadata")
+	consulClient := consulCluster.SetupConsulClient(t, false)
+	for _, serviceName := range []string{staticServerName, staticServerName + "-sidecar-proxy"} {
+		services, _, err := consulClient.Catalog().Service(serviceName, "", nil)
+		require.NoError(t, err)
+		require.Len(t, services, 1)
+		require.Equal(t, pod.Name, services[0].ServiceMeta["pod-name"])
+		require.Equal(t, pod.Namespace, services[0].ServiceMeta["k8s-namespace"])
+	}
+}
+
+// findContainer returns the container with the given name or fails the test if it doesn't exist.
+func findContainer(t *testing.T, containers []corev1.Container, name string) corev1.Container {
+	t.Helper()
+
+	for _, c := range containers {
+		if c.Name == name {
+			return c
+		}
+	}
+	require.FailNowf(t, "container not found", "container %s not found in pod", name)
+	return corev1.Container{}
+}
+
+// requireFieldRefEnv checks that the container has an env var with the given name
+// that is set from the given pod field via the downward API.
+func requireFieldRefEnv(t *testing.T, container corev1.Container, name, fieldPath string) {
+	t.Helper()
+
+	for _, env := range container.Env {
+		if env.Name == name {
+			require.NotNil(t, env.ValueFrom, "env var %s in container %s is not set from the downward API", name, container.Name)
+			require.NotNil(t, env.ValueFrom.FieldRef, "env var %s in container %s is not set from a pod field", name, container.Name)
+			require.Equal(t, fieldPath, env.ValueFrom.FieldRef.FieldPath)
+			return
+		}
+	}
+	require.Failf(t, "env var not found", "env var %s not found in container %s", name, container.Name)
+}
@@ -0,0 +1,59 @@
+package connect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// scaleNamespaceCount is the number of Kubernetes namespaces
+// that TestConnectInjectNamespaces_MirroringScale deploys into.
+const scaleNamespaceCount = 20
+
+// Test that when services are injected into many Kubernetes namespaces at the same time,
+// the connect injector mirrors each of them into a Consul namespace
+// and registers each service in the Consul namespace of its Kubernetes namespace.
+func TestConnectInjectNamespaces_MirroringScale(t *testing.T) {
+	cfg := suite.Config()
+	if !cfg.EnableEnterprise {
+		t.Skipf("skipping this test because -enable-enterprise is not set")
+	}
+	helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, config.TagNamespaces, config.TagSlow)
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"global.enableConsulNamespaces":               "true",
+		"connectInject.enabled":                       "true",
+		"connectInject.consulNamespaces.mirroringK8S": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+	consulCluster.Create(t)
+
+	namespaces := k8s.DeployAcrossNamespaces(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, scaleNamespaceCount, "../fixtures/cases/static-server-inject")
+
+	consulClients := make(map[string]*api.Client, len(namespaces))
+	for _, ns := range namespaces {
+		consulClients[ns] = consulCluster.ConsulClientForNamespace(t, false, ns)
+	}
+
+	logger.Logf(t, "checking that %s is registered in the mirrored Consul namespace of each of the %d namespaces", staticServerName, len(namespaces))
+	retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		for _, ns := range namespaces {
+			services, _, err := consulClients[ns].Catalog().Service(staticServerName, "", nil)
+			require.NoError(r, err)
+			require.Len(r, services, 1, "%s isn't registered in Consul namespace %s", staticServerName, ns)
+			require.Equal(r, ns, services[0].Namespace)
+		}
+	})
+}
@@ -7,7 +7,7 @@ import (
 	"strings"
 	"testing"
 
-	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
@@ -69,8 +69,10 @@ func TestConnectInjectNamespaces(t *testing.T) {
 		for _, tproxyEnabled := range tproxyEnabledCases {
 			name := fmt.Sprintf("%s; tproxy: %t", c.name, tproxyEnabled)
 			t.Run(name, func(t *testing.T) {
-				ctx := suite.Environment().DefaultContext(t)
 				cfg := suite.Config()
+				helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, config.TagNamespaces, helpers.TagIf(c.secure, config.TagSecure))
+				helpers.SkipUnlessInMatrix(t, cfg, config.Matrix{config.MatrixSecure: c.secure, config.MatrixTProxy: tproxyEnabled})
+				ctx := suite.Environment().DefaultContext(t)
 
 				helmValues := map[string]string{
 					"global.enableConsulNamespaces":                 "true",
@@ -89,16 +91,8 @@ func TestConnectInjectNamespaces(t *testing.T) {
 
 				consulCluster.Create(t)
 
-				staticServerOpts := &terratestk8s.KubectlOptions{
-					ContextName: ctx.KubectlOptions(t).ContextName,
-					ConfigPath:  ctx.KubectlOptions(t).ConfigPath,
-					Namespace:   staticServerNamespace,
-				}
-				staticClientOpts := &terratestk8s.KubectlOptions{
-					ContextName: ctx.KubectlOptions(t).ContextName,
-					ConfigPath:  ctx.KubectlOptions(t).ConfigPath,
-					Namespace:   staticClientNamespace,
-				}
+				staticServerOpts := ctx.KubectlOptionsForNamespace(t, staticServerNamespace)
+				staticClientOpts := ctx.KubectlOptionsForNamespace(t, staticClientNamespace)
 
 				logger.Logf(t, "creating namespaces %s and %s", staticServerNamespace, staticClientNamespace)
 				k8s.RunKubectl(t, ctx.KubectlOptions(t), "create", "ns", staticServerNamespace)
@@ -139,18 +133,18 @@ func TestConnectInjectNamespaces(t *testing.T) {
 				// Kubernetes namespace.
 				// If a single destination namespace is set, we expect all services
 				// to be registered in that destination Consul namespace.
-				serverQueryOpts := &api.QueryOptions{Namespace: staticServerNamespace}
-				clientQueryOpts := &api.QueryOptions{Namespace: staticClientNamespace}
+				serverConsulNS := staticServerNamespace
+				clientConsulNS := staticClientNamespace
 
 				if !c.mirrorK8S {
-					serverQueryOpts = &api.QueryOptions{Namespace: c.destinationNamespace}
-					clientQueryOpts = &api.QueryOptions{Namespace: c.destinationNamespace}
+					serverConsulNS = c.destinationNamespace
+					clientConsulNS = c.destinationNamespace
 				}
-				services, _, err := consulClient.Catalog().Service(staticServerName, "", serverQueryOpts)
+				services, _, err := consulCluster.ConsulClientForNamespace(t, c.secure, serverConsulNS).Catalog().Service(staticServerName, "", nil)
 				require.NoError(t, err)
 				require.Len(t, services, 1)
 
-				services, _, err = consulClient.Catalog().Service(staticClientName, "", clientQueryOpts)
+				services, _, err = consulCluster.ConsulClientForNamespace(t, c.secure, clientConsulNS).Catalog().Service(staticClientName, "", nil)
 				require.NoError(t, err)
 				require.Len(t, services, 1)
 
@@ -268,6 +262,7 @@ func TestConnectInjectNamespaces_CleanupController(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			cfg := suite.Config()
+			helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, config.TagNamespaces, helpers.TagIf(c.secure, config.TagSecure))
 			ctx := suite.Environment().DefaultContext(t)
 
 			helmValues := map[string]string{
@@ -293,23 +288,18 @@ func TestConnectInjectNamespaces_CleanupController(t *testing.T) {
 			})
 
 			logger.Log(t, "creating static-client deployment")
-			staticClientOpts := &terratestk8s.KubectlOptions{
-				ContextName: ctx.KubectlOptions(t).ContextName,
-				ConfigPath:  ctx.KubectlOptions(t).ConfigPath,
-				Namespace:   staticClientNamespace,
-			}
+			staticClientOpts := ctx.KubectlOptionsForNamespace(t, staticClientNamespace)
 			k8s.DeployKustomize(t, staticClientOpts, cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-namespaces")
 
 			logger.Log(t, "waiting for static-client to be registered with Consul")
-			consulClient := consulCluster.SetupConsulClient(t, c.secure)
 			expectedConsulNS := staticClientNamespace
 			if !c.mirrorK8S {
 				expectedConsulNS = c.destinationNamespace
 			}
-			consulQueryOpts := &api.QueryOptions{Namespace: expectedConsulNS}
+			consulClient := consulCluster.ConsulClientForNamespace(t, c.secure, expectedConsulNS)
 			retry.Run(t, func(r *retry.R) {
 				for _, name := range []string{"static-client", "static-client-sidecar-proxy"} {
-					instances, _, err := consulClient.Catalog().Service(name, "", consulQueryOpts)
+					instances, _, err := consulClient.Catalog().Service(name, "", nil)
 					r.Check(err)
 
 					if len(instances) != 1 {
@@ -331,7 +321,7 @@ func TestConnectInjectNamespaces_CleanupController(t *testing.T) {
 			logger.Log(t, "ensuring pod is deregistered")
 			retry.Run(t, func(r *retry.R) {
 				for _, name := range []string{"static-client", "static-client-sidecar-proxy"} {
-					instances, _, err := consulClient.Catalog().Service(name, "", consulQueryOpts)
+					instances, _, err := consulClient.Catalog().Service(name, "", nil)
 					r.Check(err)
 
 					for _, instance := range instances {
@@ -0,0 +1,153 @@
+package connect
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that injected pods can run in namespaces with a ResourceQuota and LimitRange,
+// as is common in shared clusters. A ResourceQuota on requests and limits rejects
+// pods with any container, including init containers, that doesn't declare them,
+// so the injected containers have to declare their own resources. The defaults of the
+// LimitRange only apply to the application containers because the LimitRanger admission
+// plugin sets them before the connect injector webhook adds its containers.
+// If the injected resources exceed the maximum of a LimitRange, the pods
+// are rejected with events that say so.
+func TestConnectInject_NamespaceQuotas(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled":                                "true",
+		"connectInject.sidecarProxy.resources.requests.memory": recommendedSidecarMemory,
+		"connectInject.sidecarProxy.resources.requests.cpu":    recommendedSidecarCPU,
+		"connectInject.sidecarProxy.resources.limits.memory":   recommendedSidecarMemory,
+		"connectInject.sidecarProxy.resources.limits.cpu":      recommendedSidecarCPU,
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	t.Run("quota", func(t *testing.T) {
+		const namespace = "connect-quota"
+		createQuotaNamespace(t, ctx, cfg.NoCleanupOnFailure, namespace, "")
+		nsOptions := ctx.KubectlOptionsForNamespace(t, namespace)
+
+		logger.Log(t, "creating static-server and static-client deployments in a namespace with a resource quota")
+		k8s.DeployKustomize(t, nsOptions, cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+		k8s.DeployKustomize(t, nsOptions, cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+		for _, app := range []string{staticServerName, staticClientName} {
+			// The quota only admits the injected pods if all their containers declare resources.
+			pods, err := ctx.KubernetesClient(t).CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "app=" + app})
+			require.NoError(t, err)
+			require.Len(t, pods.Items, 1)
+			pod := pods.Items[0]
+
+			injected := append(pod.Spec.InitContainers, pod.Spec.Containers...)
+			for _, container := range injected {
+				if container.Name == app {
+					continue
+				}
+				for _, resources := range []corev1.ResourceList{container.Resources.Requests, container.Resources.Limits} {
+					require.Contains(t, resources, corev1.ResourceCPU, "container %s has no CPU resources", container.Name)
+					require.Contains(t, resources, corev1.ResourceMemory, "container %s has no memory resources", container.Name)
+				}
+			}
+		}
+
+		logger.Log(t, "checking that connection is successful")
+		k8s.CheckStaticServerConnectionSuccessful(t, nsOptions, staticClientName, "http://localhost:1234")
+	})
+
+	t.Run("limit range maximum", func(t *testing.T) {
+		const namespace = "connect-quota-max"
+		// The maximum is below the memory limits of the sidecar and the init container.
+		createQuotaNamespace(t, ctx, cfg.NoCleanupOnFailure, namespace, "64Mi")
+		nsOptions := ctx.KubectlOptionsForNamespace(t, namespace)
+
+		// The deployment never becomes available, so it isn't deployed with k8s.DeployKustomize.
+		kustomizeDir := "../fixtures/cases/static-server-inject"
+		k8s.KubectlApplyK(t, nsOptions, kustomizeDir)
+		helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+			k8s.KubectlDeleteK(t, nsOptions, kustomizeDir)
+		})
+
+		logger.Log(t, "checking that creating the injected pod fails with an event about the maximum")
+		retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+			events, err := ctx.KubernetesClient(t).CoreV1().Events(namespace).List(context.Background(), metav1.ListOptions{FieldSelector: "reason=FailedCreate"})
+			require.NoError(r, err)
+			var messages []string
+			for _, event := range events.Items {
+				messages = append(messages, event.Message)
+			}
+			require.Contains(r, strings.Join(messages, "\n"), "maximum memory usage per Container is 64Mi")
+		})
+
+		pods, err := ctx.KubernetesClient(t).CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "app=" + staticServerName})
+		require.NoError(t, err)
+		require.Empty(t, pods.Items)
+	})
+}
+
+// createQuotaNamespace creates namespace with a ResourceQuota on CPU and memory requests
+// and limits, and a LimitRange with default resources for containers that don't declare any.
+// If maxMemory isn't empty, the LimitRange also limits the memory of each container to it.
+func createQuotaNamespace(t *testing.T, ctx environment.TestContext, noCleanupOnFailure bool, namespace, maxMemory string) {
+	t.Helper()
+
+	client := ctx.KubernetesClient(t)
+	logger.Logf(t, "creating namespace %s with a resource quota", namespace)
+	_, err := client.CoreV1().Namespaces().Create(context.Background(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	helpers.Cleanup(t, noCleanupOnFailure, func() {
+		client.CoreV1().Namespaces().Delete(context.Background(), namespace, metav1.DeleteOptions{})
+	})
+
+	_, err = client.CoreV1().ResourceQuotas(namespace).Create(context.Background(), &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "quota"},
+		Spec: corev1.ResourceQuotaSpec{
+			Hard: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("2"),
+				corev1.ResourceRequestsMemory: resource.MustParse("2Gi"),
+				corev1.ResourceLimitsCPU:      resource.MustParse("2"),
+				corev1.ResourceLimitsMemory:   resource.MustParse("2Gi"),
+			},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	limit := corev1.LimitRangeItem{
+		Type: corev1.LimitTypeContainer,
+		Default: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+			corev1.ResourceMemory: resource.MustParse("32Mi"),
+		},
+		DefaultRequest: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("16Mi"),
+		},
+	}
+	if maxMemory != "" {
+		limit.Max = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(maxMemory)}
+	}
+	_, err = client.CoreV1().LimitRanges(namespace).Create(context.Background(), &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "limits"},
+		Spec:       corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{limit}},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+}
@@ -0,0 +1,188 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// staticServerInstanceRegex matches the pod name in the responses of the
+// static-server-inject-replicas fixture.
+var staticServerInstanceRegex = regexp.MustCompile(`hello world from (\S+?)"?$`)
+
+// Test that when a service has multiple replicas behind a single Kubernetes Service,
+// each replica is registered in Consul, traffic is distributed across all of them,
+// and instances are deregistered when the deployment is scaled down.
+func TestConnectInject_MultipleReplicas(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating static-server with 3 replicas and static-client deployments")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject-replicas")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+	consulClient := consulCluster.SetupConsulClient(t, false)
+
+	logger.Log(t, "checking that all static-server replicas are registered")
+	requireServiceInstances(t, consulClient, staticServerName, 3)
+
+	logger.Log(t, "checking that traffic is distributed across all replicas")
+	pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "app=static-server"})
+	require.NoError(t, err)
+	require.Len(t, pods.Items, 3)
+	expectedInstances := make(map[string]bool)
+	for _, pod := range pods.Items {
+		expectedInstances[pod.Name] = true
+	}
+
+	helpers.Eventually(t, context.Background(), helpers.Backoff{Initial: 1 * time.Second, Multiplier: 1, Timeout: 2 * time.Minute}, func() error {
+		seen := staticServerInstances(t, ctx.KubectlOptions(t), 30)
+		for instance := range expectedInstances {
+			if !seen[instance] {
+				return fmt.Errorf("no requests were served by %s; instances that served requests: %v", instance, seen)
+			}
+		}
+		return nil
+	})
+
+	logger.Log(t, "scaling static-server down to 1 replica")
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "scale", "deploy/"+staticServerName, "--replicas=1")
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "rollout", "status", "deploy/"+staticServerName)
+
+	logger.Log(t, "checking that the removed replicas are deregistered")
+	requireServiceInstances(t, consulClient, staticServerName, 1)
+
+	pods, err = ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "app=static-server"})
+	require.NoError(t, err)
+	require.Len(t, pods.Items, 1)
+	remainingInstance := pods.Items[0].Name
+
+	logger.Logf(t, "checking that all traffic is served by %s", remainingInstance)
+	helpers.Eventually(t, context.Background(), helpers.Backoff{Initial: 1 * time.Second, Multiplier: 1, Timeout: 1 * time.Minute}, func() error {
+		seen := staticServerInstances(t, ctx.KubectlOptions(t), 10)
+		if len(seen) != 1 || !seen[remainingInstance] {
+			return fmt.Errorf("expected all requests to be served by %s, got %v", remainingInstance, seen)
+		}
+		return nil
+	})
+}
+
+// staticServerInstances makes count requests from the static-client to the static-server
+// and returns the set of static-server pod names that served them.
+func staticServerInstances(t *testing.T, options *terratestk8s.KubectlOptions, count int) map[string]bool {
+	t.Helper()
+
+	seen := make(map[string]bool)
+	for i := 0; i < count; i++ {
+		output, err := k8s.RunKubectlAndGetOutputE(t, options, "exec", "deploy/"+staticClientName, "-c", staticClientName, "--", "curl", "-sSf", "http://localhost:1234")
+		if err != nil {
+			continue
+		}
+		if matches := staticServerInstanceRegex.FindStringSubmatch(strings.TrimSpace(output)); len(matches) == 2 {
+			seen[matches[1]] = true
+		}
+	}
+	return seen
+}
+
+// requireServiceInstances waits until the service and its sidecar proxy
+// each have exactly count instances registered in Consul.
+func requireServiceInstances(t *testing.T, consulClient *api.Client, serviceName string, count int) {
+	t.Helper()
+
+	helpers.Eventually(t, context.Background(), helpers.DefaultBackoff(), func() error {
+		for _, name := range []string{serviceName, serviceName + "-sidecar-proxy"} {
+			instances, _, err := consulClient.Catalog().Service(name, "", nil)
+			if err != nil {
+				return err
+			}
+			if len(instances) != count {
+				return fmt.Errorf("expected %d instances of %s, got %d", count, name, len(instances))
+			}
+		}
+		return nil
+	})
+}
+
+// Test that when an injected deployment is scaled down to zero all of its
+// instances are deregistered from Consul, and that scaling it back up
+// registers the new instances and traffic flows again.
+func TestConnectInject_ScaleToZeroAndBack(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating static-server and static-client deployments")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject-replicas")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+	consulClient := consulCluster.SetupConsulClient(t, false)
+	requireServiceInstances(t, consulClient, staticServerName, 3)
+
+	logger.Log(t, "scaling static-server down to 0 replicas")
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "scale", "deploy/"+staticServerName, "--replicas=0")
+
+	logger.Log(t, "checking that all static-server instances are deregistered")
+	requireServiceInstances(t, consulClient, staticServerName, 0)
+
+	logger.Log(t, "checking that connection is unsuccessful")
+	k8s.CheckStaticServerConnectionMultipleFailureMessages(
+		t,
+		ctx.KubectlOptions(t),
+		false,
+		staticClientName,
+		[]string{"curl: (56) Recv failure: Connection reset by peer", "curl: (52) Empty reply from server"},
+		"http://localhost:1234")
+
+	logger.Log(t, "scaling static-server back up to 3 replicas")
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "scale", "deploy/"+staticServerName, "--replicas=3")
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "rollout", "status", "deploy/"+staticServerName)
+
+	logger.Log(t, "checking that the new static-server instances are registered")
+	requireServiceInstances(t, consulClient, staticServerName, 3)
+
+	// Check that none of the registered instances belong to the pods that were scaled down.
+	pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "app=static-server"})
+	require.NoError(t, err)
+	currentPods := make(map[string]bool)
+	for _, pod := range pods.Items {
+		currentPods[pod.Name] = true
+	}
+	instances, _, err := consulClient.Catalog().Service(staticServerName, "", nil)
+	require.NoError(t, err)
+	for _, instance := range instances {
+		require.True(t, currentPods[instance.ServiceMeta["pod-name"]], "instance %s belongs to a pod that no longer exists", instance.ServiceID)
+	}
+
+	logger.Log(t, "checking that connection is successful")
+	k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+}
@@ -0,0 +1,135 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that during a rolling update with surge pods, the number of healthy
+// instances in the Consul catalog never exceeds the number of pods Kubernetes
+// is allowed to run, and that traffic keeps flowing and is never served by
+// pods that have already been terminated.
+func TestConnectInject_RollingUpdateWithSurge(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	const replicas = 3
+	const maxSurge = 1
+
+	helmValues := map[string]string{
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating static-server and static-client deployments")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject-replicas")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+	consulClient := consulCluster.SetupConsulClient(t, false)
+	requireServiceInstances(t, consulClient, staticServerName, replicas)
+
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "patch", "deploy/"+staticServerName, "--type=merge", "-p",
+		fmt.Sprintf(`{"spec":{"strategy":{"type":"RollingUpdate","rollingUpdate":{"maxSurge":%d,"maxUnavailable":0}}}}`, maxSurge))
+
+	oldPods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "app=static-server"})
+	require.NoError(t, err)
+	oldPodNames := make(map[string]bool)
+	for _, pod := range oldPods.Items {
+		oldPodNames[pod.Name] = true
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	// Continuously check the number of healthy static-server instances in the catalog.
+	var maxHealthy int
+	var catalogErrs []string
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(500 * time.Millisecond):
+			}
+			entries, _, err := consulClient.Health().Service(staticServerName, "", true, nil)
+			if err != nil {
+				catalogErrs = append(catalogErrs, err.Error())
+				continue
+			}
+			if len(entries) > maxHealthy {
+				maxHealthy = len(entries)
+			}
+		}
+	}()
+
+	// Continuously send traffic from the static-client to the static-server,
+	// recording failed requests and the instances that served them.
+	type response struct {
+		instance string
+		at       time.Time
+	}
+	var responses []response
+	var failedRequests []string
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			output, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "exec", "deploy/"+staticClientName, "-c", staticClientName, "--", "curl", "-sSf", "http://localhost:1234")
+			if err != nil {
+				failedRequests = append(failedRequests, fmt.Sprintf("%s: %s", err, output))
+				continue
+			}
+			if matches := staticServerInstanceRegex.FindStringSubmatch(strings.TrimSpace(output)); len(matches) == 2 {
+				responses = append(responses, response{instance: matches[1], at: time.Now()})
+			}
+		}
+	}()
+
+	// Trigger a rolling update by changing the pod template.
+	logger.Log(t, "triggering a rolling update of static-server")
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "set", "env", "deploy/"+staticServerName, "-c", staticServerName, fmt.Sprintf("ROLLOUT_ID=%s", helpers.RandomName()))
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "rollout", "status", "deploy/"+staticServerName, "--timeout=5m")
+	rolloutDone := time.Now()
+
+	// Wait for the new instances to be registered, then give the traffic
+	// goroutine some time to send requests after the rollout.
+	requireServiceInstances(t, consulClient, staticServerName, replicas)
+	time.Sleep(10 * time.Second)
+	close(stop)
+	wg.Wait()
+
+	require.Empty(t, catalogErrs, "errors querying the catalog during the rollout")
+	require.LessOrEqual(t, maxHealthy, replicas+maxSurge, "the catalog had more healthy instances than pods allowed by the rollout")
+	require.Empty(t, failedRequests, "requests failed during the rollout")
+	require.NotEmpty(t, responses)
+
+	// Pods from the old replica set are terminated by the time the rollout is complete
+	// and the new instances are registered, so none of them should serve requests after that.
+	for _, r := range responses {
+		if r.at.After(rolloutDone) {
+			require.False(t, oldPodNames[r.instance], "request at %s was served by terminated pod %s", r.at.Format(time.RFC3339), r.instance)
+		}
+	}
+}
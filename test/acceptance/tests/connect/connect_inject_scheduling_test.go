@@ -0,0 +1,111 @@
+package connect
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// customSchedulerName is a scheduler that doesn't exist in the cluster,
+// so pods that use it are never scheduled.
+const customSchedulerName = "consul-test-scheduler"
+
+// Test that injection preserves the topology spread constraints and scheduler name
+// of a pod. The injector patches the pod spec, and a patch that replaces rather than
+// adds to it can silently drop these fields, so the pods would be scheduled by the
+// default scheduler without constraints.
+func TestConnectInject_PreservesSchedulingFields(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	constraints := []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       "kubernetes.io/hostname",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "scheduling-test"},
+			},
+		},
+	}
+
+	cases := []struct {
+		name          string
+		schedulerName string
+	}{
+		{"default scheduler", corev1.DefaultSchedulerName},
+		{"custom scheduler", customSchedulerName},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pods := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace)
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "scheduling-test-",
+					Labels:       map[string]string{"app": "scheduling-test"},
+					Annotations:  map[string]string{"consul.hashicorp.com/connect-inject": "true"},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:    staticClientName,
+							Image:   "docker.mirror.hashicorp.services/curlimages/curl:latest",
+							Command: []string{"/bin/sh", "-c", "--"},
+							Args:    []string{"while true; do sleep 30; done;"},
+						},
+					},
+					SchedulerName:                 c.schedulerName,
+					TopologySpreadConstraints:     constraints,
+					TerminationGracePeriodSeconds: new(int64),
+				},
+			}
+
+			logger.Logf(t, "creating an injected pod with topology spread constraints and scheduler %s", c.schedulerName)
+			pod, err := pods.Create(context.Background(), pod, metav1.CreateOptions{})
+			require.NoError(t, err)
+			helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+				pods.Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+			})
+
+			requireInjectedOnce(t, *pod)
+			require.Equal(t, c.schedulerName, pod.Spec.SchedulerName)
+			helpers.RequireObjectMatches(t, pod.Spec.TopologySpreadConstraints, constraints)
+
+			if c.schedulerName == corev1.DefaultSchedulerName {
+				logger.Log(t, "checking that the pod is scheduled")
+				retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+					pod, err := pods.Get(context.Background(), pod.Name, metav1.GetOptions{})
+					require.NoError(r, err)
+					require.NotEmpty(r, pod.Spec.NodeName)
+				})
+			} else {
+				// The default scheduler ignores pods with a different scheduler name,
+				// so if the injector dropped the field, the pod would be scheduled.
+				logger.Log(t, "checking that the default scheduler doesn't schedule the pod")
+				time.Sleep(10 * time.Second)
+				pod, err := pods.Get(context.Background(), pod.Name, metav1.GetOptions{})
+				require.NoError(t, err)
+				require.Empty(t, pod.Spec.NodeName)
+				require.Equal(t, corev1.PodPending, pod.Status.Phase)
+			}
+		})
+	}
+}
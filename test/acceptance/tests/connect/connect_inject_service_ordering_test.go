@@ -0,0 +1,103 @@
+package connect
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+// unregisteredPeriod is how long the static-server is checked
+// to stay unregistered while no service selects it.
+const unregisteredPeriod = 30 * time.Second
+
+// Test that an injected pod is registered once a Kubernetes service selects it,
+// even if the service is created after the pod or initially has a selector that
+// doesn't match it, as happens when GitOps tools apply manifests in any order.
+func TestConnectInject_ServiceCreatedAfterPod(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	consulClient := consulCluster.SetupConsulClient(t, false)
+
+	// The deployment can't become available without a service, so
+	// it is applied directly instead of with k8s.DeployKustomize.
+	logger.Log(t, "creating static-server deployment without a service")
+	fixture := "../fixtures/cases/static-server-inject-no-service"
+	k8s.KubectlApplyK(t, ctx.KubectlOptions(t), fixture)
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		k8s.WritePodsDebugInfoIfFailed(t, ctx.KubectlOptions(t), cfg.DebugDirectory, "app="+staticServerName)
+		k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "delete", "service", staticServerName, "--ignore-not-found")
+		k8s.KubectlDeleteK(t, ctx.KubectlOptions(t), fixture)
+	})
+	k8s.StreamPodLogs(t, ctx.KubectlOptions(t), cfg.DebugDirectory, "app="+staticServerName)
+
+	requireNotRegistered(t, consulClient, staticServerName)
+
+	logger.Log(t, "creating a static-server service with a selector that doesn't match the pod")
+	_, stderr, err := k8s.RunKubectlWithInputAndGetStdoutStderrE(t, ctx.KubectlOptions(t), []byte(mismatchedServiceManifest), "apply", "-f", "-")
+	require.NoError(t, err, stderr)
+
+	requireNotRegistered(t, consulClient, staticServerName)
+
+	logger.Log(t, "fixing the selector of the static-server service")
+	k8s.KubectlApply(t, ctx.KubectlOptions(t), "../fixtures/bases/static-server/service.yaml")
+
+	// The timeout allows for connect-init to be restarted with a backoff
+	// if it has given up waiting for the service to be registered.
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "wait", "--for=condition=available", "--timeout=5m", fmt.Sprintf("deploy/%s", staticServerName))
+
+	for _, serviceName := range []string{staticServerName, staticServerName + "-sidecar-proxy"} {
+		services, _, err := consulClient.Catalog().Service(serviceName, "", nil)
+		require.NoError(t, err)
+		require.Len(t, services, 1, "service %s is not registered", serviceName)
+	}
+
+	logger.Log(t, "creating static-client deployment")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+	k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+}
+
+// mismatchedServiceManifest is the static-server service
+// with a selector that doesn't match the static-server pod.
+const mismatchedServiceManifest = `apiVersion: v1
+kind: Service
+metadata:
+  name: static-server
+spec:
+  selector:
+    app: static-server-mismatched
+  ports:
+    - name: http
+      port: 80
+      targetPort: 8080
+`
+
+// requireNotRegistered checks that the service stays
+// unregistered in Consul for unregisteredPeriod.
+func requireNotRegistered(t *testing.T, consulClient *api.Client, serviceName string) {
+	t.Helper()
+
+	logger.Logf(t, "checking that %s is not registered for %s", serviceName, unregisteredPeriod)
+	deadline := time.Now().Add(unregisteredPeriod)
+	for time.Now().Before(deadline) {
+		services, _, err := consulClient.Catalog().Service(serviceName, "", nil)
+		require.NoError(t, err)
+		require.Empty(t, services, "service %s is registered without a Kubernetes service selecting its pod", serviceName)
+		time.Sleep(5 * time.Second)
+	}
+}
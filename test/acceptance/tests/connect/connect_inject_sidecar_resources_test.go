@@ -0,0 +1,143 @@
+package connect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// The recommended sidecar proxy resources documented in values.yaml.
+	recommendedSidecarMemory = "100Mi"
+	recommendedSidecarCPU    = "100m"
+
+	// loadWorkers concurrent loops of loadRequests requests each are sent
+	// through the static-client's sidecar to the static-server.
+	loadWorkers  = 20
+	loadRequests = 500
+
+	// failedRequestMarker is printed by the load script for each failed request.
+	failedRequestMarker = "request-failed"
+	// memorySampleInterval is how often the memory of the static-client sidecar is sampled under load.
+	memorySampleInterval = 2 * time.Second
+)
+
+// Test that Envoy sidecars limited to the recommended sidecar proxy resources
+// from values.yaml handle sustained concurrent traffic without being OOM killed
+// or restarted, and stay within their memory limit.
+func TestConnectInject_SidecarResourceLimits(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled":                                "true",
+		"connectInject.sidecarProxy.resources.requests.memory": recommendedSidecarMemory,
+		"connectInject.sidecarProxy.resources.requests.cpu":    recommendedSidecarCPU,
+		"connectInject.sidecarProxy.resources.limits.memory":   recommendedSidecarMemory,
+		"connectInject.sidecarProxy.resources.limits.cpu":      recommendedSidecarCPU,
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating static-server and static-client deployments")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+	memoryLimit := resource.MustParse(recommendedSidecarMemory)
+	recommended := corev1.ResourceList{
+		corev1.ResourceMemory: memoryLimit,
+		corev1.ResourceCPU:    resource.MustParse(recommendedSidecarCPU),
+	}
+	for _, labelSelector := range []string{"app=static-server", "app=static-client"} {
+		sidecar := findContainer(t, singlePod(t, ctx, labelSelector).Spec.Containers, envoySidecarName)
+		helpers.RequireObjectMatches(t, sidecar.Resources, corev1.ResourceRequirements{Requests: recommended, Limits: recommended})
+	}
+
+	k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+
+	idleAllocated := envoyMemoryAllocated(t, ctx)
+
+	logger.Logf(t, "sending %d concurrent loops of %d requests through the sidecars", loadWorkers, loadRequests)
+	// The exit status of the script is the one of wait, so failed requests are counted from the output instead.
+	load := fmt.Sprintf("for w in $(seq %d); do (for i in $(seq %d); do curl -sS -o /dev/null http://localhost:1234 || echo %s; done) & done; wait",
+		loadWorkers, loadRequests, failedRequestMarker)
+	type loadResult struct {
+		stdout, stderr string
+		err            error
+	}
+	done := make(chan loadResult, 1)
+	go func() {
+		stdout, stderr, err := k8s.RunKubectlAndGetStdoutStderrE(t, ctx.KubectlOptions(t), "exec", "deploy/"+staticClientName, "-c", staticClientName, "--", "sh", "-c", load)
+		done <- loadResult{stdout: stdout, stderr: stderr, err: err}
+	}()
+
+	// The static-server image has no shell to query Envoy's admin API with,
+	// but the static-client's sidecar proxies every request. Its memory is sampled
+	// while the load runs because Envoy frees the memory of connections once they close.
+	peakAllocated := idleAllocated
+	var result loadResult
+	for loading := true; loading; {
+		select {
+		case result = <-done:
+			loading = false
+		case <-time.After(memorySampleInterval):
+			if allocated := envoyMemoryAllocated(t, ctx); allocated > peakAllocated {
+				peakAllocated = allocated
+			}
+		}
+	}
+	require.NoError(t, result.err, result.stderr)
+	failed := strings.Count(result.stdout, failedRequestMarker)
+	require.Zero(t, failed, "%d of %d requests failed: %s", failed, loadWorkers*loadRequests, result.stderr)
+
+	logger.Logf(t, "the static-client sidecar had %s allocated when idle and up to %s under load",
+		resource.NewQuantity(idleAllocated, resource.BinarySI), resource.NewQuantity(peakAllocated, resource.BinarySI))
+	require.Greater(t, peakAllocated, idleAllocated, "the load didn't make the static-client sidecar allocate memory")
+	require.Less(t, peakAllocated, memoryLimit.Value(), "the static-client sidecar is close to running out of memory")
+
+	for _, labelSelector := range []string{"app=static-server", "app=static-client"} {
+		pod := singlePod(t, ctx, labelSelector)
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name != envoySidecarName {
+				continue
+			}
+			if terminated := status.LastTerminationState.Terminated; terminated != nil {
+				require.Failf(t, "sidecar was restarted", "the sidecar of pod %s was terminated: %s", pod.Name, terminated.Reason)
+			}
+			require.Zero(t, status.RestartCount, "the sidecar of pod %s was restarted", pod.Name)
+		}
+	}
+
+	k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+}
+
+// envoyMemoryAllocated returns the bytes of memory allocated by the Envoy sidecar
+// of the static-client, as reported by its admin API. The admin API only listens
+// on localhost, so it is queried from the static-client container.
+func envoyMemoryAllocated(t *testing.T, ctx environment.TestContext) int64 {
+	t.Helper()
+
+	output, stderr, err := k8s.RunKubectlAndGetStdoutStderrE(t, ctx.KubectlOptions(t), "exec", "deploy/"+staticClientName, "-c", staticClientName, "--",
+		"curl", "-sS", "localhost:19000/stats?filter=^server.memory_allocated$")
+	require.NoError(t, err, stderr)
+
+	// The output is a single line of the form "server.memory_allocated: 12345678".
+	parts := strings.SplitN(strings.TrimSpace(output), ":", 2)
+	require.Len(t, parts, 2, "unexpected Envoy stats: %s", output)
+	allocated, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+	require.NoError(t, err, "unexpected Envoy stats: %s", output)
+	return allocated
+}
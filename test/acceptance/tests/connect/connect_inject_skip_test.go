@@ -0,0 +1,136 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	injectInitContainerName = "consul-connect-inject-init"
+	envoySidecarName        = "envoy-sidecar"
+)
+
+// Test that with injection enabled by default, the injector skips pods that
+// opt out with the connect-inject annotation, pods that have already been
+// injected, and the pods of the chart itself, both on install and after an upgrade
+// that recreates the chart's pods.
+func TestConnectInject_SkipsExcludedAndInjectedPods(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled": "true",
+		"connectInject.default": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	requireChartPodsNotInjected(t, ctx, releaseName)
+
+	logger.Log(t, "creating a static-server that opts out of injection and an injected static-client")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject-disabled")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+	staticServer := singlePod(t, ctx, "app=static-server")
+	require.Equal(t, []string{"static-server"}, containerNames(staticServer.Spec.Containers))
+	require.Empty(t, staticServer.Spec.InitContainers)
+
+	staticClient := singlePod(t, ctx, "app=static-client")
+	requireInjectedOnce(t, staticClient)
+
+	// The webhook only handles pod creation, so an injected pod is only admitted
+	// again when it is recreated from its own spec, e.g. with kubectl replace --force.
+	// Use a copy with different labels so that the static-client replica set doesn't adopt it.
+	logger.Log(t, "recreating the injected static-client pod from its spec")
+	readmitted := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        staticClient.Name + "-readmitted",
+			Labels:      map[string]string{"app": "static-client-readmitted"},
+			Annotations: staticClient.Annotations,
+		},
+		Spec: staticClient.Spec,
+	}
+	readmitted.Spec.NodeName = ""
+	readmitted, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).Create(context.Background(), readmitted, metav1.CreateOptions{})
+	require.NoError(t, err)
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).Delete(context.Background(), readmitted.Name, metav1.DeleteOptions{})
+	})
+	requireInjectedOnce(t, *readmitted)
+	require.Equal(t, containerNames(staticClient.Spec.Containers), containerNames(readmitted.Spec.Containers))
+	require.Equal(t, containerNames(staticClient.Spec.InitContainers), containerNames(readmitted.Spec.InitContainers))
+
+	// Upgrade with a change to the server and client pod specs so that
+	// all of their pods are recreated while the injector is running.
+	logger.Log(t, "upgrading to recreate the chart's pods")
+	consulCluster.Upgrade(t, map[string]string{
+		"server.extraEnvironmentVars.TEST_UPGRADE": "true",
+		"client.extraEnvironmentVars.TEST_UPGRADE": "true",
+	})
+
+	requireChartPodsNotInjected(t, ctx, releaseName)
+	staticServer = singlePod(t, ctx, "app=static-server")
+	require.Equal(t, []string{"static-server"}, containerNames(staticServer.Spec.Containers))
+}
+
+// requireChartPodsNotInjected checks that none of the pods of the release
+// have been injected.
+func requireChartPodsNotInjected(t *testing.T, ctx environment.TestContext, releaseName string) {
+	t.Helper()
+
+	pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "release=" + releaseName})
+	require.NoError(t, err)
+	require.NotEmpty(t, pods.Items)
+	for _, pod := range pods.Items {
+		require.NotContains(t, containerNames(pod.Spec.Containers), envoySidecarName, "pod %s of the chart has been injected", pod.Name)
+		require.NotContains(t, containerNames(pod.Spec.InitContainers), injectInitContainerName, "pod %s of the chart has been injected", pod.Name)
+	}
+}
+
+// requireInjectedOnce checks that pod has exactly one Envoy sidecar and injection init container.
+func requireInjectedOnce(t *testing.T, pod corev1.Pod) {
+	t.Helper()
+
+	require.Equal(t, 1, countNames(containerNames(pod.Spec.Containers), envoySidecarName), "pod %s: %v", pod.Name, containerNames(pod.Spec.Containers))
+	require.Equal(t, 1, countNames(containerNames(pod.Spec.InitContainers), injectInitContainerName), "pod %s: %v", pod.Name, containerNames(pod.Spec.InitContainers))
+}
+
+// singlePod returns the only pod matching labelSelector.
+func singlePod(t *testing.T, ctx environment.TestContext, labelSelector string) corev1.Pod {
+	t.Helper()
+
+	pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: labelSelector})
+	require.NoError(t, err)
+	require.Len(t, pods.Items, 1, fmt.Sprintf("expected exactly one pod matching %s", labelSelector))
+	return pods.Items[0]
+}
+
+func containerNames(containers []corev1.Container) []string {
+	var names []string
+	for _, container := range containers {
+		names = append(names, container.Name)
+	}
+	return names
+}
+
+func countNames(names []string, name string) int {
+	count := 0
+	for _, n := range names {
+		if n == name {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,103 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const upgradeServerReplicas = 3
+
+// Test the documented order for upgrading Consul: the servers are upgraded
+// one at a time using server.updatePartition while the clients stay on the old
+// version, and the mesh keeps working throughout the mixed-version window.
+// The old and new images are the first and last of -consul-images,
+// e.g. two patch releases of the same minor version.
+func TestConnectInject_StaggeredServerUpgrade(t *testing.T) {
+	cfg := suite.Config()
+	helpers.SkipUnlessTag(t, cfg, config.TagSlow)
+	if len(cfg.ConsulImages) < 2 {
+		t.Skipf("skipping this test because -consul-images doesn't list at least two images to upgrade between")
+	}
+	oldImage := cfg.ConsulImages[0]
+	newImage := cfg.ConsulImages[len(cfg.ConsulImages)-1]
+
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"global.image":           oldImage,
+		"server.replicas":        strconv.Itoa(upgradeServerReplicas),
+		"server.bootstrapExpect": strconv.Itoa(upgradeServerReplicas),
+		"connectInject.enabled":  "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+	// Restarting the servers causes leader elections and dropped connections.
+	consulCluster.AllowErrorLogs(t, "No cluster leader", "agent.server.raft", "agent.server.memberlist", "rpc error", "EOF")
+
+	logger.Log(t, "creating static-server and static-client deployments")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+	k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+
+	// Setting the partition to the number of replicas changes
+	// the stateful set without restarting any of the servers.
+	logger.Logf(t, "upgrading the servers to %s with the clients pinned to %s", newImage, oldImage)
+	consulCluster.Upgrade(t, map[string]string{
+		"server.image":           newImage,
+		"client.image":           oldImage,
+		"server.updatePartition": strconv.Itoa(upgradeServerReplicas),
+	})
+	requireServerImages(t, ctx, releaseName, upgradeServerReplicas, oldImage, newImage)
+
+	for partition := upgradeServerReplicas - 1; partition >= 0; partition-- {
+		logger.Logf(t, "lowering the update partition to %d to upgrade server %d", partition, partition)
+		consulCluster.Upgrade(t, map[string]string{
+			"server.updatePartition": strconv.Itoa(partition),
+		})
+		requireServerImages(t, ctx, releaseName, partition, oldImage, newImage)
+
+		logger.Log(t, "checking that the mesh works with mixed server versions")
+		k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+	}
+
+	logger.Log(t, "checking that the clients are still on the old version")
+	clients, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("release=%s,component=client", releaseName),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, clients.Items)
+	for _, pod := range clients.Items {
+		require.Equal(t, oldImage, findContainer(t, pod.Spec.Containers, "consul").Image, "client pod %s", pod.Name)
+	}
+}
+
+// requireServerImages checks that the servers with an ordinal
+// lower than partition run oldImage and the others run newImage.
+func requireServerImages(t *testing.T, ctx environment.TestContext, releaseName string, partition int, oldImage, newImage string) {
+	t.Helper()
+
+	for i := 0; i < upgradeServerReplicas; i++ {
+		podName := fmt.Sprintf("%s-consul-server-%d", releaseName, i)
+		pod, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).Get(context.Background(), podName, metav1.GetOptions{})
+		require.NoError(t, err)
+
+		expected := newImage
+		if i < partition {
+			expected = oldImage
+		}
+		require.Equal(t, expected, findContainer(t, pod.Spec.Containers, "consul").Image, "server pod %s", podName)
+	}
+}
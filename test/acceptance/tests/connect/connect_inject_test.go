@@ -7,12 +7,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
 	"github.com/hashicorp/consul/api"
-	"github.com/hashicorp/consul/sdk/testutil/retry"
 	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -38,96 +38,104 @@ func TestConnectInject(t *testing.T) {
 			name := fmt.Sprintf("secure: %t; auto-encrypt: %t; tproxy: %t", c.secure, c.autoEncrypt, tproxyEnabled)
 			t.Run(name, func(t *testing.T) {
 				cfg := suite.Config()
-				ctx := suite.Environment().DefaultContext(t)
+				helpers.SkipUnlessTag(t, cfg, helpers.TagIf(c.secure, config.TagSecure))
+				helpers.SkipUnlessInMatrix(t, cfg, config.Matrix{
+					config.MatrixSecure:      c.secure,
+					config.MatrixAutoEncrypt: c.autoEncrypt,
+					config.MatrixTProxy:      tproxyEnabled,
+				})
+				helpers.RunWithRetries(t, 2, func(t *testing.T) {
+					ctx := suite.Environment().DefaultContext(t)
+
+					helmValues := map[string]string{
+						"connectInject.enabled":                         "true",
+						"connectInject.transparentProxy.defaultEnabled": strconv.FormatBool(tproxyEnabled),
+
+						"global.tls.enabled":           strconv.FormatBool(c.secure),
+						"global.tls.enableAutoEncrypt": strconv.FormatBool(c.autoEncrypt),
+						"global.acls.manageSystemACLs": strconv.FormatBool(c.secure),
+					}
 
-				helmValues := map[string]string{
-					"connectInject.enabled":                         "true",
-					"connectInject.transparentProxy.defaultEnabled": strconv.FormatBool(tproxyEnabled),
+					releaseName := helpers.RandomName()
+					consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
 
-					"global.tls.enabled":           strconv.FormatBool(c.secure),
-					"global.tls.enableAutoEncrypt": strconv.FormatBool(c.autoEncrypt),
-					"global.acls.manageSystemACLs": strconv.FormatBool(c.secure),
-				}
+					consulCluster.Create(t)
 
-				releaseName := helpers.RandomName()
-				consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+					logger.Log(t, "creating static-server and static-client deployments")
+					k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+					if tproxyEnabled {
+						k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-tproxy")
+					} else {
+						k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+					}
 
-				consulCluster.Create(t)
+					// Check that both static-server and static-client have been injected and now have 2 containers.
+					for _, labelSelector := range []string{"app=static-server", "app=static-client"} {
+						podList, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{
+							LabelSelector: labelSelector,
+						})
+						require.NoError(t, err)
+						require.Len(t, podList.Items, 1)
+						require.Len(t, podList.Items[0].Spec.Containers, 2)
+					}
 
-				logger.Log(t, "creating static-server and static-client deployments")
-				k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
-				if tproxyEnabled {
-					k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-tproxy")
-				} else {
-					k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
-				}
+					if c.secure {
+						logger.Log(t, "checking that the connection is not successful because there's no intention")
+						if tproxyEnabled {
+							k8s.CheckStaticServerConnectionFailing(t, ctx.KubectlOptions(t), staticClientName, "http://static-server")
+						} else {
+							k8s.CheckStaticServerConnectionFailing(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+						}
 
-				// Check that both static-server and static-client have been injected and now have 2 containers.
-				for _, labelSelector := range []string{"app=static-server", "app=static-client"} {
-					podList, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{
-						LabelSelector: labelSelector,
-					})
-					require.NoError(t, err)
-					require.Len(t, podList.Items, 1)
-					require.Len(t, podList.Items[0].Spec.Containers, 2)
-				}
+						consulClient := consulCluster.SetupConsulClient(t, true)
 
-				if c.secure {
-					logger.Log(t, "checking that the connection is not successful because there's no intention")
+						logger.Log(t, "creating intention")
+						_, _, err := consulClient.Connect().IntentionCreate(&api.Intention{
+							SourceName:      staticClientName,
+							DestinationName: staticServerName,
+							Action:          api.IntentionActionAllow,
+						}, nil)
+						require.NoError(t, err)
+					}
+
+					logger.Log(t, "checking that connection is successful")
 					if tproxyEnabled {
-						k8s.CheckStaticServerConnectionFailing(t, ctx.KubectlOptions(t), staticClientName, "http://static-server")
+						// todo: add an assertion that the traffic is going through the proxy
+						k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://static-server")
 					} else {
-						k8s.CheckStaticServerConnectionFailing(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+						k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
 					}
 
-					consulClient := consulCluster.SetupConsulClient(t, true)
-
-					logger.Log(t, "creating intention")
-					_, _, err := consulClient.Connect().IntentionCreate(&api.Intention{
-						SourceName:      staticClientName,
-						DestinationName: staticServerName,
-						Action:          api.IntentionActionAllow,
-					}, nil)
-					require.NoError(t, err)
-				}
-
-				logger.Log(t, "checking that connection is successful")
-				if tproxyEnabled {
-					// todo: add an assertion that the traffic is going through the proxy
-					k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://static-server")
-				} else {
-					k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
-				}
-
-				// Test that kubernetes readiness status is synced to Consul.
-				// Create the file so that the readiness probe of the static-server pod fails.
-				logger.Log(t, "testing k8s -> consul health checks sync by making the static-server unhealthy")
-				k8s.RunKubectl(t, ctx.KubectlOptions(t), "exec", "deploy/"+staticServerName, "--", "touch", "/tmp/unhealthy")
-
-				// The readiness probe should take a moment to be reflected in Consul, CheckStaticServerConnection will retry
-				// until Consul marks the service instance unavailable for mesh traffic, causing the connection to fail.
-				// We are expecting a "connection reset by peer" error because in a case of health checks,
-				// there will be no healthy proxy host to connect to. That's why we can't assert that we receive an empty reply
-				// from server, which is the case when a connection is unsuccessful due to intentions in other tests.
-				logger.Log(t, "checking that connection is unsuccessful")
-				if tproxyEnabled {
-					k8s.CheckStaticServerConnectionMultipleFailureMessages(
-						t,
-						ctx.KubectlOptions(t),
-						false,
-						staticClientName,
-						[]string{"curl: (56) Recv failure: Connection reset by peer", "curl: (52) Empty reply from server", "curl: (7) Failed to connect to static-server port 80: Connection refused"},
-						"http://static-server")
-				} else {
-					k8s.CheckStaticServerConnectionMultipleFailureMessages(
-						t,
-						ctx.KubectlOptions(t),
-						false,
-						staticClientName,
-						[]string{"curl: (56) Recv failure: Connection reset by peer", "curl: (52) Empty reply from server"},
-						"http://localhost:1234")
-				}
+					// Test that kubernetes readiness status is synced to Consul.
+					// Create the file so that the readiness probe of the static-server pod fails.
+					logger.Log(t, "testing k8s -> consul health checks sync by making the static-server unhealthy")
+					k8s.RunKubectl(t, ctx.KubectlOptions(t), "exec", "deploy/"+staticServerName, "--", "touch", "/tmp/unhealthy")
+
+					// The readiness probe should take a moment to be reflected in Consul, CheckStaticServerConnection will retry
+					// until Consul marks the service instance unavailable for mesh traffic, causing the connection to fail.
+					// We are expecting a "connection reset by peer" error because in a case of health checks,
+					// there will be no healthy proxy host to connect to. That's why we can't assert that we receive an empty reply
+					// from server, which is the case when a connection is unsuccessful due to intentions in other tests.
+					logger.Log(t, "checking that connection is unsuccessful")
+					if tproxyEnabled {
+						k8s.CheckStaticServerConnectionMultipleFailureMessages(
+							t,
+							ctx.KubectlOptions(t),
+							false,
+							staticClientName,
+							[]string{"curl: (56) Recv failure: Connection reset by peer", "curl: (52) Empty reply from server", "curl: (7) Failed to connect to static-server port 80: Connection refused"},
+							"http://static-server")
+					} else {
+						k8s.CheckStaticServerConnectionMultipleFailureMessages(
+							t,
+							ctx.KubectlOptions(t),
+							false,
+							staticClientName,
+							[]string{"curl: (56) Recv failure: Connection reset by peer", "curl: (52) Empty reply from server"},
+							"http://localhost:1234")
+					}
 
+				})
 			})
 		}
 	}
@@ -148,59 +156,69 @@ func TestConnectInject_CleanupKilledPods(t *testing.T) {
 		name := fmt.Sprintf("secure: %t; auto-encrypt: %t", c.secure, c.autoEncrypt)
 		t.Run(name, func(t *testing.T) {
 			cfg := suite.Config()
-			ctx := suite.Environment().DefaultContext(t)
+			helpers.SkipUnlessTag(t, cfg, helpers.TagIf(c.secure, config.TagSecure))
+			helpers.SkipUnlessInMatrix(t, cfg, config.Matrix{config.MatrixSecure: c.secure, config.MatrixAutoEncrypt: c.autoEncrypt})
+			helpers.RunWithRetries(t, 2, func(t *testing.T) {
+				ctx := suite.Environment().DefaultContext(t)
 
-			helmValues := map[string]string{
-				"connectInject.enabled":        "true",
-				"global.tls.enabled":           strconv.FormatBool(c.secure),
-				"global.tls.enableAutoEncrypt": strconv.FormatBool(c.autoEncrypt),
-				"global.acls.manageSystemACLs": strconv.FormatBool(c.secure),
-			}
+				helmValues := map[string]string{
+					"connectInject.enabled":        "true",
+					"global.tls.enabled":           strconv.FormatBool(c.secure),
+					"global.tls.enableAutoEncrypt": strconv.FormatBool(c.autoEncrypt),
+					"global.acls.manageSystemACLs": strconv.FormatBool(c.secure),
+				}
 
-			releaseName := helpers.RandomName()
-			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+				releaseName := helpers.RandomName()
+				consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
 
-			consulCluster.Create(t)
+				consulCluster.Create(t)
 
-			logger.Log(t, "creating static-client deployment")
-			k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+				logger.Log(t, "creating static-client deployment")
+				k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
 
-			logger.Log(t, "waiting for static-client to be registered with Consul")
-			consulClient := consulCluster.SetupConsulClient(t, c.secure)
-			retry.Run(t, func(r *retry.R) {
-				for _, name := range []string{"static-client", "static-client-sidecar-proxy"} {
-					instances, _, err := consulClient.Catalog().Service(name, "", nil)
-					r.Check(err)
+				logger.Log(t, "waiting for static-client to be registered with Consul")
+				consulClient := consulCluster.SetupConsulClient(t, c.secure)
+				helpers.Eventually(t, context.Background(), helpers.DefaultBackoff(), func() error {
+					for _, name := range []string{"static-client", "static-client-sidecar-proxy"} {
+						instances, _, err := consulClient.Catalog().Service(name, "", nil)
+						if err != nil {
+							return err
+						}
 
-					if len(instances) != 1 {
-						r.Errorf("expected 1 instance of %s", name)
+						if len(instances) != 1 {
+							return fmt.Errorf("expected 1 instance of %s, got %d", name, len(instances))
+						}
 					}
-				}
-			})
+					return nil
+				})
+
+				ns := ctx.KubectlOptions(t).Namespace
+				pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ns).List(context.Background(), metav1.ListOptions{LabelSelector: "app=static-client"})
+				require.NoError(t, err)
+				require.Len(t, pods.Items, 1)
+				podName := pods.Items[0].Name
+
+				logger.Logf(t, "force killing the static-client pod %q", podName)
+				var gracePeriod int64 = 0
+				err = ctx.KubernetesClient(t).CoreV1().Pods(ns).Delete(context.Background(), podName, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
+				require.NoError(t, err)
+
+				logger.Log(t, "ensuring pod is deregistered")
+				helpers.Eventually(t, context.Background(), helpers.DefaultBackoff(), func() error {
+					for _, name := range []string{"static-client", "static-client-sidecar-proxy"} {
+						instances, _, err := consulClient.Catalog().Service(name, "", nil)
+						if err != nil {
+							return err
+						}
 
-			ns := ctx.KubectlOptions(t).Namespace
-			pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ns).List(context.Background(), metav1.ListOptions{LabelSelector: "app=static-client"})
-			require.NoError(t, err)
-			require.Len(t, pods.Items, 1)
-			podName := pods.Items[0].Name
-
-			logger.Logf(t, "force killing the static-client pod %q", podName)
-			var gracePeriod int64 = 0
-			err = ctx.KubernetesClient(t).CoreV1().Pods(ns).Delete(context.Background(), podName, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
-			require.NoError(t, err)
-
-			logger.Log(t, "ensuring pod is deregistered")
-			retry.Run(t, func(r *retry.R) {
-				for _, name := range []string{"static-client", "static-client-sidecar-proxy"} {
-					instances, _, err := consulClient.Catalog().Service(name, "", nil)
-					r.Check(err)
-
-					for _, instance := range instances {
-						if strings.Contains(instance.ServiceID, podName) {
-							r.Errorf("%s is still registered", instance.ServiceID)
+						for _, instance := range instances {
+							if strings.Contains(instance.ServiceID, podName) {
+								return fmt.Errorf("%s is still registered", instance.ServiceID)
+							}
 						}
 					}
-				}
+					return nil
+				})
 			})
 		})
 	}
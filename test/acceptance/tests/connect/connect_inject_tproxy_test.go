@@ -0,0 +1,152 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that with transparent proxy, traffic to the ClusterIP of the static-server
+// is redirected through the sidecars, and that traffic to the static-server pod that
+// bypasses the mesh is denied because the sidecar of the server only accepts mTLS.
+func TestConnectInject_TransparentProxy(t *testing.T) {
+	cases := []struct {
+		secure bool
+	}{
+		{false},
+		{true},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("secure: %t", c.secure), func(t *testing.T) {
+			cfg := suite.Config()
+			helpers.SkipUnlessInMatrix(t, cfg, config.Matrix{config.MatrixSecure: c.secure, config.MatrixTProxy: true})
+			ctx := suite.Environment().DefaultContext(t)
+
+			helmValues := map[string]string{
+				"connectInject.enabled":                         "true",
+				"connectInject.transparentProxy.defaultEnabled": "true",
+
+				"global.tls.enabled":           strconv.FormatBool(c.secure),
+				"global.acls.manageSystemACLs": strconv.FormatBool(c.secure),
+			}
+
+			releaseName := helpers.RandomName()
+			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+			consulCluster.Create(t)
+
+			logger.Log(t, "creating static-server and static-client deployments")
+			k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+			k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-tproxy")
+
+			if c.secure {
+				logger.Log(t, "creating intention")
+				consulClient := consulCluster.SetupConsulClient(t, true)
+				_, _, err := consulClient.Connect().IntentionCreate(&api.Intention{
+					SourceName:      staticClientName,
+					DestinationName: staticServerName,
+					Action:          api.IntentionActionAllow,
+				}, nil)
+				require.NoError(t, err)
+			}
+
+			logger.Log(t, "checking that the connection to the static-server ClusterIP is successful")
+			k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://static-server")
+
+			logger.Log(t, "checking that the connection went through the sidecar of the static-client")
+			require.Greater(t, envoyUpstreamConnections(t, ctx, staticServerName), 0)
+
+			// The sidecar of the static-client dials the static-server at the ClusterIP of its
+			// Kubernetes service, which the static-server is registered with as its virtual address.
+			logger.Log(t, "checking that the static-server is registered with the ClusterIP of its service as its virtual address")
+			service, err := ctx.KubernetesClient(t).CoreV1().Services(ctx.KubectlOptions(t).Namespace).Get(context.Background(), staticServerName, metav1.GetOptions{})
+			require.NoError(t, err)
+			consul.RequireServiceTaggedAddress(t, consulCluster.SetupConsulClient(t, c.secure), staticServerName, consul.TaggedAddressVirtual,
+				api.ServiceAddress{Address: service.Spec.ClusterIP, Port: int(service.Spec.Ports[0].Port)}, nil)
+
+			// The sidecar of the static-server redirects all inbound traffic to its public listener,
+			// which requires mTLS, so plain HTTP to the pod IP fails even without ACLs.
+			serverPodIP := singlePod(t, ctx, "app=static-server").Status.PodIP
+			logger.Logf(t, "checking that the connection to the static-server pod IP %s is denied", serverPodIP)
+			k8s.CheckStaticServerConnectionMultipleFailureMessages(
+				t,
+				ctx.KubectlOptions(t),
+				false,
+				staticClientName,
+				[]string{"curl: (52) Empty reply from server", "curl: (56) Recv failure: Connection reset by peer"},
+				fmt.Sprintf("http://%s:8080", serverPodIP))
+		})
+	}
+}
+
+// Test that traffic to an inbound port excluded with the
+// consul.hashicorp.com/transparent-proxy-exclude-inbound-ports annotation
+// isn't redirected to the sidecar, so it reaches the application directly
+// and isn't subject to intentions.
+func TestConnectInject_TransparentProxyExcludeInboundPorts(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled":                         "true",
+		"connectInject.transparentProxy.defaultEnabled": "true",
+
+		"global.tls.enabled":           "true",
+		"global.acls.manageSystemACLs": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating static-server deployment with port 8080 excluded from redirection and static-client deployment")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-tproxy-exclude-inbound-ports")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-tproxy")
+
+	// There is no intention, so the connection is only successful if it bypasses the sidecar of the static-server.
+	serverPodIP := singlePod(t, ctx, "app=static-server").Status.PodIP
+	logger.Logf(t, "checking that the connection to the static-server pod IP %s is successful without an intention", serverPodIP)
+	k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, fmt.Sprintf("http://%s:8080", serverPodIP))
+
+	logger.Log(t, "checking that the connection to the static-server through the mesh is denied by intentions")
+	k8s.CheckStaticServerConnectionFailing(t, ctx.KubectlOptions(t), staticClientName, "http://static-server")
+}
+
+// envoyUpstreamConnections returns the number of connections that the sidecar of the
+// static-client has made to the upstream cluster of service, according to its stats.
+func envoyUpstreamConnections(t *testing.T, ctx environment.TestContext, service string) int {
+	t.Helper()
+
+	var total int
+	retry.Run(t, func(r *retry.R) {
+		output, stderr, err := k8s.RunKubectlAndGetStdoutStderrE(t, ctx.KubectlOptions(t), "exec", "deploy/"+staticClientName, "-c", staticClientName, "--",
+			"curl", "-sS", fmt.Sprintf("localhost:19000/stats?filter=^cluster\\.%s\\..*\\.upstream_cx_total$", service))
+		require.NoError(r, err, stderr)
+
+		// Each line is of the form "cluster.<cluster name>.upstream_cx_total: 1".
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		require.NotEmpty(r, lines[0], "no upstream cluster for %s in Envoy stats", service)
+		total = 0
+		for _, line := range lines {
+			parts := strings.SplitN(line, ":", 2)
+			require.Len(r, parts, 2, "unexpected Envoy stats: %s", output)
+			count, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+			require.NoError(r, err, "unexpected Envoy stats: %s", output)
+			total += count
+		}
+	})
+	return total
+}
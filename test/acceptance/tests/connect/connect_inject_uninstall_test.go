@@ -0,0 +1,106 @@
+package connect
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Test the documented behavior of injected pods when the release is uninstalled
+// while they keep running: their sidecars lose their local agent but keep the
+// configuration they last received, so requests between them may still succeed
+// or fail, but they must not hang and the pods must not crashloop.
+// After the release is installed again, restarting the pods makes the new
+// injector adopt them and Connect works again.
+func TestConnectInject_UninstallLeavesPodsRunning(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating static-server and static-client deployments")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+	logger.Log(t, "checking that connection is successful")
+	k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+
+	restartsBefore := make(map[string]map[string]int32)
+	for _, app := range []string{staticServerName, staticClientName} {
+		restartsBefore[app] = containerRestarts(singlePod(t, ctx, "app="+app))
+	}
+
+	logger.Log(t, "uninstalling the release while the injected pods keep running")
+	consulCluster.Destroy(t)
+
+	// Give the sidecars time to notice that their agent is gone.
+	logger.Log(t, "checking that requests don't hang and the pods don't crashloop")
+	deadline := time.Now().Add(1 * time.Minute)
+	for time.Now().Before(deadline) {
+		requireRequestDoesNotHang(t, ctx)
+		time.Sleep(5 * time.Second)
+	}
+	for _, app := range []string{staticServerName, staticClientName} {
+		pod := singlePod(t, ctx, "app="+app)
+		require.Equal(t, corev1.PodRunning, pod.Status.Phase, "pod %s is not running", pod.Name)
+		require.Equal(t, restartsBefore[app], containerRestarts(pod), "containers of pod %s restarted", pod.Name)
+	}
+
+	logger.Log(t, "reinstalling the release")
+	consulCluster.Create(t)
+
+	// The pods are only registered again once the new injector has injected them.
+	for _, app := range []string{staticServerName, staticClientName} {
+		logger.Logf(t, "restarting %s so that the new release adopts it", app)
+		k8s.RunKubectl(t, ctx.KubectlOptions(t), "rollout", "restart", "deploy/"+app)
+		k8s.RunKubectl(t, ctx.KubectlOptions(t), "rollout", "status", "--timeout=5m", "deploy/"+app)
+	}
+
+	consulClient := consulCluster.SetupConsulClient(t, false)
+	retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		for _, name := range []string{staticServerName, staticClientName} {
+			services, _, err := consulClient.Catalog().Service(name, "", nil)
+			require.NoError(r, err)
+			require.Len(r, services, 1, "%s is not registered with the new release", name)
+		}
+	})
+
+	logger.Log(t, "checking that connection is successful after reinstalling")
+	k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+}
+
+// containerRestarts returns the restart count of each container of pod.
+func containerRestarts(pod corev1.Pod) map[string]int32 {
+	restarts := make(map[string]int32)
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts[status.Name] = status.RestartCount
+	}
+	return restarts
+}
+
+// requireRequestDoesNotHang sends a request from the static-client to the static-server
+// and checks that it either succeeds or fails before curl's timeout.
+// curl exits with 28 when the request times out.
+func requireRequestDoesNotHang(t *testing.T, ctx environment.TestContext) {
+	t.Helper()
+
+	out, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "exec", "deploy/"+staticClientName, "-c", staticClientName,
+		"--", "sh", "-c", "curl -sS --max-time 10 http://localhost:1234 >/dev/null; echo exit=$?")
+	require.NoError(t, err, out)
+	require.False(t, strings.Contains(out, "exit=28"), "request hung after uninstalling: %s", out)
+}
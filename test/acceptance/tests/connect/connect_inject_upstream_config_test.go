@@ -0,0 +1,87 @@
+package connect
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that the per-upstream limits and passive health checks that a ServiceDefaults
+// resource of the static-client sets for the static-server in upstreamConfig.overrides
+// are applied to the Envoy cluster of the static-server in the sidecar of the static-client.
+func TestConnectInject_UpstreamConfig(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled": "true",
+		"controller.enabled":    "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+	consulClient := consulCluster.SetupConsulClient(t, false)
+
+	logger.Log(t, "creating the service-defaults of the static-client with upstream config for the static-server")
+	kustomizeDir := "../fixtures/cases/upstream-config"
+	retry.Run(t, func(r *retry.R) {
+		// Retry because the webhook of the controller may not be serving requests yet.
+		out, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "apply", "-k", kustomizeDir)
+		require.NoError(r, err, out)
+	})
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		k8s.KubectlDeleteK(t, ctx.KubectlOptions(t), kustomizeDir)
+	})
+
+	// The controller can take up to a minute to be elected leader and sync the resource.
+	retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		_, _, err := consulClient.ConfigEntries().Get(api.ServiceDefaults, staticClientName, nil)
+		require.NoError(r, err)
+	})
+
+	logger.Log(t, "creating static-server and static-client deployments")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+	logger.Log(t, "checking that connection is successful")
+	k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+
+	logger.Log(t, "checking the Envoy cluster of the static-server in the sidecar of the static-client")
+	clientPod := singlePod(t, ctx, "app=static-client")
+	retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		configDump, err := k8s.EnvoyConfigDumpE(t, ctx.KubectlOptions(t), clientPod.Name)
+		require.NoError(r, err)
+		clusters, err := k8s.EnvoyClusters(configDump)
+		require.NoError(r, err)
+
+		var upstream *k8s.EnvoyCluster
+		for i, cluster := range clusters {
+			if strings.HasPrefix(cluster.Name, staticServerName+".") {
+				upstream = &clusters[i]
+			}
+		}
+		require.NotNil(r, upstream, "no cluster for %s in the Envoy config", staticServerName)
+
+		require.Equal(r, "2.500s", upstream.ConnectTimeout)
+		require.Len(r, upstream.CircuitBreakers.Thresholds, 1)
+		thresholds := upstream.CircuitBreakers.Thresholds[0]
+		require.Equal(r, intPtr(11), thresholds.MaxConnections)
+		require.Equal(r, intPtr(12), thresholds.MaxPendingRequests)
+		require.Equal(r, intPtr(13), thresholds.MaxRequests)
+		require.Equal(r, "2s", upstream.OutlierDetection.Interval)
+		require.Equal(r, intPtr(7), upstream.OutlierDetection.Consecutive5xx)
+	})
+}
+
+func intPtr(i int) *int {
+	return &i
+}
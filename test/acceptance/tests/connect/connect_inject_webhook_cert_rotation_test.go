@@ -0,0 +1,116 @@
+package connect
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that rotating the connect-inject webhook certificate while pods are
+// continuously being created doesn't cause any admission failures.
+// Because the webhook's failure policy is Ignore, an admission failure
+// results in a pod that is created without being injected, so we check
+// that every pod created during the rotation has been injected.
+// This guards against races between the new certificate being served
+// and the CA bundle being patched into the webhook configuration.
+func TestConnectInject_WebhookCertRotationUnderLoad(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	const loadLabel = "test=webhook-cert-rotation"
+
+	helmValues := map[string]string{
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+	consulCluster.Create(t)
+
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "delete", "pod", "-l", loadLabel, "--wait=false")
+	})
+
+	certSecretName := fmt.Sprintf("%s-consul-connect-inject-webhook-cert", releaseName)
+	certSecret, err := ctx.KubernetesClient(t).CoreV1().Secrets(ctx.KubectlOptions(t).Namespace).Get(context.Background(), certSecretName, metav1.GetOptions{})
+	require.NoError(t, err)
+	oldCert := certSecret.Data["tls.crt"]
+
+	// Continuously create pods that should be injected.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	var createErrs []string
+	var podCount int
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			out, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "run", fmt.Sprintf("webhook-load-%d", i),
+				"--image=docker.mirror.hashicorp.services/hashicorp/http-echo:latest",
+				"--restart=Never",
+				"--labels="+loadLabel,
+				"--annotations=consul.hashicorp.com/connect-inject=true",
+				"--", "-text=hello", "-listen=:8080")
+			if err != nil {
+				createErrs = append(createErrs, fmt.Sprintf("%s: %s", err, out))
+			} else {
+				podCount++
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+	}()
+	// Stop creating pods if the test fails before the pods are checked,
+	// so that the goroutine doesn't outlive the test.
+	var stopOnce sync.Once
+	stopLoad := func() {
+		stopOnce.Do(func() {
+			close(stop)
+			wg.Wait()
+		})
+	}
+	defer stopLoad()
+
+	// Restarting the webhook cert manager makes it generate a new CA and
+	// certificate and patch the CA bundle in the webhook configuration.
+	logger.Log(t, "restarting the webhook cert manager to rotate the webhook certificate")
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "rollout", "restart", fmt.Sprintf("deploy/%s-consul-webhook-cert-manager", releaseName))
+
+	retry.RunWith(&retry.Timer{Timeout: 3 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		certSecret, err := ctx.KubernetesClient(t).CoreV1().Secrets(ctx.KubectlOptions(t).Namespace).Get(context.Background(), certSecretName, metav1.GetOptions{})
+		require.NoError(r, err)
+		require.False(r, bytes.Equal(oldCert, certSecret.Data["tls.crt"]), "webhook certificate has not been rotated yet")
+	})
+	logger.Log(t, "webhook certificate has been rotated")
+
+	// Keep creating pods for a while after the rotation so that we also cover
+	// the time it takes for the injector to pick up the new certificate.
+	time.Sleep(30 * time.Second)
+	stopLoad()
+
+	require.Empty(t, createErrs, "pod creations failed during the webhook certificate rotation")
+	require.NotZero(t, podCount)
+
+	pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: loadLabel})
+	require.NoError(t, err)
+	require.Len(t, pods.Items, podCount)
+	for _, pod := range pods.Items {
+		require.Equal(t, "injected", pod.Annotations["consul.hashicorp.com/connect-inject-status"], "pod %s was not injected", pod.Name)
+	}
+}
@@ -11,5 +11,7 @@ var suite testsuite.Suite
 
 func TestMain(m *testing.M) {
 	suite = testsuite.NewSuite(m)
+	// Connect tests spread replicas across nodes and need at least 3 of them.
+	suite.RequireMinimumNodes(3)
 	os.Exit(suite.Run())
 }
@@ -0,0 +1,150 @@
+package consuldns
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	coreDNSNamespace  = "kube-system"
+	coreDNSConfigMap  = "coredns"
+	coreDNSDeployment = "coredns"
+
+	staticServerName = "static-server"
+	stubDomainPod    = "dns-stub-domain-pod"
+)
+
+// Test that with the Consul domain configured as a stub domain in CoreDNS,
+// pods can resolve Consul services with the cluster DNS, including services
+// in Consul namespaces with the .ns. form of the name.
+func TestConsulDNS_StubDomain(t *testing.T) {
+	cfg := suite.Config()
+
+	cases := []struct {
+		secure           bool
+		enableNamespaces bool
+	}{
+		{false, false},
+		{true, false},
+		{false, true},
+		{true, true},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("secure: %t; namespaces: %t", c.secure, c.enableNamespaces), func(t *testing.T) {
+			if c.enableNamespaces && !cfg.EnableEnterprise {
+				t.Skipf("skipping this test because -enable-enterprise is not set")
+			}
+
+			helpers.SkipUnlessTag(t, cfg, helpers.TagIf(c.secure, config.TagSecure),
+				helpers.TagIf(c.enableNamespaces, config.TagEnterprise), helpers.TagIf(c.enableNamespaces, config.TagNamespaces))
+			ctx := suite.Environment().DefaultContext(t)
+			releaseName := helpers.RandomName()
+
+			helmValues := map[string]string{
+				"dns.enabled":           "true",
+				"connectInject.enabled": "true",
+
+				"global.tls.enabled":           strconv.FormatBool(c.secure),
+				"global.acls.manageSystemACLs": strconv.FormatBool(c.secure),
+			}
+			serviceName := fmt.Sprintf("%s.service.consul", staticServerName)
+			if c.enableNamespaces {
+				const consulNamespace = "dns"
+				helmValues["global.enableConsulNamespaces"] = "true"
+				helmValues["connectInject.consulNamespaces.consulDestinationNamespace"] = consulNamespace
+				serviceName = fmt.Sprintf("%s.service.%s.ns.consul", staticServerName, consulNamespace)
+			}
+
+			cluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+			cluster.Create(t)
+
+			dnsService, err := ctx.KubernetesClient(t).CoreV1().Services(ctx.KubectlOptions(t).Namespace).Get(context.Background(), fmt.Sprintf("%s-%s", releaseName, "consul-dns"), metav1.GetOptions{})
+			require.NoError(t, err)
+			configureCoreDNSStubDomain(t, ctx, dnsService.Spec.ClusterIP)
+
+			logger.Log(t, "creating static-server deployment")
+			k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+			serverPods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "app=static-server"})
+			require.NoError(t, err)
+			require.Len(t, serverPods.Items, 1)
+			serverIP := serverPods.Items[0].Status.PodIP
+
+			// The pod uses the cluster DNS rather than the Consul DNS service directly,
+			// so the query only succeeds if CoreDNS forwards it to Consul.
+			dnsTestPodArgs := []string{
+				"run", "-i", "--rm", stubDomainPod, "--restart", "Never", "--image", "anubhavmishra/tiny-tools", "--", "dig", serviceName,
+			}
+
+			logger.Logf(t, "checking that %s resolves to the static-server pod IP %s", serviceName, serverIP)
+			retry.Run(t, func(r *retry.R) {
+				logs, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), dnsTestPodArgs...)
+				require.NoError(r, err)
+
+				require.Contains(r, logs, "ANSWER SECTION:")
+				require.Contains(r, logs, fmt.Sprintf("%s.\t0\tIN\tA\t%s", serviceName, serverIP))
+			})
+		})
+	}
+}
+
+// configureCoreDNSStubDomain adds a server block for the consul domain that forwards
+// queries to dnsIP to the Corefile of CoreDNS and restarts CoreDNS so that it takes effect.
+// The original Corefile is restored when the test finishes. The test is skipped if the
+// cluster doesn't use CoreDNS, such as GKE clusters, which use kube-dns.
+func configureCoreDNSStubDomain(t *testing.T, ctx environment.TestContext, dnsIP string) {
+	t.Helper()
+
+	configMaps := ctx.KubernetesClient(t).CoreV1().ConfigMaps(coreDNSNamespace)
+	configMap, err := configMaps.Get(context.Background(), coreDNSConfigMap, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		t.Skipf("skipping this test because the cluster doesn't use CoreDNS")
+	}
+	require.NoError(t, err)
+
+	corefile := configMap.Data["Corefile"]
+	configMap.Data["Corefile"] = corefile + fmt.Sprintf(`
+consul:53 {
+    errors
+    cache 30
+    forward . %s
+}
+`, dnsIP)
+
+	logger.Logf(t, "configuring the consul domain as a stub domain in CoreDNS forwarding to %s", dnsIP)
+	_, err = configMaps.Update(context.Background(), configMap, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	helpers.Cleanup(t, suite.Config().NoCleanupOnFailure, func() {
+		configMap, err := configMaps.Get(context.Background(), coreDNSConfigMap, metav1.GetOptions{})
+		require.NoError(t, err)
+		configMap.Data["Corefile"] = corefile
+		_, err = configMaps.Update(context.Background(), configMap, metav1.UpdateOptions{})
+		require.NoError(t, err)
+		restartCoreDNS(t, ctx)
+	})
+
+	restartCoreDNS(t, ctx)
+}
+
+// restartCoreDNS restarts the CoreDNS pods so that they load the current Corefile
+// without waiting for the kubelet to sync the config map and the reload plugin, if any, to notice.
+func restartCoreDNS(t *testing.T, ctx environment.TestContext) {
+	t.Helper()
+
+	options := ctx.KubectlOptionsForNamespace(t, coreDNSNamespace)
+	k8s.RunKubectl(t, options, "rollout", "restart", "deployment", coreDNSDeployment)
+	k8s.RunKubectl(t, options, "rollout", "status", "deployment", coreDNSDeployment, "--timeout", "2m")
+}
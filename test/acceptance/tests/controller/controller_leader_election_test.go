@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// leaderElectionConfigMap is the name of the config map
+// the controller uses as its leader election lock.
+const leaderElectionConfigMap = "consul.hashicorp.com"
+
+// leaderElectionAnnotation is the annotation on the lock
+// that holds the leader election record.
+const leaderElectionAnnotation = "control-plane.alpha.kubernetes.io/leader"
+
+// Test that when the controller leader is deleted while custom resources are
+// still being reconciled, the standby replica takes over and every resource
+// ends up synced to Consul exactly once, both on creation and on deletion.
+func TestController_LeaderElection(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	const resourceCount = 50
+
+	helmValues := map[string]string{
+		"controller.enabled":    "true",
+		"controller.replicas":   "2",
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+	consulCluster.Create(t)
+	consulClient := consulCluster.SetupConsulClient(t, false)
+
+	manifest := serviceDefaultsManifest(t, resourceCount)
+
+	logger.Logf(t, "creating %d service-defaults custom resources", resourceCount)
+	retry.Run(t, func(r *retry.R) {
+		// Retry the kubectl apply because we've seen sporadic
+		// "connection refused" errors where the mutating webhook
+		// endpoint fails initially.
+		out, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "apply", "-f", manifest)
+		require.NoError(r, err, out)
+	})
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		// Ignore errors here because if the test ran as expected
+		// the custom resources will have been deleted.
+		k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "delete", "-f", manifest, "--ignore-not-found")
+	})
+
+	// Delete the leader while the resources are being reconciled.
+	oldLeader := deleteControllerLeader(t, ctx)
+
+	logger.Log(t, "checking that all service-defaults are synced to Consul")
+	// The standby needs to wait for the lease of the deleted leader to expire
+	// before it can take over, hence the long timeout.
+	counter := &retry.Counter{Count: 120, Wait: 2 * time.Second}
+	retry.RunWith(counter, t, func(r *retry.R) {
+		entries, _, err := consulClient.ConfigEntries().List(api.ServiceDefaults, nil)
+		require.NoError(r, err)
+		require.Len(r, entries, resourceCount)
+		for _, entry := range entries {
+			svcDefaults, ok := entry.(*api.ServiceConfigEntry)
+			require.True(r, ok, "could not cast to ServiceConfigEntry")
+			require.Equal(r, "http", svcDefaults.Protocol)
+		}
+
+		output, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "get", "servicedefaults", "-o", "json")
+		require.NoError(r, err)
+		requireAllSynced(r, output, resourceCount)
+	})
+
+	newLeader := controllerLeader(t, ctx)
+	require.NotEqual(t, oldLeader, newLeader, "expected a new controller leader to be elected")
+
+	logger.Log(t, "deleting custom resources and the new controller leader")
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "-f", manifest, "--wait=false")
+	deleteControllerLeader(t, ctx)
+
+	logger.Log(t, "checking that all service-defaults are deleted from Consul")
+	retry.RunWith(counter, t, func(r *retry.R) {
+		entries, _, err := consulClient.ConfigEntries().List(api.ServiceDefaults, nil)
+		require.NoError(r, err)
+		require.Empty(r, entries)
+
+		output, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "get", "servicedefaults", "-o", "name")
+		require.NoError(r, err)
+		require.Empty(r, strings.TrimSpace(output), "custom resources still have finalizers")
+	})
+}
+
+// serviceDefaultsManifest writes a manifest with count service-defaults
+// custom resources to a temporary file and returns its path.
+func serviceDefaultsManifest(t *testing.T, count int) string {
+	t.Helper()
+
+	var docs []string
+	for i := 0; i < count; i++ {
+		docs = append(docs, fmt.Sprintf(`apiVersion: consul.hashicorp.com/v1alpha1
+kind: ServiceDefaults
+metadata:
+  name: svc-%d
+spec:
+  protocol: "http"
+`, i))
+	}
+
+	dir, err := ioutil.TempDir("", "servicedefaults")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	path := filepath.Join(dir, "servicedefaults.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(strings.Join(docs, "---\n")), 0600))
+	return path
+}
+
+// controllerLeader returns the name of the controller pod
+// that currently holds the leader election lock.
+func controllerLeader(t *testing.T, ctx environment.TestContext) string {
+	t.Helper()
+
+	namespace := ctx.KubectlOptions(t).Namespace
+	var leader string
+	retry.Run(t, func(r *retry.R) {
+		cm, err := ctx.KubernetesClient(t).CoreV1().ConfigMaps(namespace).Get(context.Background(), leaderElectionConfigMap, metav1.GetOptions{})
+		require.NoError(r, err)
+
+		var record struct {
+			HolderIdentity string `json:"holderIdentity"`
+		}
+		require.NoError(r, json.Unmarshal([]byte(cm.Annotations[leaderElectionAnnotation]), &record))
+		require.NotEmpty(r, record.HolderIdentity)
+
+		// The holder identity is in the form <pod name>_<uuid>.
+		leader = strings.SplitN(record.HolderIdentity, "_", 2)[0]
+	})
+	return leader
+}
+
+// deleteControllerLeader deletes the controller pod that is currently
+// the leader and returns its name.
+func deleteControllerLeader(t *testing.T, ctx environment.TestContext) string {
+	t.Helper()
+
+	namespace := ctx.KubectlOptions(t).Namespace
+	leader := controllerLeader(t, ctx)
+	logger.Logf(t, "deleting controller leader %s", leader)
+	err := ctx.KubernetesClient(t).CoreV1().Pods(namespace).Delete(context.Background(), leader, metav1.DeleteOptions{})
+	require.NoError(t, err)
+	return leader
+}
+
+// requireAllSynced checks that the custom resources in the kubectl JSON output
+// all have a Synced condition that is true.
+func requireAllSynced(r *retry.R, kubectlOutput string, count int) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	require.NoError(r, json.Unmarshal([]byte(kubectlOutput), &list))
+	require.Len(r, list.Items, count)
+
+	for _, item := range list.Items {
+		synced := false
+		for _, cond := range item.Status.Conditions {
+			if cond.Type == "Synced" && cond.Status == "True" {
+				synced = true
+			}
+		}
+		require.True(r, synced, "custom resource %s is not synced", item.Metadata.Name)
+	}
+}
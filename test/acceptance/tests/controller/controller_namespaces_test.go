@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
@@ -71,6 +72,7 @@ func TestControllerNamespaces(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
+			helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, config.TagNamespaces, helpers.TagIf(c.secure, config.TagSecure))
 			ctx := suite.Environment().DefaultContext(t)
 
 			helmValues := map[string]string{
@@ -106,14 +108,12 @@ func TestControllerNamespaces(t *testing.T) {
 			// Kubernetes namespace.
 			// If a single destination namespace is set, we expect all config entries
 			// to be created in that destination Consul namespace.
-			queryOpts := &api.QueryOptions{Namespace: KubeNS}
+			consulNS := KubeNS
 			if !c.mirrorK8S {
-				queryOpts = &api.QueryOptions{Namespace: c.destinationNamespace}
+				consulNS = c.destinationNamespace
 			}
-			defaultOpts := &api.QueryOptions{
-				Namespace: DefaultConsulNamespace,
-			}
-			consulClient := consulCluster.SetupConsulClient(t, c.secure)
+			consulClient := consulCluster.ConsulClientForNamespace(t, c.secure, consulNS)
+			defaultNSClient := consulCluster.ConsulClientForNamespace(t, c.secure, DefaultConsulNamespace)
 
 			// Test creation.
 			{
@@ -133,56 +133,56 @@ func TestControllerNamespaces(t *testing.T) {
 				counter := &retry.Counter{Count: 60, Wait: 1 * time.Second}
 				retry.RunWith(counter, t, func(r *retry.R) {
 					// service-defaults
-					entry, _, err := consulClient.ConfigEntries().Get(api.ServiceDefaults, "defaults", queryOpts)
+					entry, _, err := consulClient.ConfigEntries().Get(api.ServiceDefaults, "defaults", nil)
 					require.NoError(r, err)
 					svcDefaultEntry, ok := entry.(*api.ServiceConfigEntry)
 					require.True(r, ok, "could not cast to ServiceConfigEntry")
 					require.Equal(r, "http", svcDefaultEntry.Protocol)
 
 					// service-resolver
-					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceResolver, "resolver", queryOpts)
+					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceResolver, "resolver", nil)
 					require.NoError(r, err)
 					svcResolverEntry, ok := entry.(*api.ServiceResolverConfigEntry)
 					require.True(r, ok, "could not cast to ServiceResolverConfigEntry")
 					require.Equal(r, "bar", svcResolverEntry.Redirect.Service)
 
 					// proxy-defaults
-					entry, _, err = consulClient.ConfigEntries().Get(api.ProxyDefaults, "global", defaultOpts)
+					entry, _, err = defaultNSClient.ConfigEntries().Get(api.ProxyDefaults, "global", nil)
 					require.NoError(r, err)
 					proxyDefaultEntry, ok := entry.(*api.ProxyConfigEntry)
 					require.True(r, ok, "could not cast to ProxyConfigEntry")
 					require.Equal(r, api.MeshGatewayModeLocal, proxyDefaultEntry.MeshGateway.Mode)
 
 					// mesh
-					entry, _, err = consulClient.ConfigEntries().Get(api.MeshConfig, "mesh", defaultOpts)
+					entry, _, err = defaultNSClient.ConfigEntries().Get(api.MeshConfig, "mesh", nil)
 					require.NoError(r, err)
 					meshEntry, ok := entry.(*api.MeshConfigEntry)
 					require.True(r, ok, "could not cast to MeshConfigEntry")
 					require.True(r, meshEntry.TransparentProxy.CatalogDestinationsOnly)
 
 					// service-router
-					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceRouter, "router", queryOpts)
+					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceRouter, "router", nil)
 					require.NoError(r, err)
 					svcRouterEntry, ok := entry.(*api.ServiceRouterConfigEntry)
 					require.True(r, ok, "could not cast to ServiceRouterConfigEntry")
 					require.Equal(r, "/foo", svcRouterEntry.Routes[0].Match.HTTP.PathPrefix)
 
 					// service-splitter
-					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceSplitter, "splitter", queryOpts)
+					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceSplitter, "splitter", nil)
 					require.NoError(r, err)
 					svcSplitterEntry, ok := entry.(*api.ServiceSplitterConfigEntry)
 					require.True(r, ok, "could not cast to ServiceSplitterConfigEntry")
 					require.Equal(r, float32(100), svcSplitterEntry.Splits[0].Weight)
 
 					// service-intentions
-					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceIntentions, IntentionName, queryOpts)
+					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceIntentions, IntentionName, nil)
 					require.NoError(r, err)
 					svcIntentions, ok := entry.(*api.ServiceIntentionsConfigEntry)
 					require.True(r, ok, "could not cast to ServiceSplitterConfigEntry")
 					require.Equal(r, api.IntentionActionAllow, svcIntentions.Sources[0].Action)
 
 					// ingress-gateway
-					entry, _, err = consulClient.ConfigEntries().Get(api.IngressGateway, "ingress-gateway", queryOpts)
+					entry, _, err = consulClient.ConfigEntries().Get(api.IngressGateway, "ingress-gateway", nil)
 					require.NoError(r, err)
 					ingressGatewayEntry, ok := entry.(*api.IngressGatewayConfigEntry)
 					require.True(r, ok, "could not cast to IngressGatewayConfigEntry")
@@ -193,7 +193,7 @@ func TestControllerNamespaces(t *testing.T) {
 					require.Equal(r, "foo", ingressGatewayEntry.Listeners[0].Services[0].Name)
 
 					// terminating-gateway
-					entry, _, err = consulClient.ConfigEntries().Get(api.TerminatingGateway, "terminating-gateway", queryOpts)
+					entry, _, err = consulClient.ConfigEntries().Get(api.TerminatingGateway, "terminating-gateway", nil)
 					require.NoError(r, err)
 					terminatingGatewayEntry, ok := entry.(*api.TerminatingGatewayConfigEntry)
 					require.True(r, ok, "could not cast to TerminatingGatewayConfigEntry")
@@ -204,6 +204,8 @@ func TestControllerNamespaces(t *testing.T) {
 					require.Equal(r, "keyFile", terminatingGatewayEntry.Services[0].KeyFile)
 					require.Equal(r, "sni", terminatingGatewayEntry.Services[0].SNI)
 				})
+
+				requireFixturesSynced(t, ctx.KubectlOptionsForNamespace(t, KubeNS))
 			}
 
 			// Test updates.
@@ -244,42 +246,42 @@ func TestControllerNamespaces(t *testing.T) {
 				counter := &retry.Counter{Count: 10, Wait: 500 * time.Millisecond}
 				retry.RunWith(counter, t, func(r *retry.R) {
 					// service-defaults
-					entry, _, err := consulClient.ConfigEntries().Get(api.ServiceDefaults, "defaults", queryOpts)
+					entry, _, err := consulClient.ConfigEntries().Get(api.ServiceDefaults, "defaults", nil)
 					require.NoError(r, err)
 					svcDefaultEntry, ok := entry.(*api.ServiceConfigEntry)
 					require.True(r, ok, "could not cast to ServiceConfigEntry")
 					require.Equal(r, patchProtocol, svcDefaultEntry.Protocol)
 
 					// service-resolver
-					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceResolver, "resolver", queryOpts)
+					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceResolver, "resolver", nil)
 					require.NoError(r, err)
 					svcResolverEntry, ok := entry.(*api.ServiceResolverConfigEntry)
 					require.True(r, ok, "could not cast to ServiceResolverConfigEntry")
 					require.Equal(r, patchRedirectSvc, svcResolverEntry.Redirect.Service)
 
 					// proxy-defaults
-					entry, _, err = consulClient.ConfigEntries().Get(api.ProxyDefaults, "global", defaultOpts)
+					entry, _, err = defaultNSClient.ConfigEntries().Get(api.ProxyDefaults, "global", nil)
 					require.NoError(r, err)
 					proxyDefaultsEntry, ok := entry.(*api.ProxyConfigEntry)
 					require.True(r, ok, "could not cast to ProxyConfigEntry")
 					require.Equal(r, api.MeshGatewayModeRemote, proxyDefaultsEntry.MeshGateway.Mode)
 
 					// mesh
-					entry, _, err = consulClient.ConfigEntries().Get(api.MeshConfig, "mesh", defaultOpts)
+					entry, _, err = defaultNSClient.ConfigEntries().Get(api.MeshConfig, "mesh", nil)
 					require.NoError(r, err)
 					meshEntry, ok := entry.(*api.MeshConfigEntry)
 					require.True(r, ok, "could not cast to MeshConfigEntry")
 					require.False(r, meshEntry.TransparentProxy.CatalogDestinationsOnly)
 
 					// service-router
-					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceRouter, "router", queryOpts)
+					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceRouter, "router", nil)
 					require.NoError(r, err)
 					svcRouterEntry, ok := entry.(*api.ServiceRouterConfigEntry)
 					require.True(r, ok, "could not cast to ServiceRouterConfigEntry")
 					require.Equal(r, patchPathPrefix, svcRouterEntry.Routes[0].Match.HTTP.PathPrefix)
 
 					// service-splitter
-					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceSplitter, "splitter", queryOpts)
+					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceSplitter, "splitter", nil)
 					require.NoError(r, err)
 					svcSplitter, ok := entry.(*api.ServiceSplitterConfigEntry)
 					require.True(r, ok, "could not cast to ServiceSplitterConfigEntry")
@@ -288,26 +290,28 @@ func TestControllerNamespaces(t *testing.T) {
 					require.Equal(r, "other-splitter", svcSplitter.Splits[1].Service)
 
 					// service-intentions
-					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceIntentions, IntentionName, queryOpts)
+					entry, _, err = consulClient.ConfigEntries().Get(api.ServiceIntentions, IntentionName, nil)
 					require.NoError(r, err)
 					svcIntentions, ok := entry.(*api.ServiceIntentionsConfigEntry)
 					require.True(r, ok, "could not cast to ServiceIntentionsConfigEntry")
 					require.Equal(r, api.IntentionActionDeny, svcIntentions.Sources[0].Action)
 
 					// ingress-gateway
-					entry, _, err = consulClient.ConfigEntries().Get(api.IngressGateway, "ingress-gateway", queryOpts)
+					entry, _, err = consulClient.ConfigEntries().Get(api.IngressGateway, "ingress-gateway", nil)
 					require.NoError(r, err)
 					ingressGatewayEntry, ok := entry.(*api.IngressGatewayConfigEntry)
 					require.True(r, ok, "could not cast to IngressGatewayConfigEntry")
 					require.Equal(r, patchPort, ingressGatewayEntry.Listeners[0].Port)
 
 					// terminating-gateway
-					entry, _, err = consulClient.ConfigEntries().Get(api.TerminatingGateway, "terminating-gateway", queryOpts)
+					entry, _, err = consulClient.ConfigEntries().Get(api.TerminatingGateway, "terminating-gateway", nil)
 					require.NoError(r, err)
 					terminatingGatewayEntry, ok := entry.(*api.TerminatingGatewayConfigEntry)
 					require.True(r, ok, "could not cast to TerminatingGatewayConfigEntry")
 					require.Equal(r, patchSNI, terminatingGatewayEntry.Services[0].SNI)
 				})
+
+				requireFixturesSynced(t, ctx.KubectlOptionsForNamespace(t, KubeNS))
 			}
 
 			// Test a delete.
@@ -342,47 +346,47 @@ func TestControllerNamespaces(t *testing.T) {
 				counter := &retry.Counter{Count: 10, Wait: 500 * time.Millisecond}
 				retry.RunWith(counter, t, func(r *retry.R) {
 					// service-defaults
-					_, _, err := consulClient.ConfigEntries().Get(api.ServiceDefaults, "defaults", queryOpts)
+					_, _, err := consulClient.ConfigEntries().Get(api.ServiceDefaults, "defaults", nil)
 					require.Error(r, err)
 					require.Contains(r, err.Error(), "404 (Config entry not found")
 
 					// service-resolver
-					_, _, err = consulClient.ConfigEntries().Get(api.ServiceResolver, "resolver", queryOpts)
+					_, _, err = consulClient.ConfigEntries().Get(api.ServiceResolver, "resolver", nil)
 					require.Error(r, err)
 					require.Contains(r, err.Error(), "404 (Config entry not found")
 
 					// proxy-defaults
-					_, _, err = consulClient.ConfigEntries().Get(api.ProxyDefaults, "global", defaultOpts)
+					_, _, err = defaultNSClient.ConfigEntries().Get(api.ProxyDefaults, "global", nil)
 					require.Error(r, err)
 					require.Contains(r, err.Error(), "404 (Config entry not found")
 
 					// mesh
-					_, _, err = consulClient.ConfigEntries().Get(api.MeshConfig, "mesh", defaultOpts)
+					_, _, err = defaultNSClient.ConfigEntries().Get(api.MeshConfig, "mesh", nil)
 					require.Error(r, err)
 					require.Contains(r, err.Error(), "404 (Config entry not found")
 
 					// service-router
-					_, _, err = consulClient.ConfigEntries().Get(api.ServiceRouter, "router", queryOpts)
+					_, _, err = consulClient.ConfigEntries().Get(api.ServiceRouter, "router", nil)
 					require.Error(r, err)
 					require.Contains(r, err.Error(), "404 (Config entry not found")
 
 					// service-splitter
-					_, _, err = consulClient.ConfigEntries().Get(api.ServiceSplitter, "splitter", queryOpts)
+					_, _, err = consulClient.ConfigEntries().Get(api.ServiceSplitter, "splitter", nil)
 					require.Error(r, err)
 					require.Contains(r, err.Error(), "404 (Config entry not found")
 
 					// service-intentions
-					_, _, err = consulClient.ConfigEntries().Get(api.ServiceIntentions, IntentionName, queryOpts)
+					_, _, err = consulClient.ConfigEntries().Get(api.ServiceIntentions, IntentionName, nil)
 					require.Error(r, err)
 					require.Contains(r, err.Error(), "404 (Config entry not found")
 
 					// ingress-gateway
-					_, _, err = consulClient.ConfigEntries().Get(api.IngressGateway, "ingress-gateway", queryOpts)
+					_, _, err = consulClient.ConfigEntries().Get(api.IngressGateway, "ingress-gateway", nil)
 					require.Error(r, err)
 					require.Contains(r, err.Error(), "404 (Config entry not found")
 
 					// terminating-gateway
-					_, _, err = consulClient.ConfigEntries().Get(api.IngressGateway, "terminating-gateway", queryOpts)
+					_, _, err = consulClient.ConfigEntries().Get(api.IngressGateway, "terminating-gateway", nil)
 					require.Error(r, err)
 					require.Contains(r, err.Error(), "404 (Config entry not found")
 				})
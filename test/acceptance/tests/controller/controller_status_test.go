@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// crdFixturesDir has a custom resource of every kind the controller supports.
+const crdFixturesDir = "../fixtures/crds"
+
+// customResourceKinds are the kinds of the custom resources in crdFixturesDir.
+const customResourceKinds = "servicedefaults,serviceresolver,proxydefaults,mesh,servicerouter,servicesplitter,serviceintentions,ingressgateway,terminatinggateway"
+
+// A service-router for a service without an HTTP protocol, which Consul rejects,
+// and the service-defaults that fix it.
+const (
+	unroutableServiceRouter = `apiVersion: consul.hashicorp.com/v1alpha1
+kind: ServiceRouter
+metadata:
+  name: unroutable
+spec:
+  routes:
+    - match:
+        http:
+          pathPrefix: "/admin"
+      destination:
+        service: admin
+`
+	unroutableServiceDefaults = `apiVersion: consul.hashicorp.com/v1alpha1
+kind: ServiceDefaults
+metadata:
+  name: unroutable
+spec:
+  protocol: http
+`
+)
+
+// Test that the controller reports a failure to write a config entry
+// in the Synced condition of the custom resource and that it retries,
+// so the resource becomes synced once the cause of the failure is fixed.
+func TestController_SyncFailureStatus(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"controller.enabled":    "true",
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating a service-router for a service with the tcp protocol")
+	applyCustomResource(t, ctx.KubectlOptions(t), unroutableServiceRouter)
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "delete", "servicerouter,servicedefaults", "unroutable", "--ignore-not-found")
+	})
+
+	// On startup, the controller can take upwards of 1m to perform leader election.
+	counter := &retry.Counter{Count: 60, Wait: 1 * time.Second}
+	retry.RunWith(counter, t, func(r *retry.R) {
+		output, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "get", "servicerouter", "unroutable", "-o", "json")
+		require.NoError(r, err, output)
+		synced := syncedCondition(r, output)
+		require.Equal(r, "False", synced.Status)
+		require.Equal(r, "ConsulAgentError", synced.Reason)
+		require.Contains(r, synced.Message, "does not permit advanced routing or splitting behavior")
+	})
+
+	logger.Log(t, "setting the protocol of the service to http")
+	applyCustomResource(t, ctx.KubectlOptions(t), unroutableServiceDefaults)
+
+	// The controller retries failed resources with a backoff.
+	retry.RunWith(&retry.Counter{Count: 60, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		output, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "get", "servicerouter,servicedefaults", "unroutable", "-o", "json")
+		require.NoError(r, err, output)
+		requireAllSynced(r, output, 2)
+	})
+}
+
+// applyCustomResource applies the custom resource in yaml.
+func applyCustomResource(t *testing.T, options *terratestk8s.KubectlOptions, yaml string) {
+	t.Helper()
+
+	// Retry the kubectl apply because we've seen sporadic
+	// "connection refused" errors where the mutating webhook
+	// endpoint fails initially.
+	retry.Run(t, func(r *retry.R) {
+		_, stderr, err := k8s.RunKubectlWithInputAndGetStdoutStderrE(t, options, []byte(yaml), "apply", "-f", "-")
+		require.NoError(r, err, stderr)
+	})
+}
+
+// requireFixturesSynced waits until every custom resource from crdFixturesDir
+// in the namespace of options has a Synced condition that is true.
+func requireFixturesSynced(t *testing.T, options *terratestk8s.KubectlOptions) {
+	t.Helper()
+
+	count := fixtureResourceCount(t)
+	logger.Log(t, "checking that the custom resources are synced")
+	retry.RunWith(&retry.Counter{Count: 60, Wait: 1 * time.Second}, t, func(r *retry.R) {
+		output, err := k8s.RunKubectlAndGetOutputE(t, options, "get", customResourceKinds, "-o", "json")
+		require.NoError(r, err, output)
+		requireAllSynced(r, output, count)
+	})
+}
+
+// fixtureResourceCount returns the number of custom resources in crdFixturesDir.
+func fixtureResourceCount(t *testing.T) int {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(crdFixturesDir, "*.yaml"))
+	require.NoError(t, err)
+	kindLine := regexp.MustCompile(`(?m)^kind:`)
+	count := 0
+	for _, file := range files {
+		contents, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		count += len(kindLine.FindAll(contents, -1))
+	}
+	return count
+}
+
+// condition is a status condition of a custom resource.
+type condition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// syncedCondition returns the Synced condition of the custom resource in the kubectl JSON output.
+func syncedCondition(r *retry.R, kubectlOutput string) condition {
+	var resource struct {
+		Status struct {
+			Conditions []condition `json:"conditions"`
+		} `json:"status"`
+	}
+	require.NoError(r, json.Unmarshal([]byte(kubectlOutput), &resource))
+	for _, cond := range resource.Status.Conditions {
+		if cond.Type == "Synced" {
+			return cond
+		}
+	}
+	r.Fatalf("custom resource has no Synced condition: %v", resource.Status.Conditions)
+	return condition{}
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
@@ -35,6 +36,8 @@ func TestController(t *testing.T) {
 	for _, c := range cases {
 		name := fmt.Sprintf("secure: %t; auto-encrypt: %t", c.secure, c.autoEncrypt)
 		t.Run(name, func(t *testing.T) {
+			helpers.SkipUnlessTag(t, cfg, helpers.TagIf(c.secure, config.TagSecure))
+			helpers.SkipUnlessInMatrix(t, cfg, config.Matrix{config.MatrixSecure: c.secure, config.MatrixAutoEncrypt: c.autoEncrypt})
 			ctx := suite.Environment().DefaultContext(t)
 
 			helmValues := map[string]string{
@@ -153,6 +156,8 @@ func TestController(t *testing.T) {
 					require.Equal(r, "keyFile", terminatingGatewayEntry.Services[0].KeyFile)
 					require.Equal(r, "sni", terminatingGatewayEntry.Services[0].SNI)
 				})
+
+				requireFixturesSynced(t, ctx.KubectlOptions(t))
 			}
 
 			// Test updates.
@@ -258,6 +263,8 @@ func TestController(t *testing.T) {
 					require.True(r, ok, "could not cast to TerminatingGatewayConfigEntry")
 					require.Equal(r, patchSNI, terminatingGatewayEntry.Services[0].SNI)
 				})
+
+				requireFixturesSynced(t, ctx.KubectlOptions(t))
 			}
 
 			// Test a delete.
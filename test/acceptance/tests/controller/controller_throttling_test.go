@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// throttledPeriod is how long the components are throttled for
+// while they are expected to keep running.
+const throttledPeriod = 2 * time.Minute
+
+// Test that the controller and the connect injector keep running while the API server
+// throttles their requests, rather than crash-looping, and that they catch up once the
+// throttling stops: the controller syncs the custom resources created while it was
+// throttled, and the injector injects new pods. The API server throttles them with API Priority
+// and Fairness rather than a rate-limiting proxy in front of it, so that the components run
+// unmodified with their in-cluster config, see k8s.ThrottleServiceAccounts.
+func TestController_APIServerThrottling(t *testing.T) {
+	cfg := suite.Config()
+	helpers.SkipUnlessTag(t, cfg, config.TagSlow)
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"controller.enabled":    "true",
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+	// The injector logs errors while its requests are rejected.
+	consulCluster.AllowErrorLogs(t, "429", "[Tt]oo many requests")
+
+	components := []string{"controller", "connect-injector"}
+	serviceAccounts := []string{
+		fmt.Sprintf("%s-consul-controller", releaseName),
+		fmt.Sprintf("%s-consul-connect-injector-webhook-svc-account", releaseName),
+	}
+	throttlingName := releaseName + "-throttled"
+	unthrottle := k8s.ThrottleServiceAccounts(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, throttlingName, serviceAccounts...)
+
+	logger.Log(t, "creating custom resources while the controller is throttled")
+	retry.Run(t, func(r *retry.R) {
+		out, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "apply", "-f", crdFixturesDir)
+		require.NoError(r, err, out)
+	})
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "delete", "-f", crdFixturesDir)
+	})
+
+	logger.Logf(t, "checking that the components keep running while throttled for %s", throttledPeriod)
+	deadline := time.Now().Add(throttledPeriod)
+	for time.Now().Before(deadline) {
+		for _, component := range components {
+			requireNoRestarts(t, ctx, releaseName, component)
+		}
+		time.Sleep(10 * time.Second)
+	}
+
+	// Otherwise, the test would pass even if the throttling didn't match the components' requests.
+	rejected := k8s.RejectedRequests(t, ctx.KubectlOptions(t), throttlingName)
+	logger.Logf(t, "the API server rejected %d requests of the components while they were throttled", rejected)
+	require.NotZero(t, rejected, "the API server didn't reject any requests of the components")
+
+	unthrottle()
+
+	requireFixturesSynced(t, ctx.KubectlOptions(t))
+
+	logger.Log(t, "checking that the injector injects new pods")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+	pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "app=static-server"})
+	require.NoError(t, err)
+	require.Len(t, pods.Items, 1)
+	require.Len(t, pods.Items[0].Spec.Containers, 2)
+
+	for _, component := range components {
+		requireNoRestarts(t, ctx, releaseName, component)
+	}
+}
+
+// requireNoRestarts checks that the pods of component in the release
+// are running and that none of their containers have restarted.
+func requireNoRestarts(t *testing.T, ctx environment.TestContext, releaseName, component string) {
+	t.Helper()
+
+	pods, err := ctx.KubernetesClient(t).CoreV1().Pods(ctx.KubectlOptions(t).Namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("release=%s,component=%s", releaseName, component),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, pods.Items, "no pods for %s", component)
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			require.Zero(t, status.RestartCount, "container %s of pod %s has restarted: last state %+v", status.Name, pod.Name, status.LastTerminationState)
+			require.NotNil(t, status.State.Running, "container %s of pod %s is not running: %+v", status.Name, pod.Name, status.State)
+		}
+	}
+}
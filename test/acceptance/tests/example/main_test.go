@@ -15,6 +15,18 @@ func TestMain(m *testing.M) {
 		suite = framework.NewSuite(m)
 	*/
 
+	// If the tests of the suite share setup that should only happen once,
+	// e.g. creating fixtures, register it with BeforeAll and its teardown with AfterAll.
+	// Uncomment and modify example code below if that is the case.
+	/*
+		suite.BeforeAll(func() error {
+			return createSharedFixtures()
+		})
+		suite.AfterAll(func() error {
+			return deleteSharedFixtures()
+		})
+	*/
+
 	// If the test suite needs to run only when certain test flags are passed,
 	// you need to handle that in the TestMain function.
 	// Uncomment and modify example code below if that is the case.
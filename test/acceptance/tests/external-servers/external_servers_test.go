@@ -0,0 +1,113 @@
+package externalservers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+const staticClientName = "static-client"
+const staticServerName = "static-server"
+
+// serverNamespace is the namespace of the Consul servers that play the role of
+// external servers. They're installed as a separate release in their own namespace
+// because the framework expects one release per namespace.
+const serverNamespace = "consul-external-servers"
+
+// Test that the chart works with Consul servers that it doesn't manage:
+// the servers are installed as a separate release with clients disabled,
+// and a second release with externalServers.* talks to them over TLS,
+// bootstraps ACLs for its components with their bootstrap token,
+// and creates an auth method for connect-inject on them.
+func TestExternalServers(t *testing.T) {
+	cfg := suite.Config()
+	helpers.SkipUnlessTag(t, cfg, config.TagSecure)
+	ctx := suite.Environment().DefaultContext(t)
+
+	logger.Logf(t, "creating namespace %s for the Consul servers", serverNamespace)
+	k8s.RunKubectl(t, ctx.KubectlOptions(t), "create", "ns", serverNamespace)
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "ns", serverNamespace)
+	})
+	serverContext := environment.ContextForNamespace(ctx, serverNamespace)
+
+	serverHelmValues := map[string]string{
+		"client.enabled": "false",
+
+		"global.tls.enabled":           "true",
+		"global.acls.manageSystemACLs": "true",
+	}
+	serverReleaseName := helpers.RandomName()
+	serverCluster := consul.NewHelmCluster(t, serverHelmValues, serverContext, cfg, serverReleaseName)
+	serverCluster.Create(t)
+
+	helmValues := consul.ExternalServersHelmValues(t, serverContext, ctx, serverReleaseName, cfg.NoCleanupOnFailure)
+	helmValues["connectInject.enabled"] = "true"
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	// The release with external servers has no servers to port-forward
+	// to, so the servers are queried through their own release.
+	consulClient := serverCluster.SetupConsulClient(t, true)
+
+	logger.Log(t, "checking that the clients of the release have joined the external servers")
+	members, err := consulClient.Agent().Members(false)
+	require.NoError(t, err)
+	var clients []string
+	for _, member := range members {
+		if member.Tags["role"] == "node" && member.Status == 1 {
+			clients = append(clients, member.Name)
+		}
+	}
+	require.NotEmpty(t, clients)
+
+	logger.Log(t, "creating static-server and static-client deployments")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+	// The injected pods can only register with a token from the auth method
+	// that server-acl-init created on the external servers, and their registration
+	// reaches the servers through the clients over RPC verified with the CA of the servers.
+	logger.Log(t, "checking that the services have logged in with the auth method of the release")
+	authMethodName := fmt.Sprintf("%s-consul-k8s-auth-method", releaseName)
+	_, _, err = consulClient.ACL().AuthMethodRead(authMethodName, nil)
+	require.NoError(t, err)
+	tokens, _, err := consulClient.ACL().TokenList(nil)
+	require.NoError(t, err)
+	loggedIn := make(map[string]bool)
+	for _, token := range tokens {
+		if token.AuthMethod != authMethodName {
+			continue
+		}
+		token, _, err := consulClient.ACL().TokenRead(token.AccessorID, nil)
+		require.NoError(t, err)
+		for _, identity := range token.ServiceIdentities {
+			loggedIn[identity.ServiceName] = true
+		}
+	}
+	require.True(t, loggedIn[staticServerName], "no token for %s from auth method %s", staticServerName, authMethodName)
+	require.True(t, loggedIn[staticClientName], "no token for %s from auth method %s", staticClientName, authMethodName)
+
+	logger.Log(t, "checking that the connection is not successful because there's no intention")
+	k8s.CheckStaticServerConnectionFailing(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+
+	logger.Log(t, "creating intention")
+	_, _, err = consulClient.Connect().IntentionCreate(&api.Intention{
+		SourceName:      staticClientName,
+		DestinationName: staticServerName,
+		Action:          api.IntentionActionAllow,
+	}, nil)
+	require.NoError(t, err)
+
+	logger.Log(t, "checking that connection is successful")
+	k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+}
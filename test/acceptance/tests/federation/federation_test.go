@@ -0,0 +1,164 @@
+package federation
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+const staticClientName = "static-client"
+
+// Test that a primary and a secondary datacenter installed in two different
+// Kubernetes clusters can be federated using the federation secret
+// and that the secondary datacenter is usable from the primary,
+// both via cross-datacenter catalog queries and via Connect traffic
+// routed through mesh gateways.
+func TestFederation(t *testing.T) {
+	cases := []struct {
+		secure bool
+	}{
+		{
+			false,
+		},
+		{
+			true,
+		},
+	}
+
+	cfg := suite.Config()
+	if !cfg.EnableMultiCluster {
+		t.Skipf("skipping this test because -enable-multi-cluster is not set")
+	}
+
+	for _, c := range cases {
+		name := fmt.Sprintf("secure: %t", c.secure)
+		t.Run(name, func(t *testing.T) {
+			env := suite.Environment()
+
+			primaryContext := env.DefaultContext(t)
+			secondaryContext := env.Context(t, environment.SecondaryContextIndex)
+
+			primaryHelmValues := map[string]string{
+				"global.datacenter":                        "dc1",
+				"global.tls.enabled":                       "true",
+				"global.tls.httpsOnly":                     strconv.FormatBool(c.secure),
+				"global.federation.enabled":                "true",
+				"global.federation.createFederationSecret": "true",
+
+				"connectInject.enabled": "true",
+				"controller.enabled":    "true",
+
+				"meshGateway.enabled":  "true",
+				"meshGateway.replicas": "1",
+			}
+
+			if c.secure {
+				primaryHelmValues["global.acls.manageSystemACLs"] = "true"
+				primaryHelmValues["global.acls.createReplicationToken"] = "true"
+			}
+
+			if cfg.UseKind {
+				primaryHelmValues["meshGateway.service.type"] = "NodePort"
+				primaryHelmValues["meshGateway.service.nodePort"] = "30000"
+			}
+
+			releaseName := helpers.RandomName()
+
+			// Install the primary consul cluster in the default kubernetes context
+			primaryConsulCluster := consul.NewHelmCluster(t, primaryHelmValues, primaryContext, cfg, releaseName)
+			primaryConsulCluster.Create(t)
+
+			// Get the federation secret from the primary cluster and apply it to secondary cluster
+			federationSecretName := consul.CopyFederationSecret(t, primaryContext, secondaryContext, releaseName)
+
+			// Create secondary cluster
+			secondaryHelmValues := consul.SecondaryDatacenterHelmValues(federationSecretName, c.secure)
+			secondaryHelmValues["global.datacenter"] = "dc2"
+			secondaryHelmValues["connectInject.enabled"] = "true"
+			secondaryHelmValues["meshGateway.enabled"] = "true"
+			secondaryHelmValues["meshGateway.replicas"] = "1"
+
+			if cfg.UseKind {
+				secondaryHelmValues["meshGateway.service.type"] = "NodePort"
+				secondaryHelmValues["meshGateway.service.nodePort"] = "30000"
+			}
+
+			// Install the secondary consul cluster in the secondary kubernetes context
+			secondaryConsulCluster := consul.NewHelmCluster(t, secondaryHelmValues, secondaryContext, cfg, releaseName)
+			secondaryConsulCluster.Create(t)
+
+			primaryClient := primaryConsulCluster.SetupConsulClient(t, c.secure)
+			secondaryClient := secondaryConsulCluster.SetupConsulClient(t, c.secure)
+
+			logger.Log(t, "checking that both datacenters know about each other")
+			retry.RunWith(&retry.Timer{Timeout: 5 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+				for _, client := range []*api.Client{primaryClient, secondaryClient} {
+					datacenters, err := client.Catalog().Datacenters()
+					require.NoError(r, err)
+					require.ElementsMatch(r, []string{"dc1", "dc2"}, datacenters)
+				}
+			})
+
+			if c.secure {
+				logger.Log(t, "checking that ACL replication is running in the secondary datacenter")
+				retry.RunWith(&retry.Timer{Timeout: 5 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+					replicationStatus, _, err := secondaryClient.ACL().Replication(nil)
+					require.NoError(r, err)
+					require.True(r, replicationStatus.Enabled)
+					require.True(r, replicationStatus.Running)
+				})
+			}
+
+			logger.Log(t, "creating static-server in dc2")
+			k8s.DeployKustomize(t, secondaryContext.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+
+			// Query the catalog of dc2 from the primary datacenter to check that
+			// requests are forwarded to the secondary servers.
+			logger.Log(t, "checking that static-server in dc2 is visible from dc1")
+			retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+				services, _, err := primaryClient.Catalog().Service("static-server", "", &api.QueryOptions{Datacenter: "dc2"})
+				require.NoError(r, err)
+				require.Len(r, services, 1)
+				require.Equal(r, "dc2", services[0].Datacenter)
+			})
+
+			// Create a ProxyDefaults resource to configure services to use the mesh
+			// gateways.
+			logger.Log(t, "creating proxy-defaults config")
+			kustomizeDir := "../fixtures/bases/mesh-gateway"
+			k8s.KubectlApplyK(t, primaryContext.KubectlOptions(t), kustomizeDir)
+			helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+				k8s.KubectlDeleteK(t, primaryContext.KubectlOptions(t), kustomizeDir)
+			})
+
+			logger.Log(t, "creating static-client in dc1")
+			k8s.DeployKustomize(t, primaryContext.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-multi-dc")
+
+			if c.secure {
+				logger.Log(t, "checking that the connection is not successful because there's no intention")
+				k8s.CheckStaticServerConnectionFailing(t, primaryContext.KubectlOptions(t), staticClientName, "http://localhost:1234")
+
+				logger.Log(t, "creating intention")
+				_, _, err := primaryClient.Connect().IntentionCreate(&api.Intention{
+					SourceName:      staticClientName,
+					DestinationName: "static-server",
+					Action:          api.IntentionActionAllow,
+				}, nil)
+				require.NoError(t, err)
+			}
+
+			logger.Log(t, "checking that connection is successful")
+			k8s.CheckStaticServerConnectionSuccessful(t, primaryContext.KubectlOptions(t), staticClientName, "http://localhost:1234")
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package federation
+
+import (
+	"os"
+	"testing"
+
+	testsuite "github.com/hashicorp/consul-helm/test/acceptance/framework/suite"
+)
+
+var suite testsuite.Suite
+
+func TestMain(m *testing.M) {
+	suite = testsuite.NewSuite(m)
+	suite.RequireMinimumNodes(3)
+
+	os.Exit(suite.Run())
+}
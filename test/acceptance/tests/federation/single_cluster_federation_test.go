@@ -0,0 +1,196 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// datacenterNodeLabel is the node label used to pin the pods
+// of each datacenter to its own set of nodes.
+const datacenterNodeLabel = "consul-test-datacenter"
+
+// Test that WAN federation over mesh gateways works between two datacenters
+// installed as two releases in different namespaces of the same Kubernetes cluster.
+// This is a cheaper version of TestFederation that doesn't need two clusters.
+// Client agents use fixed host ports, so the clients of each datacenter,
+// and all pods that talk to them, need to run on their own set of nodes.
+// This means that the cluster needs at least two schedulable nodes, e.g.
+// a kind cluster created with a worker node.
+func TestFederation_SingleCluster(t *testing.T) {
+	env := suite.Environment()
+	cfg := suite.Config()
+	defaultContext := env.DefaultContext(t)
+
+	nodes := schedulableNodes(t, defaultContext)
+	if len(nodes) < 2 {
+		t.Skipf("skipping this test because it needs at least two schedulable nodes but the cluster has %d", len(nodes))
+	}
+	labelNodes(t, defaultContext, nodes[:len(nodes)/2], "dc1", cfg.NoCleanupOnFailure)
+	labelNodes(t, defaultContext, nodes[len(nodes)/2:], "dc2", cfg.NoCleanupOnFailure)
+
+	primaryContext := namespaceContext(t, defaultContext, cfg.NoCleanupOnFailure)
+	secondaryContext := namespaceContext(t, defaultContext, cfg.NoCleanupOnFailure)
+
+	// Releases need different names because the chart
+	// creates cluster-scoped resources named after the release.
+	primaryReleaseName := helpers.RandomName()
+	secondaryReleaseName := helpers.RandomName()
+
+	primaryHelmValues := map[string]string{
+		"global.datacenter":                        "dc1",
+		"global.tls.enabled":                       "true",
+		"global.tls.httpsOnly":                     "false",
+		"global.federation.enabled":                "true",
+		"global.federation.createFederationSecret": "true",
+
+		"connectInject.enabled":               "true",
+		"connectInject.k8sAllowNamespaces[0]": primaryContext.KubectlOptions(t).Namespace,
+		"controller.enabled":                  "true",
+
+		"meshGateway.enabled":  "true",
+		"meshGateway.replicas": "1",
+	}
+	mergeNodeSelectorValues(primaryHelmValues, "dc1")
+
+	if cfg.UseKind {
+		primaryHelmValues["meshGateway.service.type"] = "NodePort"
+		primaryHelmValues["meshGateway.service.nodePort"] = "30000"
+	}
+
+	primaryConsulCluster := consul.NewHelmCluster(t, primaryHelmValues, primaryContext, cfg, primaryReleaseName)
+	primaryConsulCluster.Create(t)
+
+	federationSecretName := consul.CopyFederationSecret(t, primaryContext, secondaryContext, primaryReleaseName)
+
+	secondaryHelmValues := consul.SecondaryDatacenterHelmValues(federationSecretName, false)
+	secondaryHelmValues["global.datacenter"] = "dc2"
+	secondaryHelmValues["connectInject.enabled"] = "true"
+	secondaryHelmValues["connectInject.k8sAllowNamespaces[0]"] = secondaryContext.KubectlOptions(t).Namespace
+	secondaryHelmValues["meshGateway.enabled"] = "true"
+	secondaryHelmValues["meshGateway.replicas"] = "1"
+	mergeNodeSelectorValues(secondaryHelmValues, "dc2")
+
+	if cfg.UseKind {
+		// Both gateways are exposed on the same nodes, so they need different node ports.
+		secondaryHelmValues["meshGateway.service.type"] = "NodePort"
+		secondaryHelmValues["meshGateway.service.nodePort"] = "30001"
+	}
+
+	secondaryConsulCluster := consul.NewHelmCluster(t, secondaryHelmValues, secondaryContext, cfg, secondaryReleaseName)
+	secondaryConsulCluster.Create(t)
+
+	primaryClient := primaryConsulCluster.SetupConsulClient(t, false)
+	secondaryClient := secondaryConsulCluster.SetupConsulClient(t, false)
+
+	logger.Log(t, "checking that both datacenters know about each other")
+	retry.RunWith(&retry.Timer{Timeout: 5 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		secondaryServerHealth, _, err := primaryClient.Health().Node(fmt.Sprintf("%s-consul-server-0", secondaryReleaseName), &api.QueryOptions{Datacenter: "dc2"})
+		require.NoError(r, err)
+		require.Equal(r, api.HealthPassing, secondaryServerHealth.AggregatedStatus())
+
+		primaryServerHealth, _, err := secondaryClient.Health().Node(fmt.Sprintf("%s-consul-server-0", primaryReleaseName), &api.QueryOptions{Datacenter: "dc1"})
+		require.NoError(r, err)
+		require.Equal(r, api.HealthPassing, primaryServerHealth.AggregatedStatus())
+	})
+
+	// Create a ProxyDefaults resource to configure services to use the mesh
+	// gateways.
+	logger.Log(t, "creating proxy-defaults config")
+	kustomizeDir := "../fixtures/bases/mesh-gateway"
+	k8s.KubectlApplyK(t, primaryContext.KubectlOptions(t), kustomizeDir)
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		k8s.KubectlDeleteK(t, primaryContext.KubectlOptions(t), kustomizeDir)
+	})
+
+	logger.Log(t, "creating static-server in dc2")
+	k8s.DeployKustomize(t, secondaryContext.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/single-cluster-federation/static-server-dc2")
+
+	logger.Log(t, "creating static-client in dc1")
+	k8s.DeployKustomize(t, primaryContext.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/single-cluster-federation/static-client-dc1")
+
+	logger.Log(t, "checking that connection is successful")
+	k8s.CheckStaticServerConnectionSuccessful(t, primaryContext.KubectlOptions(t), staticClientName, "http://localhost:1234")
+}
+
+// mergeNodeSelectorValues pins all components of the datacenter that talk to
+// the local client agent, and the client agents themselves, to the nodes of the datacenter.
+func mergeNodeSelectorValues(values map[string]string, datacenter string) {
+	nodeSelector := fmt.Sprintf("%s: %s", datacenterNodeLabel, datacenter)
+	for _, component := range []string{"client", "connectInject", "controller", "meshGateway"} {
+		values[component+".nodeSelector"] = nodeSelector
+	}
+}
+
+// schedulableNodes returns the names of the nodes that pods can be scheduled on,
+// i.e. nodes that are not cordoned or tainted with NoSchedule, such as the control plane of a kind cluster.
+func schedulableNodes(t *testing.T, ctx environment.TestContext) []string {
+	t.Helper()
+
+	nodes, err := ctx.KubernetesClient(t).CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+
+	var names []string
+	for _, node := range nodes.Items {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		schedulable := true
+		for _, taint := range node.Spec.Taints {
+			if taint.Effect == corev1.TaintEffectNoSchedule {
+				schedulable = false
+			}
+		}
+		if schedulable {
+			names = append(names, node.Name)
+		}
+	}
+	return names
+}
+
+// labelNodes labels the given nodes as belonging to the datacenter.
+func labelNodes(t *testing.T, ctx environment.TestContext, nodes []string, datacenter string, noCleanupOnFailure bool) {
+	t.Helper()
+
+	for _, node := range nodes {
+		k8s.RunKubectl(t, ctx.KubectlOptions(t), "label", "node", node, fmt.Sprintf("%s=%s", datacenterNodeLabel, datacenter), "--overwrite")
+	}
+	helpers.Cleanup(t, noCleanupOnFailure, func() {
+		for _, node := range nodes {
+			k8s.RunKubectl(t, ctx.KubectlOptions(t), "label", "node", node, datacenterNodeLabel+"-")
+		}
+	})
+}
+
+// namespaceContext creates a namespace with a random name and returns
+// a test context for it in the same Kubernetes cluster as ctx.
+func namespaceContext(t *testing.T, ctx environment.TestContext, noCleanupOnFailure bool) environment.TestContext {
+	t.Helper()
+
+	namespace := helpers.RandomName()
+	logger.Logf(t, "creating namespace %s", namespace)
+	_, err := ctx.KubernetesClient(t).CoreV1().Namespaces().Create(context.Background(), &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: namespace,
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	helpers.Cleanup(t, noCleanupOnFailure, func() {
+		ctx.KubernetesClient(t).CoreV1().Namespaces().Delete(context.Background(), namespace, metav1.DeleteOptions{})
+	})
+
+	options := ctx.KubectlOptions(t)
+	return environment.NewContext(namespace, options.ConfigPath, options.ContextName)
+}
@@ -5,7 +5,7 @@ import (
 	"strconv"
 	"testing"
 
-	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
@@ -40,6 +40,7 @@ func TestIngressGatewaySingleNamespace(t *testing.T) {
 	for _, c := range cases {
 		name := fmt.Sprintf("secure: %t", c.secure)
 		t.Run(name, func(t *testing.T) {
+			helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, config.TagNamespaces, helpers.TagIf(c.secure, config.TagSecure))
 			ctx := suite.Environment().DefaultContext(t)
 
 			// Install the Helm chart without the ingress gateway first
@@ -84,11 +85,7 @@ func TestIngressGatewaySingleNamespace(t *testing.T) {
 				k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "ns", testNamespace)
 			})
 
-			nsK8SOptions := &terratestk8s.KubectlOptions{
-				ContextName: ctx.KubectlOptions(t).ContextName,
-				ConfigPath:  ctx.KubectlOptions(t).ConfigPath,
-				Namespace:   testNamespace,
-			}
+			nsK8SOptions := ctx.KubectlOptionsForNamespace(t, testNamespace)
 
 			logger.Logf(t, "creating server in %s namespace", testNamespace)
 			k8s.DeployKustomize(t, nsK8SOptions, cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
@@ -174,6 +171,7 @@ func TestIngressGatewayNamespaceMirroring(t *testing.T) {
 	for _, c := range cases {
 		name := fmt.Sprintf("secure: %t", c.secure)
 		t.Run(name, func(t *testing.T) {
+			helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, config.TagNamespaces, helpers.TagIf(c.secure, config.TagSecure))
 			ctx := suite.Environment().DefaultContext(t)
 
 			// Install the Helm chart without the ingress gateway first
@@ -202,11 +200,7 @@ func TestIngressGatewayNamespaceMirroring(t *testing.T) {
 				k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "ns", testNamespace)
 			})
 
-			nsK8SOptions := &terratestk8s.KubectlOptions{
-				ContextName: ctx.KubectlOptions(t).ContextName,
-				ConfigPath:  ctx.KubectlOptions(t).ConfigPath,
-				Namespace:   testNamespace,
-			}
+			nsK8SOptions := ctx.KubectlOptionsForNamespace(t, testNamespace)
 
 			logger.Logf(t, "creating server in %s namespace", testNamespace)
 			k8s.DeployKustomize(t, nsK8SOptions, cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
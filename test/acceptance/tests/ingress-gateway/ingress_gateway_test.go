@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
@@ -35,8 +36,9 @@ func TestIngressGateway(t *testing.T) {
 	for _, c := range cases {
 		name := fmt.Sprintf("secure: %t; auto-encrypt: %t", c.secure, c.autoEncrypt)
 		t.Run(name, func(t *testing.T) {
-			ctx := suite.Environment().DefaultContext(t)
 			cfg := suite.Config()
+			helpers.SkipUnlessInMatrix(t, cfg, config.Matrix{config.MatrixSecure: c.secure, config.MatrixAutoEncrypt: c.autoEncrypt})
+			ctx := suite.Environment().DefaultContext(t)
 			helmValues := map[string]string{
 				"connectInject.enabled":                "true",
 				"ingressGateways.enabled":              "true",
@@ -0,0 +1,170 @@
+package ingressgateway
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// staticServerHost is the host the ingress gateway serves the static-server on.
+	// Hosts of TLS listeners are added to the certificate of the gateway.
+	staticServerHost = "static-server.example.com"
+	// caFile is where the Consul CA certificate is written in the static-client pod.
+	caFile = "/tmp/consul-ca.pem"
+)
+
+// Test we can connect to a service in a mirrored namespace through a TLS listener
+// of the ingress gateway on its external address, i.e. the load balancer or node port
+// of the gateway service, and that the gateway serves a certificate signed by the Consul CA.
+func TestIngressGatewayNamespaces_TLS(t *testing.T) {
+	cfg := suite.Config()
+	if !cfg.EnableEnterprise {
+		t.Skipf("skipping this test because -enable-enterprise is not set")
+	}
+
+	cases := []struct {
+		secure bool
+	}{
+		{
+			false,
+		},
+		{
+			true,
+		},
+	}
+	for _, c := range cases {
+		name := fmt.Sprintf("secure: %t", c.secure)
+		t.Run(name, func(t *testing.T) {
+			ctx := suite.Environment().DefaultContext(t)
+
+			// Kind clusters can't provision load balancers.
+			serviceType := "LoadBalancer"
+			if cfg.UseKind {
+				serviceType = "NodePort"
+			}
+
+			helmValues := map[string]string{
+				"connectInject.enabled":                       "true",
+				"connectInject.consulNamespaces.mirroringK8S": "true",
+
+				"global.enableConsulNamespaces": "true",
+				"global.acls.manageSystemACLs":  strconv.FormatBool(c.secure),
+				"global.tls.enabled":            strconv.FormatBool(c.secure),
+
+				"ingressGateways.enabled":               "true",
+				"ingressGateways.gateways[0].name":      "ingress-gateway",
+				"ingressGateways.gateways[0].replicas":  "1",
+				"ingressGateways.defaults.service.type": serviceType,
+			}
+
+			releaseName := helpers.RandomName()
+			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+			consulCluster.Create(t)
+
+			logger.Logf(t, "creating Kubernetes namespace %s", testNamespace)
+			k8s.RunKubectl(t, ctx.KubectlOptions(t), "create", "ns", testNamespace)
+			helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+				k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "ns", testNamespace)
+			})
+
+			nsK8SOptions := ctx.KubectlOptionsForNamespace(t, testNamespace)
+
+			logger.Logf(t, "creating server in %s namespace", testNamespace)
+			k8s.DeployKustomize(t, nsK8SOptions, cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+
+			// We use the static-client pod to call the external address of the gateway
+			// so that the test doesn't need a route to the nodes from the test machine.
+			logger.Logf(t, "creating static-client in %s namespace", testNamespace)
+			k8s.DeployKustomize(t, nsK8SOptions, cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/static-client")
+
+			consulClient := consulCluster.SetupConsulClient(t, c.secure)
+
+			// Listeners with custom hosts need an HTTP protocol.
+			logger.Log(t, "creating config entries")
+			_, _, err := consulClient.ConfigEntries().Set(&api.ServiceConfigEntry{
+				Kind:      api.ServiceDefaults,
+				Name:      "static-server",
+				Namespace: testNamespace,
+				Protocol:  "http",
+			}, nil)
+			require.NoError(t, err)
+
+			created, _, err := consulClient.ConfigEntries().Set(&api.IngressGatewayConfigEntry{
+				Kind:      api.IngressGateway,
+				Name:      "ingress-gateway",
+				Namespace: "default",
+				TLS:       api.GatewayTLSConfig{Enabled: true},
+				Listeners: []api.IngressListener{
+					{
+						Port:     8443,
+						Protocol: "http",
+						Services: []api.IngressService{
+							{
+								Name:      "static-server",
+								Namespace: testNamespace,
+								Hosts:     []string{staticServerHost},
+							},
+						},
+					},
+				},
+			}, nil)
+			require.NoError(t, err)
+			require.Equal(t, true, created, "config entry failed")
+
+			logger.Log(t, "copying the Consul CA certificate into the static-client pod")
+			roots, _, err := consulClient.Agent().ConnectCARoots(nil)
+			require.NoError(t, err)
+			var caPEM string
+			for _, root := range roots.Roots {
+				if root.Active {
+					caPEM = root.RootCertPEM
+				}
+			}
+			require.NotEmpty(t, caPEM, "no active Consul CA root")
+			_, stderr, err := k8s.RunKubectlWithInputAndGetStdoutStderrE(t, nsK8SOptions, []byte(caPEM),
+				"exec", "-i", "deploy/static-client", "-c", "static-client", "--", "sh", "-c", "cat > "+caFile)
+			require.NoError(t, err, stderr)
+
+			gatewayAddress := k8s.ServiceExternalAddress(t, ctx.KubernetesClient(t), ctx.KubectlOptions(t).Namespace,
+				fmt.Sprintf("%s-consul-ingress-gateway", releaseName), 8443)
+			// Connect to the external address while sending the gateway's host
+			// in the SNI and Host header so that the certificate can be verified.
+			connectTo := fmt.Sprintf("%s:443:%s", staticServerHost, gatewayAddress)
+			url := fmt.Sprintf("https://%s/", staticServerHost)
+
+			// If ACLs are enabled, test that intentions prevent connections.
+			if c.secure {
+				logger.Log(t, "testing intentions prevent ingress")
+				k8s.CheckDeniedAs(t, nsK8SOptions, "static-client", k8s.DeniedL7, "--cacert", caFile, "--connect-to", connectTo, url)
+
+				// Now we create the allow intention.
+				logger.Log(t, "creating ingress-gateway => static-server intention")
+				_, _, err = consulClient.Connect().IntentionCreate(&api.Intention{
+					SourceName:      "ingress-gateway",
+					SourceNS:        "default",
+					DestinationName: "static-server",
+					DestinationNS:   testNamespace,
+					Action:          api.IntentionActionAllow,
+				}, nil)
+				require.NoError(t, err)
+			}
+
+			logger.Log(t, "trying calls to the TLS listener of the ingress gateway")
+			k8s.CheckStaticServerConnectionSuccessful(t, nsK8SOptions, "static-client", "--cacert", caFile, "--connect-to", connectTo, url)
+
+			logger.Log(t, "checking that the gateway certificate isn't trusted without the Consul CA")
+			k8s.CheckStaticServerConnectionMultipleFailureMessages(t, nsK8SOptions, false, "static-client",
+				[]string{"curl: (60)"},
+				"--connect-to", connectTo, url)
+		})
+	}
+}
@@ -12,6 +12,7 @@ var suite testsuite.Suite
 
 func TestMain(m *testing.M) {
 	suite = testsuite.NewSuite(m)
+	suite.RequireMinimumNodes(3)
 
 	if suite.Config().EnableMultiCluster {
 		os.Exit(suite.Run())
@@ -26,7 +26,7 @@ func TestMeshGatewayDefault(t *testing.T) {
 	cfg := suite.Config()
 
 	primaryContext := env.DefaultContext(t)
-	secondaryContext := env.Context(t, environment.SecondaryContextName)
+	secondaryContext := env.Context(t, environment.SecondaryContextIndex)
 
 	primaryHelmValues := map[string]string{
 		"global.datacenter":                        "dc1",
@@ -54,43 +54,14 @@ func TestMeshGatewayDefault(t *testing.T) {
 	primaryConsulCluster.Create(t)
 
 	// Get the federation secret from the primary cluster and apply it to secondary cluster
-	federationSecretName := fmt.Sprintf("%s-consul-federation", releaseName)
-	logger.Logf(t, "retrieving federation secret %s from the primary cluster and applying to the secondary", federationSecretName)
-	federationSecret, err := primaryContext.KubernetesClient(t).CoreV1().Secrets(primaryContext.KubectlOptions(t).Namespace).Get(context.Background(), federationSecretName, metav1.GetOptions{})
-	federationSecret.ResourceVersion = ""
-	require.NoError(t, err)
-	_, err = secondaryContext.KubernetesClient(t).CoreV1().Secrets(secondaryContext.KubectlOptions(t).Namespace).Create(context.Background(), federationSecret, metav1.CreateOptions{})
-	require.NoError(t, err)
+	federationSecretName := consul.CopyFederationSecret(t, primaryContext, secondaryContext, releaseName)
 
 	// Create secondary cluster
-	secondaryHelmValues := map[string]string{
-		"global.datacenter": "dc2",
-
-		"global.tls.enabled":           "true",
-		"global.tls.httpsOnly":         "false",
-		"global.tls.caCert.secretName": federationSecretName,
-		"global.tls.caCert.secretKey":  "caCert",
-		"global.tls.caKey.secretName":  federationSecretName,
-		"global.tls.caKey.secretKey":   "caKey",
-
-		"global.federation.enabled": "true",
-
-		"server.extraVolumes[0].type":          "secret",
-		"server.extraVolumes[0].name":          federationSecretName,
-		"server.extraVolumes[0].load":          "true",
-		"server.extraVolumes[0].items[0].key":  "serverConfigJSON",
-		"server.extraVolumes[0].items[0].path": "config.json",
-
-		// Enterprise license job will fail if it runs in the secondary DC,
-		// so we're explicitly setting these values to empty to avoid that.
-		"server.enterpriseLicense.secretName": "",
-		"server.enterpriseLicense.secretKey":  "",
-
-		"connectInject.enabled": "true",
-
-		"meshGateway.enabled":  "true",
-		"meshGateway.replicas": "1",
-	}
+	secondaryHelmValues := consul.SecondaryDatacenterHelmValues(federationSecretName, false)
+	secondaryHelmValues["global.datacenter"] = "dc2"
+	secondaryHelmValues["connectInject.enabled"] = "true"
+	secondaryHelmValues["meshGateway.enabled"] = "true"
+	secondaryHelmValues["meshGateway.replicas"] = "1"
 
 	if cfg.UseKind {
 		secondaryHelmValues["meshGateway.service.type"] = "NodePort"
@@ -108,6 +79,10 @@ func TestMeshGatewayDefault(t *testing.T) {
 	logger.Log(t, "verifying federation was successful")
 	verifyFederation(t, primaryClient, secondaryClient, releaseName, false)
 
+	logger.Log(t, "verifying that the mesh gateways are registered with their LAN and WAN addresses")
+	checkMeshGatewayAddresses(t, primaryContext, primaryClient, releaseName, cfg.UseKind)
+	checkMeshGatewayAddresses(t, secondaryContext, secondaryClient, releaseName, cfg.UseKind)
+
 	// Create a ProxyDefaults resource to configure services to use the mesh
 	// gateways.
 	logger.Log(t, "creating proxy-defaults config")
@@ -151,7 +126,7 @@ func TestMeshGatewaySecure(t *testing.T) {
 			cfg := suite.Config()
 
 			primaryContext := env.DefaultContext(t)
-			secondaryContext := env.Context(t, environment.SecondaryContextName)
+			secondaryContext := env.Context(t, environment.SecondaryContextIndex)
 
 			primaryHelmValues := map[string]string{
 				"global.datacenter":            "dc1",
@@ -183,48 +158,15 @@ func TestMeshGatewaySecure(t *testing.T) {
 			primaryConsulCluster.Create(t)
 
 			// Get the federation secret from the primary cluster and apply it to secondary cluster
-			federationSecretName := fmt.Sprintf("%s-consul-federation", releaseName)
-			logger.Logf(t, "retrieving federation secret %s from the primary cluster and applying to the secondary", federationSecretName)
-			federationSecret, err := primaryContext.KubernetesClient(t).CoreV1().Secrets(primaryContext.KubectlOptions(t).Namespace).Get(context.Background(), federationSecretName, metav1.GetOptions{})
-			require.NoError(t, err)
-			federationSecret.ResourceVersion = ""
-			_, err = secondaryContext.KubernetesClient(t).CoreV1().Secrets(secondaryContext.KubectlOptions(t).Namespace).Create(context.Background(), federationSecret, metav1.CreateOptions{})
-			require.NoError(t, err)
+			federationSecretName := consul.CopyFederationSecret(t, primaryContext, secondaryContext, releaseName)
 
 			// Create secondary cluster
-			secondaryHelmValues := map[string]string{
-				"global.datacenter": "dc2",
-
-				"global.tls.enabled":           "true",
-				"global.tls.httpsOnly":         "false",
-				"global.tls.enableAutoEncrypt": c.enableAutoEncrypt,
-				"global.tls.caCert.secretName": federationSecretName,
-				"global.tls.caCert.secretKey":  "caCert",
-				"global.tls.caKey.secretName":  federationSecretName,
-				"global.tls.caKey.secretKey":   "caKey",
-
-				"global.acls.manageSystemACLs":            "true",
-				"global.acls.replicationToken.secretName": federationSecretName,
-				"global.acls.replicationToken.secretKey":  "replicationToken",
-
-				"global.federation.enabled": "true",
-
-				"server.extraVolumes[0].type":          "secret",
-				"server.extraVolumes[0].name":          federationSecretName,
-				"server.extraVolumes[0].load":          "true",
-				"server.extraVolumes[0].items[0].key":  "serverConfigJSON",
-				"server.extraVolumes[0].items[0].path": "config.json",
-
-				// Enterprise license job will fail if it runs in the secondary DC,
-				// so we're explicitly setting these values to empty to avoid that.
-				"server.enterpriseLicense.secretName": "",
-				"server.enterpriseLicense.secretKey":  "",
-
-				"connectInject.enabled": "true",
-
-				"meshGateway.enabled":  "true",
-				"meshGateway.replicas": "1",
-			}
+			secondaryHelmValues := consul.SecondaryDatacenterHelmValues(federationSecretName, true)
+			secondaryHelmValues["global.datacenter"] = "dc2"
+			secondaryHelmValues["global.tls.enableAutoEncrypt"] = c.enableAutoEncrypt
+			secondaryHelmValues["connectInject.enabled"] = "true"
+			secondaryHelmValues["meshGateway.enabled"] = "true"
+			secondaryHelmValues["meshGateway.replicas"] = "1"
 
 			if cfg.UseKind {
 				secondaryHelmValues["meshGateway.service.type"] = "NodePort"
@@ -259,7 +201,7 @@ func TestMeshGatewaySecure(t *testing.T) {
 			k8s.DeployKustomize(t, primaryContext.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-multi-dc")
 
 			logger.Log(t, "creating intention")
-			_, _, err = primaryClient.Connect().IntentionCreate(&api.Intention{
+			_, _, err := primaryClient.Connect().IntentionCreate(&api.Intention{
 				SourceName:      staticClientName,
 				DestinationName: "static-server",
 				Action:          api.IntentionActionAllow,
@@ -268,6 +210,9 @@ func TestMeshGatewaySecure(t *testing.T) {
 
 			logger.Log(t, "checking that connection is successful")
 			k8s.CheckStaticServerConnectionSuccessful(t, primaryContext.KubectlOptions(t), staticClientName, "http://localhost:1234")
+
+			logger.Log(t, "checking the locality of ACL tokens in the secondary datacenter")
+			verifySecondaryTokenLocality(t, secondaryContext, secondaryClient, releaseName)
 		})
 	}
 }
@@ -306,3 +251,74 @@ func verifyFederation(t *testing.T, primaryClient, secondaryClient *api.Client,
 
 	logger.Logf(t, "Took %s to verify federation", time.Since(start))
 }
+
+// verifySecondaryTokenLocality checks that ACL tokens created in the secondary datacenter
+// are local or global as expected. Tokens for components that only talk to their own
+// datacenter must be local so that they don't depend on replication from the primary.
+// The mesh gateway token needs to be global because mesh gateways
+// must discover services in other datacenters.
+// It also checks that tokens created by logging in with the auth method, e.g. for
+// injected services, are local since only local tokens can be created in secondary datacenters.
+func verifySecondaryTokenLocality(t *testing.T, secondaryContext environment.TestContext, secondaryClient *api.Client, releaseName string) {
+	t.Helper()
+
+	expectedLocality := map[string]bool{
+		"client":         true,
+		"connect-inject": true,
+		"mesh-gateway":   false,
+	}
+
+	for component, local := range expectedLocality {
+		secretName := fmt.Sprintf("%s-consul-%s-acl-token", releaseName, component)
+		secret, err := secondaryContext.KubernetesClient(t).CoreV1().Secrets(secondaryContext.KubectlOptions(t).Namespace).Get(context.Background(), secretName, metav1.GetOptions{})
+		require.NoError(t, err)
+
+		token, _, err := secondaryClient.ACL().TokenReadSelf(&api.QueryOptions{Token: string(secret.Data["token"])})
+		require.NoError(t, err)
+		require.Equal(t, local, token.Local, "unexpected locality for the %s token in the secondary datacenter", component)
+	}
+
+	// Tokens created by the auth method, e.g. for the static-server, must be local.
+	tokens, _, err := secondaryClient.ACL().TokenList(nil)
+	require.NoError(t, err)
+	authMethodTokens := 0
+	for _, token := range tokens {
+		if token.AuthMethod != "" {
+			authMethodTokens++
+			require.True(t, token.Local, "token %s created by auth method %s is not local", token.AccessorID, token.AuthMethod)
+		}
+	}
+	require.NotZero(t, authMethodTokens, "expected tokens created by the auth method in the secondary datacenter")
+}
+
+// checkMeshGatewayAddresses checks that the mesh gateway of the release in ctx is registered
+// with its pod IP as its LAN address and with the address that other datacenters reach it at
+// as its WAN address: the IP of its node on kind, where it's exposed with a NodePort,
+// and the address of its load balancer otherwise.
+func checkMeshGatewayAddresses(t *testing.T, ctx environment.TestContext, consulClient *api.Client, releaseName string, useKind bool) {
+	t.Helper()
+
+	namespace := ctx.KubectlOptions(t).Namespace
+	pods, err := ctx.KubernetesClient(t).CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("component=mesh-gateway,release=%s", releaseName),
+	})
+	require.NoError(t, err)
+	require.Len(t, pods.Items, 1)
+	pod := pods.Items[0]
+
+	consul.RequireServiceTaggedAddress(t, consulClient, "mesh-gateway", consul.TaggedAddressLAN,
+		api.ServiceAddress{Address: pod.Status.PodIP, Port: 8443}, nil)
+
+	wan := api.ServiceAddress{Address: pod.Status.HostIP, Port: 30000}
+	if !useKind {
+		service, err := ctx.KubernetesClient(t).CoreV1().Services(namespace).Get(context.Background(), fmt.Sprintf("%s-consul-mesh-gateway", releaseName), metav1.GetOptions{})
+		require.NoError(t, err)
+		require.NotEmpty(t, service.Status.LoadBalancer.Ingress, "mesh gateway service has no load balancer address")
+		ingress := service.Status.LoadBalancer.Ingress[0]
+		wan = api.ServiceAddress{Address: ingress.IP, Port: 443}
+		if ingress.IP == "" {
+			wan.Address = ingress.Hostname
+		}
+	}
+	consul.RequireServiceTaggedAddress(t, consulClient, "mesh-gateway", consul.TaggedAddressWAN, wan, nil)
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that the demo Prometheus server installed by the chart scrapes
+// the Consul servers and clients and the merged Envoy and application
+// metrics of injected pods using the annotations that the chart and the
+// injector add to the pods.
+func TestPrometheusScraping(t *testing.T) {
+	env := suite.Environment()
+	cfg := suite.Config()
+	ctx := env.DefaultContext(t)
+
+	helmValues := map[string]string{
+		"global.datacenter":                 "dc1",
+		"global.metrics.enabled":            "true",
+		"global.metrics.enableAgentMetrics": "true",
+
+		"connectInject.enabled":                      "true",
+		"connectInject.metrics.defaultEnableMerging": "true",
+
+		"prometheus.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating static-metrics-app")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/static-metrics-app")
+
+	// Prometheus is queried from the static-client the same way as the metrics endpoints in the other tests.
+	logger.Log(t, "creating static-client")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+	queries := []struct {
+		description string
+		query       string
+	}{
+		{"Consul server metrics", `consul_runtime_alloc_bytes{app="consul",component="server"}`},
+		{"Consul client metrics", `consul_runtime_alloc_bytes{app="consul",component="client"}`},
+		{"Envoy metrics of injected pods", `envoy_cluster_assignment_stale{app="static-metrics-app",local_cluster="server"}`},
+		{"application metrics of injected pods", `service_started_total{app="static-metrics-app"}`},
+	}
+
+	for _, q := range queries {
+		logger.Logf(t, "checking that Prometheus has scraped %s", q.description)
+		// The Prometheus config of the chart scrapes the pods every 15s,
+		// so allow a few scrape intervals for the pods to be discovered and scraped.
+		retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: 5 * time.Second}, t, func(r *retry.R) {
+			require.NotZero(r, prometheusSeriesCount(r, t, ctx, q.query), "no series for %s", q.query)
+		})
+	}
+}
+
+// prometheusSeriesCount returns the number of series that the instant query
+// returns from the Prometheus server installed by the chart.
+func prometheusSeriesCount(r *retry.R, t *testing.T, ctx environment.TestContext, query string) int {
+	output, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "exec", "deploy/"+staticClientName, "-c", staticClientName, "--",
+		"curl", "--silent", "--show-error", "--get", "--data-urlencode", fmt.Sprintf("query=%s", query),
+		fmt.Sprintf("http://prometheus-server.%s.svc/api/v1/query", ctx.KubectlOptions(t).Namespace))
+	require.NoError(r, err, output)
+
+	var response struct {
+		Status string `json:"status"`
+		Data   struct {
+			Result []json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	require.NoError(r, json.Unmarshal([]byte(output), &response), output)
+	require.Equal(r, "success", response.Status, output)
+	return len(response.Data.Result)
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that telemetry configured on the servers through extra config is honored:
+// metrics are sent to a dogstatsd sink, filtered by the prefix filter,
+// and the Prometheus endpoint is enabled by prometheus_retention_time.
+func TestServerTelemetry(t *testing.T) {
+	env := suite.Environment()
+	cfg := suite.Config()
+	ctx := env.DefaultContext(t)
+	ns := ctx.KubectlOptions(t).Namespace
+
+	releaseName := helpers.RandomName()
+
+	// The sink needs to exist before the servers start
+	// because they resolve the dogstatsd address on startup.
+	logger.Log(t, "creating statsd sink")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/statsd-sink")
+
+	// Setting JSON config with --set is error-prone, so we load
+	// the telemetry config into the servers from a config map instead.
+	telemetryConfigName := fmt.Sprintf("%s-telemetry-config", releaseName)
+	telemetryConfig := fmt.Sprintf(`{
+  "telemetry": {
+    "dogstatsd_addr": "statsd-sink.%s.svc:8125",
+    "prometheus_retention_time": "60s",
+    "prefix_filter": ["-consul.raft"]
+  }
+}`, ns)
+	_, err := ctx.KubernetesClient(t).CoreV1().ConfigMaps(ns).Create(context.Background(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: telemetryConfigName},
+		Data:       map[string]string{"telemetry.json": telemetryConfig},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		ctx.KubernetesClient(t).CoreV1().ConfigMaps(ns).Delete(context.Background(), telemetryConfigName, metav1.DeleteOptions{})
+	})
+
+	helmValues := map[string]string{
+		"server.extraVolumes[0].type":          "configMap",
+		"server.extraVolumes[0].name":          telemetryConfigName,
+		"server.extraVolumes[0].load":          "true",
+		"server.extraVolumes[0].items[0].key":  "telemetry.json",
+		"server.extraVolumes[0].items[0].path": "telemetry.json",
+	}
+
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	logger.Log(t, "checking that server metrics arrive at the statsd sink")
+	var sinkOutput string
+	retry.RunWith(&retry.Timer{Timeout: 2 * time.Minute, Wait: 5 * time.Second}, t, func(r *retry.R) {
+		var err error
+		sinkOutput, err = k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "logs", "deploy/statsd-sink")
+		require.NoError(r, err)
+		require.Contains(r, sinkOutput, "consul.runtime.")
+	})
+	require.NotContains(t, sinkOutput, "consul.raft.", "metrics excluded by the prefix filter were sent to the sink")
+
+	logger.Log(t, "checking that the prometheus endpoint is enabled")
+	metricsOutput, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "exec", fmt.Sprintf("%s-consul-server-0", releaseName), "-c", "consul", "--",
+		"wget", "-q", "-O", "-", "http://127.0.0.1:8500/v1/agent/metrics?format=prometheus")
+	require.NoError(t, err)
+	require.Contains(t, metricsOutput, "consul_runtime_")
+	require.NotContains(t, metricsOutput, "consul_raft_")
+}
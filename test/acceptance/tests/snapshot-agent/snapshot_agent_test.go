@@ -0,0 +1,154 @@
+package snapshotagent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/environment"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// snapshotBucket is the bucket created by the minio fixture.
+	snapshotBucket = "consul-snapshots"
+	// snapshotInterval is how often the snapshot agent takes snapshots.
+	snapshotInterval = 20 * time.Second
+)
+
+// Test that the snapshot agent takes snapshots on schedule and uploads them
+// to S3-compatible storage, here a MinIO server, and that the snapshots it
+// uploads can be restored.
+// The snapshot agent is only available in Consul Enterprise.
+func TestSnapshotAgent(t *testing.T) {
+	cfg := suite.Config()
+	if !cfg.EnableEnterprise {
+		t.Skipf("skipping this test because -enable-enterprise is not set")
+	}
+
+	for _, secure := range []bool{false, true} {
+		name := fmt.Sprintf("secure: %t", secure)
+		t.Run(name, func(t *testing.T) {
+			helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, helpers.TagIf(secure, config.TagSecure))
+			ctx := suite.Environment().DefaultContext(t)
+			releaseName := helpers.RandomName()
+
+			logger.Log(t, "creating minio deployment")
+			k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/minio")
+
+			configSecretName := fmt.Sprintf("%s-snapshot-agent-config", releaseName)
+			createSnapshotAgentConfig(t, ctx, cfg.NoCleanupOnFailure, configSecretName)
+
+			helmValues := map[string]string{
+				"client.snapshotAgent.enabled":                 "true",
+				"client.snapshotAgent.replicas":                "1",
+				"client.snapshotAgent.configSecret.secretName": configSecretName,
+				"client.snapshotAgent.configSecret.secretKey":  "config",
+
+				"global.acls.manageSystemACLs": strconv.FormatBool(secure),
+				"global.tls.enabled":           strconv.FormatBool(secure),
+			}
+
+			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+			consulCluster.Create(t)
+
+			consulClient := consulCluster.SetupConsulClient(t, secure)
+
+			logger.Log(t, "writing a key that should be in the next snapshots")
+			_, err := consulClient.KV().Put(&api.KVPair{Key: "snapshot-agent", Value: []byte("before")}, nil)
+			require.NoError(t, err)
+			snapshots, err := listSnapshots(t, ctx)
+			require.NoError(t, err)
+			written := len(snapshots)
+
+			// Wait for a few more snapshots to check that they are taken on schedule.
+			// The first snapshot after the write contains the key.
+			logger.Log(t, "waiting for the snapshot agent to upload snapshots")
+			retry.RunWith(&retry.Timer{Timeout: 4 * snapshotInterval, Wait: 5 * time.Second}, t, func(r *retry.R) {
+				snapshots, err = listSnapshots(t, ctx)
+				require.NoError(r, err)
+				require.GreaterOrEqual(r, len(snapshots), written+3, "expected a snapshot every %s", snapshotInterval)
+			})
+
+			_, err = consulClient.KV().Put(&api.KVPair{Key: "snapshot-agent", Value: []byte("after")}, nil)
+			require.NoError(t, err)
+
+			latest := snapshots[len(snapshots)-1]
+			logger.Logf(t, "restoring snapshot %s", latest)
+			snapshot, stderr, err := k8s.RunKubectlAndGetStdoutStderrE(t, ctx.KubectlOptions(t), "exec", "deploy/minio", "--", "cat", latest)
+			require.NoError(t, err, stderr)
+			require.NoError(t, consulClient.Snapshot().Restore(nil, bytes.NewReader([]byte(snapshot))))
+
+			kv, _, err := consulClient.KV().Get("snapshot-agent", nil)
+			require.NoError(t, err)
+			require.NotNil(t, kv)
+			require.Equal(t, "before", string(kv.Value))
+		})
+	}
+}
+
+// createSnapshotAgentConfig creates the secret with the snapshot agent config
+// that uploads snapshots to the minio fixture.
+func createSnapshotAgentConfig(t *testing.T, ctx environment.TestContext, noCleanupOnFailure bool, secretName string) {
+	t.Helper()
+
+	config, err := json.Marshal(map[string]interface{}{
+		"snapshot_agent": map[string]interface{}{
+			"snapshot": map[string]interface{}{
+				"interval": snapshotInterval.String(),
+				"retain":   100,
+			},
+			"aws_storage": map[string]interface{}{
+				"access_key_id":       "minio",
+				"secret_access_key":   "minio-secret-key",
+				"s3_region":           "us-east-1",
+				"s3_bucket":           snapshotBucket,
+				"s3_endpoint":         fmt.Sprintf("http://minio.%s.svc.cluster.local:9000", ctx.KubectlOptions(t).Namespace),
+				"s3_force_path_style": true,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	secrets := ctx.KubernetesClient(t).CoreV1().Secrets(ctx.KubectlOptions(t).Namespace)
+	_, err = secrets.Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName},
+		StringData: map[string]string{"config": string(config)},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	helpers.Cleanup(t, noCleanupOnFailure, func() {
+		secrets.Delete(context.Background(), secretName, metav1.DeleteOptions{})
+	})
+}
+
+// listSnapshots returns the paths of the snapshots in the minio pod, oldest first.
+// The snapshot names contain the time they were taken at.
+// It returns an error instead of failing the test so that it can be retried.
+func listSnapshots(t *testing.T, ctx environment.TestContext) ([]string, error) {
+	t.Helper()
+
+	output, stderr, err := k8s.RunKubectlAndGetStdoutStderrE(t, ctx.KubectlOptions(t),
+		"exec", "deploy/minio", "--", "find", "/data/"+snapshotBucket, "-type", "f", "-name", "*.snap")
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshots: %s: %s", err, stderr)
+	}
+
+	snapshots := strings.Fields(output)
+	sort.Strings(snapshots)
+	return snapshots, nil
+}
@@ -0,0 +1,95 @@
+package sync
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	allowedNamespace = "sync-allowed"
+	deniedNamespace  = "sync-denied"
+)
+
+// Test that sync catalog only syncs services from the Kubernetes namespaces
+// in k8sAllowNamespaces that aren't in k8sDenyNamespaces.
+func TestSyncCatalog_AllowDenyNamespaces(t *testing.T) {
+	cases := []struct {
+		name            string
+		allowNamespaces string
+		denyNamespaces  string
+	}{
+		{
+			"allow all but deny one namespace",
+			"{*}",
+			fmt.Sprintf("{kube-system,kube-public,%s}", deniedNamespace),
+		},
+		{
+			"allow only one namespace",
+			fmt.Sprintf("{%s}", allowedNamespace),
+			"{}",
+		},
+		{
+			"deny takes precedence over allow",
+			fmt.Sprintf("{%s,%s}", allowedNamespace, deniedNamespace),
+			fmt.Sprintf("{%s}", deniedNamespace),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := suite.Config()
+			ctx := suite.Environment().DefaultContext(t)
+
+			helmValues := map[string]string{
+				"syncCatalog.enabled":            "true",
+				"syncCatalog.k8sAllowNamespaces": c.allowNamespaces,
+				"syncCatalog.k8sDenyNamespaces":  c.denyNamespaces,
+			}
+
+			releaseName := helpers.RandomName()
+			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+			consulCluster.Create(t)
+
+			for _, ns := range []string{allowedNamespace, deniedNamespace} {
+				logger.Logf(t, "creating namespace %s with a static-server service", ns)
+				k8s.RunKubectl(t, ctx.KubectlOptions(t), "create", "ns", ns)
+				ns := ns
+				helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+					k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "ns", ns)
+				})
+				k8s.DeployKustomize(t, ctx.KubectlOptionsForNamespace(t, ns), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/static-server")
+			}
+
+			consulClient := consulCluster.SetupConsulClient(t, false)
+
+			allowedService := fmt.Sprintf("%s-%s", staticServerService, allowedNamespace)
+			deniedService := fmt.Sprintf("%s-%s", staticServerService, deniedNamespace)
+
+			logger.Logf(t, "checking that the service in namespace %s has been synced to Consul", allowedNamespace)
+			var services map[string][]string
+			counter := &retry.Counter{Count: 10, Wait: 5 * time.Second}
+			retry.RunWith(counter, t, func(r *retry.R) {
+				var err error
+				services, _, err = consulClient.Catalog().Services(nil)
+				require.NoError(r, err)
+				if _, ok := services[allowedService]; !ok {
+					r.Errorf("service '%s' is not in Consul's list of services %s", allowedService, services)
+				}
+			})
+
+			// Both services were created before the allowed service was synced,
+			// so by now catalog sync has already seen the denied service.
+			logger.Logf(t, "checking that the service in namespace %s has not been synced to Consul", deniedNamespace)
+			require.NotContains(t, services, deniedService)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package sync
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that the service-meta annotations of a Kubernetes service are synced into
+// the Meta of the Consul service, including annotations added after the service
+// was first synced. This is how operators carry ownership metadata such as teams
+// into the catalog; consul-k8s doesn't sync Kubernetes labels into Meta.
+func TestSyncCatalog_ServiceMetaAnnotations(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"syncCatalog.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating a static-server with a service")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/static-server")
+
+	consulClient := consulCluster.SetupConsulClient(t, false)
+	syncedServiceName := fmt.Sprintf("static-server-%s", ctx.KubectlOptions(t).Namespace)
+
+	logger.Log(t, "checking that the static-server service has been synced to Consul without service meta")
+	retry.RunWith(&retry.Counter{Count: 20, Wait: 5 * time.Second}, t, func(r *retry.R) {
+		services, _, err := consulClient.Catalog().Service(syncedServiceName, "", nil)
+		require.NoError(r, err)
+		require.Len(r, services, 1)
+		require.NotContains(r, services[0].ServiceMeta, "team")
+		require.NotContains(r, services[0].ServiceMeta, "owner")
+	})
+
+	logger.Log(t, "annotating the static-server service with service meta")
+	out, err := k8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "annotate", "service", "static-server",
+		"consul.hashicorp.com/service-meta-team=payments",
+		"consul.hashicorp.com/service-meta-owner=alice")
+	require.NoError(t, err, out)
+
+	logger.Log(t, "checking that the service meta has been synced to Consul")
+	retry.RunWith(&retry.Counter{Count: 20, Wait: 5 * time.Second}, t, func(r *retry.R) {
+		services, _, err := consulClient.Catalog().Service(syncedServiceName, "", nil)
+		require.NoError(r, err)
+		require.Len(r, services, 1)
+		meta := services[0].ServiceMeta
+		require.Equal(r, "payments", meta["team"], "service meta: %v", meta)
+		require.Equal(r, "alice", meta["owner"], "service meta: %v", meta)
+		// The meta that sync catalog adds itself is kept.
+		require.Equal(r, ctx.KubectlOptions(t).Namespace, meta["external-k8s-ns"], "service meta: %v", meta)
+	})
+}
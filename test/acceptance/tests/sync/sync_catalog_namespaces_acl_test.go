@@ -0,0 +1,174 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that in a secure installation, sync catalog creates the Consul namespace
+// that a Kubernetes namespace is mirrored to when it first syncs a service from it,
+// and that it syncs services to the destination namespace with the Kubernetes
+// namespace appended to their names when addK8SNamespaceSuffix is set.
+func TestSyncCatalogNamespaces_CreateNamespacesOnDemand(t *testing.T) {
+	cfg := suite.Config()
+	if !cfg.EnableEnterprise {
+		t.Skipf("skipping this test because -enable-enterprise is not set")
+	}
+
+	cases := []struct {
+		name                 string
+		destinationNamespace string
+		mirrorK8S            bool
+		mirrorK8SPrefix      string
+	}{
+		{
+			"single destination namespace (non-default)",
+			"sync-destination",
+			false,
+			"",
+		},
+		{
+			"mirror k8s namespaces with prefix",
+			"",
+			true,
+			"k8s-",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, config.TagNamespaces, config.TagSecure)
+			ctx := suite.Environment().DefaultContext(t)
+
+			helmValues := map[string]string{
+				"global.enableConsulNamespaces":                           "true",
+				"syncCatalog.enabled":                                     "true",
+				"syncCatalog.consulNamespaces.consulDestinationNamespace": c.destinationNamespace,
+				"syncCatalog.consulNamespaces.mirroringK8S":               strconv.FormatBool(c.mirrorK8S),
+				"syncCatalog.consulNamespaces.mirroringK8SPrefix":         c.mirrorK8SPrefix,
+				"syncCatalog.addK8SNamespaceSuffix":                       "true",
+
+				"global.acls.manageSystemACLs": "true",
+				"global.tls.enabled":           "true",
+			}
+
+			releaseName := helpers.RandomName()
+			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+			consulCluster.Create(t)
+
+			consulClient := consulCluster.SetupConsulClient(t, true)
+
+			consulNamespace := c.destinationNamespace
+			if c.mirrorK8S {
+				consulNamespace = c.mirrorK8SPrefix + staticServerNamespace
+
+				// server-acl-init creates the destination namespace, but mirrored
+				// namespaces can only be created by sync catalog once it sees a service in them.
+				logger.Logf(t, "checking that Consul namespace %s doesn't exist yet", consulNamespace)
+				ns, _, err := consulClient.Namespaces().Read(consulNamespace, nil)
+				require.NoError(t, err)
+				require.Nil(t, ns)
+			}
+
+			staticServerOpts := ctx.KubectlOptionsForNamespace(t, staticServerNamespace)
+
+			logger.Logf(t, "creating namespace %s", staticServerNamespace)
+			k8s.RunKubectl(t, ctx.KubectlOptions(t), "create", "ns", staticServerNamespace)
+			helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+				k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "ns", staticServerNamespace)
+			})
+
+			logger.Log(t, "creating a static-server with a service")
+			k8s.DeployKustomize(t, staticServerOpts, cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/static-server")
+
+			syncedName := fmt.Sprintf("%s-%s", staticServerService, staticServerNamespace)
+			logger.Logf(t, "checking that the service has been synced to Consul namespace %s as %s", consulNamespace, syncedName)
+			counter := &retry.Counter{Count: 10, Wait: 5 * time.Second}
+			retry.RunWith(counter, t, func(r *retry.R) {
+				services, _, err := consulCluster.ConsulClientForNamespace(t, true, consulNamespace).Catalog().Services(nil)
+				require.NoError(r, err)
+				if _, ok := services[syncedName]; !ok {
+					r.Errorf("service '%s' is not in Consul's list of services %s", syncedName, services)
+				}
+			})
+
+			ns, _, err := consulClient.Namespaces().Read(consulNamespace, nil)
+			require.NoError(t, err)
+			require.NotNil(t, ns)
+		})
+	}
+}
+
+// Test that the ACL token of sync catalog can create Consul namespaces
+// only when Consul namespaces are enabled.
+func TestSyncCatalogNamespaces_ACLTokenNamespaceCreation(t *testing.T) {
+	cfg := suite.Config()
+	if !cfg.EnableEnterprise {
+		t.Skipf("skipping this test because -enable-enterprise is not set")
+	}
+
+	cases := []struct {
+		enableNamespaces bool
+	}{
+		{false},
+		{true},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("namespaces enabled: %t", c.enableNamespaces), func(t *testing.T) {
+			helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, helpers.TagIf(c.enableNamespaces, config.TagNamespaces), config.TagSecure)
+			ctx := suite.Environment().DefaultContext(t)
+
+			helmValues := map[string]string{
+				"global.enableConsulNamespaces":             strconv.FormatBool(c.enableNamespaces),
+				"syncCatalog.enabled":                       "true",
+				"syncCatalog.consulNamespaces.mirroringK8S": strconv.FormatBool(c.enableNamespaces),
+
+				"global.acls.manageSystemACLs": "true",
+				"global.tls.enabled":           "true",
+			}
+
+			releaseName := helpers.RandomName()
+			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+			consulCluster.Create(t)
+
+			// This client uses the bootstrap token.
+			consulClient := consulCluster.SetupConsulClient(t, true)
+
+			secretName := fmt.Sprintf("%s-consul-catalog-sync-acl-token", releaseName)
+			logger.Logf(t, "reading ACL token from secret %s", secretName)
+			secret, err := ctx.KubernetesClient(t).CoreV1().Secrets(ctx.KubectlOptions(t).Namespace).Get(context.Background(), secretName, metav1.GetOptions{})
+			require.NoError(t, err)
+			syncToken := string(secret.Data["token"])
+
+			const namespace = "sync-acl-token"
+			logger.Logf(t, "creating Consul namespace %s with the sync catalog token", namespace)
+			_, _, err = consulClient.Namespaces().Create(&api.Namespace{Name: namespace}, &api.WriteOptions{Token: syncToken})
+			if c.enableNamespaces {
+				require.NoError(t, err)
+				helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+					_, err := consulClient.Namespaces().Delete(namespace, nil)
+					require.NoError(t, err)
+				})
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "Permission denied")
+			}
+		})
+	}
+}
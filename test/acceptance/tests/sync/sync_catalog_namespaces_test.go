@@ -5,12 +5,11 @@ import (
 	"testing"
 	"time"
 
-	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
-	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/sdk/testutil/retry"
 	"github.com/stretchr/testify/require"
 )
@@ -63,65 +62,62 @@ func TestSyncCatalogNamespaces(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
+			helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, config.TagNamespaces, helpers.TagIf(c.secure, config.TagSecure))
+			helpers.RunWithRetries(t, 2, func(t *testing.T) {
+				ctx := suite.Environment().DefaultContext(t)
+
+				helmValues := map[string]string{
+					"global.enableConsulNamespaces": "true",
+					"syncCatalog.enabled":           "true",
+					// When mirroringK8S is set, this setting is ignored.
+					"syncCatalog.consulNamespaces.consulDestinationNamespace": c.destinationNamespace,
+					"syncCatalog.consulNamespaces.mirroringK8S":               strconv.FormatBool(c.mirrorK8S),
+					"syncCatalog.addK8SNamespaceSuffix":                       "false",
+
+					"global.acls.manageSystemACLs": strconv.FormatBool(c.secure),
+					"global.tls.enabled":           strconv.FormatBool(c.secure),
+				}
 
-			ctx := suite.Environment().DefaultContext(t)
-
-			helmValues := map[string]string{
-				"global.enableConsulNamespaces": "true",
-				"syncCatalog.enabled":           "true",
-				// When mirroringK8S is set, this setting is ignored.
-				"syncCatalog.consulNamespaces.consulDestinationNamespace": c.destinationNamespace,
-				"syncCatalog.consulNamespaces.mirroringK8S":               strconv.FormatBool(c.mirrorK8S),
-				"syncCatalog.addK8SNamespaceSuffix":                       "false",
-
-				"global.acls.manageSystemACLs": strconv.FormatBool(c.secure),
-				"global.tls.enabled":           strconv.FormatBool(c.secure),
-			}
-
-			releaseName := helpers.RandomName()
-			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
-
-			consulCluster.Create(t)
-
-			staticServerOpts := &terratestk8s.KubectlOptions{
-				ContextName: ctx.KubectlOptions(t).ContextName,
-				ConfigPath:  ctx.KubectlOptions(t).ConfigPath,
-				Namespace:   staticServerNamespace,
-			}
-
-			logger.Logf(t, "creating namespace %s", staticServerNamespace)
-			k8s.RunKubectl(t, ctx.KubectlOptions(t), "create", "ns", staticServerNamespace)
-			helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
-				k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "ns", staticServerNamespace)
-			})
+				releaseName := helpers.RandomName()
+				consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
 
-			logger.Log(t, "creating a static-server with a service")
-			k8s.DeployKustomize(t, staticServerOpts, cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/static-server")
+				consulCluster.Create(t)
 
-			consulClient := consulCluster.SetupConsulClient(t, c.secure)
+				staticServerOpts := ctx.KubectlOptionsForNamespace(t, staticServerNamespace)
 
-			logger.Log(t, "checking that the service has been synced to Consul")
-			var services map[string][]string
-			counter := &retry.Counter{Count: 10, Wait: 5 * time.Second}
+				logger.Logf(t, "creating namespace %s", staticServerNamespace)
+				k8s.RunKubectl(t, ctx.KubectlOptions(t), "create", "ns", staticServerNamespace)
+				helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+					k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "ns", staticServerNamespace)
+				})
 
-			consulNamespace := c.destinationNamespace
-			if c.mirrorK8S {
-				consulNamespace = staticServerNamespace
-			}
+				logger.Log(t, "creating a static-server with a service")
+				k8s.DeployKustomize(t, staticServerOpts, cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/static-server")
 
-			retry.RunWith(counter, t, func(r *retry.R) {
-				var err error
-				services, _, err = consulClient.Catalog().Services(&api.QueryOptions{Namespace: consulNamespace})
-				require.NoError(r, err)
-				if _, ok := services[staticServerService]; !ok {
-					r.Errorf("service '%s' is not in Consul's list of services %s", staticServerService, services)
+				consulNamespace := c.destinationNamespace
+				if c.mirrorK8S {
+					consulNamespace = staticServerNamespace
 				}
+				consulClient := consulCluster.ConsulClientForNamespace(t, c.secure, consulNamespace)
+
+				logger.Log(t, "checking that the service has been synced to Consul")
+				var services map[string][]string
+				counter := &retry.Counter{Count: 10, Wait: 5 * time.Second}
+
+				retry.RunWith(counter, t, func(r *retry.R) {
+					var err error
+					services, _, err = consulClient.Catalog().Services(nil)
+					require.NoError(r, err)
+					if _, ok := services[staticServerService]; !ok {
+						r.Errorf("service '%s' is not in Consul's list of services %s", staticServerService, services)
+					}
+				})
+
+				service, _, err := consulClient.Catalog().Service(staticServerService, "", nil)
+				require.NoError(t, err)
+				require.Equal(t, 1, len(service))
+				require.Equal(t, []string{"k8s"}, service[0].ServiceTags)
 			})
-
-			service, _, err := consulClient.Catalog().Service(staticServerService, "", &api.QueryOptions{Namespace: consulNamespace})
-			require.NoError(t, err)
-			require.Equal(t, 1, len(service))
-			require.Equal(t, []string{"k8s"}, service[0].ServiceTags)
 		})
 	}
 }
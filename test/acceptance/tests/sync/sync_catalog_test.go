@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
@@ -53,35 +54,39 @@ func TestSyncCatalog(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			ctx := suite.Environment().DefaultContext(t)
+			helpers.SkipUnlessTag(t, suite.Config(), helpers.TagIf(c.secure, config.TagSecure))
+			helpers.SkipUnlessInMatrix(t, suite.Config(), config.Matrix{config.MatrixSecure: c.secure})
+			helpers.RunWithRetries(t, 2, func(t *testing.T) {
+				ctx := suite.Environment().DefaultContext(t)
 
-			releaseName := helpers.RandomName()
-			consulCluster := consul.NewHelmCluster(t, c.helmValues, ctx, suite.Config(), releaseName)
+				releaseName := helpers.RandomName()
+				consulCluster := consul.NewHelmCluster(t, c.helmValues, ctx, suite.Config(), releaseName)
 
-			consulCluster.Create(t)
+				consulCluster.Create(t)
 
-			logger.Log(t, "creating a static-server with a service")
-			k8s.DeployKustomize(t, ctx.KubectlOptions(t), suite.Config().NoCleanupOnFailure, suite.Config().DebugDirectory, "../fixtures/bases/static-server")
+				logger.Log(t, "creating a static-server with a service")
+				k8s.DeployKustomize(t, ctx.KubectlOptions(t), suite.Config().NoCleanupOnFailure, suite.Config().DebugDirectory, "../fixtures/bases/static-server")
 
-			consulClient := consulCluster.SetupConsulClient(t, c.secure)
+				consulClient := consulCluster.SetupConsulClient(t, c.secure)
 
-			logger.Log(t, "checking that the service has been synced to Consul")
-			var services map[string][]string
-			syncedServiceName := fmt.Sprintf("static-server-%s", ctx.KubectlOptions(t).Namespace)
-			counter := &retry.Counter{Count: 10, Wait: 5 * time.Second}
-			retry.RunWith(counter, t, func(r *retry.R) {
-				var err error
-				services, _, err = consulClient.Catalog().Services(nil)
-				require.NoError(r, err)
-				if _, ok := services[syncedServiceName]; !ok {
-					r.Errorf("service '%s' is not in Consul's list of services %s", syncedServiceName, services)
-				}
-			})
+				logger.Log(t, "checking that the service has been synced to Consul")
+				var services map[string][]string
+				syncedServiceName := fmt.Sprintf("static-server-%s", ctx.KubectlOptions(t).Namespace)
+				counter := &retry.Counter{Count: 10, Wait: 5 * time.Second}
+				retry.RunWith(counter, t, func(r *retry.R) {
+					var err error
+					services, _, err = consulClient.Catalog().Services(nil)
+					require.NoError(r, err)
+					if _, ok := services[syncedServiceName]; !ok {
+						r.Errorf("service '%s' is not in Consul's list of services %s", syncedServiceName, services)
+					}
+				})
 
-			service, _, err := consulClient.Catalog().Service(syncedServiceName, "", nil)
-			require.NoError(t, err)
-			require.Equal(t, 1, len(service))
-			require.Equal(t, []string{"k8s"}, service[0].ServiceTags)
+				service, _, err := consulClient.Catalog().Service(syncedServiceName, "", nil)
+				require.NoError(t, err)
+				require.Equal(t, 1, len(service))
+				require.Equal(t, []string{"k8s"}, service[0].ServiceTags)
+			})
 		})
 	}
 }
@@ -0,0 +1,98 @@
+package sync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Test that sync from Consul to Kubernetes creates ExternalName services
+// for Consul services, and that it doesn't take over or delete a user-created
+// Kubernetes service with the same name as a Consul service.
+func TestSyncCatalogToK8s_ConflictWithUserService(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+	client := ctx.KubernetesClient(t)
+	namespace := ctx.KubectlOptions(t).Namespace
+
+	helmValues := map[string]string{
+		"syncCatalog.enabled":  "true",
+		"syncCatalog.toConsul": "false",
+		"syncCatalog.toK8S":    "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	consulClient := consulCluster.SetupConsulClient(t, false)
+
+	const syncedServiceName = "consul-only-service"
+	const conflictingServiceName = "conflicting-service"
+
+	logger.Logf(t, "creating Kubernetes service %s", conflictingServiceName)
+	_, err := client.CoreV1().Services(namespace).Create(context.Background(), &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: conflictingServiceName,
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeClusterIP,
+			Ports: []corev1.ServicePort{
+				{Port: 80, TargetPort: intstr.FromInt(8080)},
+			},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+		client.CoreV1().Services(namespace).Delete(context.Background(), conflictingServiceName, metav1.DeleteOptions{})
+	})
+
+	for _, name := range []string{syncedServiceName, conflictingServiceName} {
+		logger.Logf(t, "registering Consul service %s", name)
+		_, err := consulClient.Catalog().Register(&api.CatalogRegistration{
+			Node:    "external-node",
+			Address: "192.0.2.1",
+			Service: &api.AgentService{
+				ID:      name,
+				Service: name,
+				Port:    8080,
+			},
+		}, nil)
+		require.NoError(t, err)
+	}
+
+	syncedService := k8s.WaitForSyncedService(t, client, namespace, syncedServiceName)
+	k8s.RequireSyncedFromConsul(t, syncedService, syncedServiceName, "consul")
+
+	// Both services are synced in the same pass, so by now
+	// catalog sync has already seen the conflicting service.
+	conflictingService, err := client.CoreV1().Services(namespace).Get(context.Background(), conflictingServiceName, metav1.GetOptions{})
+	require.NoError(t, err)
+	k8s.RequireNotOwnedBySync(t, conflictingService, corev1.ServiceTypeClusterIP)
+
+	for _, name := range []string{syncedServiceName, conflictingServiceName} {
+		logger.Logf(t, "deregistering Consul service %s", name)
+		_, err := consulClient.Catalog().Deregister(&api.CatalogDeregistration{
+			Node:      "external-node",
+			ServiceID: name,
+		}, nil)
+		require.NoError(t, err)
+	}
+
+	k8s.WaitForSyncedServiceDeleted(t, client, namespace, syncedServiceName)
+
+	// Deleting the synced service happens in the same pass
+	// as deciding whether to delete the conflicting service.
+	conflictingService, err = client.CoreV1().Services(namespace).Get(context.Background(), conflictingServiceName, metav1.GetOptions{})
+	require.NoError(t, err, "user-created service %s was deleted by catalog sync", conflictingServiceName)
+	k8s.RequireNotOwnedBySync(t, conflictingService, corev1.ServiceTypeClusterIP)
+}
@@ -0,0 +1,76 @@
+package terminatinggateway
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+// Test the documented workflow for terminating gateways when the chart manages ACLs:
+// the token that the chart creates for the gateway can't front any services, so
+// connections through the gateway fail even if intentions allow them, until a policy
+// with service:write for the fronted services is added to the token.
+func TestTerminatingGateway_ServiceWritePolicy(t *testing.T) {
+	ctx := suite.Environment().DefaultContext(t)
+	cfg := suite.Config()
+
+	helmValues := map[string]string{
+		"connectInject.enabled":                    "true",
+		"terminatingGateways.enabled":              "true",
+		"terminatingGateways.gateways[0].name":     "terminating-gateway",
+		"terminatingGateways.gateways[0].replicas": "1",
+
+		"global.acls.manageSystemACLs": "true",
+		"global.tls.enabled":           "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	// Deploy a static-server that will play the role of an external service.
+	logger.Log(t, "creating static-server deployment")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/static-server")
+
+	consulClient := consulCluster.SetupConsulClient(t, true)
+	registerExternalService(t, consulClient, "")
+	createTerminatingGatewayConfigEntry(t, consulClient, "", "")
+
+	logger.Log(t, "deploying static client")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+	logger.Log(t, "creating static-client => static-server intention")
+	_, _, err := consulClient.Connect().IntentionCreate(&api.Intention{
+		SourceName:      staticClientName,
+		DestinationName: staticServerName,
+		Action:          api.IntentionActionAllow,
+	}, nil)
+	require.NoError(t, err)
+
+	logger.Log(t, "checking that the gateway can't front static-server without service:write")
+	k8s.CheckStaticServerConnectionFailing(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+
+	tokenBefore := consul.TerminatingGatewayToken(t, consulClient, "terminating-gateway")
+	consul.AddTerminatingGatewayPolicy(t, consulClient, "terminating-gateway", "static-server-write-policy", consul.ServiceWriteRules("", staticServerName))
+
+	logger.Log(t, "checking that the policy has been added to the token of the gateway")
+	tokenAfter := consul.TerminatingGatewayToken(t, consulClient, "terminating-gateway")
+	require.Equal(t, tokenBefore.AccessorID, tokenAfter.AccessorID)
+	var policyNames []string
+	for _, policy := range tokenAfter.Policies {
+		policyNames = append(policyNames, policy.Name)
+	}
+	require.Contains(t, policyNames, "static-server-write-policy")
+	// The policies that the chart created for the gateway are kept.
+	for _, policy := range tokenBefore.Policies {
+		require.Contains(t, policyNames, policy.Name)
+	}
+
+	logger.Log(t, "checking that the gateway fronts static-server with service:write")
+	k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+}
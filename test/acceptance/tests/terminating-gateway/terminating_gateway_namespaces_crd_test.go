@@ -0,0 +1,180 @@
+package terminatinggateway
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// Test we can connect through the terminating gateway when it is configured
+// with a TerminatingGateway custom resource instead of through the Consul API,
+// and the terminating gateway, the external service, and the connect service
+// are in the same non-default namespace.
+func TestTerminatingGatewayNamespaces_CRD(t *testing.T) {
+	cfg := suite.Config()
+	if !cfg.EnableEnterprise {
+		t.Skipf("skipping this test because -enable-enterprise is not set")
+	}
+
+	cases := []struct {
+		secure bool
+	}{
+		{
+			false,
+		},
+		{
+			true,
+		},
+	}
+	for _, c := range cases {
+		name := fmt.Sprintf("secure: %t", c.secure)
+		t.Run(name, func(t *testing.T) {
+			helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, config.TagNamespaces, helpers.TagIf(c.secure, config.TagSecure))
+			ctx := suite.Environment().DefaultContext(t)
+
+			// Install the Helm chart without the terminating gateway first
+			// so that we can create the namespace for it.
+			helmValues := map[string]string{
+				"connectInject.enabled": "true",
+				"connectInject.consulNamespaces.consulDestinationNamespace": testNamespace,
+				"controller.enabled": "true",
+
+				"global.enableConsulNamespaces": "true",
+				"global.acls.manageSystemACLs":  strconv.FormatBool(c.secure),
+				"global.tls.enabled":            strconv.FormatBool(c.secure),
+			}
+
+			releaseName := helpers.RandomName()
+			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+
+			consulCluster.Create(t)
+
+			consulClient := consulCluster.SetupConsulClient(t, c.secure)
+
+			// Create the destination namespace in the non-secure case.
+			// In the secure installation, this namespace is created by the server-acl-init job.
+			if !c.secure {
+				logger.Logf(t, "creating the %s namespace in Consul", testNamespace)
+				_, _, err := consulClient.Namespaces().Create(&api.Namespace{
+					Name: testNamespace,
+				}, nil)
+				require.NoError(t, err)
+			}
+
+			logger.Log(t, "upgrading with terminating gateways enabled")
+			consulCluster.Upgrade(t, map[string]string{
+				"terminatingGateways.enabled":                     "true",
+				"terminatingGateways.gateways[0].name":            "terminating-gateway",
+				"terminatingGateways.gateways[0].replicas":        "1",
+				"terminatingGateways.gateways[0].consulNamespace": testNamespace,
+			})
+
+			logger.Logf(t, "creating Kubernetes namespace %s", testNamespace)
+			k8s.RunKubectl(t, ctx.KubectlOptions(t), "create", "ns", testNamespace)
+			helpers.Cleanup(t, cfg.NoCleanupOnFailure, func() {
+				k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "ns", testNamespace)
+			})
+
+			nsK8SOptions := ctx.KubectlOptionsForNamespace(t, testNamespace)
+
+			// Deploy a static-server that will play the role of an external service.
+			logger.Log(t, "creating static-server deployment")
+			k8s.DeployKustomize(t, nsK8SOptions, cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/static-server")
+
+			// Register the external service. The namespace already exists,
+			// so only register the service in it.
+			registerExternalServiceInNamespace(t, consulClient, testNamespace)
+
+			// If ACLs are enabled we need to update the token of the terminating gateway
+			// with service:write permissions to the static-server service
+			// so that it can can request Connect certificates for it.
+			if c.secure {
+				consul.AddTerminatingGatewayPolicy(t, consulClient, "terminating-gateway", "static-server-write-policy", consul.ServiceWriteRules(testNamespace, staticServerName))
+			}
+
+			// Create the custom resource for the terminating gateway. The controller
+			// writes it to the consulDestinationNamespace.
+			createTerminatingGatewayCustomResource(t, nsK8SOptions, cfg.NoCleanupOnFailure, testNamespace)
+			retry.RunWith(&retry.Timer{Timeout: 1 * time.Minute, Wait: 2 * time.Second}, t, func(r *retry.R) {
+				entry, _, err := consulCluster.ConsulClientForNamespace(t, c.secure, testNamespace).ConfigEntries().Get(api.TerminatingGateway, "terminating-gateway", nil)
+				require.NoError(r, err)
+				terminatingGatewayEntry, ok := entry.(*api.TerminatingGatewayConfigEntry)
+				require.True(r, ok, "could not cast to TerminatingGatewayConfigEntry")
+				require.Len(r, terminatingGatewayEntry.Services, 1)
+				require.Equal(r, staticServerName, terminatingGatewayEntry.Services[0].Name)
+				require.Equal(r, testNamespace, terminatingGatewayEntry.Services[0].Namespace)
+			})
+
+			// Deploy the static client.
+			logger.Log(t, "deploying static client")
+			k8s.DeployKustomize(t, nsK8SOptions, cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-namespaces")
+
+			// If ACLs are enabled, test that intentions prevent connections.
+			if c.secure {
+				// With the terminating gateway up, we test that we can make a call to it
+				// via the static-server. It should fail to connect with the
+				// static-server pod because of intentions.
+				assertNoConnectionAndAddIntention(t, consulClient, nsK8SOptions, testNamespace, testNamespace)
+			}
+
+			// Test that we can make a call to the terminating gateway.
+			logger.Log(t, "trying calls to terminating gateway")
+			k8s.CheckStaticServerConnectionSuccessful(t, nsK8SOptions, staticClientName, "http://localhost:1234")
+		})
+	}
+}
+
+// registerExternalServiceInNamespace registers the static-server as an external
+// service in an existing Consul namespace.
+func registerExternalServiceInNamespace(t *testing.T, consulClient *api.Client, namespace string) {
+	t.Helper()
+
+	logger.Log(t, "registering the external service")
+	_, err := consulClient.Catalog().Register(&api.CatalogRegistration{
+		Node:     "legacy_node",
+		Address:  fmt.Sprintf("%s.%s", staticServerName, namespace),
+		NodeMeta: map[string]string{"external-node": "true", "external-probe": "true"},
+		Service: &api.AgentService{
+			ID:        staticServerName,
+			Service:   staticServerName,
+			Port:      80,
+			Namespace: namespace,
+		},
+	}, nil)
+	require.NoError(t, err)
+}
+
+// createTerminatingGatewayCustomResource applies a TerminatingGateway resource
+// linking the static-server in serviceNamespace to the terminating gateway,
+// and deletes it when the test finishes.
+func createTerminatingGatewayCustomResource(t *testing.T, k8sOptions *terratestk8s.KubectlOptions, noCleanupOnFailure bool, serviceNamespace string) {
+	t.Helper()
+
+	logger.Log(t, "creating terminating-gateway custom resource")
+	resource := fmt.Sprintf(`apiVersion: consul.hashicorp.com/v1alpha1
+kind: TerminatingGateway
+metadata:
+  name: terminating-gateway
+spec:
+  services:
+    - name: %s
+      namespace: %s
+`, staticServerName, serviceNamespace)
+	_, stderr, err := k8s.RunKubectlWithInputAndGetStdoutStderrE(t, k8sOptions, []byte(resource), "apply", "-f", "-")
+	require.NoError(t, err, stderr)
+
+	helpers.Cleanup(t, noCleanupOnFailure, func() {
+		k8s.RunKubectl(t, k8sOptions, "delete", "terminatinggateway", "terminating-gateway")
+	})
+}
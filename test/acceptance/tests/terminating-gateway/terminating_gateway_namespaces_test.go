@@ -5,7 +5,7 @@ import (
 	"strconv"
 	"testing"
 
-	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
@@ -40,6 +40,7 @@ func TestTerminatingGatewaySingleNamespace(t *testing.T) {
 	for _, c := range cases {
 		name := fmt.Sprintf("secure: %t", c.secure)
 		t.Run(name, func(t *testing.T) {
+			helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, config.TagNamespaces, helpers.TagIf(c.secure, config.TagSecure))
 			ctx := suite.Environment().DefaultContext(t)
 
 			// Install the Helm chart without the terminating gateway first
@@ -84,11 +85,7 @@ func TestTerminatingGatewaySingleNamespace(t *testing.T) {
 				k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "ns", testNamespace)
 			})
 
-			nsK8SOptions := &terratestk8s.KubectlOptions{
-				ContextName: ctx.KubectlOptions(t).ContextName,
-				ConfigPath:  ctx.KubectlOptions(t).ConfigPath,
-				Namespace:   testNamespace,
-			}
+			nsK8SOptions := ctx.KubectlOptionsForNamespace(t, testNamespace)
 
 			// Deploy a static-server that will play the role of an external service.
 			logger.Log(t, "creating static-server deployment")
@@ -101,7 +98,7 @@ func TestTerminatingGatewaySingleNamespace(t *testing.T) {
 			// with service:write permissions to the static-server service
 			// so that it can can request Connect certificates for it.
 			if c.secure {
-				updateTerminatingGatewayToken(t, consulClient, fmt.Sprintf(staticServerPolicyRulesNamespace, testNamespace))
+				consul.AddTerminatingGatewayPolicy(t, consulClient, "terminating-gateway", "static-server-write-policy", consul.ServiceWriteRules(testNamespace, staticServerName))
 			}
 
 			// Create the config entry for the terminating gateway.
@@ -150,6 +147,7 @@ func TestTerminatingGatewayNamespaceMirroring(t *testing.T) {
 	for _, c := range cases {
 		name := fmt.Sprintf("secure: %t", c.secure)
 		t.Run(name, func(t *testing.T) {
+			helpers.SkipUnlessTag(t, cfg, config.TagEnterprise, config.TagNamespaces, helpers.TagIf(c.secure, config.TagSecure))
 			ctx := suite.Environment().DefaultContext(t)
 
 			// Install the Helm chart without the terminating gateway first
@@ -187,16 +185,8 @@ func TestTerminatingGatewayNamespaceMirroring(t *testing.T) {
 				k8s.RunKubectl(t, ctx.KubectlOptions(t), "delete", "ns", staticClientNamespace)
 			})
 
-			ns1K8SOptions := &terratestk8s.KubectlOptions{
-				ContextName: ctx.KubectlOptions(t).ContextName,
-				ConfigPath:  ctx.KubectlOptions(t).ConfigPath,
-				Namespace:   testNamespace,
-			}
-			ns2K8SOptions := &terratestk8s.KubectlOptions{
-				ContextName: ctx.KubectlOptions(t).ContextName,
-				ConfigPath:  ctx.KubectlOptions(t).ConfigPath,
-				Namespace:   staticClientNamespace,
-			}
+			ns1K8SOptions := ctx.KubectlOptionsForNamespace(t, testNamespace)
+			ns2K8SOptions := ctx.KubectlOptionsForNamespace(t, staticClientNamespace)
 
 			// Deploy a static-server that will play the role of an external service.
 			logger.Log(t, "creating static-server deployment")
@@ -209,7 +199,7 @@ func TestTerminatingGatewayNamespaceMirroring(t *testing.T) {
 			// with service:write permissions to the static-server service
 			// so that it can can request Connect certificates for it.
 			if c.secure {
-				updateTerminatingGatewayToken(t, consulClient, fmt.Sprintf(staticServerPolicyRulesNamespace, testNamespace))
+				consul.AddTerminatingGatewayPolicy(t, consulClient, "terminating-gateway", "static-server-write-policy", consul.ServiceWriteRules(testNamespace, staticServerName))
 			}
 
 			// Create the config entry for the terminating gateway
@@ -233,8 +223,3 @@ func TestTerminatingGatewayNamespaceMirroring(t *testing.T) {
 		})
 	}
 }
-
-const staticServerPolicyRulesNamespace = `namespace %q {
-service "static-server" {
-  policy = "write"
-}}`
@@ -0,0 +1,82 @@
+package terminatinggateway
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that services that are not part of the service mesh but are synced
+// to Consul by catalog sync can be called from injected services through
+// a terminating gateway, with intentions enforced. This is the documented
+// pattern for adopting the service mesh in an existing cluster.
+func TestTerminatingGateway_SyncCatalog(t *testing.T) {
+	for _, secure := range []bool{false, true} {
+		name := fmt.Sprintf("secure: %t", secure)
+		t.Run(name, func(t *testing.T) {
+			ctx := suite.Environment().DefaultContext(t)
+			cfg := suite.Config()
+
+			helmValues := map[string]string{
+				"connectInject.enabled":                    "true",
+				"terminatingGateways.enabled":              "true",
+				"terminatingGateways.gateways[0].name":     "terminating-gateway",
+				"terminatingGateways.gateways[0].replicas": "1",
+
+				// Only sync Kubernetes services to Consul and register them
+				// under their Kubernetes name so that the static-client's
+				// upstream resolves to the synced static-server.
+				"syncCatalog.enabled":               "true",
+				"syncCatalog.toK8S":                 "false",
+				"syncCatalog.addK8SNamespaceSuffix": "false",
+
+				"global.acls.manageSystemACLs": strconv.FormatBool(secure),
+				"global.tls.enabled":           strconv.FormatBool(secure),
+			}
+
+			logger.Log(t, "creating consul cluster")
+			releaseName := helpers.RandomName()
+			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+			consulCluster.Create(t)
+
+			// Deploy a static-server without a sidecar. Catalog sync registers
+			// its endpoints in Consul.
+			logger.Log(t, "creating static-server deployment")
+			k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/bases/static-server")
+
+			consulClient := consulCluster.SetupConsulClient(t, secure)
+
+			logger.Log(t, "checking that the static-server has been synced to Consul")
+			retry.RunWith(&retry.Counter{Count: 20, Wait: 3 * time.Second}, t, func(r *retry.R) {
+				instances, _, err := consulClient.Catalog().Service(staticServerName, "", nil)
+				require.NoError(r, err)
+				require.Len(r, instances, 1)
+				require.Equal(r, []string{"k8s"}, instances[0].ServiceTags)
+			})
+
+			if secure {
+				consul.AddTerminatingGatewayPolicy(t, consulClient, "terminating-gateway", "static-server-write-policy", consul.ServiceWriteRules("", staticServerName))
+			}
+
+			createTerminatingGatewayConfigEntry(t, consulClient, "", "")
+
+			logger.Log(t, "deploying static client")
+			k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+			if secure {
+				assertNoConnectionAndAddIntention(t, consulClient, ctx.KubectlOptions(t), "", "")
+			}
+
+			logger.Log(t, "trying calls to the synced static-server through the terminating gateway")
+			k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+		})
+	}
+}
@@ -3,10 +3,10 @@ package terminatinggateway
 import (
 	"fmt"
 	"strconv"
-	"strings"
 	"testing"
 
 	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
 	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
@@ -40,8 +40,9 @@ func TestTerminatingGateway(t *testing.T) {
 	for _, c := range cases {
 		name := fmt.Sprintf("secure: %t, auto-encrypt: %t", c.secure, c.autoEncrypt)
 		t.Run(name, func(t *testing.T) {
-			ctx := suite.Environment().DefaultContext(t)
 			cfg := suite.Config()
+			helpers.SkipUnlessInMatrix(t, cfg, config.Matrix{config.MatrixSecure: c.secure, config.MatrixAutoEncrypt: c.autoEncrypt})
+			ctx := suite.Environment().DefaultContext(t)
 
 			helmValues := map[string]string{
 				"connectInject.enabled":                    "true",
@@ -73,7 +74,7 @@ func TestTerminatingGateway(t *testing.T) {
 			// with service:write permissions to the static-server service
 			// so that it can can request Connect certificates for it.
 			if c.secure {
-				updateTerminatingGatewayToken(t, consulClient, staticServerPolicyRules)
+				consul.AddTerminatingGatewayPolicy(t, consulClient, "terminating-gateway", "static-server-write-policy", consul.ServiceWriteRules("", staticServerName))
 			}
 
 			// Create the config entry for the terminating gateway.
@@ -98,10 +99,6 @@ func TestTerminatingGateway(t *testing.T) {
 	}
 }
 
-const staticServerPolicyRules = `service "static-server" {
-  policy = "write"
-}`
-
 func registerExternalService(t *testing.T, consulClient *api.Client, namespace string) {
 	t.Helper()
 
@@ -133,35 +130,6 @@ func registerExternalService(t *testing.T, consulClient *api.Client, namespace s
 	require.NoError(t, err)
 }
 
-func updateTerminatingGatewayToken(t *testing.T, consulClient *api.Client, rules string) {
-	t.Helper()
-
-	// Create a write policy for the static-server.
-	_, _, err := consulClient.ACL().PolicyCreate(&api.ACLPolicy{
-		Name:  "static-server-write-policy",
-		Rules: rules,
-	}, nil)
-	require.NoError(t, err)
-
-	// Get the terminating gateway token.
-	tokens, _, err := consulClient.ACL().TokenList(nil)
-	require.NoError(t, err)
-	var termGwTokenID string
-	for _, token := range tokens {
-		if strings.Contains(token.Description, "terminating-gateway-terminating-gateway-token") {
-			termGwTokenID = token.AccessorID
-			break
-		}
-	}
-	termGwToken, _, err := consulClient.ACL().TokenRead(termGwTokenID, nil)
-	require.NoError(t, err)
-
-	// Add policy to the token and update it
-	termGwToken.Policies = append(termGwToken.Policies, &api.ACLTokenPolicyLink{Name: "static-server-write-policy"})
-	_, _, err = consulClient.ACL().TokenUpdate(termGwToken, nil)
-	require.NoError(t, err)
-}
-
 func createTerminatingGatewayConfigEntry(t *testing.T, consulClient *api.Client, gwNamespace, serviceNamespace string) {
 	t.Helper()
 
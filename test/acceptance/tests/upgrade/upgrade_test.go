@@ -0,0 +1,98 @@
+package upgrade
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/config"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/consul"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-helm/test/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+const staticClientName = "static-client"
+const staticServerName = "static-server"
+
+// Test that upgrading from the previous release of the chart, given by
+// -upgrade-from-chart-version, to the chart under test doesn't interrupt
+// Connect traffic between workloads that were deployed before the upgrade.
+func TestUpgrade_FromPreviousChart(t *testing.T) {
+	cases := []bool{false, true}
+
+	for _, secure := range cases {
+		name := fmt.Sprintf("secure: %t", secure)
+		t.Run(name, func(t *testing.T) {
+			cfg := suite.Config()
+			if cfg.UpgradeFromChartVersion == "" {
+				t.Skipf("skipping this test because -upgrade-from-chart-version is not set")
+			}
+			helpers.SkipUnlessTag(t, cfg, helpers.TagIf(secure, config.TagSecure))
+			helpers.SkipUnlessInMatrix(t, cfg, config.Matrix{config.MatrixSecure: secure})
+			ctx := suite.Environment().DefaultContext(t)
+
+			helmValues := map[string]string{
+				"connectInject.enabled": "true",
+
+				"global.tls.enabled":           strconv.FormatBool(secure),
+				"global.acls.manageSystemACLs": strconv.FormatBool(secure),
+			}
+
+			// Install the released chart with its own images, since the templates of
+			// the released chart may not work with the images under test.
+			previousCfg := *cfg
+			previousCfg.HelmChartPath = config.ReleasedHelmChart
+			previousCfg.HelmChartVersion = cfg.UpgradeFromChartVersion
+			previousCfg.ConsulImage = ""
+			previousCfg.ConsulK8SImage = ""
+			previousCfg.EnvoyImage = ""
+
+			releaseName := helpers.RandomName()
+			logger.Logf(t, "installing %s %s", config.ReleasedHelmChart, cfg.UpgradeFromChartVersion)
+			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, &previousCfg, releaseName)
+			consulCluster.Create(t)
+			// Restarting the servers and clients causes leader elections and dropped connections.
+			consulCluster.AllowErrorLogs(t, "No cluster leader", "agent.server.raft", "agent.server.memberlist", "rpc error", "EOF")
+
+			logger.Log(t, "creating static-server and static-client deployments")
+			k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-server-inject")
+			k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, "../fixtures/cases/static-client-inject")
+
+			if secure {
+				logger.Log(t, "creating static-client => static-server intention")
+				consulClient := consulCluster.SetupConsulClient(t, true)
+				_, _, err := consulClient.Connect().IntentionCreate(&api.Intention{
+					SourceName:      staticClientName,
+					DestinationName: staticServerName,
+					Action:          api.IntentionActionAllow,
+				}, nil)
+				require.NoError(t, err)
+			}
+
+			logger.Log(t, "checking that connection is successful")
+			k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+
+			probe := k8s.StartConnectionProbe(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+
+			// The upgrade switches to the images under test.
+			upgradeValues, err := cfg.HelmValuesFromConfig()
+			require.NoError(t, err)
+			consulCluster.UpgradeToChart(t, cfg.ChartPath(), cfg.HelmChartVersion, upgradeValues)
+
+			// Keep probing for a while after the rollouts have completed so that
+			// sidecars that reconnect to restarted clients are covered too.
+			time.Sleep(30 * time.Second)
+			requests, failures := probe.Stop()
+
+			require.Empty(t, failures, "requests failed during the chart upgrade")
+			require.NotZero(t, requests)
+
+			logger.Log(t, "checking that connection is successful after the upgrade")
+			k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), staticClientName, "http://localhost:1234")
+		})
+	}
+}